@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+// applyTokenInputs are the options that decide which operations a move will
+// execute, as captured by an apply token (with --dry-run-apply).
+type applyTokenInputs struct {
+	MirrorRoot            string   `json:"mirror"`
+	RealRoot              string   `json:"target"`
+	Excludes              []string `json:"exclude"`
+	Includes              []string `json:"include"`
+	ExcludesRel           []string `json:"exclude_rel"`
+	ExcludeNames          []string `json:"exclude_name"`
+	ExcludeNameIgnoreCase bool     `json:"exclude_name_ignore_case"`
+	SystemDirNames        []string `json:"system_dir_names"`
+	ExcludeGlobsMirror    []string `json:"exclude_glob_mirror"`
+	ExcludeGlobsTarget    []string `json:"exclude_glob_target"`
+	Direct                bool     `json:"direct"`
+	SkipEmpty             bool     `json:"skip_empty"`
+	RemoveEmpty           bool     `json:"remove_empty"`
+	MoveOrder             string   `json:"move_order"`
+	TypeChange            string   `json:"type_change"`
+	RenameRules           []string `json:"rename_rules"`
+	OnDuplicateTarget     string   `json:"on_duplicate_target"`
+	Overwrite             string   `json:"overwrite"`
+}
+
+// applyToken returns a token (a hex-encoded hash) capturing the inputs of a
+// move and a snapshot of the mirror structure (the relative path, type, size
+// and modification time of each of its elements). A real run given the token
+// of a reviewed --dry-run (with --apply-token) only proceeds if it still
+// matches, so that what executes is what was reviewed.
+func (prog *program) applyToken(ctx context.Context) (string, error) {
+	hasher := sha256.New()
+	enc := json.NewEncoder(hasher)
+
+	// The rules themselves are captured, rather than the path of their file.
+	renameRules := make([]string, 0, len(prog.renameRules))
+	for _, rule := range prog.renameRules {
+		renameRules = append(renameRules, rule.pattern.String()+" "+renameRuleSeparator+" "+rule.replacement)
+	}
+
+	if err := enc.Encode(applyTokenInputs{
+		MirrorRoot:            prog.opts.MirrorRoot,
+		RealRoot:              prog.opts.RealRoot,
+		Excludes:              prog.opts.Excludes,
+		Includes:              prog.opts.Includes,
+		ExcludesRel:           prog.opts.ExcludesRel,
+		ExcludeNames:          prog.opts.ExcludeNames,
+		ExcludeNameIgnoreCase: prog.opts.ExcludeNameIgnoreCase,
+		SystemDirNames:        prog.opts.SystemDirNames,
+		ExcludeGlobsMirror:    prog.opts.ExcludeGlobsMirror,
+		ExcludeGlobsTarget:    prog.opts.ExcludeGlobsTarget,
+		Direct:                prog.opts.Direct,
+		SkipEmpty:             prog.opts.SkipEmpty,
+		RemoveEmpty:           prog.opts.RemoveEmpty,
+		MoveOrder:             prog.opts.MoveOrder,
+		TypeChange:            prog.opts.TypeChange,
+		RenameRules:           renameRules,
+		OnDuplicateTarget:     prog.opts.OnDuplicateTarget,
+		Overwrite:             prog.opts.Overwrite,
+	}); err != nil {
+		return "", fmt.Errorf("failed to encode: %w", err)
+	}
+
+	// The walk is in lexical order, so the same structure always hashes the same.
+	if err := afero.Walk(prog.fsys, prog.opts.MirrorRoot, func(path string, e os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed checking context: %w", err)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to walk: %q (%w)", path, err)
+		}
+
+		if _, excluded := matchExclude(path, prog.opts.Excludes, prog.opts.CaseInsensitivePaths); excluded {
+			// Excluded elements (such as the program's own files) are never moved.
+			if e.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		relPath, err := filepath.Rel(prog.opts.MirrorRoot, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %q (%w)", path, err)
+		}
+
+		fmt.Fprintf(hasher, "%q %t %d %d\n", relPath, e.IsDir(), e.Size(), e.ModTime().UnixNano())
+
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// checkApplyToken creates the apply token of a --dry-run (with --dry-run-apply),
+// or refuses a real run whose --apply-token no longer matches the current state.
+func (prog *program) checkApplyToken(ctx context.Context) error {
+	token, err := prog.applyToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	if prog.opts.DryRunApply {
+		prog.log.Info("apply token created", "op", prog.opts.Mode, "token", token, "hint", "pass it as --apply-token to the real run")
+
+		return nil
+	}
+
+	if token != prog.opts.ApplyToken {
+		return fmt.Errorf("%w: %q (given) != %q (current)", errApplyTokenMismatch, prog.opts.ApplyToken, token)
+	}
+	prog.log.Info("apply token matched", "op", prog.opts.Mode, "token", token)
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Expectation: A real run should proceed with the apply token of its dry run, if nothing changed since.
+func Test_Unit_MoveFiles_ApplyToken_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/dir/file.txt": "test content",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	dryOpts := &programOptions{
+		Mode:        "move",
+		MirrorRoot:  "/mirror",
+		RealRoot:    "/real",
+		DryRun:      true,
+		DryRunApply: true,
+	}
+
+	dryProg, _, stderr := setupTestProgram(fs, dryOpts)
+	err = dryProg.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, stderr.String(), "apply token created")
+
+	token, err := dryProg.applyToken(t.Context())
+	require.NoError(t, err)
+	require.Contains(t, stderr.String(), token)
+
+	opts := &programOptions{
+		Mode:       "move",
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		ApplyToken: token,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 1, prog.state.movedFiles)
+
+	_, err = fs.Stat("/real/dir/file.txt")
+	require.NoError(t, err)
+}
+
+// Expectation: A real run should be refused with the apply token of its dry run, if the mirror changed since.
+func Test_Unit_MoveFiles_ApplyTokenChangedMirror_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/dir/file.txt": "test content",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	dryOpts := &programOptions{
+		Mode:        "move",
+		MirrorRoot:  "/mirror",
+		RealRoot:    "/real",
+		DryRun:      true,
+		DryRunApply: true,
+	}
+
+	dryProg, _, _ := setupTestProgram(fs, dryOpts)
+	token, err := dryProg.applyToken(t.Context())
+	require.NoError(t, err)
+
+	// A file arrives in the mirror after the dry run was reviewed.
+	err = createFiles(fs, map[string]string{
+		"/mirror/dir/late.txt": "late content",
+	})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		Mode:       "move",
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		ApplyToken: token,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.ErrorIs(t, err, errApplyTokenMismatch)
+	require.Zero(t, prog.state.movedFiles)
+
+	for _, path := range []string{"/mirror/dir/file.txt", "/mirror/dir/late.txt"} {
+		_, err = fs.Stat(path)
+		require.NoError(t, err)
+	}
+	_, err = fs.Stat("/real/dir")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The apply token should change with the options deciding the operations of a move.
+func Test_Unit_ApplyToken_ChangedOptions_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/dir/file.txt": "test content",
+	})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		Mode:       "move",
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	token, err := prog.applyToken(t.Context())
+	require.NoError(t, err)
+
+	again, err := prog.applyToken(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, token, again)
+
+	prog.opts.Excludes = []string{"/mirror/dir"}
+	excluded, err := prog.applyToken(t.Context())
+	require.NoError(t, err)
+	require.NotEqual(t, token, excluded)
+}
@@ -1,21 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/lmittmann/tint"
+	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
 )
 
 func (prog *program) parseArgs(cliArgs []string) error {
 	var (
-		yamlFile string
-		yamlOpts programOptions
+		yamlFile  string
+		configDir string
+		yamlOpts  programOptions
 	)
 
 	// Set any non-zero default values for the configuration.
@@ -23,31 +31,145 @@ func (prog *program) parseArgs(cliArgs []string) error {
 	yamlOpts.InitDepth = defaultInitDepth
 	yamlOpts.LogLevel = strings.ToLower(defaultLogLevel.String())
 	yamlOpts.SkipEmpty = true
+	yamlOpts.MoveOrder = moveOrderWalk
+	yamlOpts.OnDuplicateTarget = duplicateTargetFirstWins
+	yamlOpts.Overwrite = overwriteNever
+	yamlOpts.VerifyReadError = verifyReadErrorFail
+	yamlOpts.VerifyConcurrency = 1
+	yamlOpts.CaseCollision = caseCollisionNone
+	yamlOpts.TypeChange = typeChangeFail
+	yamlOpts.DedupeRun = dedupeRunNone
+	yamlOpts.CompareBy = compareByHash
+	yamlOpts.HeartbeatInterval = defaultHeartbeatInterval
+	yamlOpts.WatchQuietPeriod = defaultWatchQuietPeriod
+	yamlOpts.ProgressInterval = defaultProgressInterval
+	yamlOpts.LogFormat = logFormatText
+	yamlOpts.PathEncoding = pathEncodingEscape
+	yamlOpts.ReportFormat = reportFormatJSON
 
 	prog.flags = flag.NewFlagSet("mirrorshuttle", flag.ExitOnError)
 	prog.flags.SetOutput(prog.stderr)
 	prog.flags.Usage = func() {
 		fmt.Fprintf(prog.stderr, "usage: %q --mode=init|move --mirror=ABSPATH --target=ABSPATH\n", cliArgs[0])
-		fmt.Fprintf(prog.stderr, "\t[--exclude=ABSPATH] [--exclude=ABSPATH] [--direct] [--verify] [--skip-empty] [--remove-empty]\n")
-		fmt.Fprintf(prog.stderr, "\t[--skip-failed=false] [--slow-mode] [--init-depth=NUM] [--dry-run] [--log-level=debug|info|warn|error] [--json]\n\n")
+		fmt.Fprintf(prog.stderr, "   or: %q --mode=check --manifest=PATH --target=ABSPATH\n", cliArgs[0])
+		fmt.Fprintf(prog.stderr, "   or: %q --validate-config=PATH\n", cliArgs[0])
+		fmt.Fprintf(prog.stderr, "   or: %q --explain-exit=NUM\n", cliArgs[0])
+		fmt.Fprintf(prog.stderr, "\t[--exclude=ABSPATH] [--include=ABSPATH] [--exclude-rel=RELPATH] [--exclude-name=NAME] [--exclude-name-ignore-case] [--skip-system-dirs] [--system-dir-names=NAME] [--exclude-glob-mirror=PATTERN] [--exclude-glob-target=PATTERN] [--list-excluded] [--direct] [--bulk-rename-dirs] [--verify] [--verify-read-error=fail|retry|skip] [--verify-concurrency=NUM] [--deferred-verify] [--stat-before-remove] [--no-clobber-working-file] [--update-metadata-on-match] [--overwrite=never|always|if-newer|if-different] [--target-trash-dir=ABSPATH] [--type-change=skip|fail] [--inherit-parent-perms] [--lock-promoted] [--quarantine-dir=ABSPATH] [--quarantine-source] [--compare-by=hash|size|mtime] [--copy-buffer-size=BYTES] [--atomic-batch] [--dedupe-run=none|link|skip] [--hash-algorithms=sha256,blake3] [--checksum-algo=sha256|blake3|crc32c] [--checksum-sidecar] [--skip-empty] [--remove-empty] [--clean-mirror-on-success] [--post-move-command=TEMPLATE] [--plan-out=PATH|--plan-in=PATH] [--dry-run-reproducible] [--list-plan-only] [--dry-run-apply|--apply-token=TOKEN] [--mirror-manifest=PATH] [--mirror-readme=NAME] [--mirror-readme-from=PATH] [--verify-target-structure=PATH] [--since-file=PATH] [--rename-rules=PATH] [--on-duplicate-target=fail|first-wins|rename] [--move-order=walk|depth-first-leaves]\n")
+		fmt.Fprintf(prog.stderr, "\t[--skip-failed=false] [--skip-failed-max=NUM] [--per-file-timeout=DURATION] [--no-fail-fast] [--graceful-interrupt] [--watch --watch-interval=DURATION] [--watch-events] [--watch-quiet-period=DURATION] [--slow-mode] [--dir-rate=NUM] [--init-depth=NUM] [--init-depth-rule=RELPATH:NUM] [--init-changed-since=DURATION] [--init-merge] [--preserve-dir-times] [--skip-empty-target-dirs] [--init-skip-dirs-over=BYTES] [--two-phase-init] [--diff-exit] [--target-glob=PATTERN] [--case-collision=none|merge|warn|fail] [--case-insensitive-paths] [--umask=OCTAL] [--dry-run] [--log-level=debug|info|warn|error] [--log-format=text|json|logfmt] [--log-source] [--run-id=ID] [--trace-spans] [--explain-config] [--path-encoding=escape|base64] [--result-json] [--summary-template=TEMPLATE] [--report=PATH] [--report-format=json|csv]\n")
+		fmt.Fprintf(prog.stderr, "\t[--allow-symlinked-target] [--allow-mountpoint-mirror] [--assume-empty-mirror] [--create-target-root] [--probe-writable] [--max-load=NUM] [--require-target-perms=[max:]OCTAL] [--heartbeat-file=PATH] [--heartbeat-interval=DURATION] [--stats-interval=DURATION] [--progress-file=PATH] [--progress-interval=DURATION]\n\n")
 		prog.flags.PrintDefaults()
+		fmt.Fprintln(prog.stderr)
+		printExitCodes(prog.stderr)
 	}
 
-	prog.flags.StringVar(&prog.opts.Mode, "mode", "", "operation mode: 'init' or 'move'; always needed")
+	prog.flags.StringVar(&prog.opts.Mode, "mode", "", "operation mode: 'init', 'move' or 'check'; always needed")
 	prog.flags.StringVar(&yamlFile, "config", "", "path to a yaml configuration file; used with the specified mode")
+	prog.flags.StringVar(&configDir, "config-dir", "", "path to a directory of yaml configuration files; merged in lexical order over any --config")
+	prog.flags.StringVar(&prog.opts.ValidateConfig, "validate-config", "", "path to a yaml configuration file to only validate; reports all problems and exits")
+	prog.flags.BoolVar(&prog.opts.ExplainConfig, "explain-config", false, "log the final value of each option along with its origin; 'cli', 'config' or 'default'")
+	prog.flags.StringVar(&prog.opts.ExplainExit, "explain-exit", "", "return code to print the description of; exits without any operation")
 	prog.flags.StringVar(&prog.opts.MirrorRoot, "mirror", "", "absolute path to the mirror structure to create; files will be moved *from* here")
 	prog.flags.StringVar(&prog.opts.RealRoot, "target", "", "absolute path to the real structure to mirror; files will be moved *to* here")
-	prog.flags.Var(&prog.opts.Excludes, "exclude", "absolute path to exclude; can be repeated multiple times")
+	prog.flags.BoolVar(&prog.opts.AllowSymlinkedTarget, "allow-symlinked-target", false, "resolve a --target that is a symbolic link, instead of refusing to operate on it")
+	prog.flags.BoolVar(&prog.opts.AllowMountpointMirror, "allow-mountpoint-mirror", false, "remove and re-create a --mirror that is a mount point in --mode=init, instead of only clearing its contents")
+	prog.flags.BoolVar(&prog.opts.AssumeEmptyMirror, "assume-empty-mirror", false, "unsafe: remove an existing --mirror in --mode=init without checking it for files first; only if its emptiness is managed externally")
+	prog.flags.BoolVar(&prog.opts.CreateTargetRoot, "create-target-root", false, "create a missing --target root in --mode=move, instead of failing; could mask an unmounted target volume")
+	prog.flags.BoolVar(&prog.opts.ProbeWritable, "probe-writable", false, "confirm the target is writable (with a probe file) before any files are moved in --mode=move")
+	prog.flags.Float64Var(&prog.opts.MaxLoad, "max-load", 0, "defer the run (with its own return code) if the 1-minute system load average exceeds this at the start; 0 disables it")
+	prog.flags.StringVar(&prog.opts.RequireTargetPerms, "require-target-perms", "", "octal permissions the --target root must have exactly, such as 0755, or at most with a 'max:' prefix; fails otherwise")
+	prog.flags.Var(&prog.opts.Excludes, "exclude", "absolute path (or glob pattern, such as *.tmp; ** matches any number of components) to exclude; can be repeated multiple times")
+	prog.flags.Var(&prog.opts.Includes, "include", "absolute path to restrict operations to (along with its parents); excludes still take precedence; can be repeated")
+	prog.flags.Var(&prog.opts.ExcludesRel, "exclude-rel", "path to exclude relative to --target in --mode=init, or to --mirror in --mode=move; can be repeated")
+	prog.flags.Var(&prog.opts.ExcludeNames, "exclude-name", "exact file or directory name to exclude at any depth, such as Thumbs.db; can be repeated")
+	prog.flags.Var(&prog.opts.ExcludeGlobsMirror, "exclude-glob-mirror", "pattern of mirror paths to not move in --mode=move, such as *.partial; checked only on the mirror side; can be repeated")
+	prog.flags.Var(&prog.opts.ExcludeGlobsTarget, "exclude-glob-target", "pattern of target paths to not mirror in --mode=init or move into in --mode=move; checked only on the target side; can be repeated")
+	prog.flags.BoolVar(&prog.opts.ExcludeNameIgnoreCase, "exclude-name-ignore-case", false, "match the --exclude-name case-insensitively")
+	prog.flags.BoolVar(&prog.opts.SkipSystemDirs, "skip-system-dirs", false, "exclude well-known system directories (such as @eaDir or #recycle) at any depth; see --system-dir-names")
+	prog.flags.Var(&prog.opts.SystemDirNames, "system-dir-names", "directory name to exclude with --skip-system-dirs, instead of the well-known ones; can be repeated")
+	prog.flags.BoolVar(&prog.opts.ListExcluded, "list-excluded", false, "output each walked path that was matched by any exclude, along with the matching exclude")
 	prog.flags.BoolVar(&prog.opts.Direct, "direct", false, "use atomic rename when possible; fallback to copy and remove if it fails or crosses filesystems")
+	prog.flags.BoolVar(&prog.opts.BulkRenameDirs, "bulk-rename-dirs", false, "rename a whole mirror directory in one operation when its target directory is absent and all of it would be moved; falls back to per file")
 	prog.flags.BoolVar(&prog.opts.Verify, "verify", false, "verify again the hash of a target file after moving it; requires an extra full read of the file")
+	prog.flags.StringVar(&prog.opts.VerifyReadError, "verify-read-error", verifyReadErrorFail, "handling of read errors in the --verify pass; 'fail', 'retry' (up to 3 times) or 'skip' (the verification)")
+	prog.flags.IntVar(&prog.opts.VerifyConcurrency, "verify-concurrency", 1, "number of --verify (or --deferred-verify) passes to run concurrently; the files themselves are still moved one by one")
+	prog.flags.BoolVar(&prog.opts.DeferredVerify, "deferred-verify", false, "verify all moved files in one sweep after the move, instead of each before its source is removed; failures are only reported")
+	prog.flags.BoolVar(&prog.opts.StatBeforeRemove, "stat-before-remove", false, "confirm the size of a target file matches its source before removing the source; cheaper than --verify")
+	prog.flags.BoolVar(&prog.opts.NoClobberWorkingFile, "no-clobber-working-file", false, "skip any file whose working file already exists, instead of overwriting it; for concurrent runs")
+	prog.flags.StringVar(&prog.opts.Overwrite, "overwrite", overwriteNever, "handling of an existing target file in --mode=move; 'never' (skip it), 'always', 'if-newer' (source modified later) or 'if-different' (in content)")
+	prog.flags.StringVar(&prog.opts.TargetTrashDir, "target-trash-dir", "", "absolute path to relocate the existing target files into before these are overwritten (with --overwrite), instead of replacing them")
+	prog.flags.BoolVar(&prog.opts.UpdateMetadataOnMatch, "update-metadata-on-match", false, "for an existing target file identical in content, apply the source times and remove the source")
+	prog.flags.StringVar(&prog.opts.TypeChange, "type-change", typeChangeFail, "handling of target paths that changed between directory and file since the mirror was created; 'skip' or 'fail'")
+	prog.flags.BoolVar(&prog.opts.InheritParentPerms, "inherit-parent-perms", false, "create the target directories in --mode=move with the permissions of their existing parent")
+	prog.flags.BoolVar(&prog.opts.LockPromoted, "lock-promoted", false, "remove all write permissions from each file after it was moved (and verified) into the target")
+	prog.flags.StringVar(&prog.opts.QuarantineDir, "quarantine-dir", "", "absolute path to relocate the destination files of failed moves into for inspection, instead of removing them")
+	prog.flags.BoolVar(&prog.opts.QuarantineSource, "quarantine-source", false, "also relocate the sources of failed moves into the --quarantine-dir")
+	prog.flags.StringVar(&prog.opts.CompareBy, "compare-by", compareByHash, "basis on which an existing target file differs from the source before hashing; 'hash', 'size' or 'mtime'")
+	prog.flags.StringVar(&prog.opts.CopyBufferSize, "copy-buffer-size", "", "size of the buffer for copying files, such as 1MiB; between 4KiB and 256MiB; unset uses the default of 32KiB")
+	prog.flags.BoolVar(&prog.opts.AtomicBatch, "atomic-batch", false, "copy all files first, then rename them all in a final commit phase; nothing is committed if any copy fails")
+	prog.flags.StringVar(&prog.opts.DedupeRun, "dedupe-run", dedupeRunNone, "handling of files identical in content to a file already moved in the same run; 'none', 'link' (hard-link to it) or 'skip'")
+	prog.flags.BoolVar(&prog.opts.ChecksumSidecar, "checksum-sidecar", false, "write a sha256sum-compatible <name>.sha256 sidecar next to each moved file in --mode=move; sidecars of other files are never moved")
+	prog.flags.Var(&prog.opts.HashAlgorithms, "hash-algorithms", "comma-separated hashing algorithms for moved files; sha256, sha512, blake3, crc32c; the first is used for comparisons")
+	prog.flags.StringVar(&prog.opts.ChecksumAlgo, "checksum-algo", "", "hashing algorithm for comparing moved files and any --verify pass; sha256, blake3, crc32c; unset uses the first of --hash-algorithms")
 	prog.flags.BoolVar(&prog.opts.SkipEmpty, "skip-empty", true, "do not move empty directories; avoids accidental re-creations of (target) deletions")
 	prog.flags.BoolVar(&prog.opts.RemoveEmpty, "remove-empty", false, "remove empty directories that do not exist on target in --mode=move; --skip-empty needed")
+	prog.flags.BoolVar(&prog.opts.CleanMirror, "clean-mirror-on-success", false, "remove the empty mirror directories after a fully successful --mode=move; keeps the mirror root")
+	prog.flags.StringVar(&prog.opts.PostMoveCommand, "post-move-command", "", "command to run after each file moved in --mode=move; {src}, {dst} and {hash} are substituted")
+	prog.flags.StringVar(&prog.opts.PlanOut, "plan-out", "", "path to write the plan of a --mode=move --dry-run to; the plan can then be approved and used with --plan-in")
+	prog.flags.BoolVar(&prog.opts.DryRunReproducible, "dry-run-reproducible", false, "output only the sorted operations of a --mode=move --dry-run to stdout, without any timestamps; for diffing previews")
+	prog.flags.BoolVar(&prog.opts.ListPlanOnly, "list-plan-only", false, "output only the ordered operations of a --mode=move --dry-run to stdout, including the skips and their reasons; no logs below errors")
+	prog.flags.BoolVar(&prog.opts.DryRunApply, "dry-run-apply", false, "output a token of a --mode=move --dry-run, capturing its options and the mirror, for --apply-token")
+	prog.flags.StringVar(&prog.opts.ApplyToken, "apply-token", "", "token of a reviewed --dry-run-apply; the move is refused if the options or the mirror changed since")
+	prog.flags.StringVar(&prog.opts.PlanIn, "plan-in", "", "path to an approved plan to execute in --mode=move; fails if the filesystem has diverged from it")
+	prog.flags.StringVar(&prog.opts.MirrorManifest, "mirror-manifest", "", "path to record the mirror directories to in --mode=init, and to check the mirror against in --mode=move")
+	prog.flags.StringVar(&prog.opts.MirrorReadme, "mirror-readme", "", "name of a file explaining the staging area to write into the mirror root in --mode=init, such as README.txt; never moved")
+	prog.flags.StringVar(&prog.opts.MirrorReadmeFrom, "mirror-readme-from", "", "path to a file with the content for the --mirror-readme; unset uses a default explanation")
+	prog.flags.StringVar(&prog.opts.VerifyTargetStructure, "verify-target-structure", "", "path to record the target directories to in --mode=init; --mode=move refuses to run if they diverged")
+	prog.flags.StringVar(&prog.opts.SinceFile, "since-file", "", "path to record the start of a successful --mode=move in; the next move only considers files changed since then")
+	prog.flags.StringVar(&prog.opts.RenameRules, "rename-rules", "", "path to a file of 'regex => replacement' lines, transforming the relative paths of moved files into their target locations")
+	prog.flags.StringVar(&prog.opts.OnDuplicateTarget, "on-duplicate-target", duplicateTargetFirstWins, "handling of files transformed by --rename-rules onto the same target path; 'fail', 'first-wins' or 'rename'")
+	prog.flags.StringVar(&prog.opts.MoveOrder, "move-order", moveOrderWalk, "order of operations in --mode=move; 'walk' or 'depth-first-leaves' (files before empty directories)")
 	prog.flags.BoolVar(&prog.opts.SkipFailed, "skip-failed", false, "do not exit on non-fatal failures; skip failed element and proceed instead")
+	prog.flags.IntVar(&prog.opts.SkipFailedMax, "skip-failed-max", 0, "abort once more than this many failures were skipped with --skip-failed; 0 skips any number of them")
+	prog.flags.DurationVar(&prog.opts.PerFileTimeout, "per-file-timeout", 0, "abort the copy of any single file taking longer than the duration, failing only that file (or the --atomic-batch); 0 disables the timeout")
+	prog.flags.BoolVar(&prog.opts.NoFailFast, "no-fail-fast", false, "do not exit on non-fatal failures, but still exit with failure after all elements were processed")
+	prog.flags.BoolVar(&prog.opts.Watch, "watch", false, "keep running and re-run --mode=move in cycles, every --watch-interval; an interrupt signal stops after the current cycle")
+	prog.flags.DurationVar(&prog.opts.WatchInterval, "watch-interval", 0, "interval to wait between the cycles of a --watch, such as 15m")
+	prog.flags.BoolVar(&prog.opts.WatchEvents, "watch-events", false, "start the cycles of a --watch on filesystem events within the mirror, once these have been quiet for --watch-quiet-period")
+	prog.flags.DurationVar(&prog.opts.WatchQuietPeriod, "watch-quiet-period", defaultWatchQuietPeriod, "period without any filesystem events after which a cycle of --watch-events starts")
+	prog.flags.BoolVar(&prog.opts.GracefulInterrupt, "graceful-interrupt", false, "on a first interrupt signal, finish the current file (or directory) before stopping; a second signal aborts it")
 	prog.flags.BoolVar(&prog.opts.SlowMode, "slow-mode", false, "waits 1s after every 50 directory creations in --mode=init; avoids thrashing filesystem")
+	prog.flags.IntVar(&prog.opts.DirRate, "dir-rate", 0, "limits the directory creations in --mode=init to this many per second; supersedes --slow-mode, 0 disables it")
 	prog.flags.IntVar(&prog.opts.InitDepth, "init-depth", defaultInitDepth, "decides how deep to mirror in --mode=init, 0 is dir root; -1 is unlimited depth")
+	prog.flags.Var(&prog.opts.InitDepthRules, "init-depth-rule", "depth limit below a relative path in --mode=init, as RELPATH:NUM; overrides --init-depth there; can be repeated")
+	prog.flags.DurationVar(&prog.opts.InitChangedSince, "init-changed-since", 0, "only create directories changed within the duration in --mode=init; keeps the existing mirror")
+	prog.flags.BoolVar(&prog.opts.PreserveDirTimes, "preserve-dir-times", false, "apply the modification times of the target directories to the created mirror directories in --mode=init")
+	prog.flags.BoolVar(&prog.opts.InitMerge, "init-merge", false, "only create directories not yet mirrored in --mode=init; keeps the existing mirror, even if it contains files")
+	prog.flags.BoolVar(&prog.opts.SkipEmptyTargetDirs, "skip-empty-target-dirs", false, "do not mirror target directories without any files below them in --mode=init; adds a walk per directory")
+	prog.flags.StringVar(&prog.opts.InitSkipDirsOver, "init-skip-dirs-over", "", "do not mirror target directories with more than the size of files below them in --mode=init, such as 500GiB; adds a walk")
+	prog.flags.BoolVar(&prog.opts.TwoPhaseInit, "two-phase-init", false, "build the new mirror beside the existing one in --mode=init, then swap it into place; keeps the mirror available")
+	prog.flags.BoolVar(&prog.opts.DiffExit, "diff-exit", false, "with --mode=init and --dry-run, return a non-zero code if the mirror structure would change; for drift detection")
+	prog.flags.Var(&prog.opts.TargetGlobs, "target-glob", "relative path pattern to mirror in --mode=init; only matching subtrees are created; can be repeated")
+	prog.flags.StringVar(&prog.opts.CaseCollision, "case-collision", caseCollisionNone, "handling of target directories differing only by case in --mode=init; 'none', 'merge', 'warn' or 'fail'")
+	prog.flags.BoolVar(&prog.opts.CaseInsensitivePaths, "case-insensitive-paths", false, "compare the mirror root and --exclude paths case-insensitively; for case-insensitive volumes")
+	prog.flags.StringVar(&prog.opts.Umask, "umask", "", "octal umask for all created directories and files, such as 022; unset uses the inherited umask")
 	prog.flags.BoolVar(&prog.opts.DryRun, "dry-run", false, "preview only; no changes are written to disk")
 	prog.flags.StringVar(&prog.opts.LogLevel, "log-level", strings.ToLower(defaultLogLevel.String()), "decides the verbosity of emitted logs; debug, info, warn, error")
-	prog.flags.BoolVar(&prog.opts.JSON, "json", false, "output all emitted logs in the JSON format; results can be read from stderr")
+	prog.flags.StringVar(&prog.opts.LogFormat, "log-format", logFormatText, "decides the format of emitted logs; text, json, logfmt; results can be read from stderr")
+	prog.flags.BoolVar(&prog.opts.LogSource, "log-source", false, "include the source code location (file:line) that emitted each log record; for debugging")
+	prog.flags.StringVar(&prog.opts.RunID, "run-id", "", "identifier added as run_id to all log lines of the run, for correlating them; unset generates a unique one")
+	prog.flags.BoolVar(&prog.opts.TraceSpans, "trace-spans", false, "log begin and end events (with durations) for the run and each of its phases, sharing a trace ID with all log lines")
+	prog.flags.StringVar(&prog.opts.PathEncoding, "path-encoding", pathEncodingEscape, "encoding of logged values that are not valid UTF-8, such as legacy file names; 'escape' (as \\xNN) or 'base64'")
+	prog.flags.BoolVar(&prog.opts.ResultJSON, "result-json", false, "print the result as a single JSON line to stdout at the end; other output on stdout moves to stderr")
+	prog.flags.StringVar(&prog.opts.Report, "report", "", "path to write a JSON report of the run to at the end, with its counts, exit code, duration and any skipped paths; '-' for stdout")
+	prog.flags.StringVar(&prog.opts.ReportFormat, "report-format", reportFormatJSON, "format of the --report; 'json' or 'csv' (with a row for each skipped path)")
+	prog.flags.StringVar(&prog.opts.SummaryTemplate, "summary-template", "", "Go text/template for a single summary line printed to stdout at the end, such as '{{.Mode}} {{.Moved}} {{.ExitCode}}'")
+	prog.flags.BoolVar(&prog.opts.JSON, "json", false, "deprecated: alias for --log-format=json")
+	prog.flags.StringVar(&prog.opts.HeartbeatFile, "heartbeat-file", "", "path to a file to touch periodically while running; a liveness signal for any watchdogs")
+	prog.flags.DurationVar(&prog.opts.HeartbeatInterval, "heartbeat-interval", defaultHeartbeatInterval, "interval in which the --heartbeat-file is touched")
+	prog.flags.DurationVar(&prog.opts.StatsInterval, "stats-interval", 0, "interval in which the throughput of --mode=move is logged; 0 disables it")
+	prog.flags.StringVar(&prog.opts.ProgressFile, "progress-file", "", "path to a JSON file replaced periodically with the progress of --mode=move; for polling by external interfaces")
+	prog.flags.DurationVar(&prog.opts.ProgressInterval, "progress-interval", defaultProgressInterval, "interval in which the --progress-file is replaced")
+	prog.flags.StringVar(&prog.opts.Manifest, "manifest", "", "path to a sha256sum-format manifest to check the target files against in --mode=check")
 
 	if err := prog.flags.Parse(cliArgs[1:]); err != nil {
 		return fmt.Errorf("failed parsing flags: %w", err)
@@ -57,20 +179,46 @@ func (prog *program) parseArgs(cliArgs []string) error {
 	prog.flags.Visit(func(f *flag.Flag) {
 		setFlags[f.Name] = true
 	})
+	prog.configOrigins = make(map[string]string)
+
+	if prog.opts.ValidateConfig != "" {
+		// Validation of a configuration file takes the place of any other configuration file.
+		yamlFile = prog.opts.ValidateConfig
+	}
 
 	if yamlFile != "" {
-		f, err := prog.fsys.Open(yamlFile)
-		if err != nil {
-			return fmt.Errorf("%w: %w", errArgConfigMissing, err)
+		var r io.Reader
+
+		if yamlFile == configStdin {
+			if prog.stdin == nil {
+				return fmt.Errorf("%w: %q (no stdin)", errArgConfigMissing, yamlFile)
+			}
+			r = prog.stdin
+		} else {
+			f, err := prog.fsys.Open(yamlFile)
+			if err != nil {
+				return fmt.Errorf("%w: %w", errArgConfigMissing, err)
+			}
+			defer f.Close()
+			r = f
+			prog.configFile = yamlFile
 		}
-		defer f.Close()
 
-		dec := yaml.NewDecoder(f)
-		dec.KnownFields(true)
+		if err := prog.readConfig(r, yamlFile, &yamlOpts); err != nil {
+			return err
+		}
+	}
 
-		if err := dec.Decode(&yamlOpts); err != nil {
-			return fmt.Errorf("%w: %w", errArgConfigMalformed, err)
+	if configDir != "" && prog.opts.ValidateConfig == "" {
+		// The files of the configuration directory are merged over any --config.
+		if err := prog.readConfigDir(configDir, &yamlOpts); err != nil {
+			return err
 		}
+		prog.configDir = configDir
+	}
+
+	for name := range setFlags {
+		prog.configOrigins[name] = configOriginCLI
 	}
 
 	if !setFlags["mirror"] {
@@ -79,79 +227,758 @@ func (prog *program) parseArgs(cliArgs []string) error {
 	if !setFlags["target"] {
 		prog.opts.RealRoot = yamlOpts.RealRoot
 	}
+	if !setFlags["allow-symlinked-target"] {
+		prog.opts.AllowSymlinkedTarget = yamlOpts.AllowSymlinkedTarget
+	}
+	if !setFlags["allow-mountpoint-mirror"] {
+		prog.opts.AllowMountpointMirror = yamlOpts.AllowMountpointMirror
+	}
+	if !setFlags["assume-empty-mirror"] {
+		prog.opts.AssumeEmptyMirror = yamlOpts.AssumeEmptyMirror
+	}
+	if !setFlags["create-target-root"] {
+		prog.opts.CreateTargetRoot = yamlOpts.CreateTargetRoot
+	}
+	if !setFlags["probe-writable"] {
+		prog.opts.ProbeWritable = yamlOpts.ProbeWritable
+	}
+	if !setFlags["max-load"] {
+		prog.opts.MaxLoad = yamlOpts.MaxLoad
+	}
+	if !setFlags["require-target-perms"] {
+		prog.opts.RequireTargetPerms = yamlOpts.RequireTargetPerms
+	}
 	if !setFlags["exclude"] {
 		for _, p := range yamlOpts.Excludes {
 			// Since we established no excludes were given, easier to just append to nil-slice.
 			prog.opts.Excludes = append(prog.opts.Excludes, filepath.Clean(strings.TrimSpace(p)))
 		}
 	}
+	if !setFlags["include"] {
+		for _, p := range yamlOpts.Includes {
+			prog.opts.Includes = append(prog.opts.Includes, filepath.Clean(strings.TrimSpace(p)))
+		}
+	}
+	if !setFlags["exclude-rel"] {
+		for _, p := range yamlOpts.ExcludesRel {
+			prog.opts.ExcludesRel = append(prog.opts.ExcludesRel, filepath.Clean(strings.TrimSpace(p)))
+		}
+	}
+	if !setFlags["exclude-name"] {
+		for _, n := range yamlOpts.ExcludeNames {
+			prog.opts.ExcludeNames = append(prog.opts.ExcludeNames, normalizeName(n))
+		}
+	}
+	if !setFlags["exclude-glob-mirror"] {
+		for _, p := range yamlOpts.ExcludeGlobsMirror {
+			prog.opts.ExcludeGlobsMirror = append(prog.opts.ExcludeGlobsMirror, filepath.Clean(strings.TrimSpace(p)))
+		}
+	}
+	if !setFlags["exclude-glob-target"] {
+		for _, p := range yamlOpts.ExcludeGlobsTarget {
+			prog.opts.ExcludeGlobsTarget = append(prog.opts.ExcludeGlobsTarget, filepath.Clean(strings.TrimSpace(p)))
+		}
+	}
+	if !setFlags["skip-system-dirs"] {
+		prog.opts.SkipSystemDirs = yamlOpts.SkipSystemDirs
+	}
+	if !setFlags["system-dir-names"] {
+		for _, n := range yamlOpts.SystemDirNames {
+			prog.opts.SystemDirNames = append(prog.opts.SystemDirNames, normalizeName(n))
+		}
+	}
+	if prog.opts.SkipSystemDirs && len(prog.opts.SystemDirNames) == 0 {
+		prog.opts.SystemDirNames = slices.Clone(defaultSystemDirNames)
+	}
+	if !setFlags["exclude-name-ignore-case"] {
+		prog.opts.ExcludeNameIgnoreCase = yamlOpts.ExcludeNameIgnoreCase
+	}
+	if !setFlags["list-excluded"] {
+		prog.opts.ListExcluded = yamlOpts.ListExcluded
+	}
 	if !setFlags["direct"] {
 		prog.opts.Direct = yamlOpts.Direct
 	}
+	if !setFlags["bulk-rename-dirs"] {
+		prog.opts.BulkRenameDirs = yamlOpts.BulkRenameDirs
+	}
 	if !setFlags["verify"] {
 		prog.opts.Verify = yamlOpts.Verify
 	}
+	if !setFlags["verify-read-error"] {
+		prog.opts.VerifyReadError = yamlOpts.VerifyReadError
+	}
+	if !setFlags["verify-concurrency"] {
+		prog.opts.VerifyConcurrency = yamlOpts.VerifyConcurrency
+	}
+	if !setFlags["deferred-verify"] {
+		prog.opts.DeferredVerify = yamlOpts.DeferredVerify
+	}
+	if !setFlags["stat-before-remove"] {
+		prog.opts.StatBeforeRemove = yamlOpts.StatBeforeRemove
+	}
+	if !setFlags["no-clobber-working-file"] {
+		prog.opts.NoClobberWorkingFile = yamlOpts.NoClobberWorkingFile
+	}
+	if !setFlags["update-metadata-on-match"] {
+		prog.opts.UpdateMetadataOnMatch = yamlOpts.UpdateMetadataOnMatch
+	}
+	if !setFlags["overwrite"] {
+		prog.opts.Overwrite = yamlOpts.Overwrite
+	}
+	if !setFlags["target-trash-dir"] {
+		prog.opts.TargetTrashDir = yamlOpts.TargetTrashDir
+	}
+	if !setFlags["type-change"] {
+		prog.opts.TypeChange = yamlOpts.TypeChange
+	}
+	if !setFlags["inherit-parent-perms"] {
+		prog.opts.InheritParentPerms = yamlOpts.InheritParentPerms
+	}
+	if !setFlags["lock-promoted"] {
+		prog.opts.LockPromoted = yamlOpts.LockPromoted
+	}
+	if !setFlags["quarantine-dir"] {
+		prog.opts.QuarantineDir = yamlOpts.QuarantineDir
+	}
+	if !setFlags["quarantine-source"] {
+		prog.opts.QuarantineSource = yamlOpts.QuarantineSource
+	}
+	if !setFlags["compare-by"] {
+		prog.opts.CompareBy = yamlOpts.CompareBy
+	}
+	if !setFlags["copy-buffer-size"] {
+		prog.opts.CopyBufferSize = yamlOpts.CopyBufferSize
+	}
+	if !setFlags["atomic-batch"] {
+		prog.opts.AtomicBatch = yamlOpts.AtomicBatch
+	}
+	if !setFlags["dedupe-run"] {
+		prog.opts.DedupeRun = yamlOpts.DedupeRun
+	}
+	if !setFlags["hash-algorithms"] {
+		for _, algo := range yamlOpts.HashAlgorithms {
+			prog.opts.HashAlgorithms = append(prog.opts.HashAlgorithms, strings.ToLower(strings.TrimSpace(algo)))
+		}
+	}
+	if !setFlags["checksum-algo"] {
+		prog.opts.ChecksumAlgo = yamlOpts.ChecksumAlgo
+	}
+	if !setFlags["checksum-sidecar"] {
+		prog.opts.ChecksumSidecar = yamlOpts.ChecksumSidecar
+	}
 	if !setFlags["skip-empty"] {
 		prog.opts.SkipEmpty = yamlOpts.SkipEmpty
 	}
 	if !setFlags["remove-empty"] {
 		prog.opts.RemoveEmpty = yamlOpts.RemoveEmpty
 	}
+	if !setFlags["clean-mirror-on-success"] {
+		prog.opts.CleanMirror = yamlOpts.CleanMirror
+	}
+	if !setFlags["post-move-command"] {
+		prog.opts.PostMoveCommand = yamlOpts.PostMoveCommand
+	}
+	if !setFlags["plan-out"] {
+		prog.opts.PlanOut = yamlOpts.PlanOut
+	}
+	if !setFlags["dry-run-reproducible"] {
+		prog.opts.DryRunReproducible = yamlOpts.DryRunReproducible
+	}
+	if !setFlags["list-plan-only"] {
+		prog.opts.ListPlanOnly = yamlOpts.ListPlanOnly
+	}
+	if !setFlags["dry-run-apply"] {
+		prog.opts.DryRunApply = yamlOpts.DryRunApply
+	}
+	if !setFlags["apply-token"] {
+		prog.opts.ApplyToken = yamlOpts.ApplyToken
+	}
+	if !setFlags["plan-in"] {
+		prog.opts.PlanIn = yamlOpts.PlanIn
+	}
+	if !setFlags["mirror-manifest"] {
+		prog.opts.MirrorManifest = yamlOpts.MirrorManifest
+	}
+	if !setFlags["mirror-readme"] {
+		prog.opts.MirrorReadme = yamlOpts.MirrorReadme
+	}
+	if !setFlags["mirror-readme-from"] {
+		prog.opts.MirrorReadmeFrom = yamlOpts.MirrorReadmeFrom
+	}
+	if !setFlags["verify-target-structure"] {
+		prog.opts.VerifyTargetStructure = yamlOpts.VerifyTargetStructure
+	}
+	if !setFlags["since-file"] {
+		prog.opts.SinceFile = yamlOpts.SinceFile
+	}
+	if !setFlags["rename-rules"] {
+		prog.opts.RenameRules = yamlOpts.RenameRules
+	}
+	if !setFlags["on-duplicate-target"] {
+		prog.opts.OnDuplicateTarget = yamlOpts.OnDuplicateTarget
+	}
+	if !setFlags["move-order"] {
+		prog.opts.MoveOrder = yamlOpts.MoveOrder
+	}
 	if !setFlags["skip-failed"] {
 		prog.opts.SkipFailed = yamlOpts.SkipFailed
 	}
+	if !setFlags["skip-failed-max"] {
+		prog.opts.SkipFailedMax = yamlOpts.SkipFailedMax
+	}
+	if !setFlags["per-file-timeout"] {
+		prog.opts.PerFileTimeout = yamlOpts.PerFileTimeout
+	}
+	if !setFlags["no-fail-fast"] {
+		prog.opts.NoFailFast = yamlOpts.NoFailFast
+	}
+	if !setFlags["graceful-interrupt"] {
+		prog.opts.GracefulInterrupt = yamlOpts.GracefulInterrupt
+	}
+	if !setFlags["watch"] {
+		prog.opts.Watch = yamlOpts.Watch
+	}
+	if !setFlags["watch-interval"] {
+		prog.opts.WatchInterval = yamlOpts.WatchInterval
+	}
+	if !setFlags["watch-events"] {
+		prog.opts.WatchEvents = yamlOpts.WatchEvents
+	}
+	if !setFlags["watch-quiet-period"] {
+		prog.opts.WatchQuietPeriod = yamlOpts.WatchQuietPeriod
+	}
 	if !setFlags["slow-mode"] {
 		prog.opts.SlowMode = yamlOpts.SlowMode
 	}
+	if !setFlags["dir-rate"] {
+		prog.opts.DirRate = yamlOpts.DirRate
+	}
 	if !setFlags["init-depth"] {
 		prog.opts.InitDepth = yamlOpts.InitDepth
 	}
+	if !setFlags["init-depth-rule"] {
+		for _, r := range yamlOpts.InitDepthRules {
+			prog.opts.InitDepthRules = append(prog.opts.InitDepthRules, strings.TrimSpace(r))
+		}
+	}
+	if !setFlags["init-changed-since"] {
+		prog.opts.InitChangedSince = yamlOpts.InitChangedSince
+	}
+	if !setFlags["init-merge"] {
+		prog.opts.InitMerge = yamlOpts.InitMerge
+	}
+	if !setFlags["preserve-dir-times"] {
+		prog.opts.PreserveDirTimes = yamlOpts.PreserveDirTimes
+	}
+	if !setFlags["skip-empty-target-dirs"] {
+		prog.opts.SkipEmptyTargetDirs = yamlOpts.SkipEmptyTargetDirs
+	}
+	if !setFlags["init-skip-dirs-over"] {
+		prog.opts.InitSkipDirsOver = yamlOpts.InitSkipDirsOver
+	}
+	if !setFlags["two-phase-init"] {
+		prog.opts.TwoPhaseInit = yamlOpts.TwoPhaseInit
+	}
+	if !setFlags["diff-exit"] {
+		prog.opts.DiffExit = yamlOpts.DiffExit
+	}
+	if !setFlags["target-glob"] {
+		for _, p := range yamlOpts.TargetGlobs {
+			prog.opts.TargetGlobs = append(prog.opts.TargetGlobs, filepath.Clean(strings.TrimSpace(p)))
+		}
+	}
+	if !setFlags["case-collision"] {
+		prog.opts.CaseCollision = yamlOpts.CaseCollision
+	}
+	if !setFlags["case-insensitive-paths"] {
+		prog.opts.CaseInsensitivePaths = yamlOpts.CaseInsensitivePaths
+	}
+	if !setFlags["umask"] {
+		prog.opts.Umask = yamlOpts.Umask
+	}
 	if !setFlags["dry-run"] {
 		prog.opts.DryRun = yamlOpts.DryRun
 	}
 	if !setFlags["log-level"] {
 		prog.opts.LogLevel = yamlOpts.LogLevel
 	}
+	if !setFlags["log-format"] {
+		prog.opts.LogFormat = yamlOpts.LogFormat
+	}
+	if !setFlags["log-source"] {
+		prog.opts.LogSource = yamlOpts.LogSource
+	}
+	if !setFlags["run-id"] {
+		prog.opts.RunID = yamlOpts.RunID
+	}
+	if !setFlags["trace-spans"] {
+		prog.opts.TraceSpans = yamlOpts.TraceSpans
+	}
+	if !setFlags["path-encoding"] {
+		prog.opts.PathEncoding = yamlOpts.PathEncoding
+	}
+	if !setFlags["result-json"] {
+		prog.opts.ResultJSON = yamlOpts.ResultJSON
+	}
+	if !setFlags["summary-template"] {
+		prog.opts.SummaryTemplate = yamlOpts.SummaryTemplate
+	}
+	if !setFlags["report"] {
+		prog.opts.Report = yamlOpts.Report
+	}
+	if !setFlags["report-format"] {
+		prog.opts.ReportFormat = yamlOpts.ReportFormat
+	}
 	if !setFlags["json"] {
 		prog.opts.JSON = yamlOpts.JSON
 	}
+	if prog.opts.JSON && !setFlags["log-format"] {
+		// The deprecated --json is an alias for --log-format=json.
+		prog.opts.LogFormat = logFormatJSON
+	}
+	if !setFlags["manifest"] {
+		prog.opts.Manifest = yamlOpts.Manifest
+	}
+	if !setFlags["heartbeat-file"] {
+		prog.opts.HeartbeatFile = yamlOpts.HeartbeatFile
+	}
+	if !setFlags["heartbeat-interval"] {
+		prog.opts.HeartbeatInterval = yamlOpts.HeartbeatInterval
+	}
+	if !setFlags["stats-interval"] {
+		prog.opts.StatsInterval = yamlOpts.StatsInterval
+	}
+	if !setFlags["progress-file"] {
+		prog.opts.ProgressFile = yamlOpts.ProgressFile
+	}
+	if !setFlags["progress-interval"] {
+		prog.opts.ProgressInterval = yamlOpts.ProgressInterval
+	}
 
 	return nil
 }
 
-func (prog *program) validateOpts() error {
-	if prog.opts.Mode != "init" && prog.opts.Mode != "move" {
-		return errArgModeMismatch
+// readConfig decodes a YAML configuration over the given options, so that only
+// the options it contains are replaced, and records these for --explain-config.
+func (prog *program) readConfig(r io.Reader, name string, yamlOpts *programOptions) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read: %q (%w)", name, err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	// An empty configuration (such as an empty stdin) sets no options at all.
+	if err := dec.Decode(yamlOpts); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: %w", errArgConfigMalformed, err)
+	}
+
+	// The keys present in the configuration are only needed for --explain-config.
+	var yamlKeys map[string]any
+	_ = yaml.Unmarshal(data, &yamlKeys)
+
+	for key := range yamlKeys {
+		prog.configOrigins[key] = configOriginConfig
+	}
+
+	return nil
+}
+
+// readConfigDir decodes all of the YAML configuration files (*.yaml) within the
+// given directory in lexical order, so that the options of later files replace
+// those of earlier ones. Unknown fields are rejected for each of the files.
+func (prog *program) readConfigDir(dir string, yamlOpts *programOptions) error {
+	entries, err := afero.ReadDir(prog.fsys, dir)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errArgConfigMissing, err)
 	}
 
-	if prog.opts.MirrorRoot == "" || prog.opts.RealRoot == "" {
-		return errArgMissingMirrorTarget
+	for _, e := range entries { // Already sorted by name.
+		if e.IsDir() || filepath.Ext(e.Name()) != configDirExt {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+
+		if err := prog.readConfigFile(path, yamlOpts); err != nil {
+			return err
+		}
 	}
 
-	prog.opts.MirrorRoot = filepath.Clean(strings.TrimSpace(prog.opts.MirrorRoot))
-	prog.opts.RealRoot = filepath.Clean(strings.TrimSpace(prog.opts.RealRoot))
+	return nil
+}
+
+func (prog *program) readConfigFile(path string, yamlOpts *programOptions) error {
+	f, err := prog.fsys.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errArgConfigMissing, err)
+	}
+	defer f.Close()
 
-	if prog.opts.MirrorRoot == prog.opts.RealRoot {
-		return errArgMirrorTargetSame
+	if err := prog.readConfig(f, path, yamlOpts); err != nil {
+		return fmt.Errorf("%w (in %q)", err, path)
 	}
 
-	if !filepath.IsAbs(prog.opts.MirrorRoot) || !filepath.IsAbs(prog.opts.RealRoot) {
-		return errArgMirrorTargetNotAbs
+	return nil
+}
+
+// validateOpts validates the resolved program options, returning all of the
+// found problems joined into a single error (not only the first problem).
+func (prog *program) validateOpts() error {
+	var errs []error
+
+	if prog.opts.ValidateConfig == "" && prog.opts.Mode != "init" && prog.opts.Mode != "move" && prog.opts.Mode != "check" {
+		errs = append(errs, errArgModeMismatch)
 	}
 
-	if len(prog.opts.Excludes) > 0 {
-		for _, p := range prog.opts.Excludes {
-			if !filepath.IsAbs(p) {
-				return fmt.Errorf("%w: %q", errArgExcludePathNotAbs, p)
+	switch {
+	case prog.opts.Mode == "check":
+		// Only the target root is needed for checking the files listed in the manifest.
+		if prog.opts.RealRoot == "" || prog.opts.Manifest == "" {
+			errs = append(errs, errArgMissingManifest)
+		} else {
+			prog.opts.RealRoot = filepath.Clean(strings.TrimSpace(prog.opts.RealRoot))
+
+			if !filepath.IsAbs(prog.opts.RealRoot) {
+				errs = append(errs, errArgMirrorTargetNotAbs)
 			}
 		}
+
+	case prog.opts.MirrorRoot == "" || prog.opts.RealRoot == "":
+		errs = append(errs, errArgMissingMirrorTarget)
+
+	default:
+		prog.opts.MirrorRoot = filepath.Clean(strings.TrimSpace(prog.opts.MirrorRoot))
+		prog.opts.RealRoot = filepath.Clean(strings.TrimSpace(prog.opts.RealRoot))
+
+		if prog.opts.MirrorRoot == prog.opts.RealRoot {
+			errs = append(errs, errArgMirrorTargetSame)
+		}
+
+		if !filepath.IsAbs(prog.opts.MirrorRoot) || !filepath.IsAbs(prog.opts.RealRoot) {
+			errs = append(errs, errArgMirrorTargetNotAbs)
+		}
+	}
+
+	for _, p := range prog.opts.Excludes {
+		if _, err := filepath.Match(p, ""); !filepath.IsAbs(p) && (!isGlobPattern(p) || err != nil) {
+			errs = append(errs, fmt.Errorf("%w: %q", errArgExcludePathNotAbs, p))
+		}
+	}
+
+	for _, p := range prog.opts.Includes {
+		if !filepath.IsAbs(p) {
+			errs = append(errs, fmt.Errorf("%w: %q", errArgIncludePathNotAbs, p))
+		}
+	}
+
+	// Relative excludes are based on the root that is walked by the mode (in init,
+	// the target root; in move, the mirror root), and are expanded to absolute.
+	excludeBase := prog.opts.RealRoot
+	if prog.opts.Mode == "move" {
+		excludeBase = prog.opts.MirrorRoot
+	}
+
+	if n := prog.opts.MirrorReadme; n != "" && (n == "." || n == ".." || strings.ContainsAny(n, `/\`)) {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgMirrorReadmeInvalid, n))
+	}
+
+	for _, n := range prog.opts.ExcludeNames {
+		if n == "" || n == "." || n == ".." || strings.ContainsAny(n, `/\`) {
+			errs = append(errs, fmt.Errorf("%w: %q", errArgExcludeNameInvalid, n))
+		}
+	}
+
+	for _, n := range prog.opts.SystemDirNames {
+		if n == "" || n == "." || n == ".." || strings.ContainsAny(n, `/\`) {
+			errs = append(errs, fmt.Errorf("%w: %q", errArgSystemDirNameInvalid, n))
+		}
+	}
+
+	for _, p := range prog.opts.ExcludesRel {
+		if filepath.IsAbs(p) || p == "." || p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+			errs = append(errs, fmt.Errorf("%w: %q", errArgExcludeRelInvalid, p))
+
+			continue
+		}
+
+		if prog.opts.Mode != "" && filepath.IsAbs(excludeBase) {
+			prog.opts.Excludes = append(prog.opts.Excludes, filepath.Join(excludeBase, p))
+		}
+	}
+
+	if prog.opts.RenameRules != "" {
+		if rules, err := prog.loadRenameRules(); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %q (%w)", errArgRenameRulesInvalid, prog.opts.RenameRules, err))
+		} else {
+			prog.renameRules = rules
+		}
+	}
+
+	switch prog.opts.Overwrite {
+	case "", overwriteNever, overwriteAlways, overwriteIfNewer, overwriteIfDifferent:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", errArgOverwriteInvalid, prog.opts.Overwrite))
+	}
+
+	if prog.opts.Overwrite != "" && prog.opts.Overwrite != overwriteNever && (prog.opts.PlanOut != "" || prog.opts.PlanIn != "") {
+		errs = append(errs, errArgOverwriteConflict)
+	}
+
+	if prog.opts.OnDuplicateTarget != "" && prog.opts.OnDuplicateTarget != duplicateTargetFail &&
+		prog.opts.OnDuplicateTarget != duplicateTargetFirstWins && prog.opts.OnDuplicateTarget != duplicateTargetRename {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgOnDuplicateTargetInvalid, prog.opts.OnDuplicateTarget))
+	}
+
+	if prog.opts.MoveOrder != "" && prog.opts.MoveOrder != moveOrderWalk && prog.opts.MoveOrder != moveOrderLeavesFirst {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgMoveOrderInvalid, prog.opts.MoveOrder))
+	}
+
+	if prog.opts.Umask != "" {
+		if _, err := parseUmask(prog.opts.Umask); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %q", err, prog.opts.Umask))
+		}
+	}
+
+	if prog.opts.RequireTargetPerms != "" {
+		if _, _, err := parseTargetPerms(prog.opts.RequireTargetPerms); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %q", err, prog.opts.RequireTargetPerms))
+		}
+	}
+
+	switch prog.opts.VerifyReadError {
+	case "", verifyReadErrorFail, verifyReadErrorRetry, verifyReadErrorSkip:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", errArgVerifyReadErrorInvalid, prog.opts.VerifyReadError))
+	}
+
+	if prog.opts.SkipFailedMax < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d", errArgSkipFailedMaxInvalid, prog.opts.SkipFailedMax))
+	}
+
+	if strings.IndexFunc(prog.opts.RunID, func(r rune) bool { return unicode.IsSpace(r) || !unicode.IsPrint(r) }) >= 0 {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgRunIDInvalid, prog.opts.RunID))
+	}
+
+	if prog.opts.PerFileTimeout < 0 {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgPerFileTimeoutInvalid, prog.opts.PerFileTimeout))
+	}
+
+	if prog.opts.DirRate < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d", errArgDirRateInvalid, prog.opts.DirRate))
+	}
+
+	if prog.opts.MaxLoad < 0 {
+		errs = append(errs, fmt.Errorf("%w: %g", errArgMaxLoadInvalid, prog.opts.MaxLoad))
+	}
+
+	if prog.opts.VerifyConcurrency < 0 {
+		errs = append(errs, fmt.Errorf("%w: %d", errArgVerifyConcurrency, prog.opts.VerifyConcurrency))
+	}
+
+	if prog.opts.PlanOut != "" && ((prog.opts.ValidateConfig == "" && prog.opts.Mode != "move") || !prog.opts.DryRun) {
+		errs = append(errs, errArgPlanOutInvalid)
+	}
+
+	if prog.opts.DryRunApply && ((prog.opts.ValidateConfig == "" && prog.opts.Mode != "move") || !prog.opts.DryRun) {
+		errs = append(errs, errArgDryRunApply)
+	}
+
+	if prog.opts.ApplyToken != "" && ((prog.opts.ValidateConfig == "" && prog.opts.Mode != "move") || prog.opts.DryRun) {
+		errs = append(errs, errArgApplyToken)
+	}
+
+	if prog.opts.DiffExit && ((prog.opts.ValidateConfig == "" && prog.opts.Mode != "init") || !prog.opts.DryRun) {
+		errs = append(errs, errArgDiffExit)
+	}
+
+	if prog.opts.DryRunReproducible && ((prog.opts.ValidateConfig == "" && prog.opts.Mode != "move") || !prog.opts.DryRun || prog.opts.ResultJSON) {
+		errs = append(errs, errArgDryRunReproducible)
+	}
+
+	if prog.opts.ListPlanOnly && ((prog.opts.ValidateConfig == "" && prog.opts.Mode != "move") || !prog.opts.DryRun || prog.opts.ResultJSON || prog.opts.DryRunReproducible) {
+		errs = append(errs, errArgListPlanOnly)
+	}
+
+	if prog.opts.SummaryTemplate != "" {
+		if _, err := parseSummaryTemplate(prog.opts.SummaryTemplate); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", errArgSummaryTemplateInvalid, err))
+		} else if prog.opts.ResultJSON || prog.opts.DryRunReproducible || prog.opts.ListPlanOnly {
+			errs = append(errs, errArgSummaryTemplateConflict)
+		}
+	}
+
+	if prog.opts.ReportFormat != "" && prog.opts.ReportFormat != reportFormatJSON && prog.opts.ReportFormat != reportFormatCSV {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgReportFormatInvalid, prog.opts.ReportFormat))
+	}
+
+	if prog.opts.Report == reportStdout && (prog.opts.ResultJSON || prog.opts.SummaryTemplate != "" || prog.opts.DryRunReproducible || prog.opts.ListPlanOnly) {
+		errs = append(errs, errArgReportConflict)
+	}
+
+	if prog.opts.PlanIn != "" && ((prog.opts.ValidateConfig == "" && prog.opts.Mode != "move") || prog.opts.PlanOut != "") {
+		errs = append(errs, errArgPlanInInvalid)
+	}
+
+	if prog.opts.DeferredVerify && prog.opts.Verify {
+		errs = append(errs, errArgDeferredVerifyConflict)
+	}
+
+	if prog.opts.BulkRenameDirs && (prog.opts.AtomicBatch || (prog.opts.DedupeRun != "" && prog.opts.DedupeRun != dedupeRunNone) ||
+		prog.opts.PostMoveCommand != "" || prog.opts.ChecksumSidecar || prog.opts.InheritParentPerms || prog.opts.LockPromoted || prog.opts.MoveOrder == moveOrderLeavesFirst) {
+		errs = append(errs, errArgBulkRenameDirsConflict)
+	}
+
+	if prog.opts.AtomicBatch && prog.opts.Direct {
+		errs = append(errs, errArgAtomicBatchDirect)
+	}
+
+	if prog.opts.QuarantineDir != "" && (!filepath.IsAbs(prog.opts.QuarantineDir) ||
+		isWithinRoot(prog.opts.QuarantineDir, prog.opts.MirrorRoot) || isWithinRoot(prog.opts.QuarantineDir, prog.opts.RealRoot) ||
+		filepath.Clean(prog.opts.QuarantineDir) == prog.opts.MirrorRoot || filepath.Clean(prog.opts.QuarantineDir) == prog.opts.RealRoot) {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgQuarantineDirInvalid, prog.opts.QuarantineDir))
+	}
+
+	if prog.opts.TargetTrashDir != "" && (!filepath.IsAbs(prog.opts.TargetTrashDir) ||
+		isWithinRoot(prog.opts.TargetTrashDir, prog.opts.MirrorRoot) || isWithinRoot(prog.opts.TargetTrashDir, prog.opts.RealRoot) ||
+		filepath.Clean(prog.opts.TargetTrashDir) == prog.opts.MirrorRoot || filepath.Clean(prog.opts.TargetTrashDir) == prog.opts.RealRoot ||
+		prog.opts.Overwrite == "" || prog.opts.Overwrite == overwriteNever) {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgTargetTrashDirInvalid, prog.opts.TargetTrashDir))
+	}
+
+	switch prog.opts.CompareBy {
+	case "", compareByHash, compareBySize, compareByMtime:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", errArgCompareByInvalid, prog.opts.CompareBy))
+	}
+
+	switch prog.opts.DedupeRun {
+	case "", dedupeRunNone:
+	case dedupeRunLink, dedupeRunSkip:
+		if prog.opts.AtomicBatch {
+			errs = append(errs, errArgDedupeRunAtomicBatch)
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", errArgDedupeRunInvalid, prog.opts.DedupeRun))
+	}
+
+	for i, algo := range prog.opts.HashAlgorithms {
+		if _, err := newHasher(algo); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %q", err, algo))
+		} else if slices.Contains(prog.opts.HashAlgorithms[:i], algo) {
+			errs = append(errs, fmt.Errorf("%w: %q", errArgHashAlgorithmDuplicate, algo))
+		}
+	}
+
+	switch prog.opts.ChecksumAlgo {
+	case "", hashAlgoSHA256, hashAlgoBLAKE3, hashAlgoCRC32C:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", errArgChecksumAlgoInvalid, prog.opts.ChecksumAlgo))
+	}
+
+	// A crc32c match is too weak to remove (or link) the source file on.
+	if prog.hashAlgorithms()[0] == hashAlgoCRC32C && (prog.opts.DedupeRun == dedupeRunLink ||
+		prog.opts.UpdateMetadataOnMatch || prog.opts.Overwrite == overwriteIfDifferent) {
+		errs = append(errs, errArgChecksumAlgoConflict)
+	}
+
+	switch prog.opts.TypeChange {
+	case "", typeChangeSkip, typeChangeFail:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", errArgTypeChangeInvalid, prog.opts.TypeChange))
+	}
+
+	switch prog.opts.CaseCollision {
+	case "", caseCollisionNone, caseCollisionMerge, caseCollisionWarn, caseCollisionFail:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", errArgCaseCollisionInvalid, prog.opts.CaseCollision))
+	}
+
+	for _, p := range slices.Concat(prog.opts.ExcludeGlobsMirror, prog.opts.ExcludeGlobsTarget) {
+		if _, err := filepath.Match(p, ""); err != nil || (!filepath.IsAbs(p) && strings.ContainsRune(p, filepath.Separator)) {
+			errs = append(errs, fmt.Errorf("%w: %q", errArgExcludeGlobInvalid, p))
+		}
+	}
+
+	for _, p := range prog.opts.TargetGlobs {
+		if _, err := filepath.Match(p, ""); err != nil || filepath.IsAbs(p) {
+			errs = append(errs, fmt.Errorf("%w: %q", errArgTargetGlobInvalid, p))
+		}
+	}
+
+	if prog.opts.CopyBufferSize != "" {
+		if size, err := parseByteSize(prog.opts.CopyBufferSize); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %q", err, prog.opts.CopyBufferSize))
+		} else {
+			prog.copyBuffers = newCopyBufferPool(size)
+		}
 	}
 
 	if _, err := parseLogLevel(prog.opts.LogLevel); err != nil {
-		return fmt.Errorf("%w: %q", err, prog.opts.LogLevel)
+		errs = append(errs, fmt.Errorf("%w: %q", err, prog.opts.LogLevel))
 	}
 
-	return nil
+	if _, err := parseDepthRules(prog.opts.InitDepthRules); err != nil {
+		errs = append(errs, err)
+	}
+
+	if prog.opts.InitSkipDirsOver != "" {
+		if size, ok := parseBytes(prog.opts.InitSkipDirsOver); !ok || size == 0 {
+			errs = append(errs, fmt.Errorf("%w: %q", errArgInitSkipDirsOverInvalid, prog.opts.InitSkipDirsOver))
+		}
+	}
+
+	if prog.opts.InitChangedSince < 0 {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgInitChangedSince, prog.opts.InitChangedSince))
+	}
+
+	if prog.opts.MirrorManifest != "" && isWithinRoot(prog.opts.MirrorManifest, prog.opts.MirrorRoot) {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgMirrorManifestInvalid, prog.opts.MirrorManifest))
+	}
+
+	if prog.opts.VerifyTargetStructure != "" && isWithinRoot(prog.opts.VerifyTargetStructure, prog.opts.MirrorRoot) {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgTargetStructureInvalid, prog.opts.VerifyTargetStructure))
+	}
+
+	if prog.opts.SinceFile != "" && (isWithinRoot(prog.opts.SinceFile, prog.opts.MirrorRoot) || prog.opts.PlanIn != "") {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgSinceFileInvalid, prog.opts.SinceFile))
+	}
+
+	if prog.opts.PostMoveCommand != "" && len(strings.Fields(prog.opts.PostMoveCommand)) == 0 {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgPostMoveCommandEmpty, prog.opts.PostMoveCommand))
+	}
+
+	if prog.opts.Watch && ((prog.opts.ValidateConfig == "" && prog.opts.Mode != "move") || prog.opts.WatchInterval < 0 || (prog.opts.WatchInterval == 0 && !prog.opts.WatchEvents) ||
+		prog.opts.PlanIn != "" || prog.opts.PlanOut != "" || prog.opts.ApplyToken != "" || prog.opts.DryRunApply) {
+		errs = append(errs, errArgWatchInvalid)
+	}
+
+	if prog.opts.WatchEvents && (!prog.opts.Watch || prog.opts.WatchQuietPeriod <= 0) {
+		errs = append(errs, errArgWatchEventsInvalid)
+	}
+
+	if prog.opts.HeartbeatFile != "" && prog.opts.HeartbeatInterval <= 0 {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgHeartbeatInterval, prog.opts.HeartbeatInterval))
+	}
+
+	if prog.opts.ProgressFile != "" && prog.opts.ProgressInterval <= 0 {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgProgressInterval, prog.opts.ProgressInterval))
+	}
+
+	if prog.opts.StatsInterval < 0 {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgStatsInterval, prog.opts.StatsInterval))
+	}
+
+	if prog.opts.LogFormat != "" && prog.opts.LogFormat != logFormatText && prog.opts.LogFormat != logFormatJSON && prog.opts.LogFormat != logFormatLogfmt {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgInvalidLogFormat, prog.opts.LogFormat))
+	}
+
+	if prog.opts.PathEncoding != "" && prog.opts.PathEncoding != pathEncodingEscape && prog.opts.PathEncoding != pathEncodingBase64 {
+		errs = append(errs, fmt.Errorf("%w: %q", errArgPathEncodingInvalid, prog.opts.PathEncoding))
+	}
+
+	return errors.Join(errs...)
 }
 
 func (prog *program) printOpts() error {
@@ -160,37 +987,155 @@ func (prog *program) printOpts() error {
 		return fmt.Errorf("failed printing configuration: %w", err)
 	}
 
-	fmt.Fprintf(prog.stdout, "configuration for '--mode=%s':\n", prog.opts.Mode)
+	if prog.opts.ValidateConfig != "" {
+		fmt.Fprintf(prog.infoWriter(), "configuration in '--validate-config=%s':\n", prog.opts.ValidateConfig)
+	} else {
+		fmt.Fprintf(prog.infoWriter(), "configuration for '--mode=%s':\n", prog.opts.Mode)
+	}
 
 	lines := strings.SplitSeq(string(out), "\n")
 	for line := range lines {
 		if line != "" {
-			fmt.Fprintf(prog.stdout, "\t%s\n", line)
+			fmt.Fprintf(prog.infoWriter(), "\t%s\n", line)
 		}
 	}
 
-	fmt.Fprintln(prog.stdout)
+	fmt.Fprintln(prog.infoWriter())
 
 	return nil
 }
 
+// explainConfig logs the final value of each option along with its origin
+// (with --explain-config), being either the command-line, the configuration
+// file or the default, so that it is obvious which of these took precedence.
+func (prog *program) explainConfig() {
+	var doc yaml.Node
+	if err := doc.Encode(prog.opts); err != nil {
+		prog.log.Error("failed to explain configuration", "op", prog.opts.Mode, "error", err, "error-type", "runtime")
+
+		return
+	}
+
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key, value := doc.Content[i].Value, doc.Content[i+1]
+
+		origin, ok := prog.configOrigins[key]
+		if !ok {
+			origin = configOriginDefault
+		}
+
+		out := value.Value
+		if value.Kind != yaml.ScalarNode {
+			// Sequences are output on a single line, as these are within the logs.
+			value.Style = yaml.FlowStyle
+			if data, err := yaml.Marshal(value); err == nil {
+				out = strings.TrimSpace(string(data))
+			}
+		}
+
+		prog.log.Info("configuration option", "op", prog.opts.Mode, "option", key, "value", out, "origin", origin)
+	}
+}
+
 func (prog *program) logHandler() slog.Handler {
 	var logHandler slog.Handler
 	var logLevel slog.Level
 
 	logLevel, _ = parseLogLevel(prog.opts.LogLevel)
+	if prog.opts.ListPlanOnly {
+		// Only the errors are logged, so that the listed operations stand alone.
+		logLevel = max(logLevel, slog.LevelError)
+	}
 
-	if prog.opts.JSON {
+	switch prog.opts.LogFormat {
+	case logFormatJSON:
 		logHandler = slog.NewJSONHandler(prog.stderr, &slog.HandlerOptions{
-			Level: logLevel,
+			Level:       logLevel,
+			AddSource:   prog.opts.LogSource,
+			ReplaceAttr: prog.encodeAttr,
 		})
-	} else {
+
+	case logFormatLogfmt:
+		// The standard library's text handler emits space-separated key=value pairs,
+		// quoting any values that contain spaces, quotes or non-printable characters.
+		logHandler = slog.NewTextHandler(prog.stderr, &slog.HandlerOptions{
+			Level:       logLevel,
+			AddSource:   prog.opts.LogSource,
+			ReplaceAttr: prog.encodeAttr,
+		})
+
+	default:
 		logHandler = tint.NewHandler(prog.stderr,
 			&tint.Options{
-				Level:      logLevel,
-				TimeFormat: time.TimeOnly,
+				Level:       logLevel,
+				TimeFormat:  time.TimeOnly,
+				AddSource:   prog.opts.LogSource,
+				ReplaceAttr: prog.encodeAttr,
 			})
 	}
 
 	return logHandler
 }
+
+// encodeAttr applies the --path-encoding to any logged values that are not
+// valid UTF-8 (such as the names of legacy files), so that the emitted logs
+// remain valid. The paths themselves are never altered on the filesystem.
+func (prog *program) encodeAttr(_ []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() { //nolint:exhaustive // Only strings and errors can carry paths.
+	case slog.KindString:
+		a.Value = slog.StringValue(encodeInvalidUTF8(a.Value.String(), prog.opts.PathEncoding))
+	case slog.KindAny:
+		// Errors commonly contain the paths that they occurred on.
+		if err, ok := a.Value.Any().(error); ok && !utf8.ValidString(err.Error()) {
+			a.Value = slog.StringValue(encodeInvalidUTF8(err.Error(), prog.opts.PathEncoding))
+		}
+	}
+
+	return a
+}
+
+// excludeOwnFiles adds any of the program's own files that are within the
+// mirror to the excludes, so that these are never moved into the target.
+func (prog *program) excludeOwnFiles() {
+	reportFile := prog.opts.Report
+	if reportFile == reportStdout {
+		reportFile = ""
+	}
+
+	ownFiles := []struct {
+		flag string
+		path string
+	}{
+		{"config", prog.configFile},
+		{"config-dir", prog.configDir},
+		{"manifest", prog.opts.Manifest},
+		{"plan-out", prog.opts.PlanOut},
+		{"plan-in", prog.opts.PlanIn},
+		{"heartbeat-file", prog.opts.HeartbeatFile},
+		{"progress-file", prog.opts.ProgressFile},
+		{"rename-rules", prog.opts.RenameRules},
+		{"report", reportFile},
+	}
+
+	if path := prog.mirrorReadmePath(); path != "" && !isExcluded(path, prog.opts.Excludes) {
+		// The readme is always within the mirror by design, so it is excluded silently.
+		prog.opts.Excludes = append(prog.opts.Excludes, path)
+	}
+
+	for _, f := range ownFiles {
+		if f.path == "" || prog.opts.MirrorRoot == "" {
+			continue
+		}
+
+		// Relative paths are relative to the working directory, as with opening them.
+		path, err := filepath.Abs(f.path)
+		if err != nil || !isWithinRoot(path, prog.opts.MirrorRoot) {
+			continue
+		}
+
+		if !isExcluded(path, prog.opts.Excludes) {
+			prog.opts.Excludes = append(prog.opts.Excludes, path)
+		}
+		prog.log.Warn("own file within mirror excluded", "op", prog.opts.Mode, "path", path, "flag", "--"+f.flag)
+	}
+}
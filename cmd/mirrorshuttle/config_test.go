@@ -22,7 +22,7 @@ func Test_Unit_ParseArgs_Unset_Defaults_Success(t *testing.T) {
 		"--target=/real",
 	}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NoError(t, err)
 	require.NotNil(t, prog)
 
@@ -70,7 +70,7 @@ func Test_Unit_ParseArgs_All_Success(t *testing.T) {
 		"--log-level=warn",
 	}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NoError(t, err)
 	require.NotNil(t, prog)
 
@@ -120,7 +120,7 @@ json: true
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=move", "--config=/config.yaml"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NoError(t, err)
 	require.NotNil(t, prog)
 
@@ -188,7 +188,7 @@ log-level: invalid
 		"--log-level=warn",
 	}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NoError(t, err)
 	require.NotNil(t, prog)
 
@@ -211,6 +211,110 @@ log-level: invalid
 	require.Equal(t, "warn", prog.opts.LogLevel)
 }
 
+// Expectation: The function reads the YAML configuration from stdin, with the CLI arguments overriding it.
+func Test_Unit_ParseArgs_ConfigStdin_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	stdin := bytes.NewBufferString(`
+mirror: /mirror2
+target: /real
+verify: true
+init-depth: 3
+`)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=init", "--config=-", "--mirror=/mirror", "--init-depth=5"}
+
+	prog, err := newProgram(args, fs, stdin, &stdout, &stderr)
+	require.NoError(t, err)
+	require.NotNil(t, prog)
+
+	require.Equal(t, "/mirror", prog.opts.MirrorRoot)
+	require.Equal(t, "/real", prog.opts.RealRoot)
+	require.True(t, prog.opts.Verify)
+	require.Equal(t, 5, prog.opts.InitDepth)
+}
+
+// Expectation: The function handles an empty stdin as a configuration setting no options.
+func Test_Unit_ParseArgs_ConfigStdinEmpty_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	var stdin, stdout, stderr bytes.Buffer
+
+	args := []string{"program", "--mode=init", "--config=-", "--mirror=/mirror", "--target=/real"}
+
+	prog, err := newProgram(args, fs, &stdin, &stdout, &stderr)
+	require.NoError(t, err)
+	require.NotNil(t, prog)
+
+	require.Equal(t, "/mirror", prog.opts.MirrorRoot)
+	require.Equal(t, "/real", prog.opts.RealRoot)
+	require.Equal(t, defaultInitDepth, prog.opts.InitDepth)
+}
+
+// Expectation: The function rejects unknown fields in a YAML configuration read from stdin.
+func Test_Unit_ParseArgs_ConfigStdinUnknownField_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	stdin := bytes.NewBufferString("mirror: /mirror\ntarget: /real\nunknown: true\n")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=init", "--config=-"}
+
+	prog, err := newProgram(args, fs, stdin, &stdout, &stderr)
+	require.ErrorIs(t, err, errArgConfigMalformed)
+	require.Nil(t, prog)
+}
+
+// Expectation: The function merges the files of a configuration directory in lexical order, with later files winning.
+func Test_Unit_ParseArgs_ConfigDir_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/etc/mirrorshuttle.d/10-base.yaml":     "mirror: /mirror\ntarget: /real\nverify: true\ninit-depth: 3\nexclude:\n  - /real/a\n",
+		"/etc/mirrorshuttle.d/20-override.yaml": "target: /real2\ninit-depth: 5\n",
+		"/etc/mirrorshuttle.d/30-ignored.txt":   "not: yaml\n",
+	})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=init", "--config-dir=/etc/mirrorshuttle.d", "--init-depth=7"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NoError(t, err)
+	require.NotNil(t, prog)
+
+	require.Equal(t, "/mirror", prog.opts.MirrorRoot)
+	require.Equal(t, "/real2", prog.opts.RealRoot)
+	require.True(t, prog.opts.Verify)
+	require.Equal(t, excludeArg{"/real/a"}, prog.opts.Excludes)
+	require.Equal(t, 7, prog.opts.InitDepth)
+}
+
+// Expectation: The function rejects unknown fields in any of the files of a configuration directory.
+func Test_Unit_ParseArgs_ConfigDirUnknownField_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/etc/mirrorshuttle.d/10-base.yaml":     "mirror: /mirror\ntarget: /real\n",
+		"/etc/mirrorshuttle.d/20-override.yaml": "unknown: true\n",
+	})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=init", "--config-dir=/etc/mirrorshuttle.d"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.ErrorIs(t, err, errArgConfigMalformed)
+	require.ErrorContains(t, err, "20-override.yaml")
+	require.Nil(t, prog)
+}
+
 // Expectation: The function validates known to be correct options.
 func Test_Unit_ValidateOpts_ValidOptions_Success(t *testing.T) {
 	t.Parallel()
@@ -352,3 +456,292 @@ func Test_Unit_PrintOpts_Success(t *testing.T) {
 	require.Contains(t, output, "target: /real")
 	require.Contains(t, output, "direct: true")
 }
+
+// Expectation: The function rejects an absolute target glob pattern.
+func Test_Unit_ValidateOpts_AbsoluteTargetGlob_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	prog, _, _ := setupTestProgram(fs, nil)
+	prog.opts = &programOptions{
+		Mode:        "init",
+		MirrorRoot:  "/mirror",
+		RealRoot:    "/real",
+		TargetGlobs: globArg{"/projects/*/src"},
+		LogLevel:    "info",
+	}
+
+	err := prog.validateOpts()
+	require.ErrorIs(t, err, errArgTargetGlobInvalid)
+}
+
+// Expectation: The function rejects an unknown hashing algorithm.
+func Test_Unit_ValidateOpts_InvalidHashAlgorithm_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	prog, _, _ := setupTestProgram(fs, nil)
+	prog.opts = &programOptions{
+		Mode:           "move",
+		MirrorRoot:     "/mirror",
+		RealRoot:       "/real",
+		HashAlgorithms: hashAlgoArg{hashAlgoSHA256, "md5"},
+		LogLevel:       "info",
+	}
+
+	err := prog.validateOpts()
+	require.ErrorIs(t, err, errArgHashAlgorithmInvalid)
+}
+
+// Expectation: The function rejects a hashing algorithm that is given more than once.
+func Test_Unit_ValidateOpts_DuplicateHashAlgorithm_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	prog, _, _ := setupTestProgram(fs, nil)
+	prog.opts = &programOptions{
+		Mode:           "move",
+		MirrorRoot:     "/mirror",
+		RealRoot:       "/real",
+		HashAlgorithms: hashAlgoArg{hashAlgoSHA256, hashAlgoBLAKE3, hashAlgoSHA256},
+		LogLevel:       "info",
+	}
+
+	err := prog.validateOpts()
+	require.ErrorIs(t, err, errArgHashAlgorithmDuplicate)
+}
+
+// Expectation: The function rejects a checksum algorithm that is not supported for it.
+func Test_Unit_ValidateOpts_InvalidChecksumAlgo_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	prog, _, _ := setupTestProgram(fs, nil)
+	prog.opts = &programOptions{
+		Mode:         "move",
+		MirrorRoot:   "/mirror",
+		RealRoot:     "/real",
+		ChecksumAlgo: hashAlgoSHA512,
+		LogLevel:     "info",
+	}
+
+	err := prog.validateOpts()
+	require.ErrorIs(t, err, errArgChecksumAlgoInvalid)
+}
+
+// Expectation: The function should reject overwriting target files together with a plan.
+func Test_Unit_ValidateOpts_OverwriteConflict_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		overwrite string
+		dryRun    bool
+		planOut   string
+		planIn    string
+		wantErr   bool
+	}{
+		{"never-plan-out", overwriteNever, true, "/plan.json", "", false},
+		{"never-plan-in", overwriteNever, false, "", "/plan.json", false},
+		{"always-plan-out", overwriteAlways, true, "/plan.json", "", true},
+		{"if-newer-plan-in", overwriteIfNewer, false, "", "/plan.json", true},
+		{"if-different-plan-out", overwriteIfDifferent, true, "/plan.json", "", true},
+		{"always-no-plan", overwriteAlways, false, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+
+			prog, _, _ := setupTestProgram(fs, nil)
+			prog.opts = &programOptions{
+				Mode:       "move",
+				MirrorRoot: "/mirror",
+				RealRoot:   "/real",
+				Overwrite:  tt.overwrite,
+				DryRun:     tt.dryRun,
+				PlanOut:    tt.planOut,
+				PlanIn:     tt.planIn,
+				LogLevel:   "info",
+			}
+
+			err := prog.validateOpts()
+			if tt.wantErr {
+				require.ErrorIs(t, err, errArgOverwriteConflict)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+// Expectation: The function should reject a crc32c checksum with any setting that removes or links a source file on a match.
+func Test_Unit_ValidateOpts_ChecksumAlgoConflict_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		opts    programOptions
+		wantErr bool
+	}{
+		{"crc32c", programOptions{ChecksumAlgo: hashAlgoCRC32C}, false},
+		{"crc32c-dedupe-skip", programOptions{ChecksumAlgo: hashAlgoCRC32C, DedupeRun: dedupeRunSkip}, false},
+		{"crc32c-dedupe-link", programOptions{ChecksumAlgo: hashAlgoCRC32C, DedupeRun: dedupeRunLink}, true},
+		{"crc32c-update-metadata", programOptions{ChecksumAlgo: hashAlgoCRC32C, UpdateMetadataOnMatch: true}, true},
+		{"crc32c-overwrite-if-different", programOptions{ChecksumAlgo: hashAlgoCRC32C, Overwrite: overwriteIfDifferent}, true},
+		{"crc32c-hash-algorithms-dedupe-link", programOptions{HashAlgorithms: hashAlgoArg{hashAlgoCRC32C}, DedupeRun: dedupeRunLink}, true},
+		{"sha256-dedupe-link", programOptions{ChecksumAlgo: hashAlgoSHA256, HashAlgorithms: hashAlgoArg{hashAlgoCRC32C}, DedupeRun: dedupeRunLink}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+
+			prog, _, _ := setupTestProgram(fs, nil)
+			prog.opts = &tt.opts
+			prog.opts.Mode = "move"
+			prog.opts.MirrorRoot = "/mirror"
+			prog.opts.RealRoot = "/real"
+			prog.opts.LogLevel = "info"
+
+			err := prog.validateOpts()
+			if tt.wantErr {
+				require.ErrorIs(t, err, errArgChecksumAlgoConflict)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+// Expectation: The function should parse the --copy-buffer-size once, providing buffers of that size.
+func Test_Unit_ValidateOpts_CopyBufferSize_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	prog, _, _ := setupTestProgram(fs, nil)
+	prog.opts = &programOptions{
+		Mode:           "move",
+		MirrorRoot:     "/mirror",
+		RealRoot:       "/real",
+		CopyBufferSize: "1MiB",
+		LogLevel:       "info",
+	}
+
+	err := prog.validateOpts()
+	require.NoError(t, err)
+	require.NotNil(t, prog.copyBuffers)
+
+	buf, ok := prog.copyBuffers.Get().(*[]byte)
+	require.True(t, ok)
+	require.Len(t, *buf, 1<<20)
+}
+
+// Expectation: The function resolves the same relative exclude against the root walked by each mode.
+func Test_Unit_ValidateOpts_ExcludeRel_Success(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{mode: "init", expected: "/real/tmp"},
+		{mode: "move", expected: "/mirror/tmp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			var stdout, stderr bytes.Buffer
+
+			args := []string{
+				"program",
+				"--mode=" + tt.mode,
+				"--mirror=/mirror",
+				"--target=/real",
+				"--exclude=/exclude",
+				"--exclude-rel=tmp",
+			}
+
+			prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+			require.NoError(t, err)
+			require.NotNil(t, prog)
+
+			require.Equal(t, []string{"/exclude", tt.expected}, []string(prog.opts.Excludes))
+		})
+	}
+}
+
+// Expectation: The function rejects relative excludes that are absolute or escape their root.
+func Test_Unit_ValidateOpts_ExcludeRelInvalid_Error(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range []string{"/abs", "..", "../escape"} {
+		fs := setupTestFs()
+
+		prog, _, _ := setupTestProgram(fs, nil)
+		prog.opts = &programOptions{
+			Mode:        "init",
+			MirrorRoot:  "/mirror",
+			RealRoot:    "/real",
+			ExcludesRel: excludeArg{p},
+			LogLevel:    "info",
+		}
+
+		err := prog.validateOpts()
+		require.ErrorIs(t, err, errArgExcludeRelInvalid, p)
+	}
+}
+
+// Expectation: The function permits glob patterns as excludes, but still rejects relative paths and invalid patterns.
+func Test_Unit_ValidateOpts_ExcludeGlobPattern_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		exclude string
+		wantErr bool
+	}{
+		{"/mnt/user/**/cache", false},
+		{"/mnt/user/*/cache", false},
+		{"*.tmp", false},
+		{"*/node_modules", false},
+		{"/mnt/user/Movies [2020", false},
+		{"relative/path", true},
+		{"[invalid", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.exclude, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+
+			prog, _, _ := setupTestProgram(fs, nil)
+			prog.opts = &programOptions{
+				Mode:       "init",
+				MirrorRoot: "/mirror",
+				RealRoot:   "/real",
+				Excludes:   excludeArg{tt.exclude},
+				LogLevel:   "info",
+			}
+
+			err := prog.validateOpts()
+			if tt.wantErr {
+				require.ErrorIs(t, err, errArgExcludePathNotAbs)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// deferredFile is a moved file awaiting its verification after the move (with
+// the --deferred-verify setting), along with the hash of its source.
+type deferredFile struct {
+	dst        string
+	srcHash    string
+	verifyHash string
+	err        error // The outcome of the re-read, as set by the workers.
+}
+
+// deferVerify records a moved file for its verification after the move.
+func (prog *program) deferVerify(dst string, hashes fileHashes) {
+	if !prog.opts.DeferredVerify || prog.opts.DryRun {
+		return
+	}
+
+	prog.state.deferredFiles = append(prog.state.deferredFiles, &deferredFile{
+		dst:     dst,
+		srcHash: hashes.srcHash,
+	})
+}
+
+// runDeferredVerify re-reads all of the files that were moved (with the
+// --deferred-verify setting) across --verify-concurrency readers, comparing
+// them with the hashes of their sources. As the sources were already removed,
+// any failures can only be reported, which is done through the return code.
+func (prog *program) runDeferredVerify(ctx context.Context) error {
+	files := prog.state.deferredFiles
+	prog.state.deferredFiles = nil
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	workers := min(max(1, prog.opts.VerifyConcurrency), len(files))
+
+	prog.log.Info("verifying moved files...",
+		"op", prog.opts.Mode,
+		"files", len(files),
+		"concurrency", workers,
+	)
+
+	jobs := make(chan *deferredFile)
+	results := make(chan *deferredFile)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for f := range jobs {
+				f.verifyHash, f.err = prog.hashFile(ctx, f.dst, prog.hashAlgorithms()[0])
+				results <- f
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+
+		for _, f := range files {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- f:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// All of the results are reported from here, the workers only re-read the files.
+	for f := range results {
+		switch {
+		case errors.Is(f.err, context.Canceled):
+			continue
+
+		case f.err != nil:
+			prog.state.deferredFailures++
+			prog.log.Error("file verification failed", "op", prog.opts.Mode, "path", f.dst, "error", f.err, "error-type", "runtime", "reason", "error_occurred")
+
+		case f.verifyHash != f.srcHash:
+			prog.state.deferredFailures++
+			prog.log.Error("file verification failed", "op", prog.opts.Mode, "path", f.dst, "srcHash", f.srcHash, "verifyHash", f.verifyHash, "reason", "hash_mismatch")
+
+		default:
+			prog.log.Info("file verified", "op", prog.opts.Mode, "path", f.dst, "verifyHash", f.verifyHash)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed checking context: %w", err)
+	}
+
+	return nil
+}
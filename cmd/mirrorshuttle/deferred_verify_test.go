@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Expectation: All of the moved files should be verified in one sweep after the move.
+func Test_Unit_MoveFiles_DeferredVerify_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	files := make(map[string]string)
+	for i := range 10 {
+		files[fmt.Sprintf("/mirror/dir/file%d.txt", i)] = fmt.Sprintf("content %d", i)
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real/dir"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		Mode:              "move",
+		MirrorRoot:        "/mirror",
+		RealRoot:          "/real",
+		DeferredVerify:    true,
+		VerifyConcurrency: 3,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	require.Equal(t, 10, prog.state.movedFiles)
+	require.Zero(t, prog.state.deferredFailures)
+	require.Empty(t, prog.state.deferredFiles)
+	require.Equal(t, 10, strings.Count(stderr.String(), "file verified"))
+	require.NotContains(t, stderr.String(), "file verification failed")
+
+	for path := range files {
+		_, err := fs.Stat(strings.Replace(path, "/mirror", "/real", 1))
+		require.NoError(t, err)
+	}
+}
+
+// Expectation: A corrupted destination should only be reported after the move, its source is already removed.
+func Test_Unit_MoveFiles_DeferredVerify_Error(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	err := createFiles(memFs, map[string]string{
+		"/mirror/file.txt":  "test content",
+		"/mirror/file2.txt": "test content 2",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(memFs, []string{"/real"})
+	require.NoError(t, err)
+
+	// Every renamed file is truncated, so that each of the deferred verifications fails.
+	fs := &truncatingRenameFs{Fs: memFs}
+
+	opts := &programOptions{
+		Mode:           "move",
+		MirrorRoot:     "/mirror",
+		RealRoot:       "/real",
+		DeferredVerify: true,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	require.Equal(t, 2, prog.state.movedFiles)
+	require.Equal(t, 2, prog.state.deferredFailures)
+	require.Contains(t, stderr.String(), "hash_mismatch")
+
+	_, err = memFs.Stat("/mirror/file.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The program should exit with the dedicated exit code on a failed deferred verification.
+func Test_Integ_Run_DeferredVerifyExitCode_Error(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	err := createFiles(memFs, map[string]string{"/mirror/file.txt": "test content"})
+	require.NoError(t, err)
+	err = createDirStructure(memFs, []string{"/real"})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--deferred-verify"}
+
+	prog, err := newProgram(args, &truncatingRenameFs{Fs: memFs}, nil, &stdout, &stderr)
+	require.NoError(t, err)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeDeferredVerify, exitCode)
+}
+
+// Expectation: The configuration should be rejected when combining --deferred-verify with --verify.
+func Test_Unit_ValidateOpts_DeferredVerifyConflict_Error(t *testing.T) {
+	t.Parallel()
+
+	prog, _, _ := setupTestProgram(setupTestFs(), nil)
+	prog.opts = &programOptions{
+		Mode:           "move",
+		MirrorRoot:     "/mirror",
+		RealRoot:       "/real",
+		Verify:         true,
+		DeferredVerify: true,
+		LogLevel:       "info",
+	}
+
+	err := prog.validateOpts()
+	require.ErrorIs(t, err, errArgDeferredVerifyConflict)
+}
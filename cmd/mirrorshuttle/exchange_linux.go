@@ -0,0 +1,11 @@
+//go:build linux
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// exchangePaths atomically exchanges two existing paths, so that neither of
+// them is ever absent. It fails on filesystems not supporting the exchange.
+func exchangePaths(oldpath string, newpath string) error {
+	return unix.Renameat2(unix.AT_FDCWD, oldpath, unix.AT_FDCWD, newpath, unix.RENAME_EXCHANGE)
+}
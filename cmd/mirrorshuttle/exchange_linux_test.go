@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Expectation: The function should atomically exchange two non-empty directories.
+func Test_Unit_ExchangePaths_Success(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old")
+	newPath := filepath.Join(dir, "new")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(oldPath, "a"), dirBasePerm))
+	require.NoError(t, os.MkdirAll(filepath.Join(newPath, "b"), dirBasePerm))
+
+	err := exchangePaths(oldPath, newPath)
+	if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOSYS) {
+		t.Skip("the filesystem does not support the exchange")
+	}
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(newPath, "a"))
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(oldPath, "b"))
+	require.NoError(t, err)
+}
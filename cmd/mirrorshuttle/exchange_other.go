@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// exchangePaths does nothing, as the paths cannot be exchanged atomically on this platform.
+func exchangePaths(_ string, _ string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	hookPlaceholderSrc  = "{src}"
+	hookPlaceholderDst  = "{dst}"
+	hookPlaceholderHash = "{hash}"
+)
+
+// commandRunner runs an external command, it exists so that the execution of
+// any commands (such as with --post-move-command) can be replaced for testing.
+type commandRunner interface {
+	Run(ctx context.Context, name string, args ...string) error
+}
+
+// execRunner is the [commandRunner] that executes commands on the system.
+type execRunner struct{}
+
+func (execRunner) Run(ctx context.Context, name string, args ...string) error {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %q", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// expandHookCommand splits a command template into its arguments and then
+// substitutes the placeholders within each of them. No shell is involved, so
+// any substituted paths are always passed to the command as single arguments.
+func expandHookCommand(template string, src string, dst string, hash string) []string {
+	r := strings.NewReplacer(hookPlaceholderSrc, src, hookPlaceholderDst, dst, hookPlaceholderHash, hash)
+
+	args := strings.Fields(template)
+	for i, arg := range args {
+		args[i] = r.Replace(arg)
+	}
+
+	return args
+}
+
+// runPostMoveCommand runs the --post-move-command for a file that was moved,
+// it does nothing if no such command was configured by the user.
+func (prog *program) runPostMoveCommand(ctx context.Context, src string, dst string, hash string, e os.FileInfo) error {
+	if prog.opts.PostMoveCommand == "" {
+		return nil
+	}
+
+	args := expandHookCommand(prog.opts.PostMoveCommand, src, dst, hash)
+
+	if !prog.opts.DryRun {
+		if err := prog.runner.Run(ctx, args[0], args[1:]...); err != nil {
+			return prog.walkError(src, e, fmt.Errorf("failed to run post-move command: %q (%w)", dst, err))
+		}
+	}
+	prog.log.Info("post-move command run", "op", prog.opts.Mode, "dst", dst, "command", args, "dry-run", prog.opts.DryRun)
+
+	return nil
+}
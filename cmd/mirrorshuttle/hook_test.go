@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errTestCommandFailed = errors.New("command failed")
+
+type fakeRunner struct {
+	mu    sync.Mutex
+	calls [][]string
+	err   error
+}
+
+func (r *fakeRunner) Run(_ context.Context, name string, args ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, append([]string{name}, args...))
+
+	return r.err
+}
+
+// Expectation: The function should substitute the placeholders within each of the arguments.
+func Test_Unit_ExpandHookCommand_Success(t *testing.T) {
+	t.Parallel()
+
+	args := expandHookCommand("thumb --in={src} {dst} {hash}", "/mirror/a b.jpg", "/real/a b.jpg", "abc")
+
+	require.Equal(t, []string{"thumb", "--in=/mirror/a b.jpg", "/real/a b.jpg", "abc"}, args)
+}
+
+// Expectation: The command should be run once per moved file with the substituted arguments.
+func Test_Unit_MoveFiles_PostMoveCommand_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/a/file1.txt": "abc",
+		"/mirror/b/file2.txt": "abc",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:      "/mirror",
+		RealRoot:        "/real",
+		PostMoveCommand: "thumb {dst} {src} {hash}",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	runner := &fakeRunner{}
+	prog.runner = runner
+
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	abcHash := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	require.Equal(t, [][]string{
+		{"thumb", "/real/a/file1.txt", "/mirror/a/file1.txt", abcHash},
+		{"thumb", "/real/b/file2.txt", "/mirror/b/file2.txt", abcHash},
+	}, runner.calls)
+}
+
+// Expectation: The command should not be run in dry mode, but still be logged.
+func Test_Unit_MoveFiles_PostMoveCommandDryRun_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{"/mirror/a/file.txt": "abc"})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:      "/mirror",
+		RealRoot:        "/real",
+		DryRun:          true,
+		PostMoveCommand: "thumb {dst}",
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	runner := &fakeRunner{}
+	prog.runner = runner
+
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	require.Empty(t, runner.calls)
+	require.Contains(t, stderr.String(), "post-move command run")
+	require.Contains(t, stderr.String(), "/real/a/file.txt")
+}
+
+// Expectation: A failing command should be skipped with --skip-failed, otherwise fail the operation.
+func Test_Unit_MoveFiles_PostMoveCommandFailure_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		skipFailed bool
+		wantCalls  int
+	}{
+		{"skip-failed", true, 2},
+		{"no-skip-failed", false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{
+				"/mirror/a/file1.txt": "abc",
+				"/mirror/b/file2.txt": "abc",
+			})
+			require.NoError(t, err)
+			err = createDirStructure(fs, []string{"/real"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:      "/mirror",
+				RealRoot:        "/real",
+				SkipFailed:      tt.skipFailed,
+				PostMoveCommand: "thumb {dst}",
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			runner := &fakeRunner{err: errTestCommandFailed}
+			prog.runner = runner
+
+			err = prog.moveFiles(t.Context())
+			if tt.skipFailed {
+				require.NoError(t, err)
+				require.True(t, prog.state.hasPartialFailures)
+			} else {
+				require.ErrorIs(t, err, errTestCommandFailed)
+			}
+
+			require.Len(t, runner.calls, tt.wantCalls)
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// systemLoadAverage does nothing, as the load average is not read on this platform.
+func systemLoadAverage() (float64, bool) {
+	return 0, false
+}
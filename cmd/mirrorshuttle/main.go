@@ -34,7 +34,7 @@ clients full write access, helping defend against data tampering or ransomware.
   - CLI and YAML config: Combine structured config files with runtime flags.
   - Failure-safe: Fails early on either misconfiguration or unsafe states.
   - Lightweight: No root required; simply honors current user's `umask`.
-  - Scriptable: JSON output mode and return codes allow complex scripting.
+  - Scriptable: JSON/logfmt output modes and return codes allow complex scripting.
 
 # INSTALLATION
 
@@ -49,20 +49,50 @@ system locations; ensure they are executable by running `chmod +x` before use.
 # USAGE
 
 	mirrorshuttle --mode=init|move --mirror=ABSPATH --target=ABSPATH [flags]
+	mirrorshuttle --mode=check --manifest=PATH --target=ABSPATH [flags]
+	mirrorshuttle --validate-config=PATH
 
 # ARGUMENTS
 
-	--mode [init|move]
+	--mode [init|move|check]
 		Required. Mode of operation for the program.
 
 		In `--mode=init` the `--mirror` directory must not contain any files, as
 		it will be removed and re-created with the latest structure. If any
 		files are detected, the operation will fail with a specific return code.
 
+		In `--mode=check` the files listed in a `--manifest` are re-read from the
+		`--target` and verified against their recorded hashes, detecting any
+		missing or corrupted (bit-rotten) files. No `--mirror` is needed for this.
+
 	--config string
 		Optional. Path to a YAML configuration file with any CLI arguments.
 		Exception: `--mode` argument must always be specified via command-line.
 		Direct CLI arguments always override values set via configuration file.
+		A path of `-` reads the configuration from standard input (stdin).
+
+	--config-dir string
+		Optional. Path to a directory of YAML configuration files (`*.yaml`),
+		such as a base file with environment-specific overrides. The files are
+		merged in lexical order, so that the options of a later file override
+		those of an earlier one, and all of them are merged over any `--config`.
+		Unknown fields are rejected for each of the files. Direct CLI arguments
+		always override values set via the configuration files.
+
+	--validate-config string
+		Optional. Path to a YAML configuration file that is only to be
+		validated. All problems found within the file are reported (not only the
+		first), then the program exits with either a success or a configuration
+		failure return code. No `--mode` is needed and no other filesystem
+		operations are performed.
+
+	--explain-exit int
+		Optional. Prints the description of the given return code (see RETURN
+		CODES), such as for interpreting the result of a scheduled run, then
+		exits with a success return code (or a configuration failure return code
+		for an unknown one). No `--mode` is needed and no other operations are
+		performed. The table of all return codes is also part of the `--help`
+		output.
 
 	--mirror string
 		Required. Absolute path to the mirror structure. This is where mirrored
@@ -73,10 +103,255 @@ system locations; ensure they are executable by running `chmod +x` before use.
 		Required. Absolute path to the real (target) structure. This is the
 		source of truth in init mode and the destination in move mode.
 
+	--allow-symlinked-target
+		Optional. By default, the operation is refused when the `--target` path
+		is itself a symbolic link (such as `/mnt/user -> /mnt/cache/user`), as
+		operations could then land on an unexpected underlying store. With this
+		setting, such a link is instead resolved and the operations are
+		performed on the resolved path (along with any `--mirror` and
+		`--exclude` paths contained within). This does not apply to a `--mirror`
+		that is a symbolic link.
+
+		Default: false
+
+	--allow-mountpoint-mirror
+		Optional. By default, an (empty) existing `--mirror` that is a mount
+		point (residing on another device than its parent) is not removed and
+		re-created in `--mode=init`, as this could destroy or shadow the mount;
+		only its contents are removed instead. With this setting, such a mirror
+		is removed and re-created like any other. The detection is only
+		available on Unix-like systems.
+
+		Default: false
+
+	--assume-empty-mirror
+		Optional. Unsafe. Skips checking an existing `--mirror` for any files in
+		`--mode=init` (along with the check that it can be removed), and
+		proceeds directly to removing and re-creating it. This avoids the full
+		walk of the mirror, but any files that are still within it are removed
+		without any warning. It is only meant for automation that manages the
+		emptiness of the mirror externally (such as by always running
+		`--mode=move` with success right before). A prominent warning is logged
+		whenever it takes effect.
+
+		Default: false
+
+	--create-target-root
+		Optional. Creates the `--target` root in `--mode=move` if it does not
+		exist, rather than failing, for bootstrapping a fresh (empty) target
+		volume. Only the target root itself is created (its parent needs to
+		exist), any structure below it is created only as dictated by the
+		mirror.
+
+		Use with caution: a missing target root can also be the sign of a target
+		volume that is not (yet) mounted, in which case the files would be moved
+		into the directory the volume is mounted onto instead. By default, a
+		missing target root fails the operation, which is the safe choice.
+
+		Default: false
+
+	--probe-writable
+		Optional. Confirms that the `--target` is writable before any files are
+		moved in `--mode=move`, by creating, writing and removing a small probe
+		file at the target root. A target that was (re)mounted read-only then
+		fails the operation with a clear error right away, rather than in the
+		middle of a long move. Skipped with `--dry-run`.
+
+		Default: false
+
+	--max-load float
+		Optional. Defers the run if the 1-minute load average of the system
+		exceeds this value at its start, so that an opportunistic background run
+		gives way to any peak usage. A deferred run does nothing and exits with
+		its own return code (`9`), so that a scheduler (such as cron) can retry
+		it later. The load average is read from `/proc/loadavg` on Linux; where
+		it is not available, a warning is logged and the run proceeds. A value
+		of `0` disables the check.
+
+		Default: 0
+
+	--require-target-perms string
+		Optional. The permissions (in octal, such as `0755`) that the `--target`
+		root must have, which are checked before anything else is done in any
+		mode. Without a prefix, the permissions must match exactly. With the
+		`max:` prefix (such as `max:0755`), the permissions must not have any
+		bits set beyond the given ones, so stricter permissions (such as `0700`)
+		also satisfy it. The operation fails if the permissions are not as
+		required.
+
+		This allows for enforcing a certain security posture on the target, such
+		as that it must never be group-writable.
+
+		Default: ""
+
+	--manifest string
+		Optional. Path to a manifest in the format of the common `sha256sum`
+		tool, with each line holding a SHA-256 hash and the path of a file.
+		Required with `--mode=check`, where every listed file is re-hashed and
+		compared with its recorded hash. Relative paths are resolved against
+		`--target`.
+
+	--heartbeat-file string
+		Optional. Path to a file that is touched (created if absent, otherwise
+		its modification time updated) in the `--heartbeat-interval` while the
+		program runs. This gives watchdogs a reliable liveness signal distinct
+		from the output, such as for slow, but still working, moves of large
+		files.
+
+	--heartbeat-interval duration
+		Optional. The interval in which the `--heartbeat-file` is touched, as a
+		duration (such as `30s` or `1m`).
+
+		Default: 10s
+
+	--stats-interval duration
+		Optional. The interval in which the throughput of `--mode=move` is
+		logged (as a duration, such as `1m`). Each such line contains the files
+		and megabytes per second that were moved since the previous line, along
+		with the totals so far, which helps with spotting when a long run slows
+		down (such as on a slow region of a disk). Unlike the
+		`--heartbeat-file`, this is part of the logs.
+
+		Default: 0 (disabled)
+
+	--progress-file string
+		Optional. Path to a JSON file that is replaced with a snapshot of the
+		progress of `--mode=move` in the `--progress-interval`, intended for
+		polling by external user interfaces (such as a web dashboard) during the
+		run. Each snapshot contains the files and bytes moved so far, the file
+		currently being moved and, if the totals are known beforehand (with
+		`--plan-in`), these totals and a percentage. The file is replaced
+		atomically (through a renamed working file), so it is never read
+		partially, and it is finalized with a `finished` state once the move is
+		over.
+
+		Default: "" (disabled)
+
+	--progress-interval duration
+		Optional. The interval in which the `--progress-file` is replaced, as a
+		duration (such as `1s` or `10s`).
+
+		Default: 2s
+
 	--exclude string
 		Optional. Absolute path to exclude from operations. Can be repeated.
 		This prevents specified directories from being mirrored or moved.
 
+		An exclude containing glob metacharacters (`*`, `?` or `[`) is also
+		matched as a pattern (as understood by Go's `filepath.Match`), one path
+		component at a time, where a component of just `**` matches any number
+		of path components. Such patterns may also be relative, in which case
+		they are matched at any depth, such as `*.tmp` or `~$*`. As with paths,
+		everything within a matched directory is excluded. With
+		`--case-insensitive-paths`, patterns (including any character classes
+		such as `[A-Z]`) are matched case-insensitively. In `--mode=move`, an
+		exclude applies to both the mirror paths and the target paths they would
+		be moved to, so to exclude by pattern on only one side, use
+		`--exclude-glob-mirror` or `--exclude-glob-target` instead.
+
+	--include string
+		Optional. Absolute path to restrict operations to. Can be repeated. When
+		given, only the included paths (and everything within them) are mirrored
+		or moved, along with the parent directories leading to them, while all
+		other directories and files are skipped. This is simpler than excluding
+		everything else when only a few subtrees of a large target are of
+		interest.
+
+		Any exclusions (such as `--exclude` or `--exclude-name`) take precedence
+		over the included paths, so a path that is both included and excluded
+		(or within an excluded directory) is always skipped. In `--mode=move`, a
+		file is included if either its path within the mirror or its path within
+		the target is.
+
+		For example: `--include=/mnt/user/media/movies`
+
+	--exclude-rel string
+		Optional. Path to exclude from operations, relative to the root that is
+		walked by the mode. Can be repeated. The base is mode-dependent: in
+		`--mode=init` it is the `--target` and in `--mode=move` it is the
+		`--mirror`. Such paths are expanded to absolute paths and coexist with
+		any `--exclude` paths.
+
+		For example: `--exclude-rel=tmp`
+
+	--exclude-name string
+		Optional. Excludes all files and directories with exactly the given
+		name, at any depth, such as `Thumbs.db` or `.DS_Store`. Can be repeated.
+		This is simpler (and cheaper) than excluding each of the paths. In
+		`--mode=init`, directories with the name are not mirrored (including
+		their subdirectories); in `--mode=move`, files with the name are not
+		moved (counting as unmoved files) and directories with the name are not
+		traversed. The names are compared in the same unicode normalization
+		form.
+
+		For example: `--exclude-name=Thumbs.db --exclude-name=.DS_Store`
+
+		Default: none
+
+	--exclude-name-ignore-case
+		Optional. Compares the names of `--exclude-name` case-insensitively.
+
+		Default: false
+
+	--skip-system-dirs
+		Optional. Excludes the directories that NAS systems (and operating
+		systems) keep within shares for their own use, such as `@eaDir`,
+		`#recycle` or `.snapshots`, at any depth. Their names are compared
+		case-insensitively against `--system-dir-names`. In `--mode=init`, these
+		directories are not mirrored, and in `--mode=move`, any such directories
+		within the mirror are left there and not moved into the target. This
+		saves enumerating them all with `--exclude-name`.
+
+		Default: false
+
+	--system-dir-names string
+		Optional. A directory name to exclude with `--skip-system-dirs`, instead
+		of the well-known ones (`@eaDir`, `#recycle`, `#snapshot`, `.snapshots`,
+		`@Recycle`, `@Recently-Snapshot`, `$RECYCLE.BIN` and `System Volume
+		Information`). Can be repeated to exclude multiple names.
+
+		For example: `--system-dir-names=@eaDir --system-dir-names=.snapshots`
+
+		Default: the well-known names
+
+	--exclude-glob-mirror string
+		Optional. A glob pattern of mirror paths to not move in `--mode=move`.
+		It is checked only against the walked mirror paths, never against the
+		target paths they would be moved to. Can be repeated for multiple
+		patterns.
+
+		A pattern without a path separator is matched against the name of each
+		walked path (at any depth), any other pattern must be absolute and is
+		matched against the full path the same as an `--exclude` pattern.
+		Matched files are not moved (counting as unmoved files) and matched
+		directories are not traversed.
+
+		For example: `--exclude-glob-mirror=*.partial`
+
+		Default: none
+
+	--exclude-glob-target string
+		Optional. A glob pattern of target paths to not mirror in `--mode=init`,
+		or to not move into in `--mode=move`. It is checked only against the
+		target paths, never against the mirror paths. Can be repeated for
+		multiple patterns.
+
+		The patterns are matched the same as for `--exclude-glob-mirror`, so
+		absolute patterns must point into the `--target` directory.
+
+		For example: `--exclude-glob-target=/mnt/user/media/.cache*`
+
+		Default: none
+
+	--list-excluded
+		Optional. Output each walked path that was matched by any of the
+		`--exclude` (or `--exclude-rel`) paths or `--exclude-name` names, along
+		with the matching exclusion, at the info log level. This helps confirm
+		that the exclusions match exactly what is intended. Each matched
+		directory is output only once, as nothing below it is walked.
+
+		Default: false
+
 	--direct
 		Optional. Attempt atomic rename operations. If this fails (e.g., across
 		filesystems), fallback to copy and remove.
@@ -87,6 +362,22 @@ system locations; ensure they are executable by running `chmod +x` before use.
 
 		Default: false
 
+	--bulk-rename-dirs
+		Optional. Moves a whole mirror directory into the target with a single
+		rename, when its target directory does not exist yet and all of its
+		contents would be moved as they are. This is much faster for large new
+		subtrees on the same filesystem. The directory is moved per file instead
+		if anything within it would not be moved (such as an excluded name, or
+		an empty directory with `--skip-empty`), or if the rename fails (such as
+		across filesystems). Renamed directories keep the permissions they have
+		within the mirror, while the (then empty) mirror directories are
+		re-created, and each of the moved files is still logged. This setting
+		cannot be used together with `--atomic-batch`, `--dedupe-run`,
+		`--post-move-command`, `--checksum-sidecar`, `--inherit-parent-perms`,
+		`--lock-promoted` or `--move-order=depth-first-leaves`.
+
+		Default: false
+
 	--verify
 		Optional. Re-read the target file again after moving and verify against
 		a previously calculated (source file) hash, ensuring target was written
@@ -94,6 +385,276 @@ system locations; ensure they are executable by running `chmod +x` before use.
 
 		Default: false
 
+	--verify-read-error [fail|retry|skip]
+		Optional. Decides the handling of read errors during the re-read of the
+		`--verify` pass, which happens after the file was already renamed into
+		the target. With `fail`, the move of the file fails (as usual), and the
+		target file is removed again while the source file is left intact. With
+		`retry`, the re-read is attempted up to three more times (after a short
+		delay), before failing as with `fail`. With `skip`, the move is accepted
+		without its verification, which is logged as an error.
+
+		A mismatching hash of a successful re-read always fails the move of the
+		file.
+
+		Default: fail
+
+	--verify-concurrency int
+		Optional. The number of `--verify` passes to run concurrently. The files
+		themselves are still moved one by one (in the order of the walk), only
+		the re-reading of the moved files is spread across this many concurrent
+		readers, which can help where the re-reading is the bottleneck. The
+		source of a file is only removed once its own verify pass has succeeded.
+
+		Has no effect without `--verify`, with `--atomic-batch` or with
+		`--dry-run`; any value below `2` verifies the files one by one.
+
+		With `--deferred-verify`, it is instead the number of concurrent readers
+		of the verification sweep after the move, which is not affected by
+		`--atomic-batch`.
+
+		Default: 1
+
+	--deferred-verify
+		Optional. Verifies all of the moved files in one sweep after the move,
+		instead of verifying each file before its source is removed (as
+		`--verify` does). This avoids the read-after-write of each file while
+		moving, so the move itself is faster, and the sweep re-reads the files
+		across `--verify-concurrency` readers.
+
+		The sources are removed as soon as their files are moved, so any file
+		failing its verification can only be reported, which is done with a
+		dedicated return code. Files moved by a rename syscall (with `--direct`)
+		are not verified. Cannot be used together with `--verify`.
+
+		Default: false
+
+	--stat-before-remove
+		Optional. Re-stat the target file after moving and confirm its size
+		matches the size of the source file, before the source file is removed.
+		This is a lightweight safeguard catching gross write failures (such as a
+		truncated target file), without the cost of the full re-read of
+		`--verify`. On a mismatch, the target file is removed and the source
+		file is kept.
+
+		Default: false
+
+	--no-clobber-working-file
+		Optional. Creates the working file of each file exclusively, so that an
+		existing working file (such as of a concurrent run on the same target)
+		is never overwritten. Any such file is skipped instead, leaving the
+		existing working file untouched, and counts as an unmoved file.
+
+		Without the setting, an existing working file is overwritten, as it is
+		presumed to be left behind by an earlier interrupted run.
+
+		Default: false
+
+	--update-metadata-on-match
+		Optional. When a target file already exists in `--mode=move`, compare it
+		with the source file (with the basis of `--compare-by`), and if both are
+		identical, apply the modification time of the source file to the target
+		file and then remove the source file. This reconciles harmless
+		differences (such as timestamp drift) instead of leaving the file
+		unmoved. A differing target file is not changed by this setting, but
+		handled as per `--overwrite`.
+
+		Default: false
+
+	--overwrite [never|always|if-newer|if-different]
+		Optional. Decides how a target file that already exists is handled in
+		`--mode=move`. With `never`, the target file is never overwritten and
+		the source file remains unmoved. With `always`, the target file is
+		overwritten by the source file. With `if-newer`, it is only overwritten
+		if the source file was modified later than the target file. With
+		`if-different`, it is only overwritten if both files differ (with the
+		basis of `--compare-by`), while a source file identical to the target
+		file is removed without rewriting the target file.
+
+		An overwrite goes through the working file like any other move, which is
+		only renamed over the target file once it is complete, so an interrupted
+		overwrite never corrupts the existing target file. Any
+		`--update-metadata-on-match` is applied before this setting. As a plan
+		never replaces an existing target file, any other setting than `never`
+		cannot be used together with `--plan-out` or `--plan-in`.
+
+		Default: never
+
+	--target-trash-dir string
+		Optional. Absolute path to a directory that an existing target file is
+		relocated into right before it is overwritten (with `--overwrite`),
+		instead of being replaced, so that an unwanted overwrite can be
+		reverted. A target file is only trashed once the file replacing it is
+		complete (and compared), and is moved back into place if the replacement
+		then fails (such as in a `--verify` pass). The files keep their path
+		relative to `--target` (and their modification times) within the trash,
+		and each is logged as it is trashed. The directory is created if it does
+		not exist and is checked to be writable before any files are moved. It
+		cannot be within `--mirror` or `--target`, and should be on the same
+		filesystem as the `--target`, as the files are renamed into it.
+
+		An earlier file is never replaced within the trash, so a target file
+		that cannot be trashed (such as one already trashed in an earlier run)
+		is not overwritten, and the operation fails (or skips the file with
+		`--skip-failed`). The trash is never emptied by the program.
+
+		Default: "" (disabled)
+
+	--type-change [skip|fail]
+		Optional. Decides how a target path that changed its type since the
+		mirror was created is handled in `--mode=move`, such as a target
+		directory that is now a file (or the other way around), as the mirrored
+		path can then no longer be moved into it. With `skip`, the path is
+		skipped (along with everything below it) and left within the mirror for
+		resolution by the user, counting towards the unmoved files. With `fail`,
+		the operation fails (or skips the path with `--skip-failed`).
+
+		Default: fail
+
+	--inherit-parent-perms
+		Optional. Creates the missing target directories in `--mode=move` with
+		the permissions of their existing parent within the target (regardless
+		of the umask), so that a moved structure matches the permissions of the
+		surrounding archive. The default permissions are used for a directory
+		whose parent cannot be inspected. Note that a parent without write
+		permissions also leaves its new directories without them.
+
+		Default: false
+
+	--lock-promoted
+		Optional. Removes all of the write permissions (the `w` bits for the
+		owner, group and others) from each file once it was moved into the
+		target, and verified with `--verify`, so that promoted files are not
+		easily altered, in keeping with a write-once-read-many archive. This
+		applies to all ways in which files are moved (such as with `--direct`
+		renames). Only the permissions of the files are changed, their
+		directories are left writable for any further moves.
+
+		Default: false
+
+	--compare-by string
+		Optional. The basis on which an existing target file is decided to
+		differ from its source file with `--update-metadata-on-match` (or with
+		`--overwrite=if-different`), before any file is read. The sizes of both
+		files are always compared first, so files that obviously differ are
+		never read. With `mtime`, files whose modification times differ are also
+		considered different without reading them. As the source file is removed
+		on a match, files not decided to differ are always hashed and compared,
+		so a match is never decided on the file metadata alone (and `size`
+		behaves the same as `hash`).
+
+		Default: hash
+
+	--quarantine-dir string
+		Optional. Absolute path to a directory that the destination files of
+		failed moves (such as on a failed copy, a hash mismatch or a failed
+		`--verify` pass) are relocated into for inspection, instead of being
+		removed. These keep their path relative to `--target`, below a `target`
+		subdirectory of the quarantine, and are logged along with the reason of
+		the failure. The directory is created if it does not exist and is
+		checked to be writable before any files are moved. It cannot be within
+		`--mirror` or `--target`. A file that cannot be quarantined (such as one
+		already quarantined in an earlier run) is removed as usual. The run
+		itself continues only with `--skip-failed`, as with any other failed
+		move.
+
+		Default: "" (disabled)
+
+	--quarantine-source
+		Optional. Also relocates the source files of failed moves into the
+		`--quarantine-dir`, keeping their path relative to `--mirror` below a
+		`mirror` subdirectory of the quarantine. Without this setting, the
+		source files remain within the mirror (where these can be moved again).
+
+		Default: false
+
+	--copy-buffer-size string
+		Optional. The size of the buffer that is used for copying files (with
+		copy and remove), which can improve the throughput for large files,
+		especially over high-latency mounts. Accepts a number of bytes with an
+		optional binary suffix (`K`/`KiB`, `M`/`MiB` or `G`/`GiB`), between
+		`4KiB` and `256MiB`. When unset, the default buffer of 32KiB is used.
+
+		For example: `--copy-buffer-size=4MiB`
+
+	--atomic-batch
+		Optional. Restructures `--mode=move` into a copy phase and a commit
+		phase. All files are first copied into working files next to their
+		targets (and verified, with `--verify`), and only once every copy has
+		succeeded are all of them renamed into place in a tight loop, followed
+		by the removal of their sources. Readers of the target hence see either
+		the old state or (nearly) the full new batch, minimizing the window in
+		which only a part of the batch is visible.
+
+		If any of the copies fails, all working files of the batch are removed
+		and the operation fails without committing anything (regardless of
+		`--skip-failed`). This setting cannot be used together with `--direct`.
+
+		Default: false
+
+	--dedupe-run string
+		Optional. Detects source files in `--mode=move` that are identical in
+		content to a file that was already moved in the same run, such as the
+		same file staged below two different directories. This requires hashing
+		each source before it is moved, so an extra full read of the file.
+
+		With `link`, such a file is hard-linked to the already moved file
+		(instead of being copied) and its source is removed. If linking is not
+		possible (e.g., across filesystems), the file is moved as usual. With
+		`skip`, such a file is not moved (counting as an unmoved file). With
+		`none`, files are not deduplicated at all. This setting cannot be used
+		together with `--atomic-batch`.
+
+		Default: none
+
+	--hash-algorithms string
+		Optional. Comma-separated list of hashing algorithms (`sha256`,
+		`sha512`, `blake3` or `crc32c`) to compute for each file that is moved
+		with copy and remove. All of the digests are computed from the single
+		read of the source file, and are output in the operational logs. The
+		first algorithm (unless another is set with `--checksum-algo`) is the
+		one that is used for comparing the source with the target file (and for
+		any `--verify` pass). Each algorithm can only be listed once.
+
+		For example: `--hash-algorithms=sha256,blake3`
+
+		Default: sha256
+
+	--checksum-algo [sha256|blake3|crc32c]
+		Optional. Selects the hashing algorithm that is used for comparing the
+		source with the target file of each file that is moved with copy and
+		remove (and for any `--verify` pass), taking precedence over the first
+		of `--hash-algorithms`. Any other `--hash-algorithms` are still computed
+		alongside it. The algorithm is output as `checksumAlgo` in the `file
+		moved` logs, so that parsing programs know which digest they are
+		reading.
+
+		For large files, `blake3` is typically much faster than `sha256`, while
+		`crc32c` is faster still, but only guards against accidental corruption
+		(and not against deliberate tampering). As a `crc32c` match is too weak
+		to remove or link a source file on, it cannot be used together with
+		`--dedupe-run=link`, `--update-metadata-on-match` or
+		`--overwrite=if-different`.
+
+		Default: "" (the first of --hash-algorithms)
+
+	--checksum-sidecar
+		Optional. Writes a checksum sidecar next to each file moved in
+		`--mode=move`, named after the file (as `<name>.sha256`). It always
+		holds the SHA-256 digest, regardless of the `--hash-algorithms` (or
+		`--checksum-algo`), in the format of `sha256sum` (`<hex>  <name>`), so
+		the target can be checked with it (using `sha256sum -c`). The digest of
+		the source is used where it was already computed while moving; renamed
+		files (with `--direct`) or those hashed with other algorithms are hashed
+		again after their move. An existing target file of the name of a sidecar
+		is never replaced, but warned about. Any mirror files that are the
+		sidecars of other files (next to a file of their name within the mirror
+		or the target) are never moved, but count as unmoved files, as they
+		could otherwise replace the sidecar of that file; any other files of the
+		extension are moved as usual.
+
+		Default: false
+
 	--skip-empty
 		Optional. Do not move empty directories in `--mode=move`. This setting
 		can help prevent accidental re-creation of directories which no longer
@@ -101,6 +662,9 @@ system locations; ensure they are executable by running `chmod +x` before use.
 		Such a case can happen when the target structure has changed and it was
 		forgotten to run `--mode=init` to reflect these changes on the mirror.
 
+		When disabled, a warning is emitted for each empty directory that does not
+		exist in the target structure, as a hint towards such a forgotten `--mode=init`.
+
 		Default: true
 
 	--remove-empty
@@ -116,18 +680,317 @@ system locations; ensure they are executable by running `chmod +x` before use.
 
 		Default: false
 
+	--clean-mirror-on-success
+		Optional. Remove the (then empty) mirror directories after a
+		`--mode=move`, keeping only the mirror root itself. This happens only
+		when the move was fully successful, so when no files remained unmoved
+		and no partial failures occurred; otherwise the mirror is left
+		untouched. Any excluded directories (such as with `--exclude`) are left
+		in place, along with their parents.
+
+		This saves the need for cleaning up the mirror skeleton after a move,
+		but a subsequent `--mode=init` is then needed to recreate the mirror
+		structure.
+
+		Default: false
+
+	--post-move-command string
+		Optional. A command to run after each file that was moved in
+		`--mode=move`, such as for generating thumbnails. The placeholders
+		`{src}`, `{dst}` and `{hash}` are substituted with the source path, the
+		target path and the source hash (of the first `--hash-algorithms`; empty
+		for files moved with a rename) of the moved file.
+
+		The command is split into its arguments at any whitespace and run
+		directly, so without a shell being involved; substituted paths are
+		always passed as single arguments. A failing command fails the operation
+		(or is skipped with `--skip-failed`), but the file remains moved. With
+		`--dry-run`, the command is only output, but not run.
+
+		For example: `--post-move-command="thumbnailer --in={dst}"`
+
+	--plan-out string
+		Optional. Path to write a structured (JSON) plan of a `--mode=move` to,
+		requires `--dry-run`. The plan contains the ordered list of operations
+		that would be performed, such as directory creations and file moves
+		(with source, target and size), and can be reviewed and approved by a
+		human before being executed with `--plan-in`.
+
+	--plan-in string
+		Optional. Path to a previously written (and approved) `--plan-out` plan
+		to execute in `--mode=move`, instead of walking the mirror. Only the
+		operations contained within the plan are executed, in their planned
+		order. Before anything is done, all operations are checked against the
+		filesystem; the operation fails without any changes if it has diverged
+		from the plan (such as a planned source no longer existing, having
+		changed its size or modification time, or having been replaced by a
+		symbolic link, or a conflicting target having appeared).
+
+	--dry-run-reproducible
+		Optional. With `--mode=move` and `--dry-run`, outputs only the
+		operations that would be performed to standard output, one `mkdir
+		<path>` or `move <src> -> <dst>` line per operation, sorted and without
+		any timestamps or colors. All other output (such as the logs and the
+		configuration) goes to standard error, so the previews of two runs can
+		be compared with `diff`, such as to review the planned changes. This
+		setting cannot be used together with `--result-json`.
+
+		Default: false
+
+	--list-plan-only
+		Optional. With `--mode=move` and `--dry-run`, outputs only the ordered
+		list of operations that would be performed to standard output, one
+		`mkdir <path>`, `move <src> -> <dst>` or `skip <path> (<reason>)` line
+		per operation, in the order in which they were decided. The skips come
+		from the same decisions as in a real move (such as conflicting targets
+		or exclusions). Logs below the error level are suppressed and all other
+		output (such as the configuration) goes to standard error, so the list
+		can be parsed by scripts. This setting cannot be used together with
+		`--result-json` or `--dry-run-reproducible`.
+
+		Default: false
+
+	--dry-run-apply
+		Optional. Outputs an apply token (as the `token` of an `apply token
+		created` log line) from a `--mode=move` `--dry-run`. The token captures
+		the options that decide the operations of the move (such as the paths
+		and the excludes), along with a snapshot of the mirror structure (the
+		path, type, size and modification time of each of its elements). It is a
+		lightweight alternative to `--plan-out`, for refusing a real run whose
+		mirror changed since its dry run was reviewed (with `--apply-token`).
+
+		Default: false
+
+	--apply-token string
+		Optional. The apply token of a reviewed `--dry-run-apply`, which the
+		real run of `--mode=move` needs to still match before it moves anything.
+		The operation is refused (and fails) if the mirror or any of the
+		captured options changed since the dry run.
+
+		Default: ""
+
+	--mirror-manifest string
+		Optional. Path to a file recording the directories of the mirror, which
+		is written after `--mode=init` (one path relative to `--mirror` per
+		line) and checked against in any later `--mode=move`. Any directories
+		that were added to or removed from the mirror since the init, such as by
+		clients tampering with the mirror, are then output as warnings before
+		the move. The move fails if the file does not exist. The path cannot be
+		within the `--mirror`.
+
+	--verify-target-structure string
+		Optional. Records the target directories that were mirrored in
+		`--mode=init` to the given file, one relative path per line. A later
+		`--mode=move` with the same file then verifies beforehand that all of
+		these directories still exist within the target, and refuses to run
+		(with a dedicated return code) if any of them were removed, renamed or
+		replaced by files in the meantime. A move into such a diverged target
+		structure could otherwise re-create the directories that were
+		(intentionally) removed from it. Directories added to the target do not
+		count as diverged.
+
+		Unlike `--mirror-manifest`, which checks the mirror, this guards the
+		target (such as against read-only archives that were rearranged). The
+		file cannot be within the mirror.
+
+		Default: "" (disabled)
+
+	--mirror-readme string
+		Optional. Writes a file with the given name into the `--mirror` root
+		after each `--mode=init`, which explains the staging area to the clients
+		that are working with the mirror (that any files placed there are moved
+		into the archive, and that existing files are never overwritten). The
+		file is never moved into the target and does not count towards a
+		non-empty mirror. The name must be a plain filename, without any
+		directories.
+
+		Default: "" (disabled)
+
+	--mirror-readme-from string
+		Optional. Path to a file whose content is written as the
+		`--mirror-readme`, instead of the built-in explanation (such as for a
+		translated one). Has no effect without `--mirror-readme`.
+
+		Default: "" (built-in)
+
+	--since-file string
+		Optional. Turns `--mode=move` into an incremental one for frequent
+		(scheduled) moves over a large mirror. The start time of a move is
+		recorded in the given file, but only if all files were moved (no unmoved
+		files or failures). Subsequent moves then only consider those mirror
+		files that were modified since the recorded time, less a margin of one
+		minute for any clock skew. Older files are neither moved nor counted as
+		unmoved files. Without the file, all files are considered (as usual).
+
+		Note that files placed into the mirror with preserved (older)
+		modification times, such as with `cp -p` or `rsync -a`, are not
+		considered; remove the file to consider all files again. Cannot be used
+		with `--plan-in`.
+
+		Default: "" (disabled)
+
+	--rename-rules string
+		Optional. A path to a file of ordered `regex => replacement` rules (one
+		per line), which transform the relative path of each file that is moved
+		with `--mode=move` into its location within the target, such as to
+		lightly restructure files while promoting them without arranging them in
+		the mirror first. The first rule whose regular expression matches the
+		(slash-separated) relative path wins, with the replacement supporting
+		the `$1` (or `${name}`) references to the groups of the expression. A
+		path matching no rule is moved unchanged. Empty lines and lines starting
+		with a `#` are ignored.
+
+		For example:
+		`^incoming/(2024)/(.*)$ => archive/$1/$2`
+
+		The rules are compiled at startup, with any invalid rule failing the
+		validation of the configuration. Any missing parent directories of a
+		transformed path are created, and the exclusions and the handling of
+		conflicting target files apply to the transformed path. A rule
+		transforming a path to outside of the target (or into the mirror) fails
+		the respective file.
+
+		Default: "" (disabled)
+
+	--on-duplicate-target [fail|first-wins|rename]
+		Optional. Decides the handling of files that `--rename-rules` would
+		transform onto the same target path (such as two files from different
+		directories routed into one), which are found by a walk of the mirror
+		ahead of the move. The first file of the walk always keeps the target
+		path, for the others:
+
+		  - `fail`: Fails the operation before any files were moved.
+		  - `first-wins`: Skips the other files, which remain within the mirror
+		    as unmoved files.
+		  - `rename`: Moves the other files to a disambiguated target path, with
+		    ` (2)`, ` (3)`, ... inserted before the extension of their name.
+
+		Files conflicting with files that already exist in the target are not
+		affected by this, these are handled as any other conflicting files.
+
+		Default: first-wins
+
+	--move-order [walk|depth-first-leaves]
+		Optional. Decides the order of operations in `--mode=move`. With `walk`,
+		directories are created as they are encountered, before any of the files
+		within them are moved. With `depth-first-leaves`, only the parent
+		directories needed for a file are created right before it is moved,
+		deferring the creation of any other (empty) directories until all files
+		were moved. This shortens the window in which the target contains new
+		directories, but not yet any of their files.
+
+		Default: walk
+
 	--skip-failed
 		Optional. Do not exit on non-fatal failures, skip the failed element
 		and proceed instead; returns with a partial failure return code.
+		The number of skipped failures is output in the final summary, while
+		each of them (with its path) is also listed with `--log-level=debug`.
+
+		Default: false
+
+	--skip-failed-max int
+		Optional. The maximum number of failures to skip with `--skip-failed`.
+		Once more failures than this have occurred, the operation is aborted
+		with a failure return code rather than skipping any further ones, as so
+		many failures likely point to a systemic problem (such as a target that
+		went read-only during the operation). A value of `0` skips any number of
+		failures.
+
+		Default: 0
+
+	--per-file-timeout duration
+		Optional. The maximum duration for copying any single file into its
+		working file (such as `30m`), so that one pathologically slow file (such
+		as on a failing disk) cannot stall an otherwise healthy operation. This
+		applies to all of the copies, also with `--verify-concurrency` and
+		`--atomic-batch`. A file exceeding it is aborted, its working file is
+		cleaned up and the file is failed (left unmoved), proceeding with the
+		other files under `--skip-failed` or `--no-fail-fast` (except with
+		`--atomic-batch`, where it fails the entire batch). A value of `0`
+		disables the timeout.
+
+		Default: 0
+
+	--no-fail-fast
+		Optional. Do not exit on non-fatal failures, skip the failed element and
+		proceed instead (as with `--skip-failed`), but still return with a
+		failure return code once all elements were processed. This tells apart
+		an operation which attempted everything, but had failures, from one
+		which merely skipped non-fatal failures. The number of failures is
+		output in the final summary. Has no effect together with
+		`--skip-failed`.
+
+		Default: false
+
+	--graceful-interrupt
+		Optional. Changes the handling of an interrupt signal (such as `SIGINT`
+		or `SIGTERM`). By default, the operation is aborted right away,
+		including any file that is in the middle of being copied (which is then
+		discarded). With this setting, a first signal only stops the operation
+		from starting on any further files (or directories), while the current
+		one is still finished, which avoids wasting the work on large files that
+		are nearly copied. A second signal then aborts the operation right away.
 
 		Default: false
 
+	--watch
+		Optional. Keeps the program running as a long-lived process, which
+		re-runs `--mode=move` in cycles, waiting for the `--watch-interval`
+		between them, instead of relying on an external scheduler (such as
+		cron). The options are resolved only once, and each cycle logs its own
+		summary along with the statistics accumulated over all cycles. A failing
+		cycle (such as with the target being unmounted) does not end the watch;
+		it is simply retried in the next cycle. A first interrupt signal stops
+		the watch after the current cycle, a second one also aborts the current
+		cycle. The return code is that of the last cycle. This setting cannot be
+		used together with `--plan-in`, `--plan-out`, `--dry-run-apply` or
+		`--apply-token`.
+
+		Default: false
+
+	--watch-interval duration
+		Optional. The interval to wait between the cycles of a `--watch` (such
+		as `15m`), counted from the end of each cycle. Required with `--watch`,
+		unless `--watch-events` is used.
+
+		Default: 0
+
+	--watch-events
+		Optional. Starts the cycles of a `--watch` on filesystem events within
+		the mirror (such as arriving files), instead of only in the
+		`--watch-interval`, so that files are moved in near-real-time. A cycle
+		starts once no further events were seen for the `--watch-quiet-period`,
+		so that files still being written are not grabbed. New directories
+		within the mirror are watched as they appear. With a `--watch-interval`,
+		cycles also start in that interval, such as to catch any missed events.
+		The events are those of the operating system (such as inotify on Linux),
+		which may limit the number of watched directories; the watch fails to
+		start where these are not available.
+
+		Default: false
+
+	--watch-quiet-period duration
+		Optional. The period without any filesystem events after which a cycle
+		of `--watch-events` starts (such as `30s`). It should be longer than the
+		pauses of the slowest clients writing into the mirror.
+
+		Default: 10s
+
 	--slow-mode
 		Optional. Adds a 1 second timeout after each 50 directories created
 		in `--mode=init`; helps avoid thrashing more sensitive filesystems.
 
 		Default: false
 
+	--dir-rate int
+		Optional. Limits the directory creations in `--mode=init` to this many
+		per second, pacing each of the creations evenly rather than pausing
+		after a batch of them. Supersedes `--slow-mode` when both are set. A
+		value of `0` disables the limit.
+
+		Default: 0
+
 	--init-depth int
 		Optional. A numeric value that decides how deep directories are
 		mirrored in `--mode=init`. A value of 0 mirrors only the contents
@@ -135,9 +998,171 @@ system locations; ensure they are executable by running `chmod +x` before use.
 
 		Default: -1
 
+	--init-depth-rule value
+		Optional. A depth limit for the directories below a path (relative to
+		`--target`) in `--mode=init`, as `RELPATH:NUM`, overriding
+		`--init-depth` for them. Can be repeated, with the most specific
+		matching rule being used; directories not below any rule's path fall
+		back to `--init-depth`. The value counts the same way as with
+		`--init-depth`, but from the rule's path; a value of 0 mirrors only its
+		contents, while negative values impose no limit. The rule's path itself
+		(and its parents) are always mirrored.
+
+		For example: `--init-depth-rule=projects:-1 --init-depth-rule=media:1`
+
+	--init-changed-since duration
+		Optional. Turns `--mode=init` into an incremental one, which only
+		creates those mirror directories whose target directory was modified
+		within the given duration (such as `24h`), for large and mostly static
+		targets where only few parts change. The existing mirror is kept as it
+		is (and not required to be empty), so any older mirror directories
+		remain intact, as do those that already exist.
+
+		Older parent directories of a recently modified directory are also
+		created, if they are missing. Note that a directory's modification time
+		changes only with its direct contents, so all of the target is still
+		walked.
+
+		Default: 0 (disabled)
+
+	--init-merge
+		Optional. Merges the target structure into an existing mirror in
+		`--mode=init`, instead of requiring the mirror to be empty and
+		re-creating it. Only those mirror directories that do not yet exist are
+		created, while all of the existing mirror is kept as it is, including
+		any files that are staged within it but not yet moved. This allows for
+		adding newly created target directories to the mirror without having to
+		run `--mode=move` first.
+
+		Mirror directories whose target directory no longer exists are not
+		removed. With this setting, `--two-phase-init` has no effect on an
+		existing mirror.
+
+		Default: false
+
+	--preserve-dir-times
+		Optional. Applies the modification times of the target directories to
+		their created mirror directories in `--mode=init`, so that the mirror
+		can be sorted by date just like the target. The times are applied after
+		all directories were created, children before their parents, as creating
+		a child directory changes the modification time of its parent. A time
+		that cannot be applied is warned about, but does not fail the mirroring.
+
+		Default: false
+
+	--skip-empty-target-dirs
+		Optional. Do not mirror target directories in `--mode=init` that contain
+		no files anywhere below them, keeping the mirror free of empty directory
+		skeletons that are not expected to receive any files. Directories
+		leading to any files are always mirrored.
+
+		Note that this requires an additional walk of the target below each of
+		the directories, which can add considerable cost for large and deep
+		targets.
+
+		Default: false
+
+	--init-skip-dirs-over string
+		Optional. Does not mirror those target directories in `--mode=init`
+		whose files (recursively) take up more than the given size, such as
+		`500GiB`, for (archive) directories that are never staged into. Accepts
+		a size in bytes, optionally with a binary suffix (`K`/`KiB`, `M`/`MiB`,
+		`G`/`GiB` or `T`/`TiB`).
+
+		This is expensive for large targets, as all of the target's files are
+		walked to calculate the sizes (which are calculated once per directory
+		and cached for the run).
+
+		Default: "" (disabled)
+
+	--two-phase-init
+		Optional. Builds the new mirror structure of `--mode=init` in a hidden
+		sibling directory of the mirror root (`.<mirror>.init`) rather than in
+		place, and only then swaps it into place of the existing mirror, which
+		remains available to any clients for the duration of the (possibly long)
+		directory walk. The sibling is on the same filesystem as the mirror
+		root, so on Linux the swap is a single atomic exchange of both
+		directories, after which the old one is removed, and the mirror root is
+		never absent. Where the exchange is not supported, the swap consists of
+		two quick renames: the existing mirror is set aside as `.<mirror>.old`,
+		the new one is renamed into place, and the set aside one is removed.
+
+		If building the new mirror fails, it is removed and the existing mirror
+		is left untouched; if the swap fails, the existing mirror is restored.
+		As clients may stage files into the existing mirror during the walk, it
+		is checked for emptiness once more before it is removed; if it contains
+		files, it is kept as `.<mirror>.old` (with the new mirror in place) and
+		the operation fails with return code `3`, for the files to be resolved
+		by the user. Not applicable with an existing mirror that is kept (see
+		`--init-changed-since` and `--allow-mountpoint-mirror`), in which case
+		the mirror is built in place.
+
+		Default: false
+
+	--diff-exit
+		Optional. With `--mode=init` and `--dry-run`, compares the mirror
+		structure that would be created with the existing mirror, warning about
+		any directories that would be added or removed, and returns with return
+		code `10` if there are any. Without changes, the return code is `0`.
+		This allows for a scheduled drift detection, such as alerting when the
+		target structure has changed since the mirror was last built, without
+		modifying the mirror.
+
+		With `--init-merge` or `--init-changed-since`, the existing mirror is
+		kept, so only the directories that would be added are reported.
+
+		Default: false
+
+	--target-glob string
+		Optional. Relative path pattern (as understood by Go's `filepath.Match`)
+		restricting which subtrees are mirrored in `--mode=init`. Can be
+		repeated. Only directories whose path relative to `--target` matches at
+		least one pattern, along with everything below them, are created within
+		the mirror. Any parents needed to reach a matching directory are also
+		created, but none of their other children.
+
+		For example, `archive/20??` mirrors all year directories of the archive
+		(and the archive itself), without mirroring any of their siblings. Each
+		pattern component matches exactly one path component, except for a
+		component of just `**`, which matches any number of them.
+
+	--case-collision [none|merge|warn|fail]
+		Optional. Decides how target directories differing only by case (such as
+		`Photos` and `photos`) are handled in `--mode=init`, as these would
+		collapse into one another on a case-insensitive mirror. With `none`, no
+		detection takes place and all directories are mirrored as they are. With
+		`merge`, each colliding directory is merged into the first seen one, so
+		its subdirectories are mirrored there. With `warn`, each colliding
+		directory is skipped (along with everything below it) and a warning is
+		emitted. With `fail`, the operation fails (or skips the directory with
+		`--skip-failed`).
+
+		Default: none
+
+	--case-insensitive-paths
+		Optional. Compares the paths of the mirror root (and of any `--exclude`)
+		with the walked paths case-insensitively, as a case-insensitive volume
+		may yield these in a different case than they were given in (such as
+		`/real/incoming` for a `--mirror` of `/real/Incoming`). Without it, such
+		a mirror root within the target is not recognized as such, and the
+		mirror could be mirrored into itself.
+
+		Default: false
+
+	--umask string
+		Optional. Sets the umask (as an octal mask, such as `022`) for the
+		duration of the operation, so that all of the created directories and
+		files receive deterministic permissions, regardless of the umask that is
+		inherited from the environment. The previous umask is restored
+		afterwards. Has no effect on platforms without a umask (such as
+		Windows), where a warning is emitted instead.
+
+		Default: "" (inherited umask)
+
 	--dry-run
 		Optional. Perform a preview of operations, without filesystem changes.
-		Useful for verifying behavior before execution.
+		Useful for verifying behavior before execution. The summary reports the
+		directories and files that would be created and moved in a real run.
 
 		Default: false
 
@@ -146,9 +1171,135 @@ system locations; ensure they are executable by running `chmod +x` before use.
 
 		Default: info
 
+	--log-format [text|json|logfmt]
+		Optional. Controls the format of the operational logs that are emitted.
+		Both `json` and `logfmt` (space-separated `key=value` pairs) allow for
+		programmatic parsing of output from standard error (stderr).
+
+		Default: text
+
+	--log-source
+		Optional. Includes the location in the source code (file and line) that
+		emitted each log record, in any of the `--log-format` formats. This
+		helps with diagnosing which code path led to a certain skip or error,
+		but is otherwise not needed.
+
+		Default: false
+
+	--run-id string
+		Optional. Sets the identifier of the run, which is added as `run_id` to
+		every log line, so that all logs of a single run (such as one invocation
+		by a scheduler) can be correlated, even when the logs of many runs end
+		up interleaved in the same place. Without this option, a unique
+		identifier is generated from the start time of the run (in UTC) and a
+		random suffix, such as `20250102T030405Z-1a2b3c4d`.
+
+		The identifier must not contain any whitespace or non-printable
+		characters.
+
+		Default: "" (generated)
+
+	--trace-spans
+		Optional. Logs a begin and an end event (`span started` and `span
+		ended`) for the run and for each of its phases, such as the preflight
+		checks and the mode itself. The end event carries the duration of the
+		phase and any error it ended with. Each span has its own `span_id` (and
+		the `parent_span_id` of the phase it is part of), while all log lines of
+		a run share the same `trace_id`, so that these can be correlated within
+		a tracing backend. Best used with `--log-format=json`.
+
+		Default: false
+
+	--explain-config
+		Optional. Logs the final value of each option after all of the
+		configuration was resolved, along with its origin: `cli` (the
+		command-line), `config` (the configuration file) or `default`. This
+		makes it obvious which of these took precedence for an option, such as
+		for an unexpected value within the configuration file.
+
+		Default: false
+
+	--path-encoding [escape|base64]
+		Optional. Controls how logged values that are not valid UTF-8 (such as
+		the names of legacy files in Latin-1) are emitted, so that the logs (and
+		especially `json`) remain valid. With `escape`, each invalid byte is
+		emitted as `\xNN`. With `base64`, the entire value is emitted
+		base64-encoded and prefixed with `base64:`, which allows for recovering
+		the exact bytes. Values that are valid UTF-8 are always emitted
+		unchanged.
+
+		This only concerns the output, the files themselves are moved with their
+		names unchanged.
+
+		Default: escape
+
+	--result-json
+		Optional. Prints the result of the operation as exactly one compact JSON
+		line to standard output at its end, regardless of the `--log-format`,
+		for capturing it from within shell scripts (such as with
+		`result=$(mirrorshuttle ...)`). Any other output that would go to
+		standard output (the program banner and the printed configuration) goes
+		to standard error instead. The logs are always emitted to standard
+		error.
+
+		For example:
+		`{"mode":"move","exit":4,"moved":2,"unmoved":1,"created":1,"bytes":15}`
+
+		The `exit` is the return code of the program, `moved` and `unmoved`
+		count the (un)moved files, `created` counts the created directories, and
+		`bytes` sums up the sizes of the moved files. With `--dry-run`, these
+		preview the real run.
+
+		Default: false
+
+	--summary-template string
+		Optional. Renders a single summary line from the given Go
+		`text/template` to standard output at the end of the operation, for
+		fitting the summary into existing log parsers without parsing the logs.
+		As with `--result-json`, any other output that would go to standard
+		output goes to standard error instead. An invalid template fails the
+		validation of the configuration. This setting cannot be used together
+		with `--result-json`, `--dry-run-reproducible` or `--list-plan-only`.
+
+		The available fields are `{{.Mode}}`, `{{.ExitCode}}`, `{{.Moved}}`,
+		`{{.Unmoved}}`, `{{.Created}}` and `{{.Bytes}}`, with the same meanings
+		as within `--result-json`.
+
+		For example:
+		`--summary-template='{{.Mode}} moved={{.Moved}} exit={{.ExitCode}}'`
+
+		Default: "" (disabled)
+
+	--report string
+		Optional. Writes a JSON report of the operation to the given path at the
+		end, also when it ended with failures, for capturing its results from
+		within scripts. With `-`, the report is written to standard output
+		instead, where (as with `--result-json`) any other output that would go
+		to standard output goes to standard error. This cannot be used together
+		with `--result-json`, `--summary-template`, `--dry-run-reproducible` or
+		`--list-plan-only`.
+
+		The report contains the `mode`, `dry_run`, the `exit` code, the
+		`started` time and `duration_seconds`, the counts of `created`
+		directories, `moved` and `unmoved` files, moved `bytes` and skipped
+		`failures`, along with a list of all `skipped` paths (each with its
+		`path` and the `reason` as in the operational logs), such as the files
+		that were not moved due to a conflicting target file.
+
+		Default: "" (disabled)
+
+	--report-format [json|csv]
+		Optional. Decides the format of the `--report`. With `json`, the report
+		is written as described above. With `csv`, it is written as CSV (as of
+		RFC 4180, with a header row) instead, with a row for each of the
+		`skipped` paths and the columns `path` and `reason`, for reviewing these
+		within a spreadsheet. The counts of the operation are only contained
+		within the `json` report.
+
+		Default: json
+
 	--json
-		Optional. Outputs in JSON format the operational logs that are emitted.
-		Allows for programmatic parsing of output from standard error (stderr).
+		Optional. Deprecated alias for `--log-format=json`, which is preferred.
 
 		Default: false
 
@@ -156,19 +1307,116 @@ system locations; ensure they are executable by running `chmod +x` before use.
 
 	mirror: /mirror/path
 	target: /real/path
+	allow-symlinked-target: false
+	allow-mountpoint-mirror: false
+	assume-empty-mirror: false
+	create-target-root: false
+	probe-writable: false
+	max-load: 0
+	require-target-perms: ""
 	exclude:
 	  - /real/path/skip-this
 	  - /real/path/temp
+	include: []
+	exclude-rel:
+	  - tmp
+	exclude-name:
+	  - Thumbs.db
+	  - .DS_Store
+	exclude-name-ignore-case: false
+	skip-system-dirs: false
+	system-dir-names:
+	  - "@eaDir"
+	  - "#recycle"
+	exclude-glob-mirror:
+	  - "*.partial"
+	exclude-glob-target:
+	  - "/mnt/user/media/.cache*"
+	list-excluded: false
 	direct: false
+	bulk-rename-dirs: false
 	verify: false
+	verify-read-error: fail
+	verify-concurrency: 1
+	deferred-verify: false
+	stat-before-remove: false
+	no-clobber-working-file: false
+	update-metadata-on-match: false
+	overwrite: never
+	target-trash-dir: ""
+	type-change: fail
+	inherit-parent-perms: false
+	lock-promoted: false
+	compare-by: hash
+	quarantine-dir: ""
+	quarantine-source: false
+	copy-buffer-size: ""
+	atomic-batch: false
+	dedupe-run: none
+	hash-algorithms:
+	  - sha256
+	checksum-algo: ""
+	checksum-sidecar: false
 	skip-empty: true
 	remove-empty: false
+	clean-mirror-on-success: false
+	post-move-command: ""
+	plan-out: ""
+	plan-in: ""
+	dry-run-reproducible: false
+	list-plan-only: false
+	dry-run-apply: false
+	apply-token: ""
+	mirror-manifest: ""
+	verify-target-structure: ""
+	mirror-readme: ""
+	mirror-readme-from: ""
+	since-file: ""
+	rename-rules: ""
+	on-duplicate-target: first-wins
+	move-order: walk
 	skip-failed: false
+	skip-failed-max: 0
+	per-file-timeout: 0s
+	no-fail-fast: false
+	graceful-interrupt: false
+	watch: false
+	watch-interval: 0s
+	watch-events: false
+	watch-quiet-period: 10s
 	slow-mode: false
+	dir-rate: 0
 	init-depth: -1
+	init-depth-rule: []
+	init-changed-since: 0s
+	init-merge: false
+	preserve-dir-times: false
+	skip-empty-target-dirs: false
+	init-skip-dirs-over: ""
+	two-phase-init: false
+	diff-exit: false
+	target-glob: []
+	case-collision: none
+	case-insensitive-paths: false
+	umask: ""
 	dry-run: false
 	log-level: info
+	log-format: text
+	log-source: false
+	run-id: ""
+	trace-spans: false
+	path-encoding: escape
+	result-json: false
+	summary-template: ""
+	report: ""
+	report-format: json
 	json: false
+	manifest: ""
+	heartbeat-file: ""
+	heartbeat-interval: 10s
+	stats-interval: 0s
+	progress-file: ""
+	progress-interval: 2s
 
 For convenience, a default configuration is provided within the repository.
 Invalid configurations (unknown or malformed fields) are rejected at runtime.
@@ -181,6 +1429,11 @@ Invalid configurations (unknown or malformed fields) are rejected at runtime.
   - `3`: Mirror directory contains unmoved files (with `--mode=init`)
   - `4`: Unmoved files due to conflicting target files (with `--mode=move`)
   - `5`: Invalid command-line arguments and/or configuration file provided
+  - `6`: Files failed verification against the manifest (with `--mode=check`)
+  - `7`: Target structure diverged (with `--verify-target-structure`)
+  - `8`: Moved files failed their deferred verification (with `--deferred-verify`)
+  - `9`: Run deferred, as the system load was too high (with `--max-load`)
+  - `10`: Mirror structure would change (with `--diff-exit`)
 
 # IMPLEMENTATION
 
@@ -243,12 +1496,31 @@ are explicitly safe and in a known-consistent state. As a result, even minor
 issues can cause the process to halt, but this behavior ensures users retain
 full control over the outcome and can take corrective action with confidence.
 
+Files are first copied into working files carrying a `.mirsht` suffix, before
+being renamed to their final name within the target. To never confuse any user
+data with these working files, any files in the mirror that themselves end with
+this suffix are not moved, but left in place and reported as unmoved files.
+
+Similarly, any of the program's own files that reside within the mirror (the
+`--config`, `--manifest`, `--plan-out`, `--plan-in`, `--heartbeat-file`,
+`--progress-file`, `--rename-rules` or `--report`) are implicitly excluded, with
+a warning, so that these are never moved themselves.
+
+In `--mode=move`, an exclusion can match either the source path (within the
+mirror) or the target path it would be moved to, with the source side being
+checked first. Either way, the file or directory stays within the mirror (and
+excluded directories are not descended into). Excluded paths and glob patterns
+are deliberate, so such files do not count as unmoved. Files excluded by their
+name (`--exclude-name`) are meant for resolution by the user, so these do count
+as unmoved (as reflected in the return code).
+
 The program is intentionally designed not to be run as root. All operations are
 expected to be performed under a regular user account. When moving files back
 into the target structure, ownership of those files will reflect the user
 executing the tool. Additionally, file and directory permissions are created
-respecting the environment's current `umask`, ensuring predictable behavior
-across environments without requiring privileged access.
+respecting the environment's current `umask` (or the one set with `--umask`),
+ensuring predictable behavior across environments without requiring privileged
+access.
 
 # POSSIBLE USE CASES IN PRODUCTION
 
@@ -276,6 +1548,8 @@ import (
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -289,47 +1563,238 @@ const (
 	exitCodeMirrNotEmpty   = 3
 	exitCodeUnmovedFiles   = 4
 	exitCodeConfigFailure  = 5
+	exitCodeFailedChecks   = 6
+	exitCodeTargetDiverged = 7
+	exitCodeDeferredVerify = 8
+	exitCodeDeferredLoad   = 9
+	exitCodeMirrorDiffers  = 10
 
 	dirCreationBatch   = 50
 	dirCreationTimeout = 1 * time.Second
 
+	configStdin  = "-"
+	configDirExt = ".yaml"
+
+	configOriginCLI     = "cli"
+	configOriginConfig  = "config"
+	configOriginDefault = "default"
+
+	moveOrderWalk        = "walk"
+	moveOrderLeavesFirst = "depth-first-leaves"
+
+	duplicateTargetFail      = "fail"
+	duplicateTargetFirstWins = "first-wins"
+	duplicateTargetRename    = "rename"
+
+	overwriteNever       = "never"
+	overwriteAlways      = "always"
+	overwriteIfNewer     = "if-newer"
+	overwriteIfDifferent = "if-different"
+
+	verifyReadErrorFail  = "fail"
+	verifyReadErrorRetry = "retry"
+	verifyReadErrorSkip  = "skip"
+
+	compareByHash  = "hash"
+	compareBySize  = "size"
+	compareByMtime = "mtime"
+
+	dedupeRunNone = "none"
+	dedupeRunLink = "link"
+	dedupeRunSkip = "skip"
+
+	verifyReadRetries    = 3
+	verifyReadRetryDelay = 250 * time.Millisecond
+	verifyQueueFactor    = 2 // Pending verify passes per goroutine of --verify-concurrency.
+
+	hashAlgoSHA256 = "sha256"
+	hashAlgoSHA512 = "sha512"
+	hashAlgoBLAKE3 = "blake3"
+	hashAlgoCRC32C = "crc32c"
+	blake3Size     = 32
+
+	caseCollisionNone  = "none"
+	caseCollisionMerge = "merge"
+	caseCollisionWarn  = "warn"
+	caseCollisionFail  = "fail"
+
+	typeChangeSkip = "skip"
+	typeChangeFail = "fail"
+
+	logFormatText   = "text"
+	logFormatJSON   = "json"
+	logFormatLogfmt = "logfmt"
+
+	pathEncodingEscape = "escape"
+	pathEncodingBase64 = "base64"
+
+	reportFormatJSON = "json"
+	reportFormatCSV  = "csv"
+
+	targetPermsMaxPrefix = "max:"
+
+	workingFileSuffix   = ".mirsht"
+	removableProbeName  = ".removable"
+	writableProbeName   = ".writable"
+	twoPhaseBuildSuffix = ".init"
+	twoPhaseOldSuffix   = ".old"
+	maxSymlinkHops      = 40
+
+	minCopyBufferSize = 4 << 10
+	maxCopyBufferSize = 256 << 20
+
 	dirBasePerm      = 0o777
+	workingFilePerm  = 0o666
 	defaultLogLevel  = slog.LevelInfo
 	defaultInitDepth = -1
 
 	exitTimeout = 10 * time.Second
+
+	defaultHeartbeatInterval = 10 * time.Second
+	defaultWatchQuietPeriod  = 10 * time.Second
+	heartbeatFilePerm        = 0o644
+	defaultProgressInterval  = 2 * time.Second
 )
 
 var (
 	// Version is the application's version (filled in during compilation).
 	Version string
 
-	errArgConfigMalformed     = errors.New("--config yaml file is malformed")
-	errArgConfigMissing       = errors.New("--config yaml file does not exist")
-	errArgExcludePathNotAbs   = errors.New("--exclude paths must all be absolute")
-	errArgMirrorTargetNotAbs  = errors.New("--mirror and --target paths must all be absolute")
-	errArgMirrorTargetSame    = errors.New("--mirror and --target paths cannot be the same")
-	errArgMissingMirrorTarget = errors.New("--mirror and --target paths must both be set")
-	errArgModeMismatch        = errors.New("--mode must either be 'init' or 'move'")
-	errArgInvalidLogLevel     = errors.New("--log-level has a not recognized value")
-
-	errMemoryHashMismatch   = errors.New("in-memory hash mismatch; possible corruption during in-memory I/O")
-	errVerifyHashMismatch   = errors.New("--verify pass hash mismatch; possible corruption during disk-write I/O")
-	errMirrorNotEmpty       = errors.New("--mirror contains files; run with --mode=move to relocate them, or remove the files manually")
-	errMirrorNotExist       = errors.New("--mirror does not exist; have nowhere to move from")
-	errTargetNotExist       = errors.New("--target does not exist; have nowhere to mirror from or move to")
-	errMirrorParentNotExist = errors.New("--mirror parent does not exist; cannot create mirror inside it")
-	errMirrorParentNotDir   = errors.New("--mirror parent is not a directory; cannot create mirror inside it")
+	errArgConfigMalformed          = errors.New("--config yaml file is malformed")
+	errArgConfigMissing            = errors.New("--config yaml file does not exist")
+	errArgExcludePathNotAbs        = errors.New("--exclude paths must all be absolute, or valid glob patterns")
+	errArgIncludePathNotAbs        = errors.New("--include paths must all be absolute")
+	errArgExcludeNameInvalid       = errors.New("--exclude-name must all be basenames, without any path separators")
+	errArgSystemDirNameInvalid     = errors.New("--system-dir-names must all be basenames, without any path separators")
+	errArgMirrorReadmeInvalid      = errors.New("--mirror-readme must be a basename, without any path separators")
+	errArgExcludeRelInvalid        = errors.New("--exclude-rel paths must all be relative and within their root")
+	errArgMirrorTargetNotAbs       = errors.New("--mirror and --target paths must all be absolute")
+	errArgMirrorTargetSame         = errors.New("--mirror and --target paths cannot be the same")
+	errArgMissingMirrorTarget      = errors.New("--mirror and --target paths must both be set")
+	errArgModeMismatch             = errors.New("--mode must either be 'init', 'move' or 'check'")
+	errArgMissingManifest          = errors.New("--target and --manifest paths must both be set with --mode=check")
+	errArgInvalidLogLevel          = errors.New("--log-level has a not recognized value")
+	errArgHeartbeatInterval        = errors.New("--heartbeat-interval must be a positive duration")
+	errArgWatchEventsInvalid       = errors.New("--watch-events can only be used with --watch and a positive --watch-quiet-period")
+	errArgWatchInvalid             = errors.New("--watch can only be used with --mode=move and a positive --watch-interval (or --watch-events), and without --plan-in, --plan-out, --dry-run-apply or --apply-token")
+	errArgStatsInterval            = errors.New("--stats-interval cannot be a negative duration")
+	errArgProgressInterval         = errors.New("--progress-interval must be a positive duration")
+	errArgCopyBufferSizeInvalid    = errors.New("--copy-buffer-size must be a size between 4KiB and 256MiB")
+	errArgInitSkipDirsOverInvalid  = errors.New("--init-skip-dirs-over must be a size greater than zero")
+	errArgInitDepthRuleInvalid     = errors.New("--init-depth-rule must all be in the format of RELPATH:DEPTH")
+	errArgMirrorManifestInvalid    = errors.New("--mirror-manifest path cannot be within --mirror")
+	errArgTargetStructureInvalid   = errors.New("--verify-target-structure path cannot be within --mirror")
+	errArgSinceFileInvalid         = errors.New("--since-file path cannot be within --mirror or be used with --plan-in")
+	errArgInitChangedSince         = errors.New("--init-changed-since must not be a negative duration")
+	errArgPostMoveCommandEmpty     = errors.New("--post-move-command must contain a command to run")
+	errArgInvalidLogFormat         = errors.New("--log-format must either be 'text', 'json' or 'logfmt'")
+	errArgPathEncodingInvalid      = errors.New("--path-encoding must either be 'escape' or 'base64'")
+	errArgReportFormatInvalid      = errors.New("--report-format must either be 'json' or 'csv'")
+	errArgExcludeGlobInvalid       = errors.New("--exclude-glob-mirror and --exclude-glob-target patterns must all be valid, and either absolute or name patterns")
+	errArgTargetGlobInvalid        = errors.New("--target-glob patterns must all be valid and relative")
+	errArgUmaskInvalid             = errors.New("--umask must be an octal mask between 000 and 777")
+	errArgTargetPermsInvalid       = errors.New("--require-target-perms must be an octal mode between 000 and 777, optionally prefixed with 'max:'")
+	errArgVerifyReadErrorInvalid   = errors.New("--verify-read-error must either be 'fail', 'retry' or 'skip'")
+	errArgRunIDInvalid             = errors.New("--run-id must not contain any whitespace or non-printable characters")
+	errArgPerFileTimeoutInvalid    = errors.New("--per-file-timeout must not be a negative duration")
+	errArgSkipFailedMaxInvalid     = errors.New("--skip-failed-max cannot be negative")
+	errArgDirRateInvalid           = errors.New("--dir-rate cannot be negative")
+	errArgMaxLoadInvalid           = errors.New("--max-load cannot be negative")
+	errArgVerifyConcurrency        = errors.New("--verify-concurrency cannot be negative")
+	errArgDeferredVerifyConflict   = errors.New("--deferred-verify cannot be used together with --verify")
+	errArgBulkRenameDirsConflict   = errors.New("--bulk-rename-dirs cannot be used with --atomic-batch, --dedupe-run, --post-move-command, --checksum-sidecar, --inherit-parent-perms, --lock-promoted or --move-order=depth-first-leaves")
+	errArgRenameRulesInvalid       = errors.New("--rename-rules must be a readable file of valid 'regex => replacement' lines")
+	errArgOnDuplicateTargetInvalid = errors.New("--on-duplicate-target must either be 'fail', 'first-wins' or 'rename'")
+	errArgOverwriteInvalid         = errors.New("--overwrite must either be 'never', 'always', 'if-newer' or 'if-different'")
+	errArgOverwriteConflict        = errors.New("--overwrite cannot be used together with --plan-out or --plan-in")
+	errArgMoveOrderInvalid         = errors.New("--move-order must either be 'walk' or 'depth-first-leaves'")
+	errArgPlanOutInvalid           = errors.New("--plan-out can only be used with --mode=move and --dry-run")
+	errArgDryRunApply              = errors.New("--dry-run-apply can only be used with --mode=move and --dry-run")
+	errArgApplyToken               = errors.New("--apply-token can only be used with --mode=move, and not with --dry-run")
+	errArgDiffExit                 = errors.New("--diff-exit can only be used with --mode=init and --dry-run")
+	errArgDryRunReproducible       = errors.New("--dry-run-reproducible can only be used with --mode=move and --dry-run, and without --result-json")
+	errArgSummaryTemplateInvalid   = errors.New("--summary-template must be a valid Go text/template of the summary fields")
+	errArgSummaryTemplateConflict  = errors.New("--summary-template cannot be used together with --result-json, --dry-run-reproducible or --list-plan-only")
+	errArgReportConflict           = errors.New("--report=- cannot be used together with --result-json, --summary-template, --dry-run-reproducible or --list-plan-only")
+	errArgListPlanOnly             = errors.New("--list-plan-only can only be used with --mode=move and --dry-run, and without --result-json or --dry-run-reproducible")
+	errArgPlanInInvalid            = errors.New("--plan-in can only be used with --mode=move and without --plan-out")
+	errArgHashAlgorithmInvalid     = errors.New("--hash-algorithms must all be either 'sha256', 'sha512', 'blake3' or 'crc32c'")
+	errArgHashAlgorithmDuplicate   = errors.New("--hash-algorithms must not contain any algorithm more than once")
+	errArgChecksumAlgoInvalid      = errors.New("--checksum-algo must either be 'sha256', 'blake3' or 'crc32c'")
+	errArgChecksumAlgoConflict     = errors.New("a crc32c checksum cannot be used together with --dedupe-run=link, --update-metadata-on-match or --overwrite=if-different")
+	errArgAtomicBatchDirect        = errors.New("--atomic-batch cannot be used together with --direct")
+	errArgDedupeRunInvalid         = errors.New("--dedupe-run must either be 'none', 'link' or 'skip'")
+	errArgExplainExitInvalid       = errors.New("--explain-exit must be one of the known return codes")
+	errArgQuarantineDirInvalid     = errors.New("--quarantine-dir must be an absolute path outside of --mirror and --target")
+	errArgTargetTrashDirInvalid    = errors.New("--target-trash-dir must be an absolute path outside of --mirror and --target, and requires --overwrite")
+	errArgCompareByInvalid         = errors.New("--compare-by must either be 'hash', 'size' or 'mtime'")
+	errArgDedupeRunAtomicBatch     = errors.New("--dedupe-run cannot be used together with --atomic-batch")
+	errArgTypeChangeInvalid        = errors.New("--type-change must either be 'skip' or 'fail'")
+	errArgCaseCollisionInvalid     = errors.New("--case-collision must either be 'none', 'merge', 'warn' or 'fail'")
+
+	errMemoryHashMismatch      = errors.New("in-memory hash mismatch; possible corruption during in-memory I/O")
+	errEmptySourceChanged      = errors.New("source file is no longer empty; it was written to during the move")
+	errWorkingFileExists       = errors.New("working file already exists; possibly in use by a concurrent run")
+	errVerifyHashMismatch      = errors.New("--verify pass hash mismatch; possible corruption during disk-write I/O")
+	errStatSizeMismatch        = errors.New("--stat-before-remove size mismatch; possible failure during disk-write I/O")
+	errMirrorNotEmpty          = errors.New("--mirror contains files; run with --mode=move to relocate them, or remove the files manually")
+	errMirrorNotRemovable      = errors.New("--mirror cannot be removed in full; ensure its directories allow for the removal of entries")
+	errMirrorNotExist          = errors.New("--mirror does not exist; have nowhere to move from")
+	errTargetNotExist          = errors.New("--target does not exist; have nowhere to mirror from or move to")
+	errMirrorParentNotExist    = errors.New("--mirror parent does not exist; cannot create mirror inside it")
+	errDuplicateTarget         = errors.New("--rename-rules transformed multiple files onto the same target path")
+	errRenameRuleOutside       = errors.New("--rename-rules transformed a path to outside of the target (or into the mirror)")
+	errTargetTypeChanged       = errors.New("target path changed its type (between directory and file) since the mirror was created")
+	errCaseCollision           = errors.New("--target contains directories differing only by case")
+	errTargetIsSymlink         = errors.New("--target is a symbolic link; use --allow-symlinked-target to resolve it")
+	errTargetPermsMismatch     = errors.New("--target does not have the permissions required by --require-target-perms")
+	errQuarantineNotWritable   = errors.New("quarantine directory is not writable")
+	errTargetTrashNotWritable  = errors.New("target trash directory is not writable")
+	errGracefulInterrupt       = errors.New("interrupted after finishing the current element")
+	errWatchEventsUnavailable  = errors.New("filesystem events are not available")
+	errMirrorParentNotDir      = errors.New("--mirror parent is not a directory; cannot create mirror inside it")
+	errPerFileTimeout          = errors.New("--per-file-timeout exceeded; the file took too long to move, possibly due to a failing disk")
+	errSkipFailedMax           = errors.New("--skip-failed-max exceeded; too many failures, possibly a systemic problem")
+	errTargetNotWritable       = errors.New("--target is not writable; possibly a read-only (re)mount")
+	errTargetNotDir            = errors.New("--target is not a directory; have nowhere to move to")
+	errManifestMissing         = errors.New("--manifest file does not exist")
+	errPlanMissing             = errors.New("--plan-in file does not exist")
+	errApplyTokenMismatch      = errors.New("--apply-token does not match; the mirror (or the options) changed since the --dry-run")
+	errPlanMalformed           = errors.New("--plan-in file is malformed")
+	errPlanStale               = errors.New("--plan-in no longer matches the filesystem; create and approve a new plan")
+	errMirrorManifestMissing   = errors.New("--mirror-manifest file does not exist; run --mode=init with it first")
+	errTargetStructureMissing  = errors.New("--verify-target-structure file does not exist; run --mode=init with it first")
+	errTargetStructureDiverged = errors.New("--target structure diverged from the one recorded in --mode=init; re-run --mode=init")
+	errSinceFileMalformed      = errors.New("--since-file does not contain a valid time; remove it to consider all files again")
+	errManifestMalformed       = errors.New("--manifest file is malformed")
 )
 
 type program struct {
 	fsys   afero.Fs
-	stdout io.Writer
-	stderr io.Writer
+	runner commandRunner
+	umask  func(mask int) (old int, ok bool)
+	load   func() (avg float64, ok bool)
+	after  func(d time.Duration) <-chan time.Time
+
+	newWatcher func() (eventWatcher, error)
+	link       func(oldname string, newname string) error
+	exchange   func(oldpath string, newpath string) error
+	stdin      io.Reader
+	stdout     io.Writer
+	stderr     io.Writer
 
 	state *programState
 	opts  *programOptions
 
+	stopWatch chan struct{} // Closed to stop a --watch after its current cycle.
+
+	configFile    string
+	configDir     string
+	traceID       string            // The identifier shared by all log lines of a run (with --trace-spans).
+	configOrigins map[string]string // The origin of each option that was set, by its name (for --explain-config).
+	renameRules   []renameRule      // The compiled --rename-rules, in the order in which they are tried.
+	copyBuffers   *sync.Pool        // The reused buffers of the --copy-buffer-size, as parsed once.
+
 	log   *slog.Logger
 	flags *flag.FlagSet
 
@@ -337,27 +1802,143 @@ type program struct {
 }
 
 type programState struct {
+	mu          sync.Mutex  // Guards the moved counters and progress, which are also read by the --stats-interval and --progress-file.
+	interrupted atomic.Bool // Set by a first interrupt signal with --graceful-interrupt.
+
 	createdDirs        int
 	movedFiles         int
+	movedBytes         int64
+	unmovedFiles       int
+	checkedFiles       int
+	deferredFailures   int // Moved files that failed their verification after the move (with --deferred-verify).
+	mirrorChanges      int // Mirror directories that the init would add or remove (with --diff-exit).
 	hasUnmovedFiles    bool
 	hasPartialFailures bool
+	hasHardFailures    bool
+	hasFailedChecks    bool
+	currentFile        string // The file that is currently being moved, for the --progress-file.
+	totalFiles         int    // The files known to be moved in total (with --plan-in), for the --progress-file.
+	totalBytes         int64
+	movedSince         time.Time
+	movedHashes        map[string]string // Hashes of the files moved in the run (with --dedupe-run), to their targets.
+	plannedOps         []planOperation
+	skippedPaths       []reportPath      // The paths that were skipped in the run (with --report).
+	diffDirs           []string          // The relative mirror directories that the init would create (with --diff-exit).
+	targetDirs         []string          // The relative target directories that the init walked and mirrored (with --verify-target-structure).
+	duplicateTargets   map[string]string // Sources that --rename-rules transformed onto an already taken target path, to their new relative paths ("" for skipping them).
+	failures           []pathFailure
+	stagedFiles        []stagedFile
+	verifyPool         *verifyPool
+	deferredFiles      []*deferredFile
 }
 
 type programOptions struct {
-	Mode        string     `yaml:"-"`
-	MirrorRoot  string     `yaml:"mirror"`
-	RealRoot    string     `yaml:"target"`
-	Excludes    excludeArg `yaml:"exclude"`
-	Direct      bool       `yaml:"direct"`
-	Verify      bool       `yaml:"verify"`
-	SkipEmpty   bool       `yaml:"skip-empty"`
-	RemoveEmpty bool       `yaml:"remove-empty"`
-	SkipFailed  bool       `yaml:"skip-failed"`
-	SlowMode    bool       `yaml:"slow-mode"`
-	InitDepth   int        `yaml:"init-depth"`
-	DryRun      bool       `yaml:"dry-run"`
-	LogLevel    string     `yaml:"log-level"`
-	JSON        bool       `yaml:"json"`
+	Mode                  string        `yaml:"-"`
+	ValidateConfig        string        `yaml:"-"`
+	ExplainExit           string        `yaml:"-"`
+	ExplainConfig         bool          `yaml:"-"`
+	MirrorRoot            string        `yaml:"mirror"`
+	RealRoot              string        `yaml:"target"`
+	AllowSymlinkedTarget  bool          `yaml:"allow-symlinked-target"`
+	AllowMountpointMirror bool          `yaml:"allow-mountpoint-mirror"`
+	AssumeEmptyMirror     bool          `yaml:"assume-empty-mirror"`
+	CreateTargetRoot      bool          `yaml:"create-target-root"`
+	ProbeWritable         bool          `yaml:"probe-writable"`
+	RequireTargetPerms    string        `yaml:"require-target-perms"`
+	Excludes              excludeArg    `yaml:"exclude"`
+	Includes              includeArg    `yaml:"include"`
+	ExcludesRel           excludeArg    `yaml:"exclude-rel"`
+	ExcludeNames          nameArg       `yaml:"exclude-name"`
+	ExcludeGlobsMirror    globArg       `yaml:"exclude-glob-mirror"`
+	ExcludeGlobsTarget    globArg       `yaml:"exclude-glob-target"`
+	ExcludeNameIgnoreCase bool          `yaml:"exclude-name-ignore-case"`
+	SkipSystemDirs        bool          `yaml:"skip-system-dirs"`
+	SystemDirNames        nameArg       `yaml:"system-dir-names"`
+	ListExcluded          bool          `yaml:"list-excluded"`
+	Direct                bool          `yaml:"direct"`
+	BulkRenameDirs        bool          `yaml:"bulk-rename-dirs"`
+	Verify                bool          `yaml:"verify"`
+	VerifyReadError       string        `yaml:"verify-read-error"`
+	VerifyConcurrency     int           `yaml:"verify-concurrency"`
+	DeferredVerify        bool          `yaml:"deferred-verify"`
+	StatBeforeRemove      bool          `yaml:"stat-before-remove"`
+	NoClobberWorkingFile  bool          `yaml:"no-clobber-working-file"`
+	UpdateMetadataOnMatch bool          `yaml:"update-metadata-on-match"`
+	Overwrite             string        `yaml:"overwrite"`
+	TargetTrashDir        string        `yaml:"target-trash-dir"`
+	TypeChange            string        `yaml:"type-change"`
+	InheritParentPerms    bool          `yaml:"inherit-parent-perms"`
+	LockPromoted          bool          `yaml:"lock-promoted"`
+	QuarantineDir         string        `yaml:"quarantine-dir"`
+	QuarantineSource      bool          `yaml:"quarantine-source"`
+	CompareBy             string        `yaml:"compare-by"`
+	CopyBufferSize        string        `yaml:"copy-buffer-size"`
+	AtomicBatch           bool          `yaml:"atomic-batch"`
+	DedupeRun             string        `yaml:"dedupe-run"`
+	HashAlgorithms        hashAlgoArg   `yaml:"hash-algorithms"`
+	ChecksumAlgo          string        `yaml:"checksum-algo"`
+	ChecksumSidecar       bool          `yaml:"checksum-sidecar"`
+	SkipEmpty             bool          `yaml:"skip-empty"`
+	RemoveEmpty           bool          `yaml:"remove-empty"`
+	CleanMirror           bool          `yaml:"clean-mirror-on-success"`
+	PostMoveCommand       string        `yaml:"post-move-command"`
+	PlanOut               string        `yaml:"plan-out"`
+	DryRunReproducible    bool          `yaml:"dry-run-reproducible"`
+	ListPlanOnly          bool          `yaml:"list-plan-only"`
+	DryRunApply           bool          `yaml:"dry-run-apply"`
+	ApplyToken            string        `yaml:"apply-token"`
+	PlanIn                string        `yaml:"plan-in"`
+	MirrorManifest        string        `yaml:"mirror-manifest"`
+	MirrorReadme          string        `yaml:"mirror-readme"`
+	MirrorReadmeFrom      string        `yaml:"mirror-readme-from"`
+	SinceFile             string        `yaml:"since-file"`
+	VerifyTargetStructure string        `yaml:"verify-target-structure"`
+	RenameRules           string        `yaml:"rename-rules"`
+	OnDuplicateTarget     string        `yaml:"on-duplicate-target"`
+	MoveOrder             string        `yaml:"move-order"`
+	SkipFailed            bool          `yaml:"skip-failed"`
+	SkipFailedMax         int           `yaml:"skip-failed-max"`
+	PerFileTimeout        time.Duration `yaml:"per-file-timeout"`
+	NoFailFast            bool          `yaml:"no-fail-fast"`
+	GracefulInterrupt     bool          `yaml:"graceful-interrupt"`
+	Watch                 bool          `yaml:"watch"`
+	WatchInterval         time.Duration `yaml:"watch-interval"`
+	WatchEvents           bool          `yaml:"watch-events"`
+	WatchQuietPeriod      time.Duration `yaml:"watch-quiet-period"`
+	SlowMode              bool          `yaml:"slow-mode"`
+	DirRate               int           `yaml:"dir-rate"`
+	MaxLoad               float64       `yaml:"max-load"`
+	InitDepth             int           `yaml:"init-depth"`
+	InitDepthRules        depthRuleArg  `yaml:"init-depth-rule"`
+	InitChangedSince      time.Duration `yaml:"init-changed-since"`
+	InitMerge             bool          `yaml:"init-merge"`
+	PreserveDirTimes      bool          `yaml:"preserve-dir-times"`
+	SkipEmptyTargetDirs   bool          `yaml:"skip-empty-target-dirs"`
+	InitSkipDirsOver      string        `yaml:"init-skip-dirs-over"`
+	TwoPhaseInit          bool          `yaml:"two-phase-init"`
+	DiffExit              bool          `yaml:"diff-exit"`
+	TargetGlobs           globArg       `yaml:"target-glob"`
+	CaseCollision         string        `yaml:"case-collision"`
+	CaseInsensitivePaths  bool          `yaml:"case-insensitive-paths"`
+	DryRun                bool          `yaml:"dry-run"`
+	Umask                 string        `yaml:"umask"`
+	LogLevel              string        `yaml:"log-level"`
+	LogFormat             string        `yaml:"log-format"`
+	LogSource             bool          `yaml:"log-source"`
+	PathEncoding          string        `yaml:"path-encoding"`
+	JSON                  bool          `yaml:"json"`
+	ResultJSON            bool          `yaml:"result-json"`
+	SummaryTemplate       string        `yaml:"summary-template"`
+	Report                string        `yaml:"report"`
+	ReportFormat          string        `yaml:"report-format"`
+	Manifest              string        `yaml:"manifest"`
+	HeartbeatFile         string        `yaml:"heartbeat-file"`
+	HeartbeatInterval     time.Duration `yaml:"heartbeat-interval"`
+	ProgressFile          string        `yaml:"progress-file"`
+	ProgressInterval      time.Duration `yaml:"progress-interval"`
+	StatsInterval         time.Duration `yaml:"stats-interval"`
+	RunID                 string        `yaml:"run-id"`
+	TraceSpans            bool          `yaml:"trace-spans"`
 }
 
 func main() {
@@ -373,9 +1954,6 @@ func main() {
 		os.Exit(exitCode)
 	}()
 
-	fmt.Fprintf(os.Stdout, "MirrorShuttle (v%s) - Keep your organization, ditch the ransomware.\n", Version)
-	fmt.Fprintf(os.Stdout, "(c) 2025 - desertwitch (Rysz) / License: GNU General Public License v2\n\n")
-
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -384,7 +1962,7 @@ func main() {
 
 	doneChan := make(chan int, 1)
 
-	prog, err := newProgram(os.Args, afero.NewOsFs(), os.Stdout, os.Stderr)
+	prog, err := newProgram(os.Args, afero.NewOsFs(), os.Stdin, os.Stdout, os.Stderr)
 	if prog == nil || err != nil {
 		exitCode = exitCodeConfigFailure
 
@@ -392,6 +1970,13 @@ func main() {
 	}
 
 	go func() {
+		if prog.opts.Watch {
+			exitCode, _ := prog.watch(ctx)
+			doneChan <- exitCode
+
+			return
+		}
+
 		exitCode, _ := prog.run(ctx)
 		doneChan <- exitCode
 	}()
@@ -403,6 +1988,38 @@ func main() {
 		return
 
 	case <-sigChan:
+		if prog.opts.Watch {
+			// Only no further cycles are started, a second signal also aborts the current one.
+			close(prog.stopWatch)
+			prog.log.Warn("received interrupt signal; stopping after the current cycle (interrupt again to abort)...",
+				"op", prog.opts.Mode,
+			)
+
+			select {
+			case code := <-doneChan:
+				exitCode = code
+
+				return
+
+			case <-sigChan:
+			}
+		} else if prog.opts.GracefulInterrupt {
+			// Only no further elements are processed, a second signal also aborts the current one.
+			prog.state.interrupted.Store(true)
+			prog.log.Warn("received interrupt signal; stopping after the current element (interrupt again to abort)...",
+				"op", prog.opts.Mode,
+			)
+
+			select {
+			case code := <-doneChan:
+				exitCode = code
+
+				return
+
+			case <-sigChan:
+			}
+		}
+
 		prog.log.Warn("received interrupt signal; shutting down (waiting up to 10s)...",
 			"op", prog.opts.Mode,
 		)
@@ -426,26 +2043,58 @@ func main() {
 	}
 }
 
-func newProgram(cliArgs []string, fsys afero.Fs, stdout io.Writer, stderr io.Writer) (*program, error) {
+func newProgram(cliArgs []string, fsys afero.Fs, stdin io.Reader, stdout io.Writer, stderr io.Writer) (*program, error) {
 	prog := &program{
 		fsys:   fsys,
-		stdout: stdout,
-		stderr: stderr,
-		opts:   &programOptions{},
-		state:  &programState{},
+		runner: execRunner{},
+		umask:  setProcessUmask,
+		load:   systemLoadAverage,
+		after:  time.After,
+
+		newWatcher: newFsnotifyWatcher,
+		stdin:      stdin,
+		stdout:     stdout,
+		stderr:     stderr,
+		opts:       &programOptions{},
+		state:      &programState{},
+
+		stopWatch: make(chan struct{}),
+	}
+
+	if _, ok := fsys.(*afero.OsFs); ok {
+		// The link and exchange are system calls, so these only apply to the real filesystem.
+		prog.link = os.Link
+		prog.exchange = exchangePaths
 	}
 
 	if err := prog.parseArgs(cliArgs); err != nil {
+		printBanner(prog.stdout)
 		fmt.Fprintf(prog.stderr, "fatal: failed to parse configuration: %v\n\n", err)
 		prog.flags.Usage()
 
 		return nil, fmt.Errorf("failed to parse configuration: %w", err)
 	}
 
+	if prog.opts.ExplainExit != "" {
+		// Explaining a return code needs no further configuration (nor any other output).
+		prog.log = slog.New(prog.logHandler())
+
+		return prog, nil
+	}
+
+	// The banner goes to standard error with --result-json (or --summary-template), so it needs to be parsed first.
+	printBanner(prog.infoWriter())
+
 	if err := prog.validateOpts(); err != nil {
 		fmt.Fprintf(prog.stderr, "fatal: failed to validate configuration: %v\n\n", err)
 		prog.flags.Usage()
 
+		if prog.opts.ResultJSON {
+			prog.printResult(exitCodeConfigFailure)
+		} else if prog.opts.SummaryTemplate != "" {
+			prog.printSummary(exitCodeConfigFailure)
+		}
+
 		return nil, fmt.Errorf("failed to validate configuration: %w", err)
 	}
 
@@ -458,10 +2107,58 @@ func newProgram(cliArgs []string, fsys afero.Fs, stdout io.Writer, stderr io.Wri
 
 	prog.log = slog.New(prog.logHandler())
 
+	// All of the log lines of an invocation are correlated by its run ID.
+	if prog.opts.RunID == "" {
+		prog.opts.RunID = newRunID(time.Now())
+	}
+	prog.log = prog.log.With("run_id", prog.opts.RunID)
+
+	if prog.opts.TraceSpans {
+		// All of the log lines of the run are correlated with its spans.
+		prog.traceID = newTraceID(traceIDSize)
+		prog.log = prog.log.With("trace_id", prog.traceID)
+	}
+
+	if prog.opts.ExplainConfig {
+		prog.explainConfig()
+	}
+
+	prog.pruneExcludes()
+	prog.excludeOwnFiles()
+
 	return prog, nil
 }
 
+func printBanner(w io.Writer) {
+	fmt.Fprintf(w, "MirrorShuttle (v%s) - Keep your organization, ditch the ransomware.\n", Version)
+	fmt.Fprintf(w, "(c) 2025 - desertwitch (Rysz) / License: GNU General Public License v2\n\n")
+}
+
 func (prog *program) run(ctx context.Context) (retExitCode int, retError error) {
+	if prog.opts.ExplainExit != "" {
+		return prog.explainExit()
+	}
+
+	if prog.opts.ResultJSON && prog.opts.ValidateConfig == "" {
+		// Deferred first, so that it runs last, after any panic was recovered.
+		defer func() {
+			prog.printResult(retExitCode)
+		}()
+	}
+
+	if prog.opts.SummaryTemplate != "" && prog.opts.ValidateConfig == "" {
+		defer func() {
+			prog.printSummary(retExitCode)
+		}()
+	}
+
+	if prog.opts.Report != "" && prog.opts.ValidateConfig == "" {
+		started := time.Now()
+		defer func() {
+			prog.writeReport(retExitCode, started)
+		}()
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
 			prog.log.Error("internal panic recovered",
@@ -474,12 +2171,74 @@ func (prog *program) run(ctx context.Context) (retExitCode int, retError error)
 		}
 	}()
 
-	if prog.opts.DryRun {
+	if prog.opts.ValidateConfig != "" {
+		prog.log.Info("configuration is valid; exiting...",
+			"config", prog.opts.ValidateConfig,
+		)
+
+		return exitCodeSuccess, nil
+	}
+
+	runSpan := prog.startSpan(spanRun, nil)
+	defer func() {
+		runSpan.end(retError)
+	}()
+
+	if prog.opts.Umask != "" {
+		mask, _ := parseUmask(prog.opts.Umask)
+
+		// The umask is process-wide, so it is restored again once the run is over.
+		if oldMask, ok := prog.umask(mask); ok {
+			defer prog.umask(oldMask)
+			prog.log.Info("umask set", "op", prog.opts.Mode, "umask", fmt.Sprintf("%03o", mask), "previous", fmt.Sprintf("%03o", oldMask))
+		} else {
+			prog.log.Warn("umask not set", "op", prog.opts.Mode, "umask", prog.opts.Umask, "reason", "not_supported")
+		}
+	}
+
+	if prog.opts.MaxLoad > 0 {
+		// Opportunistic runs give way to any peak usage, to be retried again later.
+		if load, ok := prog.load(); !ok {
+			prog.log.Warn("system load not checked", "op", prog.opts.Mode, "max-load", prog.opts.MaxLoad, "reason", "not_supported")
+		} else if load > prog.opts.MaxLoad {
+			prog.log.Warn("system load exceeds the maximum - deferring the run; exiting...",
+				"op", prog.opts.Mode,
+				"load", load,
+				"max-load", prog.opts.MaxLoad,
+			)
+
+			return exitCodeDeferredLoad, nil
+		}
+	}
+
+	if prog.opts.HeartbeatFile != "" {
+		stopHeartbeat := prog.startHeartbeat(ctx)
+		defer stopHeartbeat()
+	}
+
+	if prog.opts.DryRun && (prog.opts.Mode == "init" || prog.opts.Mode == "move") {
+		// The check mode never makes any changes, so a dry run makes no difference there.
 		prog.log.Warn("running in dry mode - no changes will be made",
 			"op", prog.opts.Mode,
 		)
 	}
 
+	preflightSpan := prog.startSpan(spanPreflight, runSpan)
+	err := prog.checkTargetRoot()
+	if err == nil {
+		err = prog.checkTargetPerms()
+	}
+	preflightSpan.end(err)
+	if err != nil {
+		prog.log.Error("failed checking target",
+			"op", prog.opts.Mode,
+			"error", err,
+			"error-type", "fatal",
+		)
+
+		return exitCodeFailure, fmt.Errorf("failed checking target: %w", err)
+	}
+
 	switch prog.opts.Mode {
 	case "init":
 		prog.log.Info("setting up the mirror structure...",
@@ -488,7 +2247,10 @@ func (prog *program) run(ctx context.Context) (retExitCode int, retError error)
 			"target", prog.opts.RealRoot,
 		)
 
-		if err := prog.createMirrorStructure(ctx); err != nil {
+		modeSpan := prog.startSpan(prog.opts.Mode, runSpan)
+		err = prog.createMirrorStructure(ctx)
+		modeSpan.end(err)
+		if err != nil {
 			if !errors.Is(err, context.Canceled) {
 				prog.log.Error("failed creating mirror structure",
 					"op", prog.opts.Mode,
@@ -513,7 +2275,10 @@ func (prog *program) run(ctx context.Context) (retExitCode int, retError error)
 			"target", prog.opts.RealRoot,
 		)
 
-		if err := prog.moveFiles(ctx); err != nil {
+		modeSpan := prog.startSpan(prog.opts.Mode, runSpan)
+		err = prog.moveFiles(ctx)
+		modeSpan.end(err)
+		if err != nil {
 			if !errors.Is(err, context.Canceled) {
 				prog.log.Error("failed moving to target structure",
 					"op", prog.opts.Mode,
@@ -524,24 +2289,104 @@ func (prog *program) run(ctx context.Context) (retExitCode int, retError error)
 				)
 			}
 
+			if errors.Is(err, errTargetStructureDiverged) {
+				return exitCodeTargetDiverged, fmt.Errorf("failed moving to target structure: %w", err)
+			}
+
 			return exitCodeFailure, fmt.Errorf("failed moving to target structure: %w", err)
 		}
+
+	case "check":
+		prog.log.Info("checking target files against the manifest...",
+			"op", prog.opts.Mode,
+			"manifest", prog.opts.Manifest,
+			"target", prog.opts.RealRoot,
+		)
+
+		modeSpan := prog.startSpan(prog.opts.Mode, runSpan)
+		err = prog.checkManifest(ctx)
+		modeSpan.end(err)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				prog.log.Error("failed checking target files",
+					"op", prog.opts.Mode,
+					"error", err,
+					"error-type", "fatal",
+					"files_checked", prog.state.checkedFiles,
+				)
+			}
+
+			return exitCodeFailure, fmt.Errorf("failed checking target files: %w", err)
+		}
 	}
 
 	if prog.provokeTestPanic {
 		panic("testing program panic")
 	}
 
+	if prog.state.hasHardFailures {
+		errs := make([]error, 0, len(prog.state.failures))
+		for _, f := range prog.state.failures {
+			errs = append(errs, f.err)
+		}
+
+		prog.log.Error("mode completed, but with failures; exiting...",
+			"op", prog.opts.Mode,
+			"error-type", "fatal",
+			"dirs_created", prog.state.createdDirs,
+			"files_moved", prog.state.movedFiles,
+			"failures", len(prog.state.failures),
+		)
+
+		return exitCodeFailure, fmt.Errorf("mode completed, but with failures: %w", errors.Join(errs...))
+	}
+
+	if prog.state.deferredFailures > 0 {
+		prog.log.Error("mode completed, but moved files failed their verification; exiting...",
+			"op", prog.opts.Mode,
+			"error-type", "fatal",
+			"dirs_created", prog.state.createdDirs,
+			"files_moved", prog.state.movedFiles,
+			"files_failed", prog.state.deferredFailures,
+		)
+
+		return exitCodeDeferredVerify, nil
+	}
+
 	if prog.state.hasPartialFailures {
+		for _, f := range prog.state.failures {
+			prog.log.Debug("skipped failure", "op", prog.opts.Mode, "path", f.path, "error", f.err)
+		}
+
 		prog.log.Warn("mode completed, but with partial failures; exiting...",
 			"op", prog.opts.Mode,
 			"dirs_created", prog.state.createdDirs,
 			"files_moved", prog.state.movedFiles,
+			"failures", len(prog.state.failures),
 		)
 
 		return exitCodePartialFailure, nil
 	}
 
+	if prog.state.hasFailedChecks {
+		prog.log.Warn("mode completed, but with failed checks; exiting...",
+			"op", prog.opts.Mode,
+			"files_checked", prog.state.checkedFiles,
+		)
+
+		return exitCodeFailedChecks, nil
+	}
+
+	if prog.state.mirrorChanges > 0 {
+		prog.log.Warn("mode completed, but the mirror structure would change; exiting...",
+			"op", prog.opts.Mode,
+			"dirs_created", prog.state.createdDirs,
+			"changes", prog.state.mirrorChanges,
+		)
+
+		return exitCodeMirrorDiffers, nil
+	}
+
 	if prog.state.hasUnmovedFiles {
 		prog.log.Warn("mode completed, but with unmoved files; exiting...",
 			"op", prog.opts.Mode,
@@ -3,13 +3,16 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
@@ -22,7 +25,7 @@ func setupTestProgram(fs afero.Fs, opts *programOptions) (prog *program, stdout
 	if opts == nil {
 		args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real"}
 
-		prog, err := newProgram(args, fs, stdout, stderr)
+		prog, err := newProgram(args, fs, nil, stdout, stderr)
 		if err != nil {
 			panic("expected to set up a working program for testing")
 		}
@@ -95,7 +98,7 @@ func Test_Integ_Run_ValidInitMode_Success(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
@@ -115,7 +118,7 @@ func Test_Integ_Run_ValidInitModeStats_Success(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
@@ -126,6 +129,81 @@ func Test_Integ_Run_ValidInitModeStats_Success(t *testing.T) {
 	require.Equal(t, 0, prog.state.movedFiles)
 }
 
+// Expectation: The system calls for links and exchanges should only be set up for the real filesystem.
+func Test_Unit_NewProgram_SystemCallsOsFsOnly_Success(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	args := []string{"program", "--mode=init", "--mirror=" + filepath.Join(dir, "mirror"), "--target=" + filepath.Join(dir, "real")}
+
+	var stdout, stderr bytes.Buffer
+
+	prog, err := newProgram(args, setupTestFs(), nil, &stdout, &stderr)
+	require.NoError(t, err)
+	require.Nil(t, prog.link)
+	require.Nil(t, prog.exchange)
+
+	prog, err = newProgram(args, afero.NewOsFs(), nil, &stdout, &stderr)
+	require.NoError(t, err)
+	require.NotNil(t, prog.link)
+	require.NotNil(t, prog.exchange)
+}
+
+// Expectation: The program should neither mirror nor move any system directories with --skip-system-dirs.
+func Test_Integ_Run_SkipSystemDirs_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/@eaDir/sub", "/real/a/#recycle", "/real/a/b"})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real", "--skip-system-dirs"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeSuccess, exitCode)
+
+	_, err = fs.Stat("/mirror/a/b")
+	require.NoError(t, err)
+
+	for _, dir := range []string{"/mirror/@eaDir", "/mirror/a/#recycle"} {
+		_, err = fs.Stat(dir)
+		require.ErrorIs(t, err, os.ErrNotExist, dir)
+	}
+
+	// The system (such as a NAS indexer) creates its own directories within the mirror.
+	err = createFiles(fs, map[string]string{
+		"/mirror/a/@eaDir/file.txt/SYNOFILE_THUMB_M.jpg": "thumb",
+		"/mirror/a/#recycle/old.txt":                     "content",
+		"/mirror/a/file.txt":                             "content",
+	})
+	require.NoError(t, err)
+
+	args = []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--skip-system-dirs"}
+
+	prog, _ = newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	exitCode, err = prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeSuccess, exitCode)
+
+	_, err = fs.Stat("/real/a/file.txt")
+	require.NoError(t, err)
+
+	for _, path := range []string{"/a/@eaDir/file.txt/SYNOFILE_THUMB_M.jpg", "/a/#recycle/old.txt"} {
+		_, err = fs.Stat("/real" + path)
+		require.ErrorIs(t, err, os.ErrNotExist, path)
+
+		_, err = fs.Stat("/mirror" + path)
+		require.NoError(t, err, path)
+	}
+}
+
 // Expectation: The program should run move mode with only the required CLI arguments.
 func Test_Integ_Run_ValidMoveMode_Success(t *testing.T) {
 	t.Parallel()
@@ -143,7 +221,7 @@ func Test_Integ_Run_ValidMoveMode_Success(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
@@ -168,140 +246,795 @@ func Test_Integ_Run_ValidMoveModeStats_Success(t *testing.T) {
 	require.NoError(t, err)
 
 	var stdout, stderr bytes.Buffer
-	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real"}
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+
+	require.Equal(t, exitCodeSuccess, exitCode)
+	require.Equal(t, 1, prog.state.createdDirs)
+	require.Equal(t, 2, prog.state.movedFiles)
+}
+
+// Expectation: The program should set the configured umask for the run and restore the previous one afterwards.
+func Test_Integ_Run_Umask_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/dir1"})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real", "--umask=027"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	var masks []int
+	prog.umask = func(mask int) (int, bool) {
+		masks = append(masks, mask)
+
+		return 0o022, true
+	}
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeSuccess, exitCode)
+
+	require.Equal(t, []int{0o027, 0o022}, masks)
+}
+
+// Expectation: The program should defer the run with its own return code only while the load exceeds --max-load.
+func Test_Integ_Run_MaxLoad_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		load     float64
+		ok       bool
+		exitCode int
+		moved    bool
+	}{
+		{"above", 4.5, true, exitCodeDeferredLoad, false},
+		{"below", 1.5, true, exitCodeSuccess, true},
+		{"unavailable", 0, false, exitCodeSuccess, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{"/mirror/file.txt": "content"})
+			require.NoError(t, err)
+			err = createDirStructure(fs, []string{"/real"})
+			require.NoError(t, err)
+
+			var stdout, stderr bytes.Buffer
+			args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--max-load=2"}
+
+			prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+			require.NotNil(t, prog)
+
+			prog.load = func() (float64, bool) {
+				return tt.load, tt.ok
+			}
+
+			exitCode, err := prog.run(t.Context())
+			require.NoError(t, err)
+			require.Equal(t, tt.exitCode, exitCode)
+
+			_, err = fs.Stat("/real/file.txt")
+			if tt.moved {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, os.ErrNotExist)
+			}
+		})
+	}
+}
+
+// Expectation: The program should refuse a umask that is not a legal octal mask.
+func Test_Integ_Run_UmaskInvalid_Error(t *testing.T) {
+	t.Parallel()
+
+	for _, mask := range []string{"1000", "089", "-022", "u=rwx"} {
+		var stdout, stderr bytes.Buffer
+		args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real", "--umask=" + mask}
+
+		prog, err := newProgram(args, setupTestFs(), nil, &stdout, &stderr)
+		require.Nil(t, prog, mask)
+		require.ErrorIs(t, err, errArgUmaskInvalid, mask)
+	}
+}
+
+// Expectation: The program should print exactly one JSON line with the result to stdout.
+func Test_Integ_Run_ResultJSON_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/file.txt":       "content",
+		"/mirror/dir1/file2.txt": "content2",
+		"/mirror/file3.txt":      "content3",
+		"/real/file3.txt":        "other",
+	})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--result-json"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeUnmovedFiles, exitCode)
+
+	require.Equal(t, 1, strings.Count(stdout.String(), "\n"))
+	require.Contains(t, stderr.String(), "configuration for '--mode=move'")
+
+	var result map[string]any
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &result))
+	require.Equal(t, map[string]any{
+		"mode":    "move",
+		"exit":    float64(exitCodeUnmovedFiles),
+		"moved":   float64(2),
+		"unmoved": float64(1),
+		"created": float64(1),
+		"bytes":   float64(15),
+	}, result)
+}
+
+// Expectation: A --summary-template should be rendered as the only line on standard output at the end of the run.
+func Test_Integ_Run_SummaryTemplate_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/file.txt":       "content",
+		"/mirror/dir1/file2.txt": "content2",
+		"/mirror/file3.txt":      "content3",
+		"/real/file3.txt":        "other",
+	})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{
+		"program", "--mode=move", "--mirror=/mirror", "--target=/real",
+		"--summary-template={{.Mode}} moved={{.Moved}} unmoved={{.Unmoved}} created={{.Created}} bytes={{.Bytes}} exit={{.ExitCode}}",
+	}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeUnmovedFiles, exitCode)
+
+	require.Equal(t, "move moved=2 unmoved=1 created=1 bytes=15 exit=4\n", stdout.String())
+	require.Contains(t, stderr.String(), "configuration for '--mode=move'")
+}
+
+// Expectation: An invalid --summary-template should be rejected by the validation of the configuration.
+func Test_Integ_Run_SummaryTemplate_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		template string
+		extra    []string
+		wantErr  error
+	}{
+		{"malformed", "{{.Moved", nil, errArgSummaryTemplateInvalid},
+		{"unknown field", "{{.Files}}", nil, errArgSummaryTemplateInvalid},
+		{"result json", "{{.Moved}}", []string{"--result-json"}, errArgSummaryTemplateConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--summary-template=" + tt.template}, tt.extra...)
+
+			prog, err := newProgram(args, setupTestFs(), nil, &stdout, &stderr)
+			require.Nil(t, prog)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+// Expectation: A --report should be written with the results of the run, including the conflicting file that was skipped.
+func Test_Integ_Run_Report_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/file.txt":  "content",
+		"/mirror/file2.txt": "content2",
+		"/real/file2.txt":   "other",
+	})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--report=/report.json"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeUnmovedFiles, exitCode)
+
+	content, err := afero.ReadFile(fs, "/report.json")
+	require.NoError(t, err)
+
+	var report runReport
+	require.NoError(t, json.Unmarshal(content, &report))
+	require.Equal(t, "move", report.Mode)
+	require.Equal(t, exitCodeUnmovedFiles, report.Exit)
+	require.Equal(t, 1, report.Moved)
+	require.Equal(t, 1, report.Unmoved)
+	require.Equal(t, int64(7), report.Bytes)
+	require.Zero(t, report.Failures)
+	require.GreaterOrEqual(t, report.Duration, float64(0))
+	require.False(t, report.Started.IsZero())
+	require.Equal(t, []reportPath{{Path: "/mirror/file2.txt", Reason: "target_exists"}}, report.Skipped)
+}
+
+// Expectation: A --report with --report-format=csv should parse back with a row for each skipped path, escaping commas and quotes.
+func Test_Integ_Run_ReportCSV_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/file.txt":          "content",
+		"/mirror/a, \"quoted\".txt": "content2",
+		"/real/a, \"quoted\".txt":   "other",
+	})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--report=/report.csv", "--report-format=csv"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeUnmovedFiles, exitCode)
+
+	content, err := afero.ReadFile(fs, "/report.csv")
+	require.NoError(t, err)
+	require.Contains(t, string(content), `"/mirror/a, ""quoted"".txt"`)
+
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"path", "reason"},
+		{"/mirror/a, \"quoted\".txt", "target_exists"},
+	}, records)
+}
+
+// Expectation: An unknown --report-format should be rejected.
+func Test_Integ_Run_ReportFormat_Error(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--report=/report.xml", "--report-format=xml"}
+
+	prog, err := newProgram(args, setupTestFs(), nil, &stdout, &stderr)
+	require.Nil(t, prog)
+	require.ErrorIs(t, err, errArgReportFormatInvalid)
+}
+
+// Expectation: A --report=- should be the only output on standard output, also when partial failures occur.
+func Test_Integ_Run_ReportStdout_PartialFailure_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := flakyFs{Fs: setupTestFs(), failOnPath: "fail.txt"}
+	err := createFiles(fs, map[string]string{
+		"/mirror/ok.txt":   "ok",
+		"/mirror/fail.txt": "fail",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--skip-failed", "--report=-"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodePartialFailure, exitCode)
+	require.Contains(t, stderr.String(), "configuration for '--mode=move'")
+
+	var report runReport
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	require.Equal(t, exitCodePartialFailure, report.Exit)
+	require.Equal(t, 1, report.Moved)
+	require.Equal(t, 1, report.Failures)
+	require.Equal(t, []reportPath{{Path: "/mirror/fail.txt", Reason: "error_occurred"}}, report.Skipped)
+}
+
+// Expectation: A --report=- should be rejected together with other output on standard output.
+func Test_Integ_Run_ReportStdout_Error(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--report=-", "--result-json"}
+
+	prog, err := newProgram(args, setupTestFs(), nil, &stdout, &stderr)
+	require.Nil(t, prog)
+	require.ErrorIs(t, err, errArgReportConflict)
+}
+
+// Expectation: Two dry runs with --dry-run-reproducible over identical inputs should produce byte-identical output.
+func Test_Integ_Run_DryRunReproducible_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/z.txt":         "content",
+		"/mirror/a/new/b.txt":   "content",
+		"/mirror/a/file.txt":    "content",
+		"/mirror/exists.txt":    "content",
+		"/real/exists.txt":      "other",
+		"/real/a/something.txt": "content",
+	})
+	require.NoError(t, err)
+
+	var outputs []string
+	for range 2 {
+		var stdout, stderr bytes.Buffer
+		args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--dry-run", "--dry-run-reproducible"}
+
+		prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+		require.NotNil(t, prog)
+
+		exitCode, err := prog.run(t.Context())
+		require.NoError(t, err)
+		require.Equal(t, exitCodeUnmovedFiles, exitCode)
+
+		require.Contains(t, stderr.String(), "configuration for '--mode=move'")
+		outputs = append(outputs, stdout.String())
+	}
+
+	require.Equal(t, outputs[0], outputs[1])
+	require.Equal(t, "mkdir /real/a/new\n"+
+		"move /mirror/a/file.txt -> /real/a/file.txt\n"+
+		"move /mirror/a/new/b.txt -> /real/a/new/b.txt\n"+
+		"move /mirror/z.txt -> /real/z.txt\n", outputs[0])
+
+	// Verify nothing was moved.
+	_, err = fs.Stat("/real/z.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The program should reject --dry-run-reproducible without --dry-run.
+func Test_Integ_NewProgram_DryRunReproducibleNoDryRun_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--dry-run-reproducible"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.ErrorIs(t, err, errArgDryRunReproducible)
+	require.Nil(t, prog)
+}
+
+// Expectation: A dry run with --list-plan-only should output only the operations in walk order, including the skips.
+func Test_Integ_Run_ListPlanOnly_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/exists.txt":   "content",
+		"/real/exists.txt":     "other",
+		"/mirror/new/file.txt": "content",
+		"/mirror/tmp/file.txt": "content",
+	})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--exclude=/mirror/tmp", "--dry-run", "--list-plan-only", "--log-format=logfmt"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeUnmovedFiles, exitCode)
+
+	require.Equal(t, "skip /mirror/exists.txt (target_exists)\n"+
+		"mkdir /real/new\n"+
+		"move /mirror/new/file.txt -> /real/new/file.txt\n"+
+		"skip /mirror/tmp (is_user_excluded)\n", stdout.String())
+	require.NotContains(t, stderr.String(), "level=WARN")
+
+	// Verify nothing was moved.
+	_, err = fs.Stat("/real/new/file.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The program should reject --list-plan-only without --dry-run.
+func Test_Integ_NewProgram_ListPlanOnlyNoDryRun_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--list-plan-only"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.ErrorIs(t, err, errArgListPlanOnly)
+	require.Nil(t, prog)
+}
+
+// Expectation: The program should report the same statistics in dry mode as in a real run.
+func Test_Integ_Run_DryRunStats_Success(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		mode string
+		dirs []string
+	}{
+		{"init", "--mode=init", []string{"/real/a/b", "/real/c", "/mirror/old"}},
+		{"move", "--mode=move", []string{"/real/a", "/mirror/a/b/c", "/mirror/d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			stats := make(map[bool][2]int)
+
+			for _, dryRun := range []bool{true, false} {
+				fs := setupTestFs()
+				err := createDirStructure(fs, tt.dirs)
+				require.NoError(t, err)
+
+				err = createFiles(fs, map[string]string{
+					"/mirror/a/file1.txt":   "content",
+					"/mirror/a/b/file2.txt": "content",
+					"/mirror/d/file3.txt":   "content",
+				})
+				require.NoError(t, err)
+
+				if tt.mode == "--mode=init" {
+					require.NoError(t, fs.RemoveAll("/mirror/a"))
+					require.NoError(t, fs.RemoveAll("/mirror/d"))
+				}
+
+				var stdout, stderr bytes.Buffer
+				args := []string{"program", tt.mode, "--mirror=/mirror", "--target=/real", "--init-depth=-1", fmt.Sprintf("--dry-run=%t", dryRun)}
+
+				prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+				require.NotNil(t, prog)
+
+				exitCode, err := prog.run(t.Context())
+				require.NoError(t, err)
+				require.Equal(t, exitCodeSuccess, exitCode)
+
+				stats[dryRun] = [2]int{prog.state.createdDirs, prog.state.movedFiles}
+			}
+
+			require.NotZero(t, stats[false][0])
+			require.Equal(t, stats[false], stats[true])
+		})
+	}
+}
+
+// Expectation: The program should handle unicode correctly in init mode.
+func Test_Integ_Run_UnicodeInitMode_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	unicodeDir := "/тестr/📁/测试/ファイル"
+	err := createDirStructure(fs, []string{unicodeDir})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=init", "--mirror=/тестm", "--target=/тестr"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NoError(t, err)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeSuccess, exitCode)
+
+	_, err = fs.Stat("/тестm/📁/测试/ファイル")
+	require.NoError(t, err)
+}
+
+// Expectation: The program should handle unicode correctly in move mode.
+func Test_Integ_Run_UnicodeMoveMode_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	unicodePath := "/тестm/📁/测试/ファイル/file.txt"
+	targetDir := "/тестr/📁/测试/ファイル"
+
+	err := createDirStructure(fs, []string{filepath.Dir(unicodePath), targetDir})
+	require.NoError(t, err)
+
+	err = createFiles(fs, map[string]string{
+		unicodePath: "unicode content",
+	})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/тестm", "--target=/тестr"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NoError(t, err)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeSuccess, exitCode)
+
+	_, err = fs.Stat("/тестr/📁/测试/ファイル/file.txt")
+	require.NoError(t, err)
+}
+
+// Expectation: The program should handle unicode correctly in the config file.
+func Test_Integ_Run_UnicodeConfigFile_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	err := createDirStructure(fs, []string{"/реальний/каталог/тест"})
+	require.NoError(t, err)
+
+	yaml := `
+mirror: /дзеркало
+target: /реальний
+log-level: info
+dry-run: false
+json: true
+`
+
+	err = createFiles(fs, map[string]string{
+		"/конфіг.yaml": yaml,
+	})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=init", "--config=/конфіг.yaml"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NoError(t, err)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeSuccess, exitCode)
+
+	_, err = fs.Stat("/дзеркало/каталог/тест")
+	require.NoError(t, err)
+}
+
+// Expectation: The program should only produce JSON (on standard error) when in JSON mode.
+func Test_Integ_Run_JsonMode_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/dir1"})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real", "--json"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeSuccess, exitCode)
+
+	stderrStr := strings.TrimSpace(stderr.String())
+	require.NotEmpty(t, stderrStr)
+
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var v any
+		err := json.Unmarshal([]byte(line), &v)
+		require.NoErrorf(t, err, "stderr line %d is not valid JSON: %q", i+1, line)
+	}
+}
+
+// Expectation: The program should only produce parseable logfmt (on standard error) when in logfmt mode.
+func Test_Integ_Run_LogfmtMode_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/dir 1"})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real", "--log-format=logfmt"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
 	require.NoError(t, err)
-
 	require.Equal(t, exitCodeSuccess, exitCode)
-	require.Equal(t, 1, prog.state.createdDirs)
-	require.Equal(t, 2, prog.state.movedFiles)
+
+	stderrStr := strings.TrimSpace(stderr.String())
+	require.NotEmpty(t, stderrStr)
+	require.Contains(t, stderrStr, `path="/mirror/dir 1"`)
+
+	pair := regexp.MustCompile(`^[^\s="]+=("(?:[^"\\]|\\.)*"|[^\s"]*)(?:\s+|$)`)
+
+	lines := strings.Split(stderrStr, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		for rest := line; rest != ""; {
+			m := pair.FindString(rest)
+			require.NotEmptyf(t, m, "stderr line %d is not valid logfmt: %q", i+1, line)
+			rest = rest[len(m):]
+		}
+	}
 }
 
-// Expectation: The program should handle unicode correctly in init mode.
-func Test_Integ_Run_UnicodeInitMode_Success(t *testing.T) {
+// Expectation: The program should include the source location in each log record with --log-source.
+func Test_Integ_Run_LogSource_Success(t *testing.T) {
 	t.Parallel()
 
 	fs := setupTestFs()
-
-	unicodeDir := "/тестr/📁/测试/ファイル"
-	err := createDirStructure(fs, []string{unicodeDir})
+	err := createDirStructure(fs, []string{"/real/dir1"})
 	require.NoError(t, err)
 
 	var stdout, stderr bytes.Buffer
-	args := []string{"program", "--mode=init", "--mirror=/тестm", "--target=/тестr"}
+	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real", "--log-format=json", "--log-source"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
-	require.NoError(t, err)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
 	require.NoError(t, err)
 	require.Equal(t, exitCodeSuccess, exitCode)
 
-	_, err = fs.Stat("/тестm/📁/测试/ファイル")
-	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	require.NotEmpty(t, lines)
+
+	for i, line := range lines {
+		var record struct {
+			Source struct {
+				File string `json:"file"`
+				Line int    `json:"line"`
+			} `json:"source"`
+		}
+		require.NoErrorf(t, json.Unmarshal([]byte(line), &record), "stderr line %d is not valid JSON: %q", i+1, line)
+		require.NotEmptyf(t, record.Source.File, "stderr line %d has no source: %q", i+1, line)
+		require.Positivef(t, record.Source.Line, "stderr line %d has no source line: %q", i+1, line)
+	}
 }
 
-// Expectation: The program should handle unicode correctly in move mode.
-func Test_Integ_Run_UnicodeMoveMode_Success(t *testing.T) {
+// Expectation: The program should move a file with an invalid UTF-8 name and still emit valid JSON logs.
+func Test_Integ_Run_InvalidUTF8Name_Success(t *testing.T) {
 	t.Parallel()
 
 	fs := setupTestFs()
-
-	unicodePath := "/тестm/📁/测试/ファイル/file.txt"
-	targetDir := "/тестr/📁/测试/ファイル"
-
-	err := createDirStructure(fs, []string{filepath.Dir(unicodePath), targetDir})
-	require.NoError(t, err)
-
-	err = createFiles(fs, map[string]string{
-		unicodePath: "unicode content",
+	err := createFiles(fs, map[string]string{
+		"/mirror/caf\xe9.txt": "content",
 	})
 	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
 
 	var stdout, stderr bytes.Buffer
-	args := []string{"program", "--mode=move", "--mirror=/тестm", "--target=/тестr"}
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--log-format=json"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
-	require.NoError(t, err)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
 	require.NoError(t, err)
 	require.Equal(t, exitCodeSuccess, exitCode)
 
-	_, err = fs.Stat("/тестr/📁/测试/ファイル/file.txt")
+	// The raw bytes of the name are passed through to the filesystem unchanged.
+	content, err := afero.ReadFile(fs, "/real/caf\xe9.txt")
 	require.NoError(t, err)
+	require.Equal(t, "content", string(content))
+
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	for i, line := range lines {
+		require.Truef(t, json.Valid([]byte(line)), "stderr line %d is not valid JSON: %q", i+1, line)
+		require.Truef(t, utf8.ValidString(line), "stderr line %d is not valid UTF-8: %q", i+1, line)
+	}
+	require.Contains(t, stderr.String(), `"dst":"/real/caf\\xe9.txt"`)
 }
 
-// Expectation: The program should handle unicode correctly in the config file.
-func Test_Integ_Run_UnicodeConfigFile_Success(t *testing.T) {
+// Expectation: The program should report all of the skipped failures in its final summary.
+func Test_Integ_Run_SkipFailedSummary_Success(t *testing.T) {
 	t.Parallel()
 
 	fs := setupTestFs()
-
-	err := createDirStructure(fs, []string{"/реальний/каталог/тест"})
+	files := map[string]string{
+		"/mirror/file1.txt": "content",
+		"/mirror/file2.txt": "content2",
+	}
+	err := createFiles(fs, files)
 	require.NoError(t, err)
 
-	yaml := `
-mirror: /дзеркало
-target: /реальний
-log-level: info
-dry-run: false
-json: true
-`
-
-	err = createFiles(fs, map[string]string{
-		"/конфіг.yaml": yaml,
-	})
+	err = createDirStructure(fs, []string{"/real"})
 	require.NoError(t, err)
 
 	var stdout, stderr bytes.Buffer
-	args := []string{"program", "--mode=init", "--config=/конфіг.yaml"}
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--skip-failed", "--log-level=debug", "--log-format=json"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, afero.NewReadOnlyFs(fs), nil, &stdout, &stderr)
 	require.NoError(t, err)
+	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
 	require.NoError(t, err)
-	require.Equal(t, exitCodeSuccess, exitCode)
+	require.Equal(t, exitCodePartialFailure, exitCode)
 
-	_, err = fs.Stat("/дзеркало/каталог/тест")
-	require.NoError(t, err)
+	require.Len(t, prog.state.failures, 2)
+	require.Equal(t, "/mirror/file1.txt", prog.state.failures[0].path)
+	require.Equal(t, "/mirror/file2.txt", prog.state.failures[1].path)
+
+	require.Contains(t, stderr.String(), `"failures":2`)
+	require.Equal(t, 2, strings.Count(stderr.String(), `"msg":"skipped failure"`))
 }
 
-// Expectation: The program should only produce JSON (on standard error) when in JSON mode.
-func Test_Integ_Run_JsonMode_Success(t *testing.T) {
+// Expectation: The program should move all good files, but exit with failure after hard failures with --no-fail-fast.
+func Test_Integ_Run_NoFailFast_Error(t *testing.T) {
 	t.Parallel()
 
 	fs := setupTestFs()
-	err := createDirStructure(fs, []string{"/real/dir1"})
+	files := map[string]string{
+		"/mirror/bad1.txt":  "content",
+		"/mirror/good1.txt": "content",
+		"/mirror/bad2.txt":  "content",
+		"/mirror/good2.txt": "content",
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	err = createDirStructure(fs, []string{"/real"})
 	require.NoError(t, err)
 
 	var stdout, stderr bytes.Buffer
-	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real", "--json"}
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--no-fail-fast"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, flakyFs{Fs: fs, failOnPath: "bad"}, nil, &stdout, &stderr)
+	require.NoError(t, err)
 	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
-	require.NoError(t, err)
-	require.Equal(t, exitCodeSuccess, exitCode)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bad1.txt")
+	require.Contains(t, err.Error(), "bad2.txt")
+	require.Equal(t, exitCodeFailure, exitCode)
 
-	stderrStr := strings.TrimSpace(stderr.String())
-	require.NotEmpty(t, stderrStr)
+	require.False(t, prog.state.hasPartialFailures)
+	require.Len(t, prog.state.failures, 2)
+	require.Equal(t, 2, prog.state.movedFiles)
 
-	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
-	for i, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		var v any
-		err := json.Unmarshal([]byte(line), &v)
-		require.NoErrorf(t, err, "stderr line %d is not valid JSON: %q", i+1, line)
+	for _, path := range []string{"/real/good1.txt", "/real/good2.txt", "/mirror/bad1.txt", "/mirror/bad2.txt"} {
+		_, err = fs.Stat(path)
+		require.NoError(t, err, path)
 	}
 }
 
@@ -354,7 +1087,7 @@ json: true
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--config=/config.yaml"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	require.Equal(t, "/mirror", prog.opts.MirrorRoot)
@@ -402,7 +1135,7 @@ json: false
 		"--log-level=warn",
 	}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
@@ -461,7 +1194,7 @@ exclude:
 		"--exclude=/real/exclude-by-flag", // override YAML
 	}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 	require.True(t, prog.opts.SkipFailed)
 	require.True(t, prog.opts.Verify)
@@ -523,7 +1256,7 @@ exclude:
 		"--config=/config.yaml",
 	}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
@@ -562,7 +1295,7 @@ func Test_Integ_Run_SkipFailed_PartialFailureExitCode_Success(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--skip-failed"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	exitCode, err := prog.run(t.Context())
 	require.NoError(t, err)
 
@@ -598,7 +1331,7 @@ func Test_Integ_Run_SkipFailed_PartialFailureStats_Success(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--skip-failed"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	exitCode, err := prog.run(t.Context())
 	require.NoError(t, err)
 
@@ -625,7 +1358,7 @@ func Test_Integ_Run_NoSkipFailed_FailureExitCode_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	exitCode, err := prog.run(t.Context())
 	require.Error(t, err)
 
@@ -659,7 +1392,7 @@ func Test_Integ_Run_UnmovedFilesExitCode_Success(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
@@ -673,6 +1406,96 @@ func Test_Integ_Run_UnmovedFilesExitCode_Success(t *testing.T) {
 	require.Contains(t, stderr.String(), "unmoved files")
 }
 
+// Expectation: The program should never move its own files that are within the mirror into the target.
+func Test_Integ_Run_OwnFilesInMirror_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/file.txt":            "content",
+		"/mirror/ops/manifest.sha256": "content",
+		"/mirror/ops/config.yaml":     "target: /real\n",
+		"/mirror/ops/rename.rules":    "^nomatch$ => other\n",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--config=/mirror/ops/config.yaml", "--manifest=/mirror/ops/manifest.sha256", "--rename-rules=/mirror/ops/rename.rules", "--report=/mirror/ops/report.json"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeSuccess, exitCode)
+
+	_, err = fs.Stat("/real/file.txt")
+	require.NoError(t, err)
+
+	for _, path := range []string{"/mirror/ops/manifest.sha256", "/mirror/ops/config.yaml", "/mirror/ops/rename.rules"} {
+		_, err = fs.Stat(path)
+		require.NoError(t, err, path)
+	}
+	require.Equal(t, 4, strings.Count(stderr.String(), "own file within mirror excluded"))
+
+	for _, path := range []string{"/real/ops/manifest.sha256", "/real/ops/report.json"} {
+		_, err = fs.Stat(path)
+		require.ErrorIs(t, err, os.ErrNotExist, path)
+	}
+}
+
+// Expectation: The program should write the mirror readme in init, re-init over it, and never move it.
+func Test_Integ_Run_MirrorReadme_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/dir"})
+	require.NoError(t, err)
+
+	for _, mode := range []string{"init", "init", "move"} {
+		var stdout, stderr bytes.Buffer
+		args := []string{"program", "--mode=" + mode, "--mirror=/mirror", "--target=/real", "--mirror-readme=README.txt"}
+
+		prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+		require.NotNil(t, prog)
+
+		exitCode, err := prog.run(t.Context())
+		require.NoError(t, err, mode)
+		require.Equal(t, exitCodeSuccess, exitCode, mode)
+	}
+
+	content, err := afero.ReadFile(fs, "/mirror/README.txt")
+	require.NoError(t, err)
+	require.Equal(t, defaultMirrorReadme, string(content))
+
+	_, err = fs.Stat("/real/README.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The program should exit with the dedicated exit code on a diverged target structure.
+func Test_Integ_Run_TargetDivergedExitCode_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/mirror/a", "/real"})
+	require.NoError(t, err)
+
+	err = createFiles(fs, map[string]string{"/target.structure": "a\n"})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--verify-target-structure=/target.structure"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.ErrorIs(t, err, errTargetStructureDiverged)
+	require.Equal(t, exitCodeTargetDiverged, exitCode)
+}
+
 // Expectation: The program should produce the dry run mode warning.
 func Test_Integ_Run_DryRunMode_Success(t *testing.T) {
 	t.Parallel()
@@ -684,7 +1507,7 @@ func Test_Integ_Run_DryRunMode_Success(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real", "--dry-run"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
@@ -707,7 +1530,7 @@ func Test_Integ_Run_ExcludeSanitation_Success(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real", "--exclude=/real/dir1//", "--exclude= /real/dir2 "}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	require.Equal(t, "/real/dir1", prog.opts.Excludes[0])
@@ -731,7 +1554,7 @@ func Test_Integ_Run_PathCleaning_Success(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--mirror= /mirror// ", "--target= /real/ "}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	require.Equal(t, "/mirror", prog.opts.MirrorRoot)
@@ -754,7 +1577,7 @@ func Test_Integ_Run_ValidInitMode_CtxCancel_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	ctx, cancel := context.WithCancel(t.Context())
@@ -778,7 +1601,7 @@ func Test_Integ_Run_ValidMoveMode_CtxCancel_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	ctx, cancel := context.WithCancel(t.Context())
@@ -811,7 +1634,7 @@ func Test_Integ_Run_InitNonEmptyMirrorExitCode_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real"}
 
-	prog, _ := newProgram(args, fs, &stdout, &stderr)
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
 	require.NotNil(t, prog)
 
 	exitCode, err := prog.run(t.Context())
@@ -829,7 +1652,7 @@ func Test_Integ_NewProgram_MissingConfigFile_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--config=/config.yaml"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.ErrorIs(t, err, errArgConfigMissing)
 	require.Nil(t, prog)
 
@@ -852,7 +1675,7 @@ func Test_Integ_NewProgram_MalformedConfigFile_Error(t *testing.T) {
 	err := createFiles(fs, files)
 	require.NoError(t, err)
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.ErrorIs(t, err, errArgConfigMalformed)
 	require.Nil(t, prog)
 
@@ -881,7 +1704,7 @@ exclude:
 	err := createFiles(fs, files)
 	require.NoError(t, err)
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.ErrorIs(t, err, errArgConfigMalformed)
 	require.Nil(t, prog)
 
@@ -896,7 +1719,7 @@ func Test_Integ_NewProgram_InvalidMode_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=invalid", "--mirror=/mirror", "--target=/real"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.ErrorIs(t, err, errArgModeMismatch)
 	require.Nil(t, prog)
 
@@ -911,7 +1734,7 @@ func Test_Integ_NewProgram_MissingMode_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mirror=/mirror", "--target=/real"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.ErrorIs(t, err, errArgModeMismatch)
 	require.Nil(t, prog)
 
@@ -926,7 +1749,7 @@ func Test_Integ_NewProgram_MissingMirror_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--target=/real"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.ErrorIs(t, err, errArgMissingMirrorTarget)
 	require.Nil(t, prog)
 
@@ -941,7 +1764,7 @@ func Test_Integ_NewProgram_MissingTarget_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--mirror=/mirror"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.ErrorIs(t, err, errArgMissingMirrorTarget)
 	require.Nil(t, prog)
 
@@ -956,7 +1779,7 @@ func Test_Integ_NewProgram_SameMirrorAndTarget_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--mirror=/same", "--target=/same"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.ErrorIs(t, err, errArgMirrorTargetSame)
 	require.Nil(t, prog)
 
@@ -971,7 +1794,7 @@ func Test_Integ_NewProgram_RelativeMirrorPath_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--mirror=relative/path", "--target=/absolute"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.ErrorIs(t, err, errArgMirrorTargetNotAbs)
 	require.Nil(t, prog)
 
@@ -986,7 +1809,7 @@ func Test_Integ_NewProgram_RelativeTargetPath_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--mirror=/absolute", "--target=relative/path"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.ErrorIs(t, err, errArgMirrorTargetNotAbs)
 	require.Nil(t, prog)
 
@@ -1001,9 +1824,170 @@ func Test_Integ_NewProgram_RelativeExcludePath_Error(t *testing.T) {
 	var stdout, stderr bytes.Buffer
 	args := []string{"program", "--mode=init", "--mirror=/mirror", "--target=/real", "--exclude=relative/path"}
 
-	prog, err := newProgram(args, fs, &stdout, &stderr)
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
 	require.ErrorIs(t, err, errArgExcludePathNotAbs)
 	require.Nil(t, prog)
 
 	require.Contains(t, stderr.String(), errArgExcludePathNotAbs.Error())
 }
+
+// Expectation: The program should validate a correct configuration file without a mode.
+func Test_Integ_Run_ValidateConfig_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--validate-config=/config.yaml"}
+
+	yaml := `
+mirror: /mirror
+target: /real
+exclude:
+  - /real/exclude1
+`
+
+	files := map[string]string{
+		"/config.yaml": yaml,
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NoError(t, err)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeSuccess, exitCode)
+
+	require.Contains(t, stderr.String(), "configuration is valid")
+
+	// Verify nothing was touched besides reading the configuration.
+	_, err = fs.Stat("/mirror")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The program should log the origin of each option with --explain-config.
+func Test_Integ_NewProgram_ExplainConfig_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--config=/config.yaml", "--mirror=/mirror", "--explain-config", "--log-format=logfmt"}
+
+	yaml := `
+mirror: /other
+target: /real
+exclude:
+  - /real/exclude1
+  - /real/exclude2
+`
+	err := createFiles(fs, map[string]string{"/config.yaml": yaml})
+	require.NoError(t, err)
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NoError(t, err)
+	require.NotNil(t, prog)
+
+	logs := stderr.String()
+	require.Contains(t, logs, "option=mirror value=/mirror origin=cli")
+	require.Contains(t, logs, "option=target value=/real origin=config")
+	require.Contains(t, logs, `option=exclude value="[/real/exclude1, /real/exclude2]" origin=config`)
+	require.Contains(t, logs, "option=log-level value=info origin=default")
+}
+
+// Expectation: The program should describe a known return code with --explain-exit, and reject an unknown one.
+func Test_Integ_Run_ExplainExit_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		code     string
+		wantExit int
+		wantOut  string
+	}{
+		{"unmoved-files", "4", exitCodeSuccess, "4: Unmoved files due to conflicting target files"},
+		{"success", "0", exitCodeSuccess, "0: Success"},
+		{"unknown", "42", exitCodeConfigFailure, ""},
+		{"not-a-number", "four", exitCodeConfigFailure, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			var stdout, stderr bytes.Buffer
+			args := []string{"program", "--explain-exit=" + tt.code}
+
+			prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+			require.NoError(t, err)
+			require.NotNil(t, prog)
+
+			exitCode, err := prog.run(t.Context())
+			require.Equal(t, tt.wantExit, exitCode)
+
+			if tt.wantOut == "" {
+				require.ErrorIs(t, err, errArgExplainExitInvalid)
+				require.Empty(t, stdout.String())
+
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(stdout.String(), tt.wantOut), stdout.String())
+		})
+	}
+}
+
+// Expectation: The usage should contain the table of all return codes.
+func Test_Unit_Usage_ExitCodes_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--explain-exit=0"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NoError(t, err)
+
+	prog.flags.Usage()
+
+	require.Contains(t, stderr.String(), "return codes:")
+	for code, desc := range exitCodeDescriptions {
+		require.Contains(t, stderr.String(), fmt.Sprintf("%d\t%s", code, desc))
+	}
+}
+
+// Expectation: The program should report all problems of an invalid configuration file.
+func Test_Integ_NewProgram_ValidateConfigMultipleProblems_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--validate-config=/config.yaml"}
+
+	yaml := `
+mirror: relative/mirror
+target: /real
+exclude:
+  - relative/exclude
+log-level: invalid
+`
+
+	files := map[string]string{
+		"/config.yaml": yaml,
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.Nil(t, prog)
+
+	require.ErrorIs(t, err, errArgMirrorTargetNotAbs)
+	require.ErrorIs(t, err, errArgExcludePathNotAbs)
+	require.ErrorIs(t, err, errArgInvalidLogLevel)
+
+	require.Contains(t, stderr.String(), errArgMirrorTargetNotAbs.Error())
+	require.Contains(t, stderr.String(), errArgExcludePathNotAbs.Error())
+	require.Contains(t, stderr.String(), errArgInvalidLogLevel.Error())
+}
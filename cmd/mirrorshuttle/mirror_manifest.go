@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const mirrorManifestPerm = 0o644
+
+// mirrorDirs returns the (sorted) relative paths of all directories below the
+// mirror root, as recorded within or checked against the --mirror-manifest.
+func (prog *program) mirrorDirs(ctx context.Context) ([]string, error) {
+	var dirs []string
+
+	if err := afero.Walk(prog.fsys, prog.opts.MirrorRoot, func(path string, e os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			// An interrupt was received, so we also interrupt the walk.
+			return fmt.Errorf("failed checking context: %w", err)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to walk: %q (%w)", path, err)
+		}
+
+		if !e.IsDir() || path == prog.opts.MirrorRoot {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(prog.opts.MirrorRoot, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %q (%w)", path, err)
+		}
+		dirs = append(dirs, filepath.ToSlash(relPath))
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	slices.Sort(dirs)
+
+	return dirs, nil
+}
+
+// writeMirrorManifest records the directories of the mirror after --mode=init,
+// one relative path per line, for validating the mirror in later --mode=move.
+func (prog *program) writeMirrorManifest(ctx context.Context) error {
+	if prog.opts.DryRun {
+		prog.log.Info("mirror manifest written", "op", prog.opts.Mode, "path", prog.opts.MirrorManifest, "dry-run", prog.opts.DryRun)
+
+		return nil
+	}
+
+	dirs, err := prog.mirrorDirs(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := prog.writeDirManifest(prog.opts.MirrorManifest, dirs); err != nil {
+		return err
+	}
+	prog.log.Info("mirror manifest written", "op", prog.opts.Mode, "path", prog.opts.MirrorManifest, "dirs", len(dirs), "dry-run", prog.opts.DryRun)
+
+	return nil
+}
+
+// writeDirManifest writes the relative directory paths, one per line.
+func (prog *program) writeDirManifest(path string, dirs []string) error {
+	var sb strings.Builder
+	for _, dir := range dirs {
+		sb.WriteString(dir + "\n")
+	}
+
+	if err := afero.WriteFile(prog.fsys, path, []byte(sb.String()), mirrorManifestPerm); err != nil {
+		return fmt.Errorf("failed to write: %q (%w)", path, err)
+	}
+
+	return nil
+}
+
+// readDirManifest reads the relative directory paths that were written with
+// [program.writeDirManifest], returning errMissing if the file does not exist.
+func (prog *program) readDirManifest(path string, errMissing error) ([]string, error) {
+	f, err := prog.fsys.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errMissing, err)
+	}
+	defer f.Close()
+
+	var dirs []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			dirs = append(dirs, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read: %q (%w)", path, err)
+	}
+
+	return dirs, nil
+}
+
+// checkMirrorManifest compares the directories of the mirror with those that
+// were recorded in the --mirror-manifest after --mode=init, and warns about any
+// directories that were added or removed in the meantime (outside of the program).
+func (prog *program) checkMirrorManifest(ctx context.Context) error {
+	recorded, err := prog.readDirManifest(prog.opts.MirrorManifest, errMirrorManifestMissing)
+	if err != nil {
+		return err
+	}
+
+	current, err := prog.mirrorDirs(ctx)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(recorded))
+	for _, dir := range recorded {
+		known[dir] = true
+	}
+
+	changes := 0
+
+	for _, dir := range current {
+		if !known[dir] {
+			changes++
+			prog.log.Warn("mirror directory added", "op", prog.opts.Mode, "path", filepath.Join(prog.opts.MirrorRoot, filepath.FromSlash(dir)), "reason", "not_in_mirror_manifest")
+		}
+		delete(known, dir)
+	}
+
+	for _, dir := range recorded {
+		if known[dir] {
+			changes++
+			prog.log.Warn("mirror directory removed", "op", prog.opts.Mode, "path", filepath.Join(prog.opts.MirrorRoot, filepath.FromSlash(dir)), "reason", "not_in_mirror")
+		}
+	}
+
+	prog.log.Info("mirror manifest checked", "op", prog.opts.Mode, "path", prog.opts.MirrorManifest, "changes", changes)
+
+	return nil
+}
+
+// recordDiffDir records a mirror directory that the --dry-run of --mode=init
+// would create (with the --diff-exit setting), relative to the given root.
+func (prog *program) recordDiffDir(root string, path string) {
+	if !prog.opts.DiffExit {
+		return
+	}
+
+	if relPath, err := filepath.Rel(root, path); err == nil && relPath != "." {
+		prog.state.diffDirs = append(prog.state.diffDirs, filepath.ToSlash(relPath))
+	}
+}
+
+// recordTargetDir records a target directory that was walked and mirrored in
+// --mode=init (with the --verify-target-structure setting).
+func (prog *program) recordTargetDir(path string) {
+	if prog.opts.VerifyTargetStructure == "" {
+		return
+	}
+
+	if relPath, err := filepath.Rel(prog.opts.RealRoot, path); err == nil && relPath != "." {
+		prog.state.targetDirs = append(prog.state.targetDirs, filepath.ToSlash(relPath))
+	}
+}
+
+// diffMirror compares the mirror structure that the --dry-run of --mode=init
+// computed with the existing mirror (with the --diff-exit setting), and warns
+// about any directories that would be added or removed. The count of these is
+// kept, so that the run can signal them with its return code.
+func (prog *program) diffMirror(ctx context.Context) error {
+	var current []string
+
+	if _, err := prog.fsys.Stat(prog.opts.MirrorRoot); errors.Is(err, os.ErrNotExist) {
+		prog.state.mirrorChanges++
+		prog.log.Warn("mirror directory would be added", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "reason", "is_mirror_root")
+	} else if err != nil {
+		return fmt.Errorf("failed to stat: %q (%w)", prog.opts.MirrorRoot, err)
+	} else if current, err = prog.mirrorDirs(ctx); err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool, len(current))
+	for _, dir := range current {
+		existing[dir] = true
+	}
+
+	// The existing parents of any computed directory are left in place as well.
+	computed := make(map[string]bool, len(prog.state.diffDirs))
+	for _, dir := range prog.state.diffDirs {
+		for p := dir; p != "." && !computed[p]; p = path.Dir(p) {
+			computed[p] = true
+		}
+	}
+
+	for _, dir := range slices.Sorted(maps.Keys(computed)) {
+		if !existing[dir] {
+			prog.state.mirrorChanges++
+			prog.log.Warn("mirror directory would be added", "op", prog.opts.Mode, "path", filepath.Join(prog.opts.MirrorRoot, filepath.FromSlash(dir)), "reason", "not_in_mirror")
+		}
+	}
+
+	// A merging or incremental init keeps all of the existing directories.
+	if prog.opts.InitChangedSince == 0 && !prog.opts.InitMerge {
+		for _, dir := range current {
+			if !computed[dir] {
+				prog.state.mirrorChanges++
+				prog.log.Warn("mirror directory would be removed", "op", prog.opts.Mode, "path", filepath.Join(prog.opts.MirrorRoot, filepath.FromSlash(dir)), "reason", "not_in_target")
+			}
+		}
+	}
+
+	prog.log.Info("mirror structure compared", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "changes", prog.state.mirrorChanges)
+
+	return nil
+}
+
+// writeTargetStructure records the target directories that were mirrored in
+// --mode=init, for verifying the target structure before later --mode=move.
+func (prog *program) writeTargetStructure() error {
+	if prog.opts.DryRun {
+		prog.log.Info("target structure written", "op", prog.opts.Mode, "path", prog.opts.VerifyTargetStructure, "dry-run", prog.opts.DryRun)
+
+		return nil
+	}
+
+	// The mirror itself can differ from the target (such as with a merging or
+	// incremental init, or merged case collisions), so record the walked ones.
+	dirs := slices.Clone(prog.state.targetDirs)
+	slices.Sort(dirs)
+
+	if err := prog.writeDirManifest(prog.opts.VerifyTargetStructure, dirs); err != nil {
+		return err
+	}
+	prog.log.Info("target structure written", "op", prog.opts.Mode, "path", prog.opts.VerifyTargetStructure, "dirs", len(dirs), "dry-run", prog.opts.DryRun)
+
+	return nil
+}
+
+// verifyTargetStructure checks that all of the target directories that were
+// recorded with --verify-target-structure in --mode=init still exist, as moving
+// into a diverged target structure could re-create any removed directories.
+func (prog *program) verifyTargetStructure(ctx context.Context) error {
+	recorded, err := prog.readDirManifest(prog.opts.VerifyTargetStructure, errTargetStructureMissing)
+	if err != nil {
+		return err
+	}
+
+	diverged := 0
+
+	for _, dir := range recorded {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed checking context: %w", err)
+		}
+
+		path := filepath.Join(prog.opts.RealRoot, filepath.FromSlash(dir))
+
+		if e, err := prog.fsys.Stat(path); errors.Is(err, os.ErrNotExist) {
+			diverged++
+			prog.log.Error("target directory diverged", "op", prog.opts.Mode, "path", path, "reason", "no_longer_exists")
+		} else if err != nil {
+			return fmt.Errorf("failed to stat: %q (%w)", path, err)
+		} else if !e.IsDir() {
+			diverged++
+			prog.log.Error("target directory diverged", "op", prog.opts.Mode, "path", path, "reason", "not_a_directory")
+		}
+	}
+
+	if diverged > 0 {
+		return fmt.Errorf("%w: %d of %d directories", errTargetStructureDiverged, diverged, len(recorded))
+	}
+	prog.log.Info("target structure verified", "op", prog.opts.Mode, "path", prog.opts.VerifyTargetStructure, "dirs", len(recorded))
+
+	return nil
+}
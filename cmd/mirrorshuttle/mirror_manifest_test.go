@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// Expectation: The init should record all mirror directories, one relative path per line.
+func Test_Unit_WriteMirrorManifest_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/b/c", "/real/a"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:     "/mirror",
+		RealRoot:       "/real",
+		InitDepth:      -1,
+		MirrorManifest: "/mirror.manifest",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	content, err := afero.ReadFile(fs, "/mirror.manifest")
+	require.NoError(t, err)
+	require.Equal(t, "a\nb\nb/c\n", string(content))
+}
+
+// Expectation: The move should flag directories added to and removed from the mirror after the init.
+func Test_Unit_CheckMirrorManifest_Changed_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/a", "/real/b"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:     "/mirror",
+		RealRoot:       "/real",
+		InitDepth:      -1,
+		MirrorManifest: "/mirror.manifest",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	// Tamper with the mirror structure between the init and the move.
+	require.NoError(t, fs.Mkdir("/mirror/added", dirBasePerm))
+	require.NoError(t, fs.Remove("/mirror/b"))
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	logs := stderr.String()
+	require.Contains(t, logs, `msg="mirror directory added" op="" path=/mirror/added`)
+	require.Contains(t, logs, `msg="mirror directory removed" op="" path=/mirror/b`)
+	require.NotContains(t, logs, "path=/mirror/a reason")
+	require.Contains(t, logs, "changes=2")
+}
+
+// Expectation: The move should fail without the manifest that was to be written by the init.
+func Test_Unit_CheckMirrorManifest_Missing_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/mirror", "/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:     "/mirror",
+		RealRoot:       "/real",
+		MirrorManifest: "/mirror.manifest",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.ErrorIs(t, err, errMirrorManifestMissing)
+}
+
+// Expectation: The move should proceed into a target structure that matches the one recorded by the init.
+func Test_Unit_VerifyTargetStructure_Matching_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/a/b", "/real/c"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:            "/mirror",
+		RealRoot:              "/real",
+		InitDepth:             -1,
+		VerifyTargetStructure: "/target.structure",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	content, err := afero.ReadFile(fs, "/target.structure")
+	require.NoError(t, err)
+	require.Equal(t, "a\na/b\nc\n", string(content))
+
+	// Directories added to the target do not diverge the recorded structure.
+	require.NoError(t, fs.Mkdir("/real/added", dirBasePerm))
+	err = createFiles(fs, map[string]string{"/mirror/a/b/file.txt": "content"})
+	require.NoError(t, err)
+
+	prog, _, _ = setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	_, err = fs.Stat("/real/a/b/file.txt")
+	require.NoError(t, err)
+}
+
+// Expectation: A merging init should record the walked target directories, not the directories already within the kept mirror.
+func Test_Unit_VerifyTargetStructure_InitMerge_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/a/b", "/real/c", "/mirror/a", "/mirror/client"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:            "/mirror",
+		RealRoot:              "/real",
+		InitDepth:             -1,
+		InitMerge:             true,
+		VerifyTargetStructure: "/target.structure",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	content, err := afero.ReadFile(fs, "/target.structure")
+	require.NoError(t, err)
+	require.Equal(t, "a\na/b\nc\n", string(content))
+
+	prog, _, _ = setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+}
+
+// Expectation: The move should refuse to run into a target structure that diverged from the one recorded by the init.
+func Test_Unit_VerifyTargetStructure_Diverged_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/a/b", "/real/c"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:            "/mirror",
+		RealRoot:              "/real",
+		InitDepth:             -1,
+		VerifyTargetStructure: "/target.structure",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	// Rearrange the target structure between the init and the move.
+	require.NoError(t, fs.Rename("/real/a/b", "/real/c/b"))
+	err = createFiles(fs, map[string]string{"/mirror/a/b/file.txt": "content"})
+	require.NoError(t, err)
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.ErrorIs(t, err, errTargetStructureDiverged)
+	require.Contains(t, stderr.String(), "path=/real/a/b reason=no_longer_exists")
+
+	// Verify nothing was moved, the removed directory was not re-created.
+	_, err = fs.Stat("/real/a/b")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: A --dry-run init with --diff-exit should return zero for an unchanged mirror, and non-zero once the target changed.
+func Test_Integ_Run_DiffExit_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		change   func(fs afero.Fs) error
+		wantExit int
+		wantLogs []string
+	}{
+		{
+			name:     "unchanged",
+			change:   func(fs afero.Fs) error { return nil },
+			wantExit: exitCodeSuccess,
+			wantLogs: nil,
+		},
+		{
+			name:     "target dir added",
+			change:   func(fs afero.Fs) error { return fs.MkdirAll("/real/c/d", dirBasePerm) },
+			wantExit: exitCodeMirrorDiffers,
+			wantLogs: []string{"mirror directory would be added", "/mirror/c/d"},
+		},
+		{
+			name:     "target dir removed",
+			change:   func(fs afero.Fs) error { return fs.RemoveAll("/real/a/b") },
+			wantExit: exitCodeMirrorDiffers,
+			wantLogs: []string{"mirror directory would be removed", "/mirror/a/b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createDirStructure(fs, []string{"/real/a/b", "/real/c"})
+			require.NoError(t, err)
+
+			var stdout, stderr bytes.Buffer
+			prog, _ := newProgram([]string{"program", "--mode=init", "--mirror=/mirror", "--target=/real"}, fs, nil, &stdout, &stderr)
+			require.NotNil(t, prog)
+
+			exitCode, err := prog.run(t.Context())
+			require.NoError(t, err)
+			require.Equal(t, exitCodeSuccess, exitCode)
+
+			require.NoError(t, tt.change(fs))
+
+			stdout.Reset()
+			stderr.Reset()
+			prog, _ = newProgram([]string{"program", "--mode=init", "--mirror=/mirror", "--target=/real", "--dry-run", "--diff-exit"}, fs, nil, &stdout, &stderr)
+			require.NotNil(t, prog)
+
+			exitCode, err = prog.run(t.Context())
+			require.NoError(t, err)
+			require.Equal(t, tt.wantExit, exitCode)
+
+			require.Contains(t, stderr.String(), "mirror structure compared")
+			if tt.wantLogs == nil {
+				require.NotContains(t, stderr.String(), "would be")
+			}
+			for _, want := range tt.wantLogs {
+				require.Contains(t, stderr.String(), want)
+			}
+
+			// Verify the mirror was left untouched.
+			_, err = fs.Stat("/mirror/a/b")
+			require.NoError(t, err)
+		})
+	}
+}
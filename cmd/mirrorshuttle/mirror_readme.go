@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+const mirrorReadmePerm = 0o644
+
+// defaultMirrorReadme is the content of the --mirror-readme, unless the user
+// provides their own content with --mirror-readme-from.
+const defaultMirrorReadme = `This is a staging area, not the archive itself.
+
+The directories here mirror those of the archive. Any files that are placed
+into them are moved into the same place within the archive, after which they
+are no longer found here. The archive itself cannot be written to directly.
+
+Files that already exist within the archive are never overwritten, these stay
+here until they are resolved. This file itself is never moved.
+`
+
+// mirrorReadmePath returns the path of the --mirror-readme within the mirror
+// root, or an empty string if no such file is to be written.
+func (prog *program) mirrorReadmePath() string {
+	if prog.opts.MirrorReadme == "" || prog.opts.MirrorRoot == "" {
+		return ""
+	}
+
+	return filepath.Join(prog.opts.MirrorRoot, prog.opts.MirrorReadme)
+}
+
+// writeMirrorReadme writes the --mirror-readme into the mirror root, which
+// explains the staging area to the clients that are working with the mirror.
+func (prog *program) writeMirrorReadme() error {
+	path := prog.mirrorReadmePath()
+
+	content := []byte(defaultMirrorReadme)
+	if prog.opts.MirrorReadmeFrom != "" {
+		data, err := afero.ReadFile(prog.fsys, prog.opts.MirrorReadmeFrom)
+		if err != nil {
+			return fmt.Errorf("failed to read: %q (%w)", prog.opts.MirrorReadmeFrom, err)
+		}
+		content = data
+	}
+
+	if !prog.opts.DryRun {
+		if err := afero.WriteFile(prog.fsys, path, content, mirrorReadmePerm); err != nil {
+			return fmt.Errorf("failed to write: %q (%w)", path, err)
+		}
+	}
+	prog.log.Info("mirror readme written", "op", prog.opts.Mode, "path", path, "dry-run", prog.opts.DryRun)
+
+	return nil
+}
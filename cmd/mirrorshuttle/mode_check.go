@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type manifestEntry struct {
+	hash string
+	path string
+}
+
+func (prog *program) checkManifest(ctx context.Context) error {
+	entries, err := prog.readManifest(prog.opts.Manifest)
+	if err != nil {
+		return err
+	}
+
+	// Re-hash every file listed in the manifest and compare against the recorded hash.
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			// An interrupt was received, so we also interrupt the check.
+			return fmt.Errorf("failed checking context: %w", err)
+		}
+
+		path := entry.path
+		if !filepath.IsAbs(path) {
+			// Relative manifest paths are relative to the target root.
+			path = filepath.Join(prog.opts.RealRoot, path)
+		}
+
+		prog.state.checkedFiles++
+
+		hash, err := prog.hashFile(ctx, path, hashAlgoSHA256)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return err
+			}
+
+			prog.state.hasFailedChecks = true
+
+			if errors.Is(err, os.ErrNotExist) {
+				prog.log.Error("file check failed", "op", prog.opts.Mode, "path", path, "reason", "file_missing")
+
+				continue
+			}
+
+			prog.log.Error("file check failed", "op", prog.opts.Mode, "path", path, "error", err, "error-type", "runtime", "reason", "error_occurred")
+
+			continue
+		}
+
+		if hash != entry.hash {
+			prog.state.hasFailedChecks = true
+			prog.log.Error("file check failed", "op", prog.opts.Mode, "path", path, "expectedHash", entry.hash, "actualHash", hash, "reason", "hash_mismatch")
+
+			continue
+		}
+
+		prog.log.Info("file checked", "op", prog.opts.Mode, "path", path, "hash", hash)
+	}
+
+	return nil
+}
+
+// readManifest reads a manifest in the format of the common `sha256sum` tool,
+// with each line consisting of a hex-encoded hash, a separator (either two
+// spaces, or a space and an asterisk) and the path the hash belongs to. Empty
+// lines and lines starting with a '#' are ignored.
+func (prog *program) readManifest(path string) ([]manifestEntry, error) {
+	f, err := prog.fsys.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errManifestMissing, err)
+	}
+	defer f.Close()
+
+	var entries []manifestEntry
+
+	lineNum := 0
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		lineNum++
+
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, rest, ok := strings.Cut(line, " ")
+		if !ok || len(rest) < 2 || (rest[0] != ' ' && rest[0] != '*') {
+			return nil, fmt.Errorf("%w: line %d", errManifestMalformed, lineNum)
+		}
+
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("%w: line %d", errManifestMalformed, lineNum)
+		}
+
+		entries = append(entries, manifestEntry{
+			hash: strings.ToLower(hash),
+			path: rest[1:],
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read: %q (%w)", path, err)
+	}
+
+	return entries, nil
+}
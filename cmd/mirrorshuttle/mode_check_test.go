@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Expectation: The function should pass all files of a manifest matching the target.
+func Test_Unit_CheckManifest_Clean_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	manifest := fmt.Sprintf("%s  file.txt\n%s *dir/file2.txt\n%s  /real/dir/file3.txt\n",
+		testHash("content"), testHash("content2"), testHash("content3"))
+
+	files := map[string]string{
+		"/real/file.txt":      "content",
+		"/real/dir/file2.txt": "content2",
+		"/real/dir/file3.txt": "content3",
+		"/manifest.sha256":    manifest,
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		Mode:     "check",
+		RealRoot: "/real",
+		Manifest: "/manifest.sha256",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.checkManifest(t.Context())
+	require.NoError(t, err)
+
+	require.Equal(t, 3, prog.state.checkedFiles)
+	require.False(t, prog.state.hasFailedChecks)
+}
+
+// Expectation: The function should fail the check of a corrupted target file.
+func Test_Unit_CheckManifest_CorruptedTarget_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	manifest := fmt.Sprintf("%s  file.txt\n%s  file2.txt\n", testHash("content"), testHash("content2"))
+
+	files := map[string]string{
+		"/real/file.txt":   "content",
+		"/real/file2.txt":  "corrupted",
+		"/manifest.sha256": manifest,
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		Mode:     "check",
+		RealRoot: "/real",
+		Manifest: "/manifest.sha256",
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.checkManifest(t.Context())
+	require.NoError(t, err)
+
+	require.Equal(t, 2, prog.state.checkedFiles)
+	require.True(t, prog.state.hasFailedChecks)
+	require.Contains(t, stderr.String(), "hash_mismatch")
+}
+
+// Expectation: The function should fail the check of a missing target file.
+func Test_Unit_CheckManifest_MissingTarget_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	manifest := fmt.Sprintf("%s  file.txt\n%s  missing.txt\n", testHash("content"), testHash("content2"))
+
+	files := map[string]string{
+		"/real/file.txt":   "content",
+		"/manifest.sha256": manifest,
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		Mode:     "check",
+		RealRoot: "/real",
+		Manifest: "/manifest.sha256",
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.checkManifest(t.Context())
+	require.NoError(t, err)
+
+	require.Equal(t, 2, prog.state.checkedFiles)
+	require.True(t, prog.state.hasFailedChecks)
+	require.Contains(t, stderr.String(), "file_missing")
+}
+
+// Expectation: The function should reject a malformed manifest.
+func Test_Unit_CheckManifest_Malformed_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	files := map[string]string{
+		"/real/file.txt":   "content",
+		"/manifest.sha256": "nothex  file.txt\n",
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		Mode:     "check",
+		RealRoot: "/real",
+		Manifest: "/manifest.sha256",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.checkManifest(t.Context())
+	require.ErrorIs(t, err, errManifestMalformed)
+}
+
+// Expectation: The program should return the failed checks exit code for a corrupted target file.
+func Test_Integ_Run_CheckModeFailedChecksExitCode_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	manifest := fmt.Sprintf("%s  file.txt\n", testHash("content"))
+
+	files := map[string]string{
+		"/real/file.txt":   "corrupted",
+		"/manifest.sha256": manifest,
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=check", "--target=/real", "--manifest=/manifest.sha256"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NoError(t, err)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeFailedChecks, exitCode)
+}
+
+// Expectation: The program should not produce the dry run mode warning in check mode, which makes no changes.
+func Test_Integ_Run_CheckModeDryRun_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	manifest := fmt.Sprintf("%s  file.txt\n", testHash("content"))
+
+	files := map[string]string{
+		"/real/file.txt":   "content",
+		"/manifest.sha256": manifest,
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=check", "--target=/real", "--manifest=/manifest.sha256", "--dry-run"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NoError(t, err)
+	require.NotNil(t, prog)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeSuccess, exitCode)
+	require.NotContains(t, stderr.String(), "running in dry mode")
+}
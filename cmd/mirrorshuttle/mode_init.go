@@ -6,13 +6,25 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/spf13/afero"
 )
 
 func (prog *program) createMirrorStructure(ctx context.Context) error {
-	createdDirsBatch := 0
+	depthRules, err := parseDepthRules(prog.opts.InitDepthRules)
+	if err != nil {
+		return err
+	}
+
+	var skipDirsOver int64
+	if prog.opts.InitSkipDirsOver != "" {
+		var ok bool
+		if skipDirsOver, ok = parseBytes(prog.opts.InitSkipDirsOver); !ok || skipDirsOver == 0 {
+			return fmt.Errorf("%w: %q", errArgInitSkipDirsOverInvalid, prog.opts.InitSkipDirsOver)
+		}
+	}
 
 	// The real root needs to exist, otherwise we have nowhere to mirror from.
 	if _, err := prog.fsys.Stat(prog.opts.RealRoot); errors.Is(err, os.ErrNotExist) {
@@ -34,43 +46,174 @@ func (prog *program) createMirrorStructure(ctx context.Context) error {
 		return fmt.Errorf("%w: %q", errMirrorParentNotDir, mirrorParent)
 	}
 
+	// An incremental init only (re-)creates recently changed directories and keeps the existing mirror.
+	incremental := prog.opts.InitChangedSince > 0
+	changedSince := time.Now().Add(-prog.opts.InitChangedSince)
+	keepMirror := false
+
+	// A two-phase init builds the new mirror beside the existing one, then swaps it into place.
+	replaceMirror := false
+	buildRoot := prog.opts.MirrorRoot
+
 	// If the mirror root exists, it must be empty, otherwise it should not be removed.
-	if _, err := prog.fsys.Stat(prog.opts.MirrorRoot); err == nil {
-		prog.log.Info("testing if the existing mirror structure is empty...", "op", prog.opts.Mode)
+	if _, err := prog.fsys.Stat(prog.opts.MirrorRoot); err == nil && incremental {
+		keepMirror = true
+		prog.log.Info("mirror directory kept", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "reason", "is_incremental_init", "dry-run", prog.opts.DryRun)
+	} else if err == nil && prog.opts.InitMerge {
+		// The existing mirror may contain files, which are left intact by merging into it.
+		keepMirror = true
+		prog.log.Info("mirror directory kept", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "reason", "is_merging_init", "dry-run", prog.opts.DryRun)
+	} else if err == nil {
+		if prog.opts.AssumeEmptyMirror {
+			// The emptiness is managed externally, any files still within the mirror are lost.
+			prog.log.Warn("assuming the existing mirror structure is empty - any contents are removed unchecked",
+				"op", prog.opts.Mode,
+				"path", prog.opts.MirrorRoot,
+				"dry-run", prog.opts.DryRun,
+			)
+		} else {
+			prog.log.Info("testing if the existing mirror structure is empty...", "op", prog.opts.Mode)
+
+			empty, err := prog.isEmptyStructure(ctx, prog.opts.MirrorRoot)
+			if err != nil {
+				return fmt.Errorf("failed checking for emptiness: %q (%w)", prog.opts.MirrorRoot, err)
+			} else if !empty {
+				// The mirror root contains files, we do not want to remove it, user should resolve it.
+				return errMirrorNotEmpty
+			}
+		}
 
-		empty, err := prog.isEmptyStructure(ctx, prog.opts.MirrorRoot)
+		mountPoint, err := prog.isMountPoint(prog.opts.MirrorRoot)
 		if err != nil {
-			return fmt.Errorf("failed checking for emptiness: %q (%w)", prog.opts.MirrorRoot, err)
-		} else if !empty {
-			// The mirror root contains files, we do not want to remove it, user should resolve it.
-			return errMirrorNotEmpty
+			return err
 		}
 
-		if !prog.opts.DryRun {
-			// The mirror root is empty, we can remove it safely, for later re-creation.
-			if err := prog.fsys.RemoveAll(prog.opts.MirrorRoot); err != nil {
-				return fmt.Errorf("failed to remove: %q (%w)", prog.opts.MirrorRoot, err)
+		clearMirror := mountPoint && !prog.opts.AllowMountpointMirror
+
+		if !prog.opts.DryRun && !prog.opts.AssumeEmptyMirror {
+			// Fail before the removal rather than leave behind a partially removed mirror.
+			if err := prog.checkRemovable(ctx, prog.opts.MirrorRoot, clearMirror); err != nil {
+				return err
+			}
+		}
+
+		if clearMirror {
+			// Removing and re-creating a mount point could shadow the mount, only clear its contents.
+			if err := prog.clearDir(prog.opts.MirrorRoot); err != nil {
+				return err
+			}
+			keepMirror = true
+			prog.log.Warn("mirror directory cleared", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "reason", "is_mount_point", "dry-run", prog.opts.DryRun)
+		} else {
+			if prog.opts.TwoPhaseInit {
+				// The existing mirror remains available until the new one was built.
+				replaceMirror = true
+			} else if !prog.opts.DryRun {
+				// The mirror root is empty, we can remove it safely, for later re-creation.
+				if err := prog.fsys.RemoveAll(prog.opts.MirrorRoot); err != nil {
+					return fmt.Errorf("failed to remove: %q (%w)", prog.opts.MirrorRoot, err)
+				}
+			}
+
+			if !replaceMirror {
+				prog.log.Info("mirror directory removed", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "dry-run", prog.opts.DryRun)
 			}
 		}
-		prog.log.Info("mirror directory removed", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "dry-run", prog.opts.DryRun)
 	} else if !errors.Is(err, os.ErrNotExist) {
 		return fmt.Errorf("failed to stat: %q (%w)", prog.opts.MirrorRoot, err)
 	}
 
+	if prog.opts.TwoPhaseInit && !keepMirror {
+		// The sibling is on the same filesystem as the mirror root, allowing for the renames.
+		buildRoot = twoPhaseSibling(prog.opts.MirrorRoot, twoPhaseBuildSuffix)
+	} else if prog.opts.TwoPhaseInit {
+		prog.log.Warn("two-phase init skipped", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "reason", "mirror_is_kept")
+	}
+
 	// The mirror root either does not exist or was empty and deleted, re-create it now.
-	if !prog.opts.DryRun {
-		if err := prog.fsys.Mkdir(prog.opts.MirrorRoot, dirBasePerm); err != nil {
-			return fmt.Errorf("failed to create: %q (%w)", prog.opts.MirrorRoot, err)
+	if !keepMirror {
+		if !prog.opts.DryRun {
+			if err := prog.fsys.Mkdir(buildRoot, dirBasePerm); err != nil {
+				return fmt.Errorf("failed to create: %q (%w)", buildRoot, err)
+			}
 		}
-		prog.state.createdDirs++
+		prog.state.createdDirs++ // Counted in dry mode as well, previewing the summary of the real run.
+		prog.log.Info("mirror directory created", "op", prog.opts.Mode, "path", buildRoot, "dry-run", prog.opts.DryRun)
 	}
-	prog.log.Info("mirror directory created", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "dry-run", prog.opts.DryRun)
+
+	if err := prog.walkMirrorStructure(ctx, buildRoot, depthRules, skipDirsOver, incremental, changedSince); err != nil {
+		if buildRoot != prog.opts.MirrorRoot && !prog.opts.DryRun {
+			// Roll back the partially built mirror, the existing mirror was left untouched.
+			if rerr := prog.fsys.RemoveAll(buildRoot); rerr != nil {
+				prog.log.Error("failed to remove the partially built mirror", "op", prog.opts.Mode, "path", buildRoot, "error", rerr)
+			}
+		}
+
+		return err
+	}
+
+	if buildRoot != prog.opts.MirrorRoot {
+		if err := prog.swapMirror(ctx, buildRoot, replaceMirror); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.MirrorReadme != "" {
+		// Explain the staging area to the clients, it is never moved into the target.
+		if err := prog.writeMirrorReadme(); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.MirrorManifest != "" {
+		// Record the created mirror, so that it can be checked in later moves.
+		if err := prog.writeMirrorManifest(ctx); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.VerifyTargetStructure != "" {
+		// Record the mirrored target directories, so that later moves can verify them.
+		if err := prog.writeTargetStructure(); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.DiffExit {
+		// Tell if the mirror structure drifted from the target, as the real run would change it.
+		if err := prog.diffMirror(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walkMirrorStructure walks the target root and re-creates its directory
+// structure inside the buildRoot, which is either the mirror root itself or the
+// sibling directory of a --two-phase-init.
+func (prog *program) walkMirrorStructure(ctx context.Context, buildRoot string, depthRules []depthRule, skipDirsOver int64, incremental bool, changedSince time.Time) error {
+	createdDirsBatch := 0
+	dirSizes := make(map[string]int64)
+
+	// A --dir-rate paces each of the creations, superseding the batches of --slow-mode.
+	slowMode := prog.opts.SlowMode && prog.opts.DirRate == 0
+	var dirRate *time.Ticker
+	if prog.opts.DirRate > 0 && !prog.opts.DryRun {
+		dirRate = time.NewTicker(max(time.Second/time.Duration(prog.opts.DirRate), time.Nanosecond))
+		defer dirRate.Stop()
+	}
+	knownParents := make(map[string]bool)
+	knownCases := make(map[string]string)
+
+	// The times of the created directories are preserved only after the walk, see below.
+	var dirTimes []dirTime
 
 	// Walk the target root and re-create the directory structure inside the mirror root.
 	if err := afero.Walk(prog.fsys, prog.opts.RealRoot, func(path string, e os.FileInfo, err error) error {
-		if err := ctx.Err(); err != nil {
+		if err := prog.checkInterrupt(ctx); err != nil {
 			// An interrupt was received, so we also interrupt the walk.
-			return fmt.Errorf("failed checking context: %w", err)
+			return err
 		}
 
 		if err != nil {
@@ -82,7 +225,7 @@ func (prog *program) createMirrorStructure(ctx context.Context) error {
 			}
 
 			// Another failure has occurred during the walk (permissions, ...), handle it.
-			return prog.walkError(e, fmt.Errorf("failed to walk: %q (%w)", path, err))
+			return prog.walkError(path, e, fmt.Errorf("failed to walk: %q (%w)", path, err))
 		}
 
 		if !e.IsDir() {
@@ -90,73 +233,341 @@ func (prog *program) createMirrorStructure(ctx context.Context) error {
 			return nil
 		}
 
-		if path == prog.opts.MirrorRoot { // Check if the walked path is the mirror root.
+		if prog.samePath(path, prog.opts.MirrorRoot) || prog.samePath(path, buildRoot) { // Check if the walked path is the mirror root.
 			prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "reason", "is_mirror_root")
 
 			// The mirror root can be contained within the target root, skip it.
 			return filepath.SkipDir // Do not traverse deeper.
 		}
 
-		if isExcluded(path, prog.opts.Excludes) { // Check if the walked path is excluded.
+		if prog.isExcludedPath(path) { // Check if the walked path is excluded.
 			prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "reason", "is_user_excluded")
 
 			// The path was among the user's excluded paths, skip it.
 			return filepath.SkipDir // Do not traverse deeper.
 		}
 
+		if prog.isExcludedGlob(path, prog.opts.RealRoot, prog.opts.ExcludeGlobsTarget) { // Check if the walked path is excluded by pattern.
+			prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "reason", "is_user_excluded_target_glob")
+
+			// The path was matched by the user's target-side patterns, skip it.
+			return filepath.SkipDir // Do not traverse deeper.
+		}
+
+		if path != prog.opts.RealRoot && prog.isExcludedName(path) { // Check if the walked name is excluded.
+			prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "reason", "is_user_excluded_name")
+
+			// The name was among the user's excluded names, skip it.
+			return filepath.SkipDir // Do not traverse deeper.
+		}
+
+		if path != prog.opts.RealRoot && prog.isSystemDir(path) { // Check if the walked name is a system directory.
+			prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "reason", "is_system_dir")
+
+			// The name was among the system directories, skip it.
+			return filepath.SkipDir // Do not traverse deeper.
+		}
+
+		if !prog.isIncluded(path, true) { // Check if the walked path is outside of the included paths.
+			prog.log.Debug("path skipped", "op", prog.opts.Mode, "path", path, "reason", "no_include_match")
+
+			// The path neither is (within) nor leads to any of the user's included paths.
+			return filepath.SkipDir // Do not traverse deeper.
+		}
+
 		// Construct the mirror path from the target's relative path.
 		relPath, err := filepath.Rel(prog.opts.RealRoot, path)
 		if err != nil {
-			return prog.walkError(e, fmt.Errorf("failed to get relative path: %q (%w)", path, err))
+			return prog.walkError(path, e, fmt.Errorf("failed to get relative path: %q (%w)", path, err))
 		}
-		mirrorPath := filepath.Join(prog.opts.MirrorRoot, relPath)
+		mirrorPath := filepath.Join(buildRoot, relPath)
 
 		// Respect a user configured maximum mirroring depth for this mode.
-		if prog.opts.InitDepth >= 0 {
-			if dirDepth := dirDepth(relPath); dirDepth > prog.opts.InitDepth {
-				prog.log.Debug("path skipped", "op", prog.opts.Mode, "path", path, "dir_depth", dirDepth, "reason", "exceeds_init_depth")
+		if dirDepth, depthLimit := initDepthLimit(relPath, depthRules, prog.opts.InitDepth); depthLimit >= 0 && dirDepth > depthLimit {
+			prog.log.Debug("path skipped", "op", prog.opts.Mode, "path", path, "dir_depth", dirDepth, "reason", "exceeds_init_depth")
+
+			// The depth exceeded the user configured limit.
+			return filepath.SkipDir // Do not traverse deeper.
+		}
+
+		if mirrorPath == buildRoot {
+			// The mirror root itself was already created above, skip it.
+			return nil
+		}
+
+		// Detect any target directories differing only by case from an already seen one.
+		if prog.opts.CaseCollision != "" && prog.opts.CaseCollision != caseCollisionNone {
+			var collided bool
+
+			if relPath, collided = canonicalCase(relPath, knownCases); collided {
+				switch prog.opts.CaseCollision {
+				case caseCollisionMerge:
+					prog.log.Warn("path merged", "op", prog.opts.Mode, "path", path, "into", filepath.Join(prog.opts.RealRoot, relPath), "reason", "case_collision")
+					prog.recordTargetDir(path)
+
+					// The directory is merged into its differently-cased sibling, which
+					// already exists in the mirror, but continue mirroring its children.
+					return nil
+
+				case caseCollisionWarn:
+					prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "collides", filepath.Join(prog.opts.RealRoot, relPath), "reason", "case_collision")
+
+					return filepath.SkipDir // Do not traverse deeper.
+
+				default:
+					return prog.walkError(path, e, fmt.Errorf("%w: %q and %q", errCaseCollision, filepath.Join(prog.opts.RealRoot, relPath), path))
+				}
+			}
+			mirrorPath = filepath.Join(buildRoot, relPath)
+		}
+
+		// Respect any user configured patterns restricting the mirrored subtrees.
+		if len(prog.opts.TargetGlobs) > 0 {
+			matched, descend := matchTargetGlobs(relPath, prog.opts.TargetGlobs)
+			if !matched {
+				if !descend {
+					prog.log.Debug("path skipped", "op", prog.opts.Mode, "path", path, "reason", "no_target_glob_match")
+
+					// No descendant can match any of the patterns.
+					return filepath.SkipDir // Do not traverse deeper.
+				}
+
+				// A descendant may still match, traverse without creating.
+				return nil
+			}
 
-				// The depth exceeded the user configured limit.
+			if parentMatched, _ := matchTargetGlobs(filepath.Dir(relPath), prog.opts.TargetGlobs); !parentMatched {
+				// The first matching directory of a subtree, create its not mirrored parents.
+				if err := prog.createParentDirs(buildRoot, relPath, knownParents); err != nil {
+					return prog.walkError(path, e, err)
+				}
+			}
+		}
+
+		if prog.opts.SkipEmptyTargetDirs {
+			if empty, err := prog.isEmptyTree(ctx, path, false); err != nil {
+				return prog.walkError(path, e, fmt.Errorf("failed checking for emptiness: %q (%w)", path, err))
+			} else if empty {
+				prog.log.Debug("path skipped", "op", prog.opts.Mode, "path", path, "reason", "is_empty_dir")
+
+				// No files are contained anywhere below, so nothing could ever be moved into it.
 				return filepath.SkipDir // Do not traverse deeper.
 			}
 		}
 
-		if mirrorPath == prog.opts.MirrorRoot {
-			// The mirror root itself was already created above, skip it.
+		if skipDirsOver > 0 {
+			if size, err := prog.dirSize(ctx, path, dirSizes); err != nil {
+				return prog.walkError(path, e, fmt.Errorf("failed calculating size: %q (%w)", path, err))
+			} else if size > skipDirsOver {
+				prog.log.Debug("path skipped", "op", prog.opts.Mode, "path", path, "dir_size", size, "reason", "exceeds_init_skip_dirs_over")
+
+				// The files below are larger than the user configured limit.
+				return filepath.SkipDir // Do not traverse deeper.
+			}
+		}
+
+		// The directory is mirrored (or was already), so it is part of the target structure.
+		prog.recordTargetDir(path)
+
+		if incremental && e.ModTime().Before(changedSince) {
+			prog.log.Debug("path skipped", "op", prog.opts.Mode, "path", path, "reason", "not_changed_since")
+
+			// The directory has not changed recently, but one of its children may have.
 			return nil
 		}
 
+		if incremental || prog.opts.InitMerge {
+			if _, err := prog.fsys.Stat(mirrorPath); err == nil {
+				prog.log.Debug("path skipped", "op", prog.opts.Mode, "path", mirrorPath, "reason", "already_exists")
+
+				// The directory is already mirrored, leave it intact.
+				return nil
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return prog.walkError(path, e, fmt.Errorf("failed to stat: %q (%w)", mirrorPath, err))
+			}
+		}
+
+		if incremental {
+			// The older parents of a changed directory may not have been mirrored yet.
+			if err := prog.createParentDirs(buildRoot, relPath, knownParents); err != nil {
+				return prog.walkError(path, e, err)
+			}
+		}
+
+		if dirRate != nil {
+			// Wait for the next slot of the --dir-rate, unless interrupted.
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("failed checking context: %w", ctx.Err())
+			case <-dirRate.C:
+			}
+		}
+
 		if !prog.opts.DryRun {
 			// Create the respective mirror path for the specific target path.
-			if err := prog.fsys.Mkdir(mirrorPath, dirBasePerm); err != nil {
-				return prog.walkError(e, fmt.Errorf("failed to create: %q (%w)", mirrorPath, err))
+			if err := prog.fsys.Mkdir(mirrorPath, dirBasePerm); errors.Is(err, os.ErrExist) && (incremental || prog.opts.InitMerge) {
+				prog.log.Debug("path skipped", "op", prog.opts.Mode, "path", mirrorPath, "reason", "already_exists")
+
+				// The directory appeared since the check above (such as created by a
+				// client of the kept mirror), which leaves it just as intended.
+				return nil
+			} else if err != nil {
+				return prog.walkError(path, e, fmt.Errorf("failed to create: %q (%w)", mirrorPath, err))
 			}
 			createdDirsBatch++
-			prog.state.createdDirs++
 
-			if prog.opts.SlowMode && createdDirsBatch > dirCreationBatch {
+			if prog.opts.PreserveDirTimes {
+				dirTimes = append(dirTimes, dirTime{path: mirrorPath, modTime: e.ModTime()})
+			}
+
+			if slowMode && createdDirsBatch > dirCreationBatch {
 				time.Sleep(dirCreationTimeout)
 				createdDirsBatch = 0 // Reset the counter after timeout has passed.
 			}
 		}
+		prog.state.createdDirs++
+		prog.recordDiffDir(buildRoot, mirrorPath)
 
-		if !prog.opts.DryRun && prog.opts.SlowMode {
+		if !prog.opts.DryRun && slowMode {
 			prog.log.Info("directory created",
 				"op", prog.opts.Mode,
 				"path", mirrorPath,
-				"slow-mode", prog.opts.SlowMode,
+				"slow-mode", slowMode,
 				"slow-batch", fmt.Sprintf("%d/%d", createdDirsBatch, dirCreationBatch),
 				"dry-run", prog.opts.DryRun)
 
 			return nil
 		}
 
-		prog.log.Info("directory created", "op", prog.opts.Mode, "path", mirrorPath, "slow-mode", prog.opts.SlowMode, "dry-run", prog.opts.DryRun)
+		prog.log.Info("directory created", "op", prog.opts.Mode, "path", mirrorPath, "slow-mode", slowMode, "dry-run", prog.opts.DryRun)
 
 		return nil
 	}); err != nil {
 		return err
 	}
 
+	prog.preserveDirTimes(dirTimes)
+
+	return nil
+}
+
+// dirTime is the modification time of a target directory, as it is to be
+// preserved on its created mirror directory with --preserve-dir-times.
+type dirTime struct {
+	path    string
+	modTime time.Time
+}
+
+// preserveDirTimes applies the modification times of the target directories
+// to their created mirror directories. The times are applied in the reverse
+// order of creation (children before their parents), as creating a child bumps
+// the modification time of its parent. A time that cannot be applied is not a
+// failure of the mirroring itself, so it is only warned about.
+func (prog *program) preserveDirTimes(dirTimes []dirTime) {
+	for _, dt := range slices.Backward(dirTimes) {
+		if err := prog.fsys.Chtimes(dt.path, dt.modTime, dt.modTime); err != nil {
+			prog.log.Warn("directory times not preserved", "op", prog.opts.Mode, "path", dt.path, "error", err, "reason", "error_occurred")
+		}
+	}
+}
+
+// twoPhaseSibling returns the hidden sibling of the mirror root with the suffix.
+func twoPhaseSibling(mirrorRoot string, suffix string) string {
+	return filepath.Join(filepath.Dir(mirrorRoot), "."+filepath.Base(mirrorRoot)+suffix)
+}
+
+// swapMirror moves the mirror built by --two-phase-init into place. An existing
+// mirror (if any) is atomically exchanged with the built one where supported,
+// so that the mirror root is never absent. Otherwise, the existing mirror is
+// set aside first, which is restored if the swap fails.
+func (prog *program) swapMirror(ctx context.Context, buildRoot string, replaceMirror bool) error {
+	oldRoot := twoPhaseSibling(prog.opts.MirrorRoot, twoPhaseOldSuffix)
+
+	if prog.opts.DryRun {
+		prog.log.Info("mirror directory swapped", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "from", buildRoot, "dry-run", prog.opts.DryRun)
+
+		return nil
+	}
+
+	if replaceMirror && prog.exchange != nil {
+		err := prog.exchange(buildRoot, prog.opts.MirrorRoot)
+		if err == nil {
+			prog.log.Info("mirror directory swapped", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "from", buildRoot, "exchanged", true, "dry-run", prog.opts.DryRun)
+
+			// The existing mirror is now at the build root.
+			return prog.removeSwappedMirror(ctx, buildRoot)
+		}
+
+		// The filesystem may not support the exchange, fall back to setting the existing mirror aside.
+		prog.log.Debug("mirror directory not exchanged", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "from", buildRoot, "error", err, "action", "renaming")
+	}
+
+	if replaceMirror {
+		if err := prog.fsys.Rename(prog.opts.MirrorRoot, oldRoot); err != nil {
+			if rerr := prog.fsys.RemoveAll(buildRoot); rerr != nil {
+				prog.log.Error("failed to remove the built mirror", "op", prog.opts.Mode, "path", buildRoot, "error", rerr)
+			}
+
+			return fmt.Errorf("failed to rename: %q to %q (%w)", prog.opts.MirrorRoot, oldRoot, err)
+		}
+	}
+
+	if err := prog.fsys.Rename(buildRoot, prog.opts.MirrorRoot); err != nil {
+		if replaceMirror {
+			// Restore the existing mirror, so that it remains available.
+			if rerr := prog.fsys.Rename(oldRoot, prog.opts.MirrorRoot); rerr != nil {
+				prog.log.Error("failed to restore the existing mirror", "op", prog.opts.Mode, "path", oldRoot, "error", rerr)
+			}
+		}
+		if rerr := prog.fsys.RemoveAll(buildRoot); rerr != nil {
+			prog.log.Error("failed to remove the built mirror", "op", prog.opts.Mode, "path", buildRoot, "error", rerr)
+		}
+
+		return fmt.Errorf("failed to rename: %q to %q (%w)", buildRoot, prog.opts.MirrorRoot, err)
+	}
+
+	prog.log.Info("mirror directory swapped", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "from", buildRoot, "exchanged", false, "dry-run", prog.opts.DryRun)
+
+	if replaceMirror {
+		return prog.removeSwappedMirror(ctx, oldRoot)
+	}
+
+	return nil
+}
+
+// removeSwappedMirror removes the existing mirror after it was swapped out by
+// --two-phase-init. As it remained available to the clients during the walk,
+// it is checked for emptiness once more, and any files staged into it in the
+// meantime are never removed; it is then kept as `.<mirror>.old` instead.
+func (prog *program) removeSwappedMirror(ctx context.Context, path string) error {
+	if !prog.opts.AssumeEmptyMirror {
+		if prog.opts.MirrorReadme != "" {
+			// The readme is our own file, which is written into the new mirror again.
+			if err := prog.fsys.Remove(filepath.Join(path, prog.opts.MirrorReadme)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove: %q (%w)", filepath.Join(path, prog.opts.MirrorReadme), err)
+			}
+		}
+
+		empty, err := prog.isEmptyStructure(ctx, path)
+		if err != nil {
+			return fmt.Errorf("failed checking for emptiness: %q (%w)", path, err)
+		} else if !empty {
+			oldRoot := twoPhaseSibling(prog.opts.MirrorRoot, twoPhaseOldSuffix)
+			if path != oldRoot {
+				if err := prog.fsys.Rename(path, oldRoot); err != nil {
+					return fmt.Errorf("%w: %q (failed to rename to %q: %w)", errMirrorNotEmpty, path, oldRoot, err)
+				}
+			}
+			prog.log.Warn("previous mirror kept", "op", prog.opts.Mode, "path", oldRoot, "reason", "not_empty_dir")
+
+			// The files were staged into the existing mirror during the walk, user should resolve them.
+			return fmt.Errorf("%w: %q", errMirrorNotEmpty, oldRoot)
+		}
+	}
+
+	if err := prog.fsys.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove: %q (%w)", path, err)
+	}
+
 	return nil
 }
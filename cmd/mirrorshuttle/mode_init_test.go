@@ -2,9 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
 )
 
@@ -61,6 +68,71 @@ func Test_Unit_CreateMirrorStructure_DeepStructureSlow_Success(t *testing.T) {
 	require.True(t, prog.opts.SlowMode)
 }
 
+// Expectation: The function should keep the directory creations under the --dir-rate for a burst of directories.
+func Test_Unit_CreateMirrorStructure_DirRate_Success(t *testing.T) {
+	t.Parallel()
+
+	const rate = 100
+
+	dirs := make([]string, 0, 20)
+	for i := range cap(dirs) {
+		dirs = append(dirs, fmt.Sprintf("/real/dir%d", i))
+	}
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, dirs)
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		InitDepth:  -1,
+		SlowMode:   true,
+		DirRate:    rate,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+
+	start := time.Now()
+	err = prog.createMirrorStructure(t.Context())
+	elapsed := time.Since(start)
+	require.NoError(t, err)
+
+	require.Equal(t, len(dirs)+1, prog.state.createdDirs)
+	require.LessOrEqual(t, float64(len(dirs))/elapsed.Seconds(), float64(rate))
+
+	for _, dir := range dirs {
+		_, err = fs.Stat(strings.Replace(dir, "/real", "/mirror", 1))
+		require.NoError(t, err)
+	}
+}
+
+// Expectation: The function should not keep waiting for the --dir-rate once the context is cancelled.
+func Test_Unit_CreateMirrorStructure_DirRateCancel_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/dir1", "/real/dir2", "/real/dir3"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		InitDepth:  -1,
+		DirRate:    1,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err = prog.createMirrorStructure(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), time.Second)
+}
+
 // Expectation: The function should exclude the mirror root itself.
 func Test_Unit_CreateMirrorStructure_NestedMirror_Success(t *testing.T) {
 	t.Parallel()
@@ -162,6 +234,109 @@ func Test_Unit_CreateMirrorStructure_WithExcludes_Success(t *testing.T) {
 	require.ErrorIs(t, err, os.ErrNotExist)
 }
 
+// Expectation: The function should report each path matched by an exclude once with --list-excluded.
+func Test_Unit_CreateMirrorStructure_ListExcluded_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{
+		"/real/include",
+		"/real/exclude1/subdir",
+		"/real/exclude2/subdir",
+	})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:   "/mirror",
+		RealRoot:     "/real",
+		InitDepth:    -1,
+		Excludes:     excludeArg{"/real/exclude1", "/real/exclude2", "/real/unmatched"},
+		ListExcluded: true,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	logs := stderr.String()
+	require.Equal(t, 2, strings.Count(logs, `msg="path excluded"`))
+	require.Equal(t, 1, strings.Count(logs, "path=/real/exclude1 exclude=/real/exclude1"))
+	require.Equal(t, 1, strings.Count(logs, "path=/real/exclude2 exclude=/real/exclude2"))
+	require.NotContains(t, logs, "exclude=/real/unmatched")
+}
+
+// Expectation: The function should not mirror any directories with an excluded name, at any depth.
+func Test_Unit_CreateMirrorStructure_ExcludeName_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{
+		"/real/.DS_Store",
+		"/real/a/.ds_store/sub",
+		"/real/a/b/.DS_Store",
+		"/real/a/b/c",
+	})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:            "/mirror",
+		RealRoot:              "/real",
+		InitDepth:             -1,
+		ExcludeNames:          nameArg{".DS_Store"},
+		ExcludeNameIgnoreCase: true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	_, err = fs.Stat("/mirror/a/b/c")
+	require.NoError(t, err)
+
+	for _, dir := range []string{"/mirror/.DS_Store", "/mirror/a/.ds_store", "/mirror/a/b/.DS_Store"} {
+		_, err = fs.Stat(dir)
+		require.ErrorIs(t, err, os.ErrNotExist, dir)
+	}
+}
+
+// Expectation: Only the included subtrees (and their parents) should be mirrored, with excludes taking precedence.
+func Test_Unit_CreateMirrorStructure_WithIncludes_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{
+		"/real/media/movies/new",
+		"/real/media/movies/old",
+		"/real/media/music",
+		"/real/docs/work/drafts",
+		"/real/docs/private",
+		"/real/other",
+	})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		InitDepth:  -1,
+		Includes:   includeArg{"/real/media/movies", "/real/docs/work"},
+		Excludes:   excludeArg{"/real/media/movies/old", "/real/docs"},
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	for _, dir := range []string{"/mirror/media", "/mirror/media/movies", "/mirror/media/movies/new"} {
+		_, err = fs.Stat(dir)
+		require.NoError(t, err, dir)
+	}
+
+	for _, dir := range []string{"/mirror/media/movies/old", "/mirror/media/music", "/mirror/docs", "/mirror/other"} {
+		_, err = fs.Stat(dir)
+		require.ErrorIs(t, err, os.ErrNotExist, dir)
+	}
+}
+
 // Expectation: The function should mirror the full structure.
 func Test_Unit_CreateMirrorStructure_WithInitDepth_Unlimited_Success(t *testing.T) {
 	t.Parallel()
@@ -554,3 +729,921 @@ func Test_Unit_CreateMirrorStructure_MirrorParentNotDir_Error(t *testing.T) {
 	_, err = fs.Stat("/notexist/mirror")
 	require.ErrorIs(t, err, os.ErrNotExist)
 }
+
+// Expectation: The function should only mirror the subtrees matching the target globs.
+func Test_Unit_CreateMirrorStructure_WithTargetGlobs_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{
+		"/real/projects/a/src/deep",
+		"/real/projects/a/docs",
+		"/real/projects/b/src",
+		"/real/other/src",
+	})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:  "/mirror",
+		RealRoot:    "/real",
+		InitDepth:   -1,
+		TargetGlobs: globArg{"projects/*/src"},
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	_, err = fs.Stat("/mirror/projects/a/src/deep")
+	require.NoError(t, err)
+
+	_, err = fs.Stat("/mirror/projects/b/src")
+	require.NoError(t, err)
+
+	_, err = fs.Stat("/mirror/projects/a/docs")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = fs.Stat("/mirror/other")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// mirror, projects, projects/a, projects/a/src, projects/a/src/deep, projects/b, projects/b/src
+	require.Equal(t, 7, prog.state.createdDirs)
+}
+
+// Expectation: The function should handle case-colliding target directories according to the policy.
+func Test_Unit_CreateMirrorStructure_CaseCollision_Success(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		policy    string
+		wantErr   error
+		wantDirs  []string
+		wantNoDir []string
+		wantLog   string
+	}{
+		{
+			policy:   caseCollisionNone,
+			wantDirs: []string{"/mirror/Photos/a", "/mirror/photos/b", "/mirror/other"},
+		},
+		{
+			policy:    caseCollisionMerge,
+			wantDirs:  []string{"/mirror/Photos/a", "/mirror/Photos/b", "/mirror/other"},
+			wantNoDir: []string{"/mirror/photos"},
+			wantLog:   "case_collision",
+		},
+		{
+			policy:    caseCollisionWarn,
+			wantDirs:  []string{"/mirror/Photos/a", "/mirror/other"},
+			wantNoDir: []string{"/mirror/photos", "/mirror/Photos/b"},
+			wantLog:   "case_collision",
+		},
+		{
+			policy:  caseCollisionFail,
+			wantErr: errCaseCollision,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createDirStructure(fs, []string{
+				"/real/Photos/a",
+				"/real/photos/b",
+				"/real/other",
+			})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:    "/mirror",
+				RealRoot:      "/real",
+				InitDepth:     -1,
+				CaseCollision: tt.policy,
+			}
+
+			prog, _, stderr := setupTestProgram(fs, opts)
+			err = prog.createMirrorStructure(t.Context())
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+			require.NoError(t, err)
+
+			for _, dir := range tt.wantDirs {
+				_, err = fs.Stat(dir)
+				require.NoError(t, err, dir)
+			}
+
+			for _, dir := range tt.wantNoDir {
+				_, err = fs.Stat(dir)
+				require.ErrorIs(t, err, os.ErrNotExist, dir)
+			}
+
+			if tt.wantLog != "" {
+				require.Contains(t, stderr.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+// Expectation: The function should only create recently changed directories, keeping the existing mirror intact.
+func Test_Unit_CreateMirrorStructure_InitChangedSince_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{
+		"/real/old/new",
+		"/real/old/stale",
+		"/real/kept",
+		"/mirror/kept",
+	})
+	require.NoError(t, err)
+	err = createFiles(fs, map[string]string{"/mirror/kept/unmoved.txt": "content"})
+	require.NoError(t, err)
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, dir := range []string{"/real", "/real/old", "/real/old/stale", "/real/kept"} {
+		require.NoError(t, fs.Chtimes(dir, old, old))
+	}
+
+	opts := &programOptions{
+		MirrorRoot:       "/mirror",
+		RealRoot:         "/real",
+		InitDepth:        -1,
+		InitChangedSince: 24 * time.Hour,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	// The recently changed directory is created, along with its older parent.
+	_, err = fs.Stat("/mirror/old/new")
+	require.NoError(t, err)
+
+	// The not recently changed directory is not created.
+	_, err = fs.Stat("/mirror/old/stale")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// The existing mirror is not removed, even though it contains files.
+	_, err = fs.Stat("/mirror/kept/unmoved.txt")
+	require.NoError(t, err)
+
+	require.Equal(t, 2, prog.state.createdDirs)
+}
+
+// Expectation: The function should only add the directories not yet mirrored with --init-merge, keeping any staged files.
+func Test_Unit_CreateMirrorStructure_InitMerge_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		dryRun      bool
+		wantCreated bool
+	}{
+		{"merge", false, true},
+		{"merge-dry-run", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createDirStructure(fs, []string{
+				"/real/staged/sub",
+				"/real/new/deep",
+				"/mirror/staged",
+			})
+			require.NoError(t, err)
+			err = createFiles(fs, map[string]string{"/mirror/staged/file.txt": "content"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot: "/mirror",
+				RealRoot:   "/real",
+				InitDepth:  -1,
+				InitMerge:  true,
+				DryRun:     tt.dryRun,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.createMirrorStructure(t.Context())
+			require.NoError(t, err)
+
+			// The staged file is left intact.
+			content, err := afero.ReadFile(fs, "/mirror/staged/file.txt")
+			require.NoError(t, err)
+			require.Equal(t, "content", string(content))
+
+			for _, dir := range []string{"/mirror/staged/sub", "/mirror/new", "/mirror/new/deep"} {
+				_, err = fs.Stat(dir)
+				if tt.wantCreated {
+					require.NoError(t, err, dir)
+				} else {
+					require.ErrorIs(t, err, os.ErrNotExist, dir)
+				}
+			}
+
+			// Only the genuinely new directories are counted.
+			require.Equal(t, 3, prog.state.createdDirs)
+		})
+	}
+}
+
+// Expectation: The function should refuse a mirror containing files without --init-merge.
+func Test_Unit_CreateMirrorStructure_InitMergeDisabled_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/staged", "/real/new"})
+	require.NoError(t, err)
+	err = createFiles(fs, map[string]string{"/mirror/staged/file.txt": "content"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		InitDepth:  -1,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.ErrorIs(t, err, errMirrorNotEmpty)
+
+	_, err = fs.Stat("/mirror/new")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The function should write the mirror readme with the content of --mirror-readme-from.
+func Test_Unit_CreateMirrorStructure_MirrorReadmeFrom_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		readmeFrom  string
+		dryRun      bool
+		wantContent string
+		wantErr     bool
+	}{
+		{"default", "", false, defaultMirrorReadme, false},
+		{"from-file", "/readme.txt", false, "custom explanation", false},
+		{"from-missing-file", "/missing.txt", false, "", true},
+		{"dry-run", "", true, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createDirStructure(fs, []string{"/real/dir"})
+			require.NoError(t, err)
+			err = createFiles(fs, map[string]string{"/readme.txt": "custom explanation"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:       "/mirror",
+				RealRoot:         "/real",
+				InitDepth:        -1,
+				MirrorReadme:     "LIESMICH.txt",
+				MirrorReadmeFrom: tt.readmeFrom,
+				DryRun:           tt.dryRun,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.createMirrorStructure(t.Context())
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, os.ErrNotExist)
+
+				return
+			}
+			require.NoError(t, err)
+
+			content, err := afero.ReadFile(fs, "/mirror/LIESMICH.txt")
+			if tt.dryRun {
+				require.ErrorIs(t, err, os.ErrNotExist)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantContent, string(content))
+		})
+	}
+}
+
+// Expectation: The function should only mirror target directories leading to any files.
+func Test_Unit_CreateMirrorStructure_SkipEmptyTargetDirs_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{
+		"/real/empty/a/b",
+		"/real/mixed/empty",
+		"/real/mixed/deep/full",
+	})
+	require.NoError(t, err)
+	err = createFiles(fs, map[string]string{"/real/mixed/deep/full/file.txt": "content"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:          "/mirror",
+		RealRoot:            "/real",
+		InitDepth:           -1,
+		SkipEmptyTargetDirs: true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	_, err = fs.Stat("/mirror/mixed/deep/full")
+	require.NoError(t, err)
+
+	for _, dir := range []string{"/mirror/empty", "/mirror/mixed/empty"} {
+		_, err = fs.Stat(dir)
+		require.ErrorIs(t, err, os.ErrNotExist, dir)
+	}
+
+	require.Equal(t, 4, prog.state.createdDirs)
+}
+
+// Expectation: The function should limit the depth below each rule's prefix, otherwise fall back to the global limit.
+func Test_Unit_CreateMirrorStructure_WithInitDepthRules_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{
+		"/real/projects/a/b/c/d",
+		"/real/media/a/b/c/d",
+		"/real/other/a/b/c",
+	})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:     "/mirror",
+		RealRoot:       "/real",
+		InitDepth:      1,
+		InitDepthRules: depthRuleArg{"projects:-1", "media:1"},
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	for _, dir := range []string{"/mirror/projects/a/b/c/d", "/mirror/media/a/b", "/mirror/other/a"} {
+		_, err = fs.Stat(dir)
+		require.NoError(t, err, dir)
+	}
+
+	for _, dir := range []string{"/mirror/media/a/b/c", "/mirror/other/a/b"} {
+		_, err = fs.Stat(dir)
+		require.ErrorIs(t, err, os.ErrNotExist, dir)
+	}
+}
+
+// Expectation: The function should refuse all malformed depth rules.
+func Test_Unit_ParseDepthRules_Invalid_Error(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range []string{"media", "media:x", "/media:1", "../media:1", ":1"} {
+		_, err := parseDepthRules([]string{rule})
+		require.ErrorIs(t, err, errArgInitDepthRuleInvalid, rule)
+	}
+}
+
+// readOnlyDirFs is an [afero.Fs] refusing the creation and removal of any entries within the given directory.
+type readOnlyDirFs struct {
+	afero.Fs
+	dir string
+}
+
+func (rfs *readOnlyDirFs) Create(name string) (afero.File, error) {
+	if filepath.Dir(name) == rfs.dir {
+		return nil, &os.PathError{Op: "create", Path: name, Err: os.ErrPermission}
+	}
+
+	return rfs.Fs.Create(name)
+}
+
+func (rfs *readOnlyDirFs) Remove(name string) error {
+	if filepath.Dir(name) == rfs.dir {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrPermission}
+	}
+
+	return rfs.Fs.Remove(name)
+}
+
+func (rfs *readOnlyDirFs) RemoveAll(path string) error {
+	// Simulate a removal that fails halfway through the structure.
+	if err := rfs.Fs.RemoveAll(filepath.Join(filepath.Dir(rfs.dir), "c")); err != nil {
+		return err
+	}
+
+	return &os.PathError{Op: "remove", Path: path, Err: os.ErrPermission}
+}
+
+// Expectation: The function should fail without removing anything of a mirror that cannot be removed in full.
+func Test_Unit_CreateMirrorStructure_MirrorNotRemovable_Error(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	err := createDirStructure(memFs, []string{"/real/dir", "/mirror/a/b", "/mirror/c"})
+	require.NoError(t, err)
+
+	fs := &readOnlyDirFs{Fs: memFs, dir: "/mirror/a"}
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		InitDepth:  -1,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.ErrorIs(t, err, errMirrorNotRemovable)
+	require.Contains(t, err.Error(), `"/mirror/a"`)
+
+	// Verify the mirror was not left partially removed.
+	for _, dir := range []string{"/mirror/a/b", "/mirror/c"} {
+		_, err = memFs.Stat(dir)
+		require.NoError(t, err, dir)
+	}
+}
+
+// openCountingFs is an [afero.Fs] counting any opens of paths within the given root, such as by walks.
+type openCountingFs struct {
+	afero.Fs
+	root  string
+	opens atomic.Int64
+}
+
+func (ofs *openCountingFs) Open(name string) (afero.File, error) {
+	if name == ofs.root || isWithinRoot(name, ofs.root) {
+		ofs.opens.Add(1)
+	}
+
+	return ofs.Fs.Open(name)
+}
+
+// Expectation: The function should skip walking the existing mirror with --assume-empty-mirror, and still rebuild it.
+func Test_Unit_CreateMirrorStructure_AssumeEmptyMirror_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		assumeEmpty bool
+		wantWalked  bool
+	}{
+		{"checked", false, true},
+		{"assumed-empty", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			memFs := setupTestFs()
+			err := createDirStructure(memFs, []string{"/real/a/b", "/real/c", "/mirror/old/dir"})
+			require.NoError(t, err)
+
+			fs := &openCountingFs{Fs: memFs, root: "/mirror"}
+
+			opts := &programOptions{
+				MirrorRoot:        "/mirror",
+				RealRoot:          "/real",
+				InitDepth:         -1,
+				AssumeEmptyMirror: tt.assumeEmpty,
+			}
+
+			prog, _, stderr := setupTestProgram(fs, opts)
+			err = prog.createMirrorStructure(t.Context())
+			require.NoError(t, err)
+
+			require.Equal(t, tt.wantWalked, fs.opens.Load() > 0)
+			require.Equal(t, tt.assumeEmpty, strings.Contains(stderr.String(), "removed unchecked"))
+
+			for _, dir := range []string{"/mirror/a/b", "/mirror/c"} {
+				e, err := memFs.Stat(dir)
+				require.NoError(t, err, dir)
+				require.True(t, e.IsDir(), dir)
+			}
+
+			_, err = memFs.Stat("/mirror/old")
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
+
+// watchedMirrorFs is an [afero.Fs] recording any directory creations made while the mirror root was absent,
+// and optionally failing the creation of a given directory.
+type watchedMirrorFs struct {
+	afero.Fs
+	mirrorRoot string
+	failOnPath string
+	absent     int
+	setAside   int
+}
+
+func (wfs *watchedMirrorFs) Rename(oldname string, newname string) error {
+	if oldname == wfs.mirrorRoot {
+		wfs.setAside++
+	}
+
+	return wfs.Fs.Rename(oldname, newname)
+}
+
+func (wfs *watchedMirrorFs) Mkdir(name string, perm os.FileMode) error {
+	if _, err := wfs.Fs.Stat(wfs.mirrorRoot); err != nil {
+		wfs.absent++
+	}
+
+	if name == wfs.failOnPath {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrPermission}
+	}
+
+	return wfs.Fs.Mkdir(name, perm)
+}
+
+// Expectation: The function should build the new mirror beside the existing one, which remains available until the swap.
+func Test_Unit_CreateMirrorStructure_TwoPhaseInit_Success(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	err := createDirStructure(memFs, []string{"/real/a/b", "/real/c", "/mirror/old/dir"})
+	require.NoError(t, err)
+
+	fs := &watchedMirrorFs{Fs: memFs, mirrorRoot: "/mirror"}
+
+	opts := &programOptions{
+		MirrorRoot:   "/mirror",
+		RealRoot:     "/real",
+		InitDepth:    -1,
+		TwoPhaseInit: true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	require.Zero(t, fs.absent)
+
+	for _, dir := range []string{"/mirror/a/b", "/mirror/c"} {
+		_, err = memFs.Stat(dir)
+		require.NoError(t, err, dir)
+	}
+
+	for _, dir := range []string{"/mirror/old", "/.mirror.init", "/.mirror.old"} {
+		_, err = memFs.Stat(dir)
+		require.ErrorIs(t, err, os.ErrNotExist, dir)
+	}
+}
+
+// Expectation: The function should exchange the built mirror with the existing one where supported, never setting it aside.
+func Test_Unit_CreateMirrorStructure_TwoPhaseInitExchange_Success(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		exchangeErr error
+		wantAside   bool
+	}{
+		{"exchanged", nil, false},
+		{"unsupported", errors.ErrUnsupported, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			memFs := setupTestFs()
+			err := createDirStructure(memFs, []string{"/real/a/b", "/real/c", "/mirror/old/dir"})
+			require.NoError(t, err)
+
+			fs := &watchedMirrorFs{Fs: memFs, mirrorRoot: "/mirror"}
+
+			opts := &programOptions{
+				MirrorRoot:   "/mirror",
+				RealRoot:     "/real",
+				InitDepth:    -1,
+				TwoPhaseInit: true,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+
+			exchanged := 0
+			prog.exchange = func(oldpath string, newpath string) error {
+				exchanged++
+				if tt.exchangeErr != nil {
+					return tt.exchangeErr
+				}
+
+				// Simulate the atomic exchange without going through the watched filesystem.
+				require.NoError(t, memFs.Rename(newpath, "/swap"))
+				require.NoError(t, memFs.Rename(oldpath, newpath))
+
+				return memFs.Rename("/swap", oldpath)
+			}
+
+			err = prog.createMirrorStructure(t.Context())
+			require.NoError(t, err)
+			require.Equal(t, 1, exchanged)
+			require.Zero(t, fs.absent)
+			require.Equal(t, tt.wantAside, fs.setAside > 0)
+
+			for _, dir := range []string{"/mirror/a/b", "/mirror/c"} {
+				_, err = memFs.Stat(dir)
+				require.NoError(t, err, dir)
+			}
+
+			for _, dir := range []string{"/mirror/old", "/.mirror.init", "/.mirror.old"} {
+				_, err = memFs.Stat(dir)
+				require.ErrorIs(t, err, os.ErrNotExist, dir)
+			}
+		})
+	}
+}
+
+// Expectation: The function should keep the swapped out mirror, rather than removing it, if files were staged into it during the walk.
+func Test_Unit_CreateMirrorStructure_TwoPhaseInitStagedFiles_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		exchangeErr error
+	}{
+		{"exchanged", nil},
+		{"unsupported", errors.ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createDirStructure(fs, []string{"/real/a", "/mirror/old/dir"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:   "/mirror",
+				RealRoot:     "/real",
+				InitDepth:    -1,
+				TwoPhaseInit: true,
+			}
+
+			prog, _, stderr := setupTestProgram(fs, opts)
+			prog.exchange = func(oldpath string, newpath string) error {
+				// A client stages a file into the existing mirror while the new one is built.
+				require.NoError(t, afero.WriteFile(fs, "/mirror/old/staged.txt", []byte("staged"), 0o644))
+
+				if tt.exchangeErr != nil {
+					return tt.exchangeErr
+				}
+
+				require.NoError(t, fs.Rename(newpath, "/swap"))
+				require.NoError(t, fs.Rename(oldpath, newpath))
+
+				return fs.Rename("/swap", oldpath)
+			}
+
+			err = prog.createMirrorStructure(t.Context())
+			require.ErrorIs(t, err, errMirrorNotEmpty)
+			require.Contains(t, stderr.String(), "previous mirror kept")
+
+			_, err = fs.Stat("/mirror/a")
+			require.NoError(t, err)
+
+			content, err := afero.ReadFile(fs, "/.mirror.old/old/staged.txt")
+			require.NoError(t, err)
+			require.Equal(t, "staged", string(content))
+		})
+	}
+}
+
+// Expectation: The function should remove the partially built mirror on failure, leaving the existing mirror untouched.
+func Test_Unit_CreateMirrorStructure_TwoPhaseInitRollback_Error(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	err := createDirStructure(memFs, []string{"/real/a", "/real/b", "/mirror/old/dir"})
+	require.NoError(t, err)
+
+	fs := &watchedMirrorFs{Fs: memFs, mirrorRoot: "/mirror", failOnPath: "/.mirror.init/b"}
+
+	opts := &programOptions{
+		MirrorRoot:   "/mirror",
+		RealRoot:     "/real",
+		InitDepth:    -1,
+		TwoPhaseInit: true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.ErrorIs(t, err, os.ErrPermission)
+
+	require.Zero(t, fs.absent)
+
+	_, err = memFs.Stat("/mirror/old/dir")
+	require.NoError(t, err)
+
+	_, err = memFs.Stat("/.mirror.init")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The function should not mirror target directories with more than the given size of files below them.
+func Test_Unit_CreateMirrorStructure_InitSkipDirsOver_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/real/large/sub/file1.bin":  strings.Repeat("x", 600),
+		"/real/large/file2.bin":      strings.Repeat("x", 600),
+		"/real/small/sub/file.bin":   strings.Repeat("x", 100),
+		"/real/mixed/large/file.bin": strings.Repeat("x", 2000),
+		"/real/mixed/small/file.bin": strings.Repeat("x", 10),
+	})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:       "/mirror",
+		RealRoot:         "/real",
+		InitDepth:        -1,
+		InitSkipDirsOver: "1K",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	for _, dir := range []string{"/mirror/small/sub"} {
+		_, err = fs.Stat(dir)
+		require.NoError(t, err, dir)
+	}
+
+	for _, dir := range []string{"/mirror/large", "/mirror/mixed"} {
+		_, err = fs.Stat(dir)
+		require.ErrorIs(t, err, os.ErrNotExist, dir)
+	}
+}
+
+// Expectation: The function should calculate the recursive size once per directory, caching all subdirectories.
+func Test_Unit_DirSize_Cache_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/real/a/file1.txt":   "abc",
+		"/real/a/b/file2.txt": "abcde",
+	})
+	require.NoError(t, err)
+
+	prog, _, _ := setupTestProgram(fs, &programOptions{})
+
+	cache := make(map[string]int64)
+	size, err := prog.dirSize(t.Context(), "/real/a", cache)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), size)
+	require.Equal(t, map[string]int64{"/real/a": 8, "/real/a/b": 5}, cache)
+}
+
+// Expectation: The function should skip a case-differing mirror root and exclude with --case-insensitive-paths.
+func Test_Unit_CreateMirrorStructure_CaseInsensitivePaths_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		caseInsensitive bool
+	}{
+		{"case-insensitive", true},
+		{"case-sensitive", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// The walk yields the lower-case paths, as these are stored on the (case-insensitive) volume.
+			fs := setupTestFs()
+			err := createDirStructure(fs, []string{"/real/incoming/dir", "/real/photos/dir", "/real/docs"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:           "/real/Incoming",
+				RealRoot:             "/real",
+				Excludes:             []string{"/real/Photos"},
+				InitDepth:            -1,
+				CaseInsensitivePaths: tt.caseInsensitive,
+			}
+
+			prog, _, stderr := setupTestProgram(fs, opts)
+			err = prog.createMirrorStructure(t.Context())
+			require.NoError(t, err)
+
+			_, err = fs.Stat("/real/Incoming/docs")
+			require.NoError(t, err)
+
+			_, selfErr := fs.Stat("/real/Incoming/incoming")
+			_, exclErr := fs.Stat("/real/Incoming/photos")
+
+			if tt.caseInsensitive {
+				require.ErrorIs(t, selfErr, os.ErrNotExist)
+				require.ErrorIs(t, exclErr, os.ErrNotExist)
+				require.Contains(t, stderr.String(), "is_mirror_root")
+			} else {
+				// The paths are considered different, so the mirror recurses into itself.
+				require.NoError(t, selfErr)
+				require.NoError(t, exclErr)
+			}
+		})
+	}
+}
+
+// Expectation: The function should apply the modification times of the target directories to the mirror directories.
+func Test_Unit_CreateMirrorStructure_PreserveDirTimes_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/a/b/c"})
+	require.NoError(t, err)
+
+	times := map[string]time.Time{
+		"/a":     time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		"/a/b":   time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+		"/a/b/c": time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	for path, mtime := range times {
+		require.NoError(t, fs.Chtimes("/real"+path, mtime, mtime))
+	}
+
+	opts := &programOptions{
+		MirrorRoot:       "/mirror",
+		RealRoot:         "/real",
+		InitDepth:        -1,
+		PreserveDirTimes: true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+
+	for path, mtime := range times {
+		info, err := fs.Stat("/mirror" + path)
+		require.NoError(t, err)
+		require.True(t, mtime.Equal(info.ModTime()), path)
+	}
+}
+
+// racingMkdirFs is an [afero.Fs] creating a given directory itself right before its creation is attempted,
+// as a client of the mirror would in a race with the program.
+type racingMkdirFs struct {
+	afero.Fs
+	raceOn string
+}
+
+func (rfs *racingMkdirFs) Mkdir(name string, perm os.FileMode) error {
+	if name == rfs.raceOn {
+		if err := rfs.Fs.Mkdir(name, perm); err != nil {
+			return err
+		}
+	}
+
+	return rfs.Fs.Mkdir(name, perm)
+}
+
+// Expectation: A repeated --init-merge should not fail on mirror directories that already exist, even if they appeared meanwhile.
+func Test_Unit_CreateMirrorStructure_InitMergeTwice_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/real/a/b", "/real/c"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		InitDepth:  -1,
+		InitMerge:  true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 4, prog.state.createdDirs)
+
+	err = createDirStructure(fs, []string{"/real/c/new"})
+	require.NoError(t, err)
+
+	prog, _, _ = setupTestProgram(&racingMkdirFs{Fs: fs, raceOn: "/mirror/c/new"}, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 0, prog.state.createdDirs)
+
+	for _, dir := range []string{"/mirror/a/b", "/mirror/c/new"} {
+		_, err = fs.Stat(dir)
+		require.NoError(t, err, dir)
+	}
+}
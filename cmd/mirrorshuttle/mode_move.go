@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/afero"
 )
@@ -21,61 +25,236 @@ func (prog *program) moveFiles(ctx context.Context) error {
 		return fmt.Errorf("failed to stat: %q (%w)", prog.opts.MirrorRoot, err)
 	}
 
+	if prog.opts.DryRunApply || prog.opts.ApplyToken != "" {
+		// Refuse to move anything other than what was reviewed in the --dry-run.
+		if err := prog.checkApplyToken(ctx); err != nil {
+			return err
+		}
+	}
+
 	// The target root needs to exist, otherwise we have nowhere to move to.
-	if _, err := prog.fsys.Stat(prog.opts.RealRoot); errors.Is(err, os.ErrNotExist) {
-		return fmt.Errorf("%w: %q", errTargetNotExist, prog.opts.RealRoot)
+	if e, err := prog.fsys.Stat(prog.opts.RealRoot); errors.Is(err, os.ErrNotExist) {
+		if !prog.opts.CreateTargetRoot {
+			return fmt.Errorf("%w: %q", errTargetNotExist, prog.opts.RealRoot)
+		}
+
+		// Only the target root itself is created, its parent needs to exist.
+		if _, err := prog.fsys.Stat(filepath.Dir(prog.opts.RealRoot)); err != nil {
+			return fmt.Errorf("%w: %q (%w)", errTargetNotExist, prog.opts.RealRoot, err)
+		}
+
+		if !prog.opts.DryRun {
+			if err := prog.fsys.Mkdir(prog.opts.RealRoot, dirBasePerm); err != nil {
+				return fmt.Errorf("failed to create: %q (%w)", prog.opts.RealRoot, err)
+			}
+		}
+		prog.state.createdDirs++
+		prog.log.Warn("target directory created", "op", prog.opts.Mode, "path", prog.opts.RealRoot, "reason", "create_target_root", "dry-run", prog.opts.DryRun)
 	} else if err != nil {
 		return fmt.Errorf("failed to stat: %q (%w)", prog.opts.RealRoot, err)
+	} else if !e.IsDir() {
+		// The target root is not a directory, we cannot move anything inside.
+		return fmt.Errorf("%w: %q", errTargetNotDir, prog.opts.RealRoot)
+	}
+
+	if prog.opts.ProbeWritable && !prog.opts.DryRun {
+		// Catch a read-only target before any files are moved, not in the middle.
+		if err := prog.probeWritable(); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.MirrorManifest != "" {
+		// Output any directories that were changed in the mirror since its creation.
+		if err := prog.checkMirrorManifest(ctx); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.VerifyTargetStructure != "" {
+		// Refuse to move into a target structure that was rearranged since the init.
+		if err := prog.verifyTargetStructure(ctx); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.QuarantineDir != "" {
+		// Make sure that failed files can be quarantined, before any are moved.
+		if err := prog.checkQuarantineDir(); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.TargetTrashDir != "" {
+		// Make sure that overwritten files can be trashed, before any are moved.
+		if err := prog.checkTargetTrashDir(); err != nil {
+			return err
+		}
+	}
+
+	moveStart := time.Now()
+	if prog.opts.SinceFile != "" {
+		// Only consider the files changed since the start of the last successful move.
+		since, err := prog.readSinceFile()
+		if err != nil {
+			return err
+		}
+		prog.state.movedSince = since
+	}
+
+	if prog.opts.StatsInterval > 0 {
+		stopStats := prog.startStats(ctx)
+		defer stopStats()
+	}
+
+	if prog.opts.ProgressFile != "" {
+		stopProgress := prog.startProgress(ctx)
+		defer stopProgress()
+	}
+
+	if len(prog.renameRules) > 0 && prog.opts.PlanIn == "" {
+		// Find any files that would be moved onto the same target path, before any are moved.
+		if err := prog.findDuplicateTargets(ctx); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.Verify && prog.opts.VerifyConcurrency > 1 && !prog.opts.DryRun && !prog.opts.AtomicBatch {
+		// Only the verify passes run concurrently, the files are still moved in order.
+		prog.startVerifyPool(ctx, prog.opts.VerifyConcurrency)
+	}
+
+	var err error
+	if prog.opts.PlanIn != "" {
+		// Execute only the operations of a previously approved plan.
+		err = prog.applyPlan(ctx)
+	} else {
+		err = prog.walkMirror(ctx)
+	}
+	if verifyErr := prog.finishVerifyPool(ctx); err == nil {
+		// The moves of any files still awaiting their verify pass are completed.
+		err = verifyErr
+	}
+	if err != nil {
+		// Nothing of an incomplete batch may get committed, discard all of it.
+		prog.discardStagedFiles()
+
+		return err
+	}
+
+	if prog.opts.AtomicBatch {
+		// All of the files were copied successfully, commit them all at once.
+		if err := prog.commitStagedFiles(ctx); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.DeferredVerify {
+		// Re-read all of the moved files in one sweep, now that the move has completed.
+		if err := prog.runDeferredVerify(ctx); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.PlanOut != "" {
+		// Write out the operations that were recorded during the (dry) run.
+		if err := prog.writePlan(); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.DryRunReproducible {
+		// Output the operations that were recorded during the dry run for diffing.
+		prog.printReproduciblePlan()
+	}
+
+	if prog.opts.ListPlanOnly {
+		// Output the operations that were recorded during the dry run in their order.
+		prog.printListPlan()
+	}
+
+	if prog.opts.CleanMirror && !prog.state.hasUnmovedFiles && !prog.state.hasPartialFailures {
+		// All files were moved, so the remaining mirror skeleton can be removed.
+		if err := prog.cleanMirror(ctx); err != nil {
+			return err
+		}
+	}
+
+	if prog.opts.SinceFile != "" {
+		if prog.state.hasUnmovedFiles || prog.state.hasPartialFailures || prog.state.hasHardFailures {
+			// Files left behind would otherwise no longer be considered in the next move.
+			prog.log.Warn("since file not written", "op", prog.opts.Mode, "path", prog.opts.SinceFile, "reason", "not_all_files_moved")
+		} else if err := prog.writeSinceFile(moveStart); err != nil {
+			return err
+		}
 	}
 
+	return nil
+}
+
+func (prog *program) walkMirror(ctx context.Context) error {
+	knownDirs := make(map[string]bool)
+	var deferredDirs []deferredDir
+
 	// Walk the mirror root and move any contents that do not exist in the target root.
 	if err := afero.Walk(prog.fsys, prog.opts.MirrorRoot, func(path string, e os.FileInfo, err error) error {
-		if err := ctx.Err(); err != nil {
+		if err := prog.checkInterrupt(ctx); err != nil {
 			// An interrupt was received, so we also interrupt the walk.
-			return fmt.Errorf("failed checking context: %w", err)
+			return err
 		}
 
 		if err != nil {
 			if errors.Is(err, os.ErrNotExist) {
 				prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "reason", "no_longer_exists")
+				prog.recordSkip(path, "no_longer_exists")
 
 				// An element has disappeared during the walk, skip it.
 				return nil
 			}
 
 			// Another failure has occurred during the walk (permissions, ...), handle it.
-			return prog.walkError(e, fmt.Errorf("failed to walk: %q (%w)", path, err))
-		}
-
-		if isExcluded(path, prog.opts.Excludes) { // Check if the source path is excluded.
-			prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "reason", "is_user_excluded")
-
-			// The source path was among the user's excluded paths, skip it.
-			if e.IsDir() {
-				return filepath.SkipDir // Do not traverse deeper.
-			}
-
-			return nil
+			return prog.walkError(path, e, fmt.Errorf("failed to walk: %q (%w)", path, err))
 		}
 
 		// Construct the target path from the mirror's relative path.
 		relPath, err := filepath.Rel(prog.opts.MirrorRoot, path)
 		if err != nil {
-			return prog.walkError(e, fmt.Errorf("failed to get relative path: %q (%w)", path, err))
+			return prog.walkError(path, e, fmt.Errorf("failed to get relative path: %q (%w)", path, err))
 		}
 		movePath := filepath.Join(prog.opts.RealRoot, relPath)
 
-		if movePath == prog.opts.MirrorRoot { // Check if target path is the mirror root.
-			prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", movePath, "reason", "mirror_into_mirror")
+		renamed := false
+		if !e.IsDir() && len(prog.renameRules) > 0 {
+			// The target location of the file is transformed, any checks apply to the new one.
+			renamedPath, err := prog.renameTarget(relPath)
+			if err != nil {
+				return prog.walkError(path, e, err)
+			}
 
-			// The target path is the mirror root, skip it (prevent insane recursion).
-			return filepath.SkipDir
+			if dupPath, ok := prog.state.duplicateTargets[path]; ok && dupPath == "" {
+				return prog.skipDuplicateTarget(path, filepath.Join(prog.opts.RealRoot, renamedPath))
+			} else if ok {
+				// Another file of the run was already transformed onto the same target path.
+				renamedPath = dupPath
+			}
+
+			if renamedPath != relPath {
+				relPath, renamed = renamedPath, true
+				movePath = filepath.Join(prog.opts.RealRoot, relPath)
+				prog.log.Debug("target path renamed", "op", prog.opts.Mode, "path", path, "dst", movePath)
+			}
 		}
 
-		if isExcluded(movePath, prog.opts.Excludes) { // Check if the target path is excluded.
-			prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", movePath, "reason", "is_user_excluded")
+		if excl, excluded := prog.checkExclusion(path, movePath, e.IsDir()); excluded {
+			prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", excl.path, "reason", excl.reason)
+			prog.recordSkip(excl.path, excl.reason)
+
+			if excl.unmoved {
+				// The file remains within the mirror, where it needs resolution by the user.
+				prog.state.hasUnmovedFiles = true
+				prog.state.unmovedFiles++
+			}
 
-			// The target path was among the user's excluded paths, skip it.
 			if e.IsDir() {
 				return filepath.SkipDir // Do not traverse deeper.
 			}
@@ -83,193 +262,1246 @@ func (prog *program) moveFiles(ctx context.Context) error {
 			return nil
 		}
 
-		if e.IsDir() { // Handle directories.
-			if _, err := prog.fsys.Stat(movePath); errors.Is(err, os.ErrNotExist) { // Check if the target directory exists.
-				if prog.opts.SkipEmpty { // Check if empty source directories should be skipped.
-					if empty, err := prog.isEmptyStructure(ctx, path); err != nil {
-						return prog.walkError(e, fmt.Errorf("failed checking for emptiness: %q (%w)", path, err))
-					} else if empty { // The source directory is empty, skip it.
-						prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "reason", "is_empty_dir")
-
-						if prog.opts.RemoveEmpty { // Check if empty source directories should be removed.
-							if !prog.opts.DryRun {
-								if err := prog.fsys.RemoveAll(path); err != nil { // The source directory is empty, remove it.
-									return prog.walkError(e, fmt.Errorf("failed to remove: %q (%w)", path, err))
-								}
-							}
-							prog.log.Warn("empty directory removed", "op", prog.opts.Mode, "path", path, "reason", "dst_no_longer_exists", "dry-run", prog.opts.DryRun)
-						}
+		if prog.samePath(movePath, prog.opts.MirrorRoot) { // Check if target path is the mirror root.
+			prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", movePath, "reason", "mirror_into_mirror")
+			prog.recordSkip(path, "mirror_into_mirror")
 
-						return filepath.SkipDir // Do not traverse deeper.
-					}
-				}
-				if !prog.opts.DryRun {
-					// Create the target directory, if it does not exist.
-					if err := prog.fsys.Mkdir(movePath, dirBasePerm); err != nil {
-						return prog.walkError(e, fmt.Errorf("failed to create: %q (%w)", movePath, err))
-					}
-					prog.state.createdDirs++
-				}
-				prog.log.Info("directory created", "op", prog.opts.Mode, "path", movePath, "dry-run", prog.opts.DryRun)
-			} else if err != nil {
-				return prog.walkError(e, fmt.Errorf("failed to stat: %q (%w)", movePath, err))
-			}
+			// The target path is the mirror root, skip it (prevent insane recursion).
+			return filepath.SkipDir
+		}
 
-			return nil
+		if e.IsDir() { // Handle directories.
+			return prog.moveDir(ctx, path, movePath, e, &deferredDirs)
 		} // Must be a file from here downwards.
 
-		if _, err := prog.fsys.Stat(movePath); err == nil { // Check if the target file exists.
-			prog.state.hasUnmovedFiles = true
-			prog.log.Warn("target already exists", "op", prog.opts.Mode, "src", path, "dst", movePath, "action", "skipped")
+		if !prog.state.movedSince.IsZero() && e.ModTime().Before(prog.state.movedSince) {
+			prog.log.Debug("path skipped", "op", prog.opts.Mode, "path", path, "reason", "not_changed_since")
+			prog.recordSkip(path, "not_changed_since")
 
-			// The target file exists; do not overwrite it, set unmoved files bit and skip it.
+			// The file was not changed since the last successful move, so it is not considered.
 			return nil
-		} else if !errors.Is(err, os.ErrNotExist) {
-			return prog.walkError(e, fmt.Errorf("failed to stat: %q (%w)", movePath, err))
 		}
 
-		if !prog.opts.DryRun {
-			if prog.opts.Direct {
-				// Direct mode; attempt a rename syscall, otherwise copy and remove.
-				if err := prog.fsys.Rename(path, movePath); err == nil {
-					prog.log.Info("file moved", "op", prog.opts.Mode, "mode", "direct", "src", path, "dst", movePath, "dry-run", prog.opts.DryRun)
-					prog.state.movedFiles++
-
-					return nil
-				} // Rename syscall must have failed from here downwards.
+		if prog.opts.MoveOrder == moveOrderLeavesFirst || renamed {
+			// Create only the parent chain that is needed for this file.
+			if err := prog.createParentDirs(prog.opts.RealRoot, relPath, knownDirs); err != nil {
+				return prog.walkError(path, e, err)
 			}
+		}
 
-			// Do the regular copy and remove operation and handle any failures.
-			retHashes, err := prog.copyAndRemove(ctx, path, movePath)
-			if err != nil {
-				return prog.walkError(e, fmt.Errorf("failed to move: %q -x-> %q (%w)", path, movePath, err))
+		return prog.moveFile(ctx, path, movePath, e)
+	}); err != nil {
+		return err
+	}
+
+	// Create the directories that were deferred until after all files were moved.
+	for _, dir := range deferredDirs {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed checking context: %w", err)
+		}
+
+		if knownDirs[dir.dst] {
+			// The directory was already created as a parent of a moved file.
+			continue
+		}
+
+		if err := prog.createDir(dir.dst); err != nil {
+			if err := prog.walkError(dir.dst, dir.info, err); err != nil && !errors.Is(err, filepath.SkipDir) {
+				return err
 			}
+		}
+	}
 
-			// Output the SHA-256 hashes for this operation as well, as parsing programs may care about them.
-			prog.log.Info("file moved",
-				"op", prog.opts.Mode,
-				"mode", "c+r",
-				"src", path,
-				"dst", movePath,
-				"srcHash", retHashes.srcHash,
-				"dstHash", retHashes.dstHash,
-				"verifyHash", retHashes.verifyHash,
-				"verify", prog.opts.Verify,
-				"dry-run", prog.opts.DryRun)
+	return nil
+}
 
-			prog.state.movedFiles++
+// exclusion is the decision of [checkExclusion] on a walked path that is
+// excluded from the move.
+type exclusion struct {
+	path    string // The path that is excluded, either the source or the target one.
+	reason  string
+	unmoved bool // The excluded file counts towards the unmoved files.
+}
 
-			return nil
-		} // Must be in dry mode from here downwards.
+// checkExclusion decides if a walked mirror path (with its target path) is
+// excluded from the move, with the source side taking precedence:
+//
+//   - A source path excluded by --exclude or --exclude-glob-mirror is left
+//     within the mirror deliberately (such as the program's own files), so it
+//     does not count as unmoved.
+//   - A source name excluded by --exclude-name is left within the mirror, but
+//     as a file that needs resolution by the user, so it counts as unmoved.
+//   - A source directory excluded by --skip-system-dirs is left within the
+//     mirror, as it is kept there by the system (and not by the user).
+//   - A target path excluded by --exclude or --exclude-glob-target is never
+//     written into, so it is left within the mirror, but it does not count as
+//     unmoved (the exclusion of the target is deliberate).
+//   - A path that is not included by --include (on neither side) is left within
+//     the mirror, but it does not count as unmoved (it is excluded deliberately).
+//     Any of the exclusions above take precedence over the included paths.
+//
+// Excluded directories never count as unmoved, and the walk does not descend
+// into them, so their contents are not considered at all.
+func (prog *program) checkExclusion(path string, movePath string, isDir bool) (exclusion, bool) {
+	switch {
+	case prog.isExcludedPath(path):
+		return exclusion{path: path, reason: "is_user_excluded"}, true
 
-		prog.log.Info("file moved", "op", prog.opts.Mode, "mode", "", "src", path, "dst", movePath, "dry-run", prog.opts.DryRun)
+	case prog.isExcludedGlob(path, prog.opts.MirrorRoot, prog.opts.ExcludeGlobsMirror):
+		return exclusion{path: path, reason: "is_user_excluded_mirror_glob"}, true
 
-		return nil
-	}); err != nil {
-		return err
+	case path != prog.opts.MirrorRoot && prog.isExcludedName(path):
+		return exclusion{path: path, reason: "is_user_excluded_name", unmoved: !isDir}, true
+
+	case isDir && path != prog.opts.MirrorRoot && prog.isSystemDir(path):
+		return exclusion{path: path, reason: "is_system_dir"}, true
+
+	case prog.isExcludedPath(movePath):
+		return exclusion{path: movePath, reason: "is_user_excluded"}, true
+
+	case prog.isExcludedGlob(movePath, prog.opts.RealRoot, prog.opts.ExcludeGlobsTarget):
+		return exclusion{path: movePath, reason: "is_user_excluded_target_glob"}, true
+
+	case !prog.isIncluded(path, isDir) && !prog.isIncluded(movePath, isDir):
+		return exclusion{path: path, reason: "no_include_match"}, true
 	}
 
-	return nil
+	return exclusion{}, false
 }
 
-func (prog *program) copyAndRemove(ctx context.Context, src string, dst string) (retHashes fileHashes, retErr error) {
-	workingFile := dst + ".mirsht" // We work on a temporary file first.
+// cleanMirror removes the (empty) subdirectories of the mirror root, keeping
+// only the mirror root itself. It is meant to run only after a fully successful
+// move, and leaves any subdirectory that still contains files (or any excluded
+// directory) untouched.
+func (prog *program) cleanMirror(ctx context.Context) error {
+	if prog.opts.DryRun {
+		// Files were not really moved in dry mode, so the mirror is not yet empty.
+		prog.log.Info("mirror cleanup skipped", "op", prog.opts.Mode, "path", prog.opts.MirrorRoot, "reason", "is_dry_run")
 
-	in, err := prog.fsys.Open(src)
+		return nil
+	}
+
+	entries, err := afero.ReadDir(prog.fsys, prog.opts.MirrorRoot)
 	if err != nil {
-		return retHashes, fmt.Errorf("failed to open: %q (%w)", src, err)
+		return fmt.Errorf("failed to read: %q (%w)", prog.opts.MirrorRoot, err)
 	}
-	defer in.Close()
 
-	out, err := prog.fsys.Create(workingFile)
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed checking context: %w", err)
+		}
+
+		if !e.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(prog.opts.MirrorRoot, e.Name())
+
+		if prog.isExcludedPath(path) {
+			continue
+		}
+
+		if empty, err := prog.isEmptyStructure(ctx, path); err != nil {
+			return fmt.Errorf("failed checking for emptiness: %q (%w)", path, err)
+		} else if !empty {
+			prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "reason", "not_empty_dir")
+
+			continue
+		}
+
+		if _, err := prog.removeEmptyDirs(ctx, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeEmptyDirs removes a directory of the mirror after its subdirectories,
+// deepest first, but never descends into an excluded subdirectory, which is
+// left in place along with its parents. It reports if the directory itself was
+// removed.
+func (prog *program) removeEmptyDirs(ctx context.Context, dir string) (bool, error) {
+	entries, err := afero.ReadDir(prog.fsys, dir)
 	if err != nil {
-		return retHashes, fmt.Errorf("failed to open: %q (%w)", workingFile, err)
+		return false, fmt.Errorf("failed to read: %q (%w)", dir, err)
 	}
-	defer out.Close()
 
-	defer func() {
-		if retErr != nil {
-			if _, err := prog.fsys.Stat(src); err == nil {
-				if err := prog.fsys.Remove(workingFile); err == nil {
-					prog.log.Info("incomplete file removed", "op", prog.opts.Mode+"_cleanup", "path", workingFile)
-				} else if !errors.Is(err, os.ErrNotExist) {
-					prog.log.Error("incomplete file not removed", "op", prog.opts.Mode+"_cleanup", "path", workingFile, "error", err, "error-type", "runtime", "reason", "error_occurred")
+	removable := true
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return false, fmt.Errorf("failed checking context: %w", err)
+		}
+
+		path := filepath.Join(dir, e.Name())
+
+		if !e.IsDir() || prog.isExcludedPath(path) {
+			removable = false
+
+			continue
+		}
+
+		removed, err := prog.removeEmptyDirs(ctx, path)
+		if err != nil {
+			return false, err
+		}
+		removable = removable && removed
+	}
+
+	if !removable {
+		return false, nil
+	}
+
+	if err := prog.fsys.Remove(dir); err != nil {
+		return false, fmt.Errorf("failed to remove: %q (%w)", dir, err)
+	}
+	prog.log.Info("empty directory removed", "op", prog.opts.Mode, "path", dir, "reason", "clean_mirror_on_success")
+
+	return true, nil
+}
+
+// deferredDir is a directory whose creation was deferred until after all of
+// the files were moved (used for the [moveOrderLeavesFirst] ordering).
+type deferredDir struct {
+	dst  string
+	info os.FileInfo
+}
+
+func (prog *program) moveDir(ctx context.Context, path string, movePath string, e os.FileInfo, deferredDirs *[]deferredDir) error {
+	if dstInfo, err := prog.fsys.Stat(movePath); err == nil && !dstInfo.IsDir() { // Check if the target is no longer a directory.
+		return prog.handleTypeChange(path, movePath, e, dstInfo)
+	} else if errors.Is(err, os.ErrNotExist) { // Check if the target directory exists.
+		if prog.opts.SkipEmpty { // Check if empty source directories should be skipped.
+			if empty, err := prog.isEmptyStructure(ctx, path); err != nil {
+				return prog.walkError(path, e, fmt.Errorf("failed checking for emptiness: %q (%w)", path, err))
+			} else if empty { // The source directory is empty, skip it.
+				prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "reason", "is_empty_dir")
+				prog.recordSkip(path, "is_empty_dir")
+
+				if prog.opts.RemoveEmpty { // Check if empty source directories should be removed.
+					if !prog.opts.DryRun {
+						if err := prog.fsys.RemoveAll(path); err != nil { // The source directory is empty, remove it.
+							return prog.walkError(path, e, fmt.Errorf("failed to remove: %q (%w)", path, err))
+						}
+					}
+					prog.log.Warn("empty directory removed", "op", prog.opts.Mode, "path", path, "reason", "dst_no_longer_exists", "dry-run", prog.opts.DryRun)
 				}
-			} else if errors.Is(err, os.ErrNotExist) {
-				prog.log.Warn("file not found", "op", prog.opts.Mode+"_cleanup", "path", src)
-				prog.log.Warn("incomplete file not removed", "op", prog.opts.Mode+"_cleanup", "path", workingFile, "reason", "src_no_longer_exists")
-			} else {
-				prog.log.Error("failed to stat", "op", prog.opts.Mode+"_cleanup", "path", src, "error", err, "error-type", "runtime")
-				prog.log.Warn("incomplete file not removed", "op", prog.opts.Mode+"_cleanup", "path", src, "reason", "src_existence_unknown")
-				prog.log.Warn("incomplete file not removed", "op", prog.opts.Mode+"_cleanup", "path", workingFile, "reason", "src_existence_unknown")
+
+				return filepath.SkipDir // Do not traverse deeper.
 			}
+		} else if empty, err := prog.isEmptyStructure(ctx, path); err == nil && empty {
+			// An empty directory that no longer exists in the target is likely a removed
+			// one that is about to be re-created, because the mirror was not re-initialized.
+			prog.log.Warn("empty directory not in target", "op", prog.opts.Mode, "path", path, "dst", movePath, "reason", "dst_no_longer_exists", "hint", "forgotten --mode=init?")
 		}
-	}()
 
-	srcHasher := sha256.New()
-	dstHasher := sha256.New()
+		if prog.opts.BulkRenameDirs && !prog.opts.DryRun {
+			if moved, err := prog.bulkRenameDir(ctx, path, movePath); err != nil {
+				return prog.walkError(path, e, err)
+			} else if moved {
+				return filepath.SkipDir // The whole subtree was moved.
+			}
+		}
 
-	ctxReader := &contextReader{ctx, io.TeeReader(in, srcHasher)}
-	multiWriter := io.MultiWriter(out, dstHasher)
+		if prog.opts.MoveOrder == moveOrderLeavesFirst {
+			// Defer the creation, the directory may still be created as a parent of a file.
+			*deferredDirs = append(*deferredDirs, deferredDir{dst: movePath, info: e})
 
-	if _, err := io.Copy(multiWriter, ctxReader); err != nil {
-		return retHashes, fmt.Errorf("failed during io: %w", err)
-	}
+			return nil
+		}
 
-	if err := out.Sync(); err != nil {
-		return retHashes, fmt.Errorf("failed during sync: %w", err)
+		if err := prog.createDir(movePath); err != nil {
+			return prog.walkError(path, e, err)
+		}
+	} else if err != nil {
+		return prog.walkError(path, e, fmt.Errorf("failed to stat: %q (%w)", movePath, err))
 	}
 
-	if err := in.Close(); err != nil {
-		return retHashes, fmt.Errorf("failed to close: %q (%w)", src, err)
+	return nil
+}
+
+// bulkRenameDir moves a whole mirror directory into its absent target directory
+// with a single rename (with the --bulk-rename-dirs setting). It returns false
+// if not all of the subtree would be moved as it is, or if the rename fails
+// (such as across filesystems), so that the directory is moved per file.
+func (prog *program) bulkRenameDir(ctx context.Context, path string, movePath string) (bool, error) {
+	subtree, renamable, err := prog.bulkRenameSubtree(ctx, path, movePath)
+	if err != nil || !renamable {
+		return false, err
 	}
 
-	if err := out.Close(); err != nil {
-		return retHashes, fmt.Errorf("failed to close: %q (%w)", workingFile, err)
+	if err := prog.fsys.Rename(path, movePath); err != nil {
+		prog.log.Debug("directory not bulk renamed", "op", prog.opts.Mode, "src", path, "dst", movePath, "error", err, "reason", "rename_failed")
+
+		return false, nil
 	}
 
-	retHashes.srcHash = hex.EncodeToString(srcHasher.Sum(nil))
-	retHashes.dstHash = hex.EncodeToString(dstHasher.Sum(nil))
+	prog.state.mu.Lock()
+	prog.state.movedFiles += len(subtree.files)
+	prog.state.movedBytes += subtree.size
+	prog.state.createdDirs += len(subtree.dirs)
+	prog.state.mu.Unlock()
 
-	if retHashes.srcHash != retHashes.dstHash {
-		return retHashes, fmt.Errorf("%w: %q (srcHash) != %q (dstHash)", errMemoryHashMismatch, retHashes.srcHash, retHashes.dstHash)
+	for _, relPath := range subtree.files {
+		prog.log.Info("file moved", "op", prog.opts.Mode, "mode", "bulk", "src", filepath.Join(path, relPath), "dst", filepath.Join(movePath, relPath), "dry-run", prog.opts.DryRun)
 	}
+	prog.log.Info("directory moved", "op", prog.opts.Mode, "mode", "bulk", "src", path, "dst", movePath, "files", len(subtree.files), "dirs", len(subtree.dirs), "dry-run", prog.opts.DryRun)
 
-	if err := prog.fsys.Rename(workingFile, dst); err != nil {
-		return retHashes, fmt.Errorf("failed to rename: %q -x-> %q (%w)", workingFile, dst, err)
+	// The mirror directories remain available to the clients, as when moved per file.
+	for _, relPath := range subtree.dirs {
+		mirrorPath := filepath.Join(path, relPath)
+
+		if err := prog.fsys.Mkdir(mirrorPath, dirBasePerm); err != nil {
+			prog.log.Warn("mirror directory not re-created", "op", prog.opts.Mode, "path", mirrorPath, "error", err, "reason", "error_occurred")
+
+			break
+		}
 	}
 
-	workingFile = dst // We work on the actual destination file now.
+	return true, nil
+}
 
-	if prog.opts.Verify {
-		verifyHasher := sha256.New()
+// bulkSubtree are the elements of a mirror subtree that is moved with a single
+// rename (relative to it, in walk order), as they would have been counted and
+// logged by moving it per file.
+type bulkSubtree struct {
+	files []string
+	dirs  []string
+	size  int64
+}
+
+// bulkRenameSubtree walks a mirror subtree and decides if it can be moved as
+// it is, that is, if each of its elements would also be moved when walked per
+// file. Any exclusion, file skipped by its name or age, file transformed by the
+// --rename-rules, empty directory skipped with --skip-empty, or element other
+// than regular files and directories within the subtree rules out the rename.
+func (prog *program) bulkRenameSubtree(ctx context.Context, path string, movePath string) (bulkSubtree, bool, error) {
+	var subtree bulkSubtree
+
+	errNotRenamable := errors.New("not renamable")
+	hasFiles := make(map[string]bool)
+
+	if err := afero.Walk(prog.fsys, path, func(subPath string, e os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed checking context: %w", err)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to walk: %q (%w)", subPath, err)
+		}
 
-		verifier, err := prog.fsys.Open(workingFile)
+		relPath, err := filepath.Rel(path, subPath)
 		if err != nil {
-			return retHashes, fmt.Errorf("failed to re-open for --verify pass: %q (%w)", workingFile, err)
+			return fmt.Errorf("failed to get relative path: %q (%w)", subPath, err)
+		}
+		subMovePath := filepath.Join(movePath, relPath)
+
+		if _, excluded := prog.checkExclusion(subPath, subMovePath, e.IsDir()); excluded {
+			return errNotRenamable
 		}
-		defer verifier.Close()
 
-		ctxReader := &contextReader{ctx, verifier}
+		switch {
+		case e.IsDir():
+			if prog.samePath(subMovePath, prog.opts.MirrorRoot) {
+				return errNotRenamable
+			}
+			hasFiles[subPath] = false // Walked before any of its contents.
+			subtree.dirs = append(subtree.dirs, relPath)
+
+		case e.Mode().IsRegular():
+			if strings.HasSuffix(subPath, workingFileSuffix) {
+				return errNotRenamable
+			}
+
+			if mirrorRelPath, err := filepath.Rel(prog.opts.MirrorRoot, subPath); err != nil {
+				return fmt.Errorf("failed to get relative path: %q (%w)", subPath, err)
+			} else if renamedPath, err := prog.renameTarget(mirrorRelPath); err != nil || renamedPath != mirrorRelPath {
+				return errNotRenamable
+			}
+
+			if !prog.state.movedSince.IsZero() && e.ModTime().Before(prog.state.movedSince) {
+				return errNotRenamable
+			}
+
+			// None of the directories containing the file are empty.
+			for dir := filepath.Dir(subPath); len(dir) >= len(path); dir = filepath.Dir(dir) {
+				hasFiles[dir] = true
+			}
+			subtree.files = append(subtree.files, relPath)
+			subtree.size += e.Size()
 
-		if _, err := io.Copy(verifyHasher, ctxReader); err != nil {
-			return retHashes, fmt.Errorf("failed to re-read for --verify pass: %q (%w)", workingFile, err)
+		default:
+			return errNotRenamable
 		}
 
-		if err := verifier.Close(); err != nil {
-			return retHashes, fmt.Errorf("failed to close after --verify pass: %q (%w)", workingFile, err)
+		return nil
+	}); errors.Is(err, errNotRenamable) {
+		return subtree, false, nil
+	} else if err != nil {
+		return subtree, false, err
+	}
+
+	for _, dirHasFiles := range hasFiles {
+		if prog.opts.SkipEmpty && !dirHasFiles {
+			// An empty directory would have been skipped when walked per file.
+			return subtree, false, nil
 		}
+	}
 
-		retHashes.verifyHash = hex.EncodeToString(verifyHasher.Sum(nil))
+	return subtree, true, nil
+}
 
-		if retHashes.srcHash != retHashes.verifyHash {
-			return retHashes, fmt.Errorf("%w: %q (srcHash) != %q (verifyHash)", errVerifyHashMismatch, retHashes.srcHash, retHashes.verifyHash)
+// mkdirTarget creates a directory within the target, with the permissions of
+// its existing parent with the --inherit-parent-perms setting, so that a moved
+// structure matches the surrounding archive. The base permissions are used if
+// the parent cannot be inspected.
+func (prog *program) mkdirTarget(path string) error {
+	if !prog.opts.InheritParentPerms {
+		if err := prog.fsys.Mkdir(path, dirBasePerm); err != nil {
+			return fmt.Errorf("failed to create: %q (%w)", path, err)
 		}
+
+		return nil
 	}
 
-	if err := prog.fsys.Remove(src); err != nil {
-		return retHashes, fmt.Errorf("failed to remove (after move): %q (%w)", src, err)
+	perm := os.FileMode(dirBasePerm)
+	if parent, err := prog.fsys.Stat(filepath.Dir(path)); err == nil {
+		perm = parent.Mode().Perm()
+	} else {
+		prog.log.Warn("parent permissions not inherited", "op", prog.opts.Mode, "path", path, "error", err, "reason", "error_occurred")
 	}
 
-	return retHashes, nil
+	if err := prog.fsys.Mkdir(path, perm); err != nil {
+		return fmt.Errorf("failed to create: %q (%w)", path, err)
+	}
+
+	// The umask would otherwise mask some of the inherited permissions.
+	if err := prog.fsys.Chmod(path, perm); err != nil {
+		return fmt.Errorf("failed to chmod: %q (%w)", path, err)
+	}
+
+	return nil
+}
+
+// lockPromotedFile removes all of the write permissions from a file that was
+// moved into the target (with the --lock-promoted setting), so that it is not
+// easily altered once promoted into the archive.
+func (prog *program) lockPromotedFile(path string) error {
+	if !prog.opts.LockPromoted {
+		return nil
+	}
+
+	if !prog.opts.DryRun {
+		info, err := prog.fsys.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat: %q (%w)", path, err)
+		}
+
+		if err := prog.fsys.Chmod(path, info.Mode().Perm()&^0o222); err != nil {
+			return fmt.Errorf("failed to chmod: %q (%w)", path, err)
+		}
+	}
+	prog.log.Info("file locked", "op", prog.opts.Mode, "path", path, "dry-run", prog.opts.DryRun)
+
+	return nil
+}
+
+// handleTypeChange handles a target path that changed between directory and
+// file since the mirror was created, as per the --type-change setting, rather
+// than failing on it later on in a confusing way (such as within a Mkdir).
+func (prog *program) handleTypeChange(path string, movePath string, e os.FileInfo, dstInfo os.FileInfo) error {
+	srcType, dstType := fileTypeName(e), fileTypeName(dstInfo)
+
+	if prog.opts.TypeChange == typeChangeSkip {
+		// The source remains within the mirror, where it needs resolution by the user.
+		prog.state.hasUnmovedFiles = true
+		if !e.IsDir() {
+			prog.state.unmovedFiles++
+		}
+		prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "dst", movePath, "src-type", srcType, "dst-type", dstType, "reason", "type_changed", "action", "skipped")
+		prog.recordSkip(path, "type_changed")
+
+		if e.IsDir() {
+			return filepath.SkipDir // Do not traverse deeper.
+		}
+
+		return nil
+	}
+
+	return prog.walkError(path, e, fmt.Errorf("%w: %q (%s) -x-> %q (%s)", errTargetTypeChanged, path, srcType, movePath, dstType))
+}
+
+// fileTypeName returns the type of the given file, as it is output in logs.
+func fileTypeName(e os.FileInfo) string {
+	if e.IsDir() {
+		return "directory"
+	}
+
+	return "file"
+}
+
+func (prog *program) createDir(movePath string) error {
+	if !prog.opts.DryRun {
+		// Create the target directory, if it does not exist.
+		if err := prog.mkdirTarget(movePath); err != nil {
+			return err
+		}
+	}
+	prog.state.createdDirs++
+	prog.recordPlan(planOperation{Op: planOpMkdir, Dst: movePath})
+	prog.log.Info("directory created", "op", prog.opts.Mode, "path", movePath, "dry-run", prog.opts.DryRun)
+
+	return nil
+}
+
+func (prog *program) moveFile(ctx context.Context, path string, movePath string, e os.FileInfo) error {
+	if strings.HasSuffix(path, workingFileSuffix) { // Check if the source file could be mistaken for a working file.
+		prog.state.hasUnmovedFiles = true
+		prog.state.unmovedFiles++
+		prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "reason", "is_working_file_name", "action", "skipped")
+		prog.recordSkip(path, "is_working_file_name")
+
+		// The source file carries our working file suffix; promoting it could later
+		// see it clobbered or removed as one of our own working files, so skip it.
+		return nil
+	}
+
+	if prog.isChecksumSidecar(path, movePath) { // Check if the source file could be mistaken for a checksum sidecar.
+		prog.state.hasUnmovedFiles = true
+		prog.state.unmovedFiles++
+		prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "reason", "is_checksum_sidecar", "action", "skipped")
+		prog.recordSkip(path, "is_checksum_sidecar")
+
+		// The source file is the checksum sidecar of another file; promoting it
+		// could replace the sidecar written for that file, so skip it.
+		return nil
+	}
+
+	var overwrite bool
+	if dstInfo, err := prog.fsys.Stat(movePath); err == nil { // Check if the target file exists.
+		if dstInfo.IsDir() { // Check if the target is no longer a file.
+			return prog.handleTypeChange(path, movePath, e, dstInfo)
+		}
+
+		if prog.opts.UpdateMetadataOnMatch {
+			if matched, err := prog.updateMetadataOnMatch(ctx, path, movePath, e); err != nil {
+				return prog.walkError(path, e, err)
+			} else if matched {
+				return nil
+			}
+		}
+
+		var handled bool
+		if overwrite, handled, err = prog.checkOverwrite(ctx, path, movePath, e, dstInfo); err != nil {
+			return prog.walkError(path, e, err)
+		} else if handled {
+			return nil
+		}
+
+		if !overwrite {
+			prog.state.hasUnmovedFiles = true
+			prog.state.unmovedFiles++
+			prog.log.Warn("target already exists", "op", prog.opts.Mode, "src", path, "dst", movePath, "action", "skipped")
+			prog.recordSkip(path, "target_exists")
+
+			// The target file exists; do not overwrite it, set unmoved files bit and skip it.
+			return nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return prog.walkError(path, e, fmt.Errorf("failed to stat: %q (%w)", movePath, err))
+	}
+
+	prog.setCurrentFile(path)
+
+	var dedupeHash string
+	if prog.opts.DedupeRun != "" && prog.opts.DedupeRun != dedupeRunNone {
+		hash, handled, err := prog.dedupeFile(ctx, path, movePath, e)
+		if err != nil {
+			return prog.walkError(path, e, err)
+		} else if handled {
+			return nil
+		}
+		dedupeHash = hash
+	}
+
+	if !prog.opts.DryRun {
+		if prog.opts.AtomicBatch {
+			// Batch mode; only copy for now, the files are committed after the walk.
+			return prog.stageFile(ctx, path, movePath, e, overwrite)
+		}
+
+		if prog.opts.Direct {
+			// Direct mode; attempt a rename syscall, otherwise copy and remove.
+			trashPath, err := prog.trashTargetFile(movePath)
+			if err != nil {
+				return prog.walkError(path, e, err)
+			}
+
+			if err := prog.fsys.Rename(path, movePath); err == nil {
+				prog.log.Info("file moved", "op", prog.opts.Mode, "mode", "direct", "src", path, "dst", movePath, "dry-run", prog.opts.DryRun)
+				prog.countMoved(e.Size())
+				prog.rememberMovedHash(dedupeHash, movePath)
+
+				return prog.finishMove(ctx, path, movePath, fileHashes{}, e)
+			} // Rename syscall must have failed from here downwards.
+
+			prog.restoreTrashedFile(movePath, trashPath) // Trashed again once the copy is complete.
+		}
+
+		if prog.state.verifyPool != nil {
+			// Copy now, but leave the verify pass and removal of the source to the pool.
+			return prog.copyAndQueueVerify(ctx, path, movePath, e, dedupeHash)
+		}
+
+		// Do the regular copy and remove operation and handle any failures.
+		retHashes, err := prog.copyAndRemove(ctx, path, movePath)
+		if errors.Is(err, errWorkingFileExists) {
+			return prog.skipExistingWorkingFile(path, movePath)
+		} else if err != nil {
+			return prog.walkError(path, e, fmt.Errorf("failed to move: %q -x-> %q (%w)", path, movePath, err))
+		}
+
+		prog.logFileMoved("c+r", path, movePath, retHashes)
+		prog.countMoved(e.Size())
+		prog.deferVerify(movePath, retHashes)
+		prog.rememberMovedHash(dedupeHash, movePath)
+
+		return prog.finishMove(ctx, path, movePath, retHashes, e)
+	} // Must be in dry mode from here downwards.
+
+	prog.recordPlan(planOperation{Op: planOpMove, Src: path, Dst: movePath, Size: e.Size(), Mtime: e.ModTime()})
+	prog.log.Info("file moved", "op", prog.opts.Mode, "mode", "", "src", path, "dst", movePath, "dry-run", prog.opts.DryRun)
+	prog.countMoved(e.Size()) // The summary of a dry run previews the counts of the real run.
+	prog.rememberMovedHash(dedupeHash, movePath)
+
+	return prog.finishMove(ctx, path, movePath, fileHashes{}, e)
+}
+
+// skipExistingWorkingFile skips a file whose working file already exists (with
+// the --no-clobber-working-file setting), as it may be in use by another run.
+func (prog *program) skipExistingWorkingFile(path string, movePath string) error {
+	prog.state.hasUnmovedFiles = true
+	prog.state.unmovedFiles++
+	prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "dst", movePath, "reason", "working_file_exists", "action", "skipped")
+	prog.recordSkip(path, "working_file_exists")
+
+	return nil
+}
+
+// dedupeFile handles a source file that is identical in content to a file that
+// was already moved in the same run (with the --dedupe-run setting), by either
+// hard-linking it to that file or skipping it. It returns false (along with the
+// hash of the source) if the file is to be moved as usual.
+func (prog *program) dedupeFile(ctx context.Context, path string, movePath string, e os.FileInfo) (string, bool, error) {
+	srcHash, err := prog.hashFile(ctx, path, prog.hashAlgorithms()[0])
+	if err != nil {
+		return "", false, err
+	}
+
+	first, ok := prog.state.movedHashes[srcHash]
+	if !ok {
+		return srcHash, false, nil
+	}
+
+	if prog.opts.DedupeRun == dedupeRunSkip {
+		prog.state.hasUnmovedFiles = true
+		prog.state.unmovedFiles++
+		prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "dst", first, "srcHash", srcHash, "reason", "duplicate_in_run", "action", "skipped")
+		prog.recordSkip(path, "duplicate_in_run")
+
+		return srcHash, true, nil
+	}
+
+	if !prog.opts.DryRun {
+		if prog.link == nil {
+			// Links are only supported on the real filesystem, the file is then still moved.
+			prog.log.Warn("file not linked", "op", prog.opts.Mode, "src", path, "dst", movePath, "path", first, "reason", "links_unsupported", "action", "moving")
+
+			return srcHash, false, nil
+		}
+
+		if err := prog.link(first, movePath); err != nil {
+			// Linking fails across filesystems (among others), the file is then still moved.
+			prog.log.Warn("file not linked", "op", prog.opts.Mode, "src", path, "dst", movePath, "path", first, "error", err, "action", "moving")
+
+			return srcHash, false, nil
+		}
+
+		if err := prog.fsys.Remove(path); err != nil {
+			prog.removeWorkingFile(path, movePath)
+
+			return "", false, fmt.Errorf("failed to remove (after link): %q (%w)", path, err)
+		}
+	} else {
+		// The plan has no operation for links, so the file is moved as usual with it.
+		prog.recordPlan(planOperation{Op: planOpMove, Src: path, Dst: movePath, Size: e.Size(), Mtime: e.ModTime()})
+	}
+
+	prog.log.Info("file moved", "op", prog.opts.Mode, "mode", "link", "src", path, "dst", movePath, "path", first, "checksumAlgo", prog.hashAlgorithms()[0], "srcHash", srcHash, "dry-run", prog.opts.DryRun)
+	prog.countMoved(0) // Nothing was written for the link.
+
+	hashes := fileHashes{srcHash: srcHash, srcHashes: []fileDigest{{algo: prog.hashAlgorithms()[0], hash: srcHash}}}
+
+	return srcHash, true, prog.finishMove(ctx, path, movePath, hashes, e)
+}
+
+// rememberMovedHash remembers the target of a moved file by its hash, so that
+// any later identical files of the same run can be deduplicated against it.
+func (prog *program) rememberMovedHash(hash string, movePath string) {
+	if hash == "" {
+		return
+	}
+
+	if prog.state.movedHashes == nil {
+		prog.state.movedHashes = make(map[string]string)
+	}
+	prog.state.movedHashes[hash] = movePath
+}
+
+// updateMetadataOnMatch reconciles a source file with its already existing
+// target file, if both are identical (as decided by [isSameFile]), by applying
+// the modification time of the source to the target and then removing the
+// source. It returns false (and does nothing) if the two files differ.
+func (prog *program) updateMetadataOnMatch(ctx context.Context, path string, movePath string, e os.FileInfo) (bool, error) {
+	same, srcHash, dstHash, err := prog.isSameFile(ctx, path, movePath, e)
+	if err != nil || !same {
+		return false, err
+	}
+
+	if !prog.opts.DryRun {
+		if err := prog.fsys.Chtimes(movePath, e.ModTime(), e.ModTime()); err != nil {
+			return false, fmt.Errorf("failed to update times: %q (%w)", movePath, err)
+		}
+
+		if err := prog.fsys.Remove(path); err != nil {
+			return false, fmt.Errorf("failed to remove (after update): %q (%w)", path, err)
+		}
+	}
+	prog.log.Info("target metadata updated", "op", prog.opts.Mode, "src", path, "dst", movePath, "srcHash", srcHash, "dstHash", dstHash, "compare-by", prog.opts.CompareBy, "reason", "content_matches", "dry-run", prog.opts.DryRun)
+
+	return true, nil
+}
+
+// checkOverwrite decides if an existing target file is overwritten by its source,
+// as per the --overwrite setting. The overwrite goes through the working file
+// like any other move, so that an interrupted one never corrupts the target.
+// With "if-different", a source that is identical to its target (which is never
+// decided without comparing the hashes of both files) is removed without
+// rewriting the target, in which case the file is reported as handled.
+func (prog *program) checkOverwrite(ctx context.Context, path string, movePath string, e os.FileInfo, dstInfo os.FileInfo) (overwrite bool, handled bool, err error) {
+	switch prog.opts.Overwrite {
+	case overwriteAlways:
+		overwrite = true
+
+	case overwriteIfNewer:
+		overwrite = e.ModTime().After(dstInfo.ModTime())
+
+	case overwriteIfDifferent:
+		same, srcHash, dstHash, err := prog.isSameFile(ctx, path, movePath, e)
+		if err != nil {
+			return false, false, err
+		}
+
+		if same {
+			if !prog.opts.DryRun {
+				if err := prog.fsys.Remove(path); err != nil {
+					return false, false, fmt.Errorf("failed to remove (after match): %q (%w)", path, err)
+				}
+			}
+			prog.log.Info("source removed", "op", prog.opts.Mode, "src", path, "dst", movePath, "srcHash", srcHash, "dstHash", dstHash, "compare-by", prog.opts.CompareBy, "reason", "content_matches", "dry-run", prog.opts.DryRun)
+
+			return false, true, nil
+		}
+		overwrite = true
+	}
+
+	if overwrite {
+		prog.log.Info("overwriting target", "op", prog.opts.Mode, "src", path, "dst", movePath, "overwrite", prog.opts.Overwrite, "dry-run", prog.opts.DryRun)
+	}
+
+	return overwrite, false, nil
+}
+
+// isSameFile decides if an existing target file is identical to its source.
+// The sizes (and with --compare-by=mtime, the modification times) are compared
+// first, so that obviously differing files are never read. As the source file
+// is removed on a match, a match is always confirmed by hashing both files.
+func (prog *program) isSameFile(ctx context.Context, path string, movePath string, e os.FileInfo) (bool, string, string, error) {
+	dst, err := prog.fsys.Stat(movePath)
+	if err != nil {
+		return false, "", "", fmt.Errorf("failed to stat: %q (%w)", movePath, err)
+	}
+
+	if dst.Size() != e.Size() {
+		return false, "", "", nil
+	}
+
+	if prog.opts.CompareBy == compareByMtime && !dst.ModTime().Equal(e.ModTime()) {
+		return false, "", "", nil
+	}
+
+	algo := prog.hashAlgorithms()[0]
+
+	srcHash, err := prog.hashFile(ctx, path, algo)
+	if err != nil {
+		return false, "", "", err
+	}
+
+	dstHash, err := prog.hashFile(ctx, movePath, algo)
+	if err != nil {
+		return false, "", "", err
+	}
+
+	return srcHash == dstHash, srcHash, dstHash, nil
+}
+
+// logFileMoved outputs a moved file along with the hashes for its operation,
+// as parsing programs may care about them.
+func (prog *program) logFileMoved(mode string, src string, dst string, hashes fileHashes) {
+	logArgs := []any{
+		"op", prog.opts.Mode,
+		"mode", mode,
+		"src", src,
+		"dst", dst,
+		"checksumAlgo", prog.hashAlgorithms()[0],
+		"srcHash", hashes.srcHash,
+		"dstHash", hashes.dstHash,
+		"verifyHash", hashes.verifyHash,
+		"verify", prog.opts.Verify,
+		"dry-run", prog.opts.DryRun,
+	}
+
+	if len(hashes.srcHashes) > 1 {
+		// Output all of the digests when multiple hashing algorithms are in use.
+		digests := make([]any, 0, len(hashes.srcHashes))
+		for _, d := range hashes.srcHashes {
+			digests = append(digests, slog.String(d.algo, d.hash))
+		}
+		logArgs = append(logArgs, slog.Group("srcHashes", digests...))
+	}
+
+	prog.log.Info("file moved", logArgs...)
+}
+
+// stagedFile is a file that was copied into its working file (with the
+// --atomic-batch setting), awaiting its rename in the final commit phase.
+type stagedFile struct {
+	src         string
+	dst         string
+	workingFile string
+	info        os.FileInfo
+	hashes      fileHashes
+	overwrite   bool   // The existing target file is replaced (with the --overwrite setting).
+	trashPath   string // The replaced target file within the --target-trash-dir, if any.
+}
+
+func (prog *program) stageFile(ctx context.Context, path string, movePath string, e os.FileInfo, overwrite bool) error {
+	retHashes, workingFile, err := prog.copyToWorkingFile(ctx, path, movePath)
+	if err != nil {
+		// Any failed copy fails the entire batch, so it is not handled as a walk error.
+		return fmt.Errorf("failed to stage: %q -x-> %q (%w)", path, movePath, err)
+	}
+
+	if prog.opts.Verify {
+		if err := prog.verifyFile(ctx, workingFile, &retHashes); err != nil {
+			prog.discardFailedFile(path, workingFile, err)
+
+			return fmt.Errorf("failed to stage: %q -x-> %q (%w)", path, movePath, err)
+		}
+	}
+
+	prog.state.stagedFiles = append(prog.state.stagedFiles, stagedFile{
+		src:         path,
+		dst:         movePath,
+		workingFile: workingFile,
+		info:        e,
+		hashes:      retHashes,
+		overwrite:   overwrite,
+	})
+	prog.log.Info("file staged", "op", prog.opts.Mode, "src", path, "dst", movePath, "path", workingFile, "srcHash", retHashes.srcHash)
+
+	return nil
+}
+
+// discardStagedFiles removes the working files of all staged files, as used
+// when the batch could not be copied in its entirety.
+func (prog *program) discardStagedFiles() {
+	for _, f := range prog.state.stagedFiles {
+		prog.removeWorkingFile(f.src, f.workingFile)
+	}
+
+	prog.state.stagedFiles = nil
+}
+
+// commitStagedFiles renames all staged working files towards their destination
+// in a tight loop, and only then removes their sources, minimizing the window
+// in which only a part of the batch is visible within the target.
+func (prog *program) commitStagedFiles(ctx context.Context) error {
+	staged := prog.state.stagedFiles
+	prog.state.stagedFiles = nil
+
+	committed := make([]stagedFile, 0, len(staged))
+
+	for i, f := range staged {
+		if err := ctx.Err(); err != nil {
+			// An interrupt was received, so nothing more of the batch gets committed.
+			prog.state.stagedFiles = staged[i:]
+			prog.discardStagedFiles()
+
+			return fmt.Errorf("failed checking context: %w", err)
+		}
+
+		if _, err := prog.fsys.Stat(f.dst); err == nil && !f.overwrite { // Check if a target file appeared in the meantime.
+			prog.state.hasUnmovedFiles = true
+			prog.state.unmovedFiles++
+			prog.log.Warn("target already exists", "op", prog.opts.Mode, "src", f.src, "dst", f.dst, "action", "skipped")
+			prog.recordSkip(f.src, "target_exists")
+			prog.removeWorkingFile(f.src, f.workingFile)
+
+			continue
+		}
+
+		trashPath, err := prog.trashTargetFile(f.dst)
+		if err != nil {
+			prog.removeWorkingFile(f.src, f.workingFile)
+
+			if err := prog.walkError(f.src, f.info, err); err != nil {
+				prog.state.stagedFiles = staged[i+1:]
+				prog.discardStagedFiles()
+
+				return err
+			}
+
+			continue
+		}
+
+		if err := prog.fsys.Rename(f.workingFile, f.dst); err != nil {
+			prog.removeWorkingFile(f.src, f.workingFile)
+			prog.restoreTrashedFile(f.dst, trashPath)
+
+			if err := prog.walkError(f.src, f.info, fmt.Errorf("failed to rename: %q -x-> %q (%w)", f.workingFile, f.dst, err)); err != nil {
+				prog.state.stagedFiles = staged[i+1:]
+				prog.discardStagedFiles()
+
+				return err
+			}
+
+			continue
+		}
+
+		f.trashPath = trashPath
+		committed = append(committed, f)
+	}
+
+	for _, f := range committed {
+		if prog.opts.StatBeforeRemove {
+			if err := prog.statFile(f.src, f.dst); err != nil {
+				prog.removeWorkingFile(f.src, f.dst)
+				prog.restoreTrashedFile(f.dst, f.trashPath)
+
+				if err := prog.walkError(f.src, f.info, err); err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		if err := prog.fsys.Remove(f.src); err != nil {
+			if err := prog.walkError(f.src, f.info, fmt.Errorf("failed to remove (after move): %q (%w)", f.src, err)); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		prog.logFileMoved("batch", f.src, f.dst, f.hashes)
+		prog.countMoved(f.info.Size())
+		prog.deferVerify(f.dst, f.hashes)
+
+		if err := prog.finishMove(ctx, f.src, f.dst, f.hashes, f.info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (prog *program) copyAndRemove(ctx context.Context, src string, dst string) (retHashes fileHashes, retErr error) {
+	retHashes, workingFile, err := prog.copyToWorkingFile(ctx, src, dst)
+	if err != nil {
+		return retHashes, err
+	}
+
+	var trashPath string
+	defer func() {
+		if retErr != nil {
+			prog.discardFailedFile(src, workingFile, retErr)
+			prog.restoreTrashedFile(dst, trashPath)
+		}
+	}()
+
+	trashPath, err = prog.trashTargetFile(dst)
+	if err != nil {
+		return retHashes, err
+	}
+
+	if err := prog.fsys.Rename(workingFile, dst); err != nil {
+		return retHashes, fmt.Errorf("failed to rename: %q -x-> %q (%w)", workingFile, dst, err)
+	}
+
+	workingFile = dst // We work on the actual destination file now.
+
+	if prog.opts.Verify {
+		if err := prog.verifyFile(ctx, workingFile, &retHashes); err != nil {
+			return retHashes, err
+		}
+	}
+
+	if prog.opts.StatBeforeRemove {
+		if err := prog.statFile(src, workingFile); err != nil {
+			return retHashes, err
+		}
+	}
+
+	if err := prog.fsys.Remove(src); err != nil {
+		return retHashes, fmt.Errorf("failed to remove (after move): %q (%w)", src, err)
+	}
+
+	return retHashes, nil
+}
+
+// copyToWorkingFile copies the source into a working file next to the given
+// destination, hashing both in-memory and comparing the hashes. The working
+// file is removed again if any of this fails. The copy happens within its own
+// deadline (with the --per-file-timeout setting), so that a single slow file
+// (such as on a failing disk) fails just that file rather than stalling the run.
+func (prog *program) copyToWorkingFile(ctx context.Context, src string, dst string) (retHashes fileHashes, retWorkingFile string, retErr error) {
+	workingFile := dst + workingFileSuffix // We work on a temporary file first.
+
+	if prog.opts.PerFileTimeout > 0 {
+		runCtx := ctx
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, prog.opts.PerFileTimeout)
+		defer cancel()
+
+		defer func() {
+			if errors.Is(retErr, context.DeadlineExceeded) && runCtx.Err() == nil {
+				retErr = fmt.Errorf("%w: %s (%w)", errPerFileTimeout, prog.opts.PerFileTimeout, retErr)
+			}
+		}()
+	}
+
+	in, err := prog.fsys.Open(src)
+	if err != nil {
+		return retHashes, "", fmt.Errorf("failed to open: %q (%w)", src, err)
+	}
+	defer in.Close()
+
+	var out afero.File
+	if prog.opts.NoClobberWorkingFile {
+		// An existing working file may belong to a concurrent run, so never truncate it.
+		out, err = prog.fsys.OpenFile(workingFile, os.O_RDWR|os.O_CREATE|os.O_EXCL, workingFilePerm)
+		if errors.Is(err, os.ErrExist) {
+			return retHashes, "", fmt.Errorf("%w: %q", errWorkingFileExists, workingFile)
+		}
+	} else {
+		out, err = prog.fsys.Create(workingFile)
+	}
+	if err != nil {
+		return retHashes, "", fmt.Errorf("failed to open: %q (%w)", workingFile, err)
+	}
+	defer out.Close()
+
+	defer func() {
+		if retErr != nil {
+			prog.discardFailedFile(src, workingFile, retErr)
+		}
+	}()
+
+	if info, err := in.Stat(); err == nil && info.Size() == 0 {
+		// Empty files need no hashing, their digests are known constants.
+		retHashes, err = prog.copyEmptyFile(in, out, src, workingFile)
+		if err != nil {
+			return retHashes, "", err
+		}
+
+		return retHashes, workingFile, nil
+	}
+
+	algos := prog.hashAlgorithms()
+
+	// The source is hashed with all of the algorithms from the single read stream,
+	// while the in-memory comparison with the destination uses the primary one.
+	srcHashers := make([]hash.Hash, 0, len(algos))
+	srcWriters := make([]io.Writer, 0, len(algos))
+	for _, algo := range algos {
+		h, err := newHasher(algo)
+		if err != nil {
+			return retHashes, "", fmt.Errorf("%w: %q", err, algo)
+		}
+		srcHashers = append(srcHashers, h)
+		srcWriters = append(srcWriters, h)
+	}
+
+	dstHasher, err := newHasher(algos[0])
+	if err != nil {
+		return retHashes, "", fmt.Errorf("%w: %q", err, algos[0])
+	}
+
+	ctxReader := &contextReader{ctx, io.TeeReader(in, io.MultiWriter(srcWriters...))}
+	multiWriter := io.MultiWriter(out, dstHasher)
+
+	var buf []byte
+	if prog.copyBuffers != nil {
+		bufPtr := prog.copyBuffers.Get().(*[]byte) //nolint:forcetypeassert // The pool only holds these.
+		defer prog.copyBuffers.Put(bufPtr)
+		buf = *bufPtr
+	}
+
+	if _, err := io.CopyBuffer(multiWriter, ctxReader, buf); err != nil {
+		return retHashes, "", fmt.Errorf("failed during io: %w", err)
+	}
+
+	if err := out.Sync(); err != nil {
+		if !errors.Is(err, errors.ErrUnsupported) && !errors.Is(err, syscall.ENOTSUP) {
+			return retHashes, "", fmt.Errorf("failed during sync: %w", err)
+		}
+
+		// Some filesystems do not support syncing, this is not to fail the file.
+		prog.log.Warn("file not synced", "op", prog.opts.Mode, "path", workingFile, "error", err, "reason", "sync_not_supported")
+	}
+
+	if err := in.Close(); err != nil {
+		return retHashes, "", fmt.Errorf("failed to close: %q (%w)", src, err)
+	}
+
+	if err := out.Close(); err != nil {
+		return retHashes, "", fmt.Errorf("failed to close: %q (%w)", workingFile, err)
+	}
+
+	for i, h := range srcHashers {
+		retHashes.srcHashes = append(retHashes.srcHashes, fileDigest{algo: algos[i], hash: hex.EncodeToString(h.Sum(nil))})
+	}
+
+	retHashes.srcHash = retHashes.srcHashes[0].hash
+	retHashes.dstHash = hex.EncodeToString(dstHasher.Sum(nil))
+
+	if retHashes.srcHash != retHashes.dstHash {
+		return retHashes, "", fmt.Errorf("%w: %q (srcHash) != %q (dstHash)", errMemoryHashMismatch, retHashes.srcHash, retHashes.dstHash)
+	}
+
+	return retHashes, workingFile, nil
+}
+
+// copyEmptyFile completes the (empty) working file for an empty source, with
+// the well-known digests of empty content instead of hashing. The source is
+// probed for any content, in case it was written to since it was opened.
+func (prog *program) copyEmptyFile(in afero.File, out afero.File, src string, workingFile string) (fileHashes, error) {
+	var retHashes fileHashes
+
+	if n, err := in.Read(make([]byte, 1)); n > 0 {
+		return retHashes, fmt.Errorf("%w: %q", errEmptySourceChanged, src)
+	} else if err != nil && !errors.Is(err, io.EOF) {
+		return retHashes, fmt.Errorf("failed during io: %w", err)
+	}
+
+	if err := in.Close(); err != nil {
+		return retHashes, fmt.Errorf("failed to close: %q (%w)", src, err)
+	}
+
+	if err := out.Close(); err != nil {
+		return retHashes, fmt.Errorf("failed to close: %q (%w)", workingFile, err)
+	}
+
+	for _, algo := range prog.hashAlgorithms() {
+		digest, err := emptyDigest(algo)
+		if err != nil {
+			return retHashes, fmt.Errorf("%w: %q", err, algo)
+		}
+		retHashes.srcHashes = append(retHashes.srcHashes, fileDigest{algo: algo, hash: digest})
+	}
+
+	retHashes.srcHash = retHashes.srcHashes[0].hash
+	retHashes.dstHash = retHashes.srcHash
+
+	return retHashes, nil
+}
+
+// verifyFile re-reads the given file from disk and compares its hash with the
+// previously calculated hash of the source file (the --verify pass).
+func (prog *program) verifyFile(ctx context.Context, path string, hashes *fileHashes) error {
+	verifyHash, err := prog.hashFile(ctx, path, prog.hashAlgorithms()[0])
+
+	for attempt := 1; err != nil && prog.opts.VerifyReadError == verifyReadErrorRetry && attempt <= verifyReadRetries; attempt++ {
+		if ctx.Err() != nil {
+			break
+		}
+		prog.log.Warn("verify read failed", "op", prog.opts.Mode, "path", path, "attempt", attempt, "error", err, "action", "retrying")
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(verifyReadRetryDelay):
+			verifyHash, err = prog.hashFile(ctx, path, prog.hashAlgorithms()[0])
+		}
+	}
+
+	if err != nil {
+		if prog.opts.VerifyReadError == verifyReadErrorSkip && ctx.Err() == nil {
+			// The move is accepted without its verification, which the user must be made aware of.
+			prog.log.Error("file not verified", "op", prog.opts.Mode, "path", path, "error", err, "error-type", "runtime", "reason", "verify_read_error", "action", "accepted")
+
+			return nil
+		}
+
+		return fmt.Errorf("failed during --verify pass: %w", err)
+	}
+
+	hashes.verifyHash = verifyHash
+
+	if hashes.srcHash != hashes.verifyHash {
+		return fmt.Errorf("%w: %q (srcHash) != %q (verifyHash)", errVerifyHashMismatch, hashes.srcHash, hashes.verifyHash)
+	}
+
+	return nil
+}
+
+// statFile is a lightweight alternative to a full --verify pass, which only
+// confirms that the target file is of the same size as the source file.
+func (prog *program) statFile(src string, dst string) error {
+	srcInfo, err := prog.fsys.Stat(src)
+	if err != nil {
+		return fmt.Errorf("failed to stat: %q (%w)", src, err)
+	}
+
+	dstInfo, err := prog.fsys.Stat(dst)
+	if err != nil {
+		return fmt.Errorf("failed to stat: %q (%w)", dst, err)
+	}
+
+	if srcInfo.Size() != dstInfo.Size() {
+		return fmt.Errorf("%w: %d (srcSize) != %d (dstSize)", errStatSizeMismatch, srcInfo.Size(), dstInfo.Size())
+	}
+
+	return nil
+}
+
+// removeWorkingFile removes a (failed) working file, but only as long as the
+// source file still exists, so that no data can be lost.
+func (prog *program) removeWorkingFile(src string, workingFile string) {
+	if _, err := prog.fsys.Stat(src); err == nil {
+		if err := prog.fsys.Remove(workingFile); err == nil {
+			prog.log.Info("incomplete file removed", "op", prog.opts.Mode+"_cleanup", "path", workingFile)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			prog.log.Error("incomplete file not removed", "op", prog.opts.Mode+"_cleanup", "path", workingFile, "error", err, "error-type", "runtime", "reason", "error_occurred")
+		}
+	} else if errors.Is(err, os.ErrNotExist) {
+		prog.log.Warn("file not found", "op", prog.opts.Mode+"_cleanup", "path", src)
+		prog.log.Warn("incomplete file not removed", "op", prog.opts.Mode+"_cleanup", "path", workingFile, "reason", "src_no_longer_exists")
+	} else {
+		prog.log.Error("failed to stat", "op", prog.opts.Mode+"_cleanup", "path", src, "error", err, "error-type", "runtime")
+		prog.log.Warn("incomplete file not removed", "op", prog.opts.Mode+"_cleanup", "path", src, "reason", "src_existence_unknown")
+		prog.log.Warn("incomplete file not removed", "op", prog.opts.Mode+"_cleanup", "path", workingFile, "reason", "src_existence_unknown")
+	}
 }
@@ -2,8 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
 	"os"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
@@ -139,6 +147,146 @@ func Test_Unit_MoveFiles_FileAlreadyExists_Success(t *testing.T) {
 	require.True(t, prog.state.hasUnmovedFiles)
 }
 
+// Expectation: The function should only reconcile an existing target file with identical content.
+func Test_Unit_MoveFiles_UpdateMetadataOnMatch_Success(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		dstContent  string
+		wantMatched bool
+	}{
+		{"identical-content", "content", true},
+		{"differing-content", "different", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			files := map[string]string{
+				"/mirror/file.txt": "content",
+				"/real/file.txt":   tt.dstContent,
+			}
+			err := createFiles(fs, files)
+			require.NoError(t, err)
+
+			srcTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			dstTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			require.NoError(t, fs.Chtimes("/mirror/file.txt", srcTime, srcTime))
+			require.NoError(t, fs.Chtimes("/real/file.txt", dstTime, dstTime))
+
+			opts := &programOptions{
+				MirrorRoot:            "/mirror",
+				RealRoot:              "/real",
+				UpdateMetadataOnMatch: true,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+
+			// Verify the target content is never changed.
+			content, err := afero.ReadFile(fs, "/real/file.txt")
+			require.NoError(t, err)
+			require.Equal(t, tt.dstContent, string(content))
+
+			info, err := fs.Stat("/real/file.txt")
+			require.NoError(t, err)
+
+			_, srcErr := fs.Stat("/mirror/file.txt")
+
+			if tt.wantMatched {
+				require.True(t, info.ModTime().Equal(srcTime))
+				require.ErrorIs(t, srcErr, os.ErrNotExist)
+				require.False(t, prog.state.hasUnmovedFiles)
+			} else {
+				require.True(t, info.ModTime().Equal(dstTime))
+				require.NoError(t, srcErr)
+				require.True(t, prog.state.hasUnmovedFiles)
+			}
+		})
+	}
+}
+
+// Expectation: The function should only read the files not already differing on the basis of --compare-by, and always confirm a match by hash.
+func Test_Unit_MoveFiles_CompareBy_Table(t *testing.T) {
+	t.Parallel()
+
+	srcTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	dstTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		compareBy   string
+		dstContent  string
+		dstTime     time.Time
+		wantRead    bool
+		wantMatched bool
+	}{
+		{"hash-identical", compareByHash, "content", dstTime, true, true},
+		{"hash-size-differs", compareByHash, "other content", dstTime, false, false},
+		{"hash-size-matches-content-differs", compareByHash, "CONTENT", dstTime, true, false},
+		{"size-identical", compareBySize, "content", dstTime, true, true},
+		{"size-size-matches-content-differs", compareBySize, "CONTENT", dstTime, true, false},
+		{"size-size-differs", compareBySize, "other content", dstTime, false, false},
+		{"mtime-identical", compareByMtime, "content", srcTime, true, true},
+		{"mtime-matches-content-differs", compareByMtime, "CONTENT", srcTime, true, false},
+		{"mtime-differs", compareByMtime, "content", dstTime, false, false},
+		{"mtime-matches-size-differs", compareByMtime, "other content", srcTime, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			memFs := setupTestFs()
+			err := createFiles(memFs, map[string]string{
+				"/mirror/file.txt": "content",
+				"/real/file.txt":   tt.dstContent,
+			})
+			require.NoError(t, err)
+			require.NoError(t, memFs.Chtimes("/mirror/file.txt", srcTime, srcTime))
+			require.NoError(t, memFs.Chtimes("/real/file.txt", tt.dstTime, tt.dstTime))
+
+			// Any reading of the target file fails, so it must not be hashed unless required.
+			failures := 0
+			if !tt.wantRead {
+				failures = 1
+			}
+			fs := &failingOpenFs{Fs: memFs, failOn: "/real/file.txt", failures: failures}
+
+			opts := &programOptions{
+				MirrorRoot:            "/mirror",
+				RealRoot:              "/real",
+				UpdateMetadataOnMatch: true,
+				CompareBy:             tt.compareBy,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+			require.Equal(t, failures, fs.failures)
+
+			// Verify the target content is never changed.
+			content, err := afero.ReadFile(memFs, "/real/file.txt")
+			require.NoError(t, err)
+			require.Equal(t, tt.dstContent, string(content))
+
+			_, srcErr := memFs.Stat("/mirror/file.txt")
+
+			if tt.wantMatched {
+				require.ErrorIs(t, srcErr, os.ErrNotExist)
+				require.False(t, prog.state.hasUnmovedFiles)
+			} else {
+				require.NoError(t, srcErr)
+				require.True(t, prog.state.hasUnmovedFiles)
+			}
+		})
+	}
+}
+
 // Expectation: The function should not move or delete excluded files.
 func Test_Unit_MoveFiles_WithSrcFileExcludes_Success(t *testing.T) {
 	t.Parallel()
@@ -315,6 +463,558 @@ func Test_Unit_MoveFiles_WithDstDirExcludes_Success(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// Expectation: The program should not move any files or directories with an excluded name, at any depth.
+func Test_Unit_MoveFiles_WithExcludeNames_Success(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		ignoreCase bool
+		excluded   []string
+		moved      []string
+	}{
+		{"case-sensitive", false, []string{"/a/.DS_Store", "/a/b/.DS_Store", "/Thumbs.db"}, []string{"/include.txt", "/a/.ds_store", "/a/b/file.txt"}},
+		{"ignore-case", true, []string{"/a/.DS_Store", "/a/b/.DS_Store", "/a/.ds_store", "/Thumbs.db"}, []string{"/include.txt", "/a/b/file.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{
+				"/mirror/include.txt":       "content",
+				"/mirror/a/.DS_Store":       "content",
+				"/mirror/a/.ds_store":       "content",
+				"/mirror/a/b/.DS_Store":     "content",
+				"/mirror/a/b/file.txt":      "content",
+				"/mirror/Thumbs.db/sub.txt": "content",
+			})
+			require.NoError(t, err)
+			err = createDirStructure(fs, []string{"/real"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:            "/mirror",
+				RealRoot:              "/real",
+				ExcludeNames:          nameArg{".DS_Store", "Thumbs.db"},
+				ExcludeNameIgnoreCase: tt.ignoreCase,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+			require.True(t, prog.state.hasUnmovedFiles)
+
+			for _, path := range tt.moved {
+				_, err = fs.Stat("/real" + path)
+				require.NoError(t, err, path)
+			}
+
+			for _, path := range tt.excluded {
+				_, err = fs.Stat("/real" + path)
+				require.ErrorIs(t, err, os.ErrNotExist, path)
+
+				_, err = fs.Stat("/mirror" + path)
+				require.NoError(t, err, path)
+			}
+		})
+	}
+}
+
+// Expectation: The program should return an error when the target root is a file.
+func Test_Unit_MoveFiles_TargetNotDir_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	err := createFiles(fs, map[string]string{
+		"/mirror/dir/file.txt": "content",
+		"/real":                "content",
+	})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		DryRun:     false,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.ErrorIs(t, err, errTargetNotDir)
+
+	// Verify mirror file is not removed.
+	_, err = fs.Stat("/mirror/dir/file.txt")
+	require.NoError(t, err)
+	require.Zero(t, prog.state.createdDirs)
+}
+
+// Expectation: The glob excludes should only be matched on their own side of the move.
+func Test_Unit_MoveFiles_WithExcludeGlobs_Success(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		mirror   globArg
+		target   globArg
+		excluded []string
+		moved    []string
+	}{
+		{"mirror-name", globArg{"*.partial"}, nil, []string{"/a/x.partial", "/b/y.partial"}, []string{"/a/x.txt", "/b/y.txt"}},
+		{"mirror-path", globArg{"/mirror/a/*"}, nil, []string{"/a/x.partial", "/a/x.txt"}, []string{"/b/y.partial", "/b/y.txt"}},
+		{"mirror-path-on-target", globArg{"/real/a/*"}, nil, nil, []string{"/a/x.partial", "/a/x.txt", "/b/y.partial", "/b/y.txt"}},
+		{"target-path", nil, globArg{"/real/b"}, []string{"/b/y.partial", "/b/y.txt"}, []string{"/a/x.partial", "/a/x.txt"}},
+		{"target-path-on-mirror", nil, globArg{"/mirror/b"}, nil, []string{"/a/x.partial", "/a/x.txt", "/b/y.partial", "/b/y.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{
+				"/mirror/a/x.partial": "content",
+				"/mirror/a/x.txt":     "content",
+				"/mirror/b/y.partial": "content",
+				"/mirror/b/y.txt":     "content",
+			})
+			require.NoError(t, err)
+			err = createDirStructure(fs, []string{"/real"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:         "/mirror",
+				RealRoot:           "/real",
+				ExcludeGlobsMirror: tt.mirror,
+				ExcludeGlobsTarget: tt.target,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+
+			for _, path := range tt.moved {
+				_, err = fs.Stat("/real" + path)
+				require.NoError(t, err, path)
+			}
+
+			for _, path := range tt.excluded {
+				_, err = fs.Stat("/real" + path)
+				require.ErrorIs(t, err, os.ErrNotExist, path)
+
+				_, err = fs.Stat("/mirror" + path)
+				require.NoError(t, err, path)
+			}
+		})
+	}
+}
+
+// Expectation: Only files within the included paths (of either side) should be moved, with excludes taking precedence.
+func Test_Unit_MoveFiles_WithIncludes_Success(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		includes includeArg
+		excludes excludeArg
+		excluded []string
+		moved    []string
+	}{
+		{"no-includes", nil, nil, nil, []string{"/a/x.txt", "/a/keep/y.txt", "/a/keep/skip/z.txt", "/b/w.txt"}},
+		{"target-include", includeArg{"/real/a/keep"}, nil, []string{"/a/x.txt", "/b/w.txt"}, []string{"/a/keep/y.txt", "/a/keep/skip/z.txt"}},
+		{"mirror-include", includeArg{"/mirror/a"}, nil, []string{"/b/w.txt"}, []string{"/a/x.txt", "/a/keep/y.txt", "/a/keep/skip/z.txt"}},
+		{"overlapping-exclude", includeArg{"/real/a/keep"}, excludeArg{"/real/a/keep/skip"}, []string{"/a/x.txt", "/a/keep/skip/z.txt", "/b/w.txt"}, []string{"/a/keep/y.txt"}},
+		{"exclude-over-include", includeArg{"/real/a/keep"}, excludeArg{"/mirror/a"}, []string{"/a/x.txt", "/a/keep/y.txt", "/a/keep/skip/z.txt", "/b/w.txt"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{
+				"/mirror/a/x.txt":           "content",
+				"/mirror/a/keep/y.txt":      "content",
+				"/mirror/a/keep/skip/z.txt": "content",
+				"/mirror/b/w.txt":           "content",
+			})
+			require.NoError(t, err)
+			err = createDirStructure(fs, []string{"/real"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot: "/mirror",
+				RealRoot:   "/real",
+				Includes:   tt.includes,
+				Excludes:   tt.excludes,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+			require.Equal(t, len(tt.moved), prog.state.movedFiles)
+			require.Zero(t, prog.state.unmovedFiles)
+
+			for _, path := range tt.moved {
+				_, err = fs.Stat("/real" + path)
+				require.NoError(t, err, path)
+			}
+
+			for _, path := range tt.excluded {
+				_, err = fs.Stat("/real" + path)
+				require.ErrorIs(t, err, os.ErrNotExist, path)
+
+				_, err = fs.Stat("/mirror" + path)
+				require.NoError(t, err, path)
+			}
+		})
+	}
+}
+
+// Expectation: An existing target file should be overwritten (or kept) as per the --overwrite policy.
+func Test_Unit_MoveFiles_Overwrite_Table(t *testing.T) {
+	t.Parallel()
+
+	older := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	newer := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	dstTime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		overwrite   string
+		compareBy   string
+		atomicBatch bool
+		dstContent  string
+		srcTime     time.Time
+		wantContent string
+		wantMoved   int
+		wantUnmoved int
+		wantSrcGone bool
+	}{
+		{"never", overwriteNever, "", false, "other", newer, "other", 0, 1, false},
+		{"always", overwriteAlways, "", false, "other", older, "content", 1, 0, true},
+		{"always-atomic-batch", overwriteAlways, "", true, "other", older, "content", 1, 0, true},
+		{"if-newer-newer", overwriteIfNewer, "", false, "other", newer, "content", 1, 0, true},
+		{"if-newer-older", overwriteIfNewer, "", false, "other", older, "other", 0, 1, false},
+		{"if-different-different", overwriteIfDifferent, "", false, "differs", older, "content", 1, 0, true},
+		{"if-different-identical", overwriteIfDifferent, "", false, "content", newer, "content", 0, 0, true},
+		{"if-different-compare-by-size", overwriteIfDifferent, compareBySize, false, "CONTENT", older, "content", 1, 0, true},
+		{"if-different-compare-by-mtime", overwriteIfDifferent, compareByMtime, false, "CONTENT", dstTime, "content", 1, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{
+				"/mirror/file.txt": "content",
+				"/real/file.txt":   tt.dstContent,
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, fs.Chtimes("/mirror/file.txt", tt.srcTime, tt.srcTime))
+			require.NoError(t, fs.Chtimes("/real/file.txt", dstTime, dstTime))
+
+			opts := &programOptions{
+				MirrorRoot:  "/mirror",
+				RealRoot:    "/real",
+				Overwrite:   tt.overwrite,
+				CompareBy:   tt.compareBy,
+				AtomicBatch: tt.atomicBatch,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+			require.Equal(t, tt.wantMoved, prog.state.movedFiles)
+			require.Equal(t, tt.wantUnmoved, prog.state.unmovedFiles)
+
+			content, err := afero.ReadFile(fs, "/real/file.txt")
+			require.NoError(t, err)
+			require.Equal(t, tt.wantContent, string(content))
+
+			_, err = fs.Stat("/mirror/file.txt")
+			if tt.wantSrcGone {
+				require.ErrorIs(t, err, os.ErrNotExist)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// The working file never remains next to the target.
+			_, err = fs.Stat("/real/file.txt" + workingFileSuffix)
+			require.ErrorIs(t, err, os.ErrNotExist)
+
+			if tt.wantMoved == 0 {
+				// A target that is kept (or identical) is never rewritten.
+				info, err := fs.Stat("/real/file.txt")
+				require.NoError(t, err)
+				require.True(t, info.ModTime().Equal(dstTime))
+			}
+		})
+	}
+}
+
+// Expectation: The exclusion decision should follow the documented semantics for all source and target combinations.
+func Test_Unit_CheckExclusion_Table(t *testing.T) {
+	t.Parallel()
+
+	sources := []struct {
+		name    string
+		opts    programOptions
+		reason  string
+		unmoved bool
+	}{
+		{"src-none", programOptions{}, "", false},
+		{"src-path", programOptions{Excludes: excludeArg{"/mirror/a/x"}}, "is_user_excluded", false},
+		{"src-glob", programOptions{ExcludeGlobsMirror: globArg{"/mirror/a/*"}}, "is_user_excluded_mirror_glob", false},
+		{"src-name", programOptions{ExcludeNames: nameArg{"x"}}, "is_user_excluded_name", true},
+	}
+
+	targets := []struct {
+		name   string
+		opts   programOptions
+		reason string
+	}{
+		{"dst-none", programOptions{}, ""},
+		{"dst-path", programOptions{Excludes: excludeArg{"/real/a/x"}}, "is_user_excluded"},
+		{"dst-glob", programOptions{ExcludeGlobsTarget: globArg{"/real/a/*"}}, "is_user_excluded_target_glob"},
+	}
+
+	for _, isDir := range []bool{false, true} {
+		for _, src := range sources {
+			for _, dst := range targets {
+				name := fmt.Sprintf("file/%s/%s", src.name, dst.name)
+				if isDir {
+					name = fmt.Sprintf("dir/%s/%s", src.name, dst.name)
+				}
+
+				t.Run(name, func(t *testing.T) {
+					t.Parallel()
+
+					opts := &programOptions{
+						MirrorRoot:         "/mirror",
+						RealRoot:           "/real",
+						Excludes:           append(append(excludeArg{}, src.opts.Excludes...), dst.opts.Excludes...),
+						ExcludeGlobsMirror: src.opts.ExcludeGlobsMirror,
+						ExcludeGlobsTarget: dst.opts.ExcludeGlobsTarget,
+						ExcludeNames:       src.opts.ExcludeNames,
+					}
+
+					prog, _, _ := setupTestProgram(setupTestFs(), opts)
+					excl, excluded := prog.checkExclusion("/mirror/a/x", "/real/a/x", isDir)
+
+					switch {
+					case src.reason != "":
+						// The source side takes precedence over the target side.
+						require.True(t, excluded)
+						require.Equal(t, "/mirror/a/x", excl.path)
+						require.Equal(t, src.reason, excl.reason)
+						require.Equal(t, src.unmoved && !isDir, excl.unmoved)
+
+					case dst.reason != "":
+						require.True(t, excluded)
+						require.Equal(t, "/real/a/x", excl.path)
+						require.Equal(t, dst.reason, excl.reason)
+						require.False(t, excl.unmoved)
+
+					default:
+						require.False(t, excluded)
+					}
+				})
+			}
+		}
+	}
+}
+
+// Expectation: The move should leave excluded files and directories within the mirror for all source and target combinations.
+func Test_Unit_MoveFiles_ExclusionCombinations_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		excludes    excludeArg
+		names       nameArg
+		excluded    string
+		wantUnmoved int
+	}{
+		{"file-none", nil, nil, "", 0},
+		{"file-src", excludeArg{"/mirror/a/x.txt"}, nil, "/a/x.txt", 0},
+		{"file-src-name", nil, nameArg{"x.txt"}, "/a/x.txt", 1},
+		{"file-dst", excludeArg{"/real/a/x.txt"}, nil, "/a/x.txt", 0},
+		{"file-src-and-dst", excludeArg{"/mirror/a/x.txt", "/real/a/x.txt"}, nil, "/a/x.txt", 0},
+		{"dir-none", nil, nil, "", 0},
+		{"dir-src", excludeArg{"/mirror/a"}, nil, "/a", 0},
+		{"dir-src-name", nil, nameArg{"a"}, "/a", 0},
+		{"dir-dst", excludeArg{"/real/a"}, nil, "/a", 0},
+		{"dir-src-and-dst", excludeArg{"/mirror/a", "/real/a"}, nil, "/a", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{
+				"/mirror/a/x.txt": "content",
+				"/mirror/b/y.txt": "content",
+			})
+			require.NoError(t, err)
+			err = createDirStructure(fs, []string{"/real"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:   "/mirror",
+				RealRoot:     "/real",
+				Excludes:     tt.excludes,
+				ExcludeNames: tt.names,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+
+			require.Equal(t, tt.wantUnmoved, prog.state.unmovedFiles)
+			require.Equal(t, tt.wantUnmoved > 0, prog.state.hasUnmovedFiles)
+
+			// Files that are not excluded are always moved.
+			_, err = fs.Stat("/real/b/y.txt")
+			require.NoError(t, err)
+
+			if tt.excluded == "" {
+				require.Equal(t, 2, prog.state.movedFiles)
+
+				return
+			}
+			require.Equal(t, 1, prog.state.movedFiles)
+
+			_, err = fs.Stat("/real" + tt.excluded)
+			require.ErrorIs(t, err, os.ErrNotExist)
+
+			_, err = fs.Stat("/mirror/a/x.txt")
+			require.NoError(t, err)
+		})
+	}
+}
+
+// Expectation: A file identical to one already moved in the run should be linked or skipped with --dedupe-run.
+func Test_Unit_MoveFiles_DedupeRun_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		dedupeRun   string
+		wantLinks   int
+		wantMoved   int
+		wantUnmoved int
+	}{
+		{"none", dedupeRunNone, 0, 3, 0},
+		{"link", dedupeRunLink, 1, 3, 0},
+		{"skip", dedupeRunSkip, 0, 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{
+				"/mirror/a/file.txt":  "same content",
+				"/mirror/b/file.txt":  "same content",
+				"/mirror/b/other.txt": "other content",
+			})
+			require.NoError(t, err)
+			err = createDirStructure(fs, []string{"/real/a", "/real/b"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot: "/mirror",
+				RealRoot:   "/real",
+				DedupeRun:  tt.dedupeRun,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+
+			var links [][2]string
+			prog.link = func(oldname string, newname string) error {
+				links = append(links, [2]string{oldname, newname})
+
+				// The in-memory filesystem has no links, so the link is simulated by a copy.
+				data, err := afero.ReadFile(fs, oldname)
+				if err != nil {
+					return err
+				}
+
+				return afero.WriteFile(fs, newname, data, 0o644)
+			}
+
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+
+			require.Len(t, links, tt.wantLinks)
+			if tt.wantLinks > 0 {
+				require.Equal(t, [2]string{"/real/a/file.txt", "/real/b/file.txt"}, links[0])
+			}
+			require.Equal(t, tt.wantMoved, prog.state.movedFiles)
+			require.Equal(t, tt.wantUnmoved, prog.state.unmovedFiles)
+			require.Equal(t, tt.wantUnmoved > 0, prog.state.hasUnmovedFiles)
+
+			_, err = fs.Stat("/mirror/b/file.txt")
+			if tt.dedupeRun == dedupeRunSkip {
+				require.NoError(t, err)
+
+				_, err = fs.Stat("/real/b/file.txt")
+				require.ErrorIs(t, err, os.ErrNotExist)
+			} else {
+				require.ErrorIs(t, err, os.ErrNotExist)
+
+				data, err := afero.ReadFile(fs, "/real/b/file.txt")
+				require.NoError(t, err)
+				require.Equal(t, "same content", string(data))
+			}
+
+			// Files differing in content are never deduplicated.
+			_, err = fs.Stat("/real/b/other.txt")
+			require.NoError(t, err)
+		})
+	}
+}
+
+// Expectation: A file should still be moved as usual when it cannot be linked with --dedupe-run.
+func Test_Unit_MoveFiles_DedupeRunLinkFailure_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/a/file.txt": "same content",
+		"/mirror/b/file.txt": "same content",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real/a", "/real/b"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		DedupeRun:  dedupeRunLink,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	prog.link = func(string, string) error {
+		return syscall.EXDEV
+	}
+
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 2, prog.state.movedFiles)
+	require.Contains(t, stderr.String(), "file not linked")
+
+	data, err := afero.ReadFile(fs, "/real/b/file.txt")
+	require.NoError(t, err)
+	require.Equal(t, "same content", string(data))
+}
+
 // Expectation: The program should respect the dry-run mode.
 func Test_Unit_MoveFiles_DryRun_Success(t *testing.T) {
 	t.Parallel()
@@ -463,6 +1163,37 @@ func Test_Unit_MoveFiles_SkipEmptyTrue_Success(t *testing.T) {
 	require.Contains(t, stderr.String(), "skipped")
 }
 
+// Expectation: The function should warn about re-creating empty directories no longer in the target.
+func Test_Unit_MoveFiles_SkipEmptyFalseReinitHint_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/mirror/gone", "/mirror/full", "/real"})
+	require.NoError(t, err)
+	err = createFiles(fs, map[string]string{"/mirror/full/file.txt": "content"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		SkipEmpty:  false,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	// Verify the empty directory was still created.
+	_, err = fs.Stat("/real/gone")
+	require.NoError(t, err)
+
+	// Only the empty directory should carry the advisory warning.
+	require.Contains(t, stderr.String(), "empty directory not in target")
+	require.Contains(t, stderr.String(), "path=/mirror/gone dst=/real/gone")
+	require.NotContains(t, stderr.String(), "path=/mirror/full dst=/real/full")
+}
+
 // Expectation: The function should remove the empty source directories.
 func Test_Unit_MoveFiles_SkipEmptyRemoveEmptyTrue_Success(t *testing.T) {
 	t.Parallel()
@@ -838,6 +1569,59 @@ func Test_Unit_MoveFiles_CtxCancel_Error(t *testing.T) {
 	require.ErrorIs(t, err, os.ErrNotExist)
 }
 
+// Expectation: The function should create only a missing target root with --create-target-root.
+func Test_Unit_MoveFiles_CreateTargetRoot_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		target     string
+		dirs       []string
+		createRoot bool
+		wantErr    error
+	}{
+		{"present", "/real", []string{"/real"}, true, nil},
+		{"absent-with-flag", "/real", nil, true, nil},
+		{"absent-without-flag", "/real", nil, false, errTargetNotExist},
+		{"absent-parent-with-flag", "/vol/real", nil, true, errTargetNotExist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{"/mirror/dir/file.txt": "content"})
+			require.NoError(t, err)
+			err = createDirStructure(fs, tt.dirs)
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:       "/mirror",
+				RealRoot:         tt.target,
+				CreateTargetRoot: tt.createRoot,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				_, err = fs.Stat(tt.target)
+				require.ErrorIs(t, err, os.ErrNotExist)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			_, err = fs.Stat(tt.target + "/dir/file.txt")
+			require.NoError(t, err)
+		})
+	}
+}
+
 // Expectation: The function should not run if the target directory does not exist.
 func Test_Unit_MoveFiles_TargetNotExist_Error(t *testing.T) {
 	t.Parallel()
@@ -945,6 +1729,164 @@ func Test_Unit_CopyAndRemove_Verify_Success(t *testing.T) {
 	require.True(t, prog.opts.Verify)
 }
 
+// truncatingRenameFs is an [afero.Fs] truncating any file after its rename, simulating a failed disk-write.
+type truncatingRenameFs struct {
+	afero.Fs
+}
+
+func (tfs *truncatingRenameFs) Rename(oldname, newname string) error {
+	if err := tfs.Fs.Rename(oldname, newname); err != nil {
+		return err
+	}
+
+	return afero.WriteFile(tfs.Fs, newname, []byte("test"), 0o666)
+}
+
+// Expectation: The function should preserve the source when the target size differs with --stat-before-remove.
+func Test_Unit_CopyAndRemove_StatBeforeRemove_Error(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	files := map[string]string{
+		"/src/file.txt": "test content",
+	}
+	err := createFiles(memFs, files)
+	require.NoError(t, err)
+
+	fs := &truncatingRenameFs{Fs: memFs}
+
+	prog, _, _ := setupTestProgram(fs, nil)
+	prog.opts.StatBeforeRemove = true
+
+	_, err = prog.copyAndRemove(t.Context(), "/src/file.txt", "/dst/file.txt")
+	require.ErrorIs(t, err, errStatSizeMismatch)
+
+	// Verify source is preserved.
+	content, err := afero.ReadFile(memFs, "/src/file.txt")
+	require.NoError(t, err)
+	require.Equal(t, "test content", string(content))
+
+	// Verify the truncated destination was removed.
+	_, err = memFs.Stat("/dst/file.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// unsyncableFs is an [afero.Fs] creating files that do not support syncing.
+type unsyncableFs struct {
+	afero.Fs
+}
+
+type unsyncableFile struct {
+	afero.File
+}
+
+func (ufs *unsyncableFs) Create(name string) (afero.File, error) {
+	f, err := ufs.Fs.Create(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &unsyncableFile{f}, nil
+}
+
+func (uf *unsyncableFile) Sync() error {
+	return &os.PathError{Op: "sync", Path: uf.Name(), Err: syscall.ENOTSUP}
+}
+
+// Expectation: The function should still move the file when syncing is not supported, but warn about it.
+func Test_Unit_CopyAndRemove_SyncNotSupported_Success(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	files := map[string]string{
+		"/src/file.txt": "test content",
+	}
+	err := createFiles(memFs, files)
+	require.NoError(t, err)
+
+	prog, _, stderr := setupTestProgram(&unsyncableFs{Fs: memFs}, nil)
+
+	_, err = prog.copyAndRemove(t.Context(), "/src/file.txt", "/dst/file.txt")
+	require.NoError(t, err)
+
+	// Verify source is removed.
+	_, err = memFs.Stat("/src/file.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// Verify destination exists with correct content.
+	content, err := afero.ReadFile(memFs, "/dst/file.txt")
+	require.NoError(t, err)
+	require.Equal(t, "test content", string(content))
+
+	require.Contains(t, stderr.String(), "sync_not_supported")
+}
+
+// Expectation: The function should produce correct content and hashes with a large copy buffer.
+func Test_Unit_CopyAndRemove_CopyBufferSize_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	content := strings.Repeat("0123456789abcdef", 1<<16) // 1MiB, spanning multiple buffers.
+	err := createFiles(fs, map[string]string{"/src/file.bin": content})
+	require.NoError(t, err)
+
+	prog, _, _ := setupTestProgram(fs, nil)
+	prog.copyBuffers = newCopyBufferPool(256 << 10)
+	prog.opts.Verify = true
+
+	hashes, err := prog.copyAndRemove(t.Context(), "/src/file.bin", "/dst/file.bin")
+	require.NoError(t, err)
+
+	sum := sha256.Sum256([]byte(content))
+	require.Equal(t, hex.EncodeToString(sum[:]), hashes.srcHash)
+	require.Equal(t, hashes.srcHash, hashes.dstHash)
+	require.Equal(t, hashes.srcHash, hashes.verifyHash)
+
+	dstContent, err := afero.ReadFile(fs, "/dst/file.bin")
+	require.NoError(t, err)
+	require.Equal(t, content, string(dstContent))
+}
+
+func Benchmark_CopyAndRemove_CopyBufferSize(b *testing.B) {
+	content := []byte(strings.Repeat("0123456789abcdef", 1<<20)) // 16MiB.
+
+	for _, size := range []string{"", "1MiB", "16MiB"} {
+		name := size
+		if name == "" {
+			name = "default"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			fs := setupTestFs()
+			prog, _, _ := setupTestProgram(fs, nil)
+
+			if size != "" {
+				n, err := parseByteSize(size)
+				if err != nil {
+					b.Fatal(err)
+				}
+				prog.copyBuffers = newCopyBufferPool(n)
+			}
+
+			b.SetBytes(int64(len(content)))
+
+			for b.Loop() {
+				if err := afero.WriteFile(fs, "/src/file.bin", content, 0o666); err != nil {
+					b.Fatal(err)
+				}
+
+				if _, err := prog.copyAndRemove(b.Context(), "/src/file.bin", "/dst/file.bin"); err != nil {
+					b.Fatal(err)
+				}
+
+				if err := fs.Remove("/dst/file.bin"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 // Expectation: The function should overwrite an existing temporary file.
 func Test_Unit_CopyAndRemove_DstTmpFileExists_Success(t *testing.T) {
 	t.Parallel()
@@ -1022,3 +1964,1248 @@ func Test_Unit_CopyAndRemove_CtxCancel_Error(t *testing.T) {
 	_, err = fs.Stat("/dst/file.txt")
 	require.ErrorIs(t, err, os.ErrNotExist)
 }
+
+// Expectation: The function should move files before creating their empty sibling directories.
+func Test_Unit_MoveFiles_MoveOrderLeavesFirst_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	files := map[string]string{
+		"/mirror/b/deep/file.txt": "content",
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	err = createDirStructure(fs, []string{"/mirror/a", "/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		SkipEmpty:  false,
+		MoveOrder:  moveOrderLeavesFirst,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	content, err := afero.ReadFile(fs, "/real/b/deep/file.txt")
+	require.NoError(t, err)
+	require.Equal(t, "content", string(content))
+
+	_, err = fs.Stat("/real/a")
+	require.NoError(t, err)
+
+	require.Equal(t, 3, prog.state.createdDirs)
+	require.Equal(t, 1, prog.state.movedFiles)
+
+	// Verify the file was moved before the empty sibling directory was created.
+	logs := stderr.String()
+	fileIdx := strings.Index(logs, "file moved")
+	dirIdx := strings.Index(logs, "path=/real/a ")
+	require.NotEqual(t, -1, fileIdx)
+	require.NotEqual(t, -1, dirIdx)
+	require.Less(t, fileIdx, dirIdx)
+}
+
+// Expectation: The function should create empty sibling directories before moving files in walk order.
+func Test_Unit_MoveFiles_MoveOrderWalk_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	files := map[string]string{
+		"/mirror/b/deep/file.txt": "content",
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	err = createDirStructure(fs, []string{"/mirror/a", "/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		SkipEmpty:  false,
+		MoveOrder:  moveOrderWalk,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	logs := stderr.String()
+	fileIdx := strings.Index(logs, "file moved")
+	dirIdx := strings.Index(logs, "path=/real/a ")
+	require.NotEqual(t, -1, fileIdx)
+	require.NotEqual(t, -1, dirIdx)
+	require.Greater(t, fileIdx, dirIdx)
+}
+
+// Expectation: The function should not move or delete a source file carrying the working file suffix.
+func Test_Unit_MoveFiles_WorkingFileSuffixSource_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	files := map[string]string{
+		"/mirror/real.mirsht": "real data",
+		"/mirror/file.txt":    "content",
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	// Verify the regular file was moved.
+	_, err = fs.Stat("/real/file.txt")
+	require.NoError(t, err)
+
+	// Verify the suffixed file was neither moved nor deleted.
+	content, err := afero.ReadFile(fs, "/mirror/real.mirsht")
+	require.NoError(t, err)
+	require.Equal(t, "real data", string(content))
+
+	_, err = fs.Stat("/real/real.mirsht")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	require.True(t, prog.state.hasUnmovedFiles)
+	require.Contains(t, stderr.String(), "is_working_file_name")
+}
+
+// Expectation: The function should remove the mirror skeleton after a fully successful move.
+func Test_Unit_MoveFiles_CleanMirrorOnSuccess_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	files := map[string]string{
+		"/mirror/file.txt":          "content",
+		"/mirror/dir/file.txt":      "content2",
+		"/mirror/dir/sub/file.txt":  "content3",
+		"/mirror/dir2/sub/file.txt": "content4",
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	err = createDirStructure(fs, []string{"/real", "/mirror/empty/sub"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:  "/mirror",
+		RealRoot:    "/real",
+		CleanMirror: true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	require.Equal(t, 4, prog.state.movedFiles)
+	require.False(t, prog.state.hasUnmovedFiles)
+
+	_, err = fs.Stat("/real/dir2/sub/file.txt")
+	require.NoError(t, err)
+
+	// Verify the mirror skeleton was removed, but not the mirror root.
+	entries, err := afero.ReadDir(fs, "/mirror")
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+// Expectation: The function should leave any excluded directories (and their parents) in place when removing the mirror skeleton.
+func Test_Unit_MoveFiles_CleanMirrorOnSuccessExcluded_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/dir/file.txt": "content",
+	})
+	require.NoError(t, err)
+
+	err = createDirStructure(fs, []string{"/real", "/mirror/dir/keep/nested", "/mirror/dir/empty"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:  "/mirror",
+		RealRoot:    "/real",
+		Excludes:    []string{"/mirror/dir/keep"},
+		CleanMirror: true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 1, prog.state.movedFiles)
+
+	// Verify only the empty walked directories were removed.
+	_, err = fs.Stat("/mirror/dir/keep/nested")
+	require.NoError(t, err)
+
+	_, err = fs.Stat("/mirror/dir/empty")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The function should not remove the mirror skeleton if files remained unmoved.
+func Test_Unit_MoveFiles_CleanMirrorOnSuccessUnmovedFiles_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	files := map[string]string{
+		"/mirror/dir/file.txt":  "content",
+		"/mirror/dir2/file.txt": "content2",
+		"/real/dir2/file.txt":   "existing",
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:  "/mirror",
+		RealRoot:    "/real",
+		CleanMirror: true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	require.Equal(t, 1, prog.state.movedFiles)
+	require.True(t, prog.state.hasUnmovedFiles)
+
+	// Verify the mirror skeleton was left untouched.
+	_, err = fs.Stat("/mirror/dir")
+	require.NoError(t, err)
+
+	_, err = fs.Stat("/mirror/dir2/file.txt")
+	require.NoError(t, err)
+}
+
+// Expectation: The function should compute the digests of all hashing algorithms from a single read.
+func Test_Unit_CopyAndRemove_HashAlgorithms_Success(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sha256Abc = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+		blake3Abc = "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"
+	)
+
+	fs := setupTestFs()
+	files := map[string]string{
+		"/src/file.txt":  "abc",
+		"/src/file2.txt": "abc",
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	prog, _, _ := setupTestProgram(fs, nil)
+	prog.opts.HashAlgorithms = hashAlgoArg{hashAlgoSHA256, hashAlgoBLAKE3}
+
+	hashes, err := prog.copyAndRemove(t.Context(), "/src/file.txt", "/dst/file.txt")
+	require.NoError(t, err)
+
+	require.Equal(t, []fileDigest{
+		{algo: hashAlgoSHA256, hash: sha256Abc},
+		{algo: hashAlgoBLAKE3, hash: blake3Abc},
+	}, hashes.srcHashes)
+	require.Equal(t, sha256Abc, hashes.srcHash)
+	require.Equal(t, sha256Abc, hashes.dstHash)
+
+	// The first algorithm is the one used for comparisons, also in the verify pass.
+	prog.opts.HashAlgorithms = hashAlgoArg{hashAlgoBLAKE3, hashAlgoSHA256}
+	prog.opts.Verify = true
+
+	hashes, err = prog.copyAndRemove(t.Context(), "/src/file2.txt", "/dst/file2.txt")
+	require.NoError(t, err)
+
+	require.Equal(t, blake3Abc, hashes.srcHash)
+	require.Equal(t, blake3Abc, hashes.dstHash)
+	require.Equal(t, blake3Abc, hashes.verifyHash)
+	require.Equal(t, sha256Abc, hashes.srcHashes[1].hash)
+}
+
+// Expectation: The --checksum-algo should be the primary algorithm, also in the verify pass, and should be logged.
+func Test_Unit_MoveFiles_ChecksumAlgo_Success(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sha256Abc = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+		crc32cAbc = "364b3fb7"
+	)
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{"/mirror/file.txt": "abc"})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:     "/mirror",
+		RealRoot:       "/real",
+		HashAlgorithms: hashAlgoArg{hashAlgoSHA256, hashAlgoCRC32C},
+		ChecksumAlgo:   hashAlgoCRC32C,
+		Verify:         true,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	require.Equal(t, []string{hashAlgoCRC32C, hashAlgoSHA256}, prog.hashAlgorithms())
+
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 1, prog.state.movedFiles)
+
+	require.Contains(t, stderr.String(), "checksumAlgo="+hashAlgoCRC32C+" srcHash="+crc32cAbc+" dstHash="+crc32cAbc+" verifyHash="+crc32cAbc)
+	require.Contains(t, stderr.String(), "srcHashes.sha256="+sha256Abc)
+}
+
+// Expectation: The function should move a zero-byte file with the well-known digests of empty content.
+func Test_Unit_CopyAndRemove_EmptyFile_Success(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sha256Empty = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+		blake3Empty = "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"
+	)
+
+	fs := setupTestFs()
+	files := map[string]string{
+		"/src/file.txt": "",
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	prog, _, _ := setupTestProgram(fs, nil)
+	prog.opts.HashAlgorithms = hashAlgoArg{hashAlgoSHA256, hashAlgoBLAKE3}
+	prog.opts.Verify = true
+
+	hashes, err := prog.copyAndRemove(t.Context(), "/src/file.txt", "/dst/file.txt")
+	require.NoError(t, err)
+
+	require.Equal(t, []fileDigest{
+		{algo: hashAlgoSHA256, hash: sha256Empty},
+		{algo: hashAlgoBLAKE3, hash: blake3Empty},
+	}, hashes.srcHashes)
+	require.Equal(t, sha256Empty, hashes.srcHash)
+	require.Equal(t, sha256Empty, hashes.dstHash)
+	require.Equal(t, sha256Empty, hashes.verifyHash)
+
+	_, err = fs.Stat("/src/file.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	info, err := fs.Stat("/dst/file.txt")
+	require.NoError(t, err)
+	require.Zero(t, info.Size())
+
+	_, err = fs.Stat("/dst/file.txt" + workingFileSuffix)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// failingCreateFs is an [afero.Fs] failing the creation of any file containing the given string.
+type failingCreateFs struct {
+	afero.Fs
+	failOn string
+}
+
+func (ffs *failingCreateFs) Create(name string) (afero.File, error) {
+	if strings.Contains(name, ffs.failOn) {
+		return nil, &os.PathError{Op: "create", Path: name, Err: os.ErrPermission}
+	}
+
+	return ffs.Fs.Create(name)
+}
+
+// Expectation: The function should commit all files of a batch only after all of them were copied.
+func Test_Unit_MoveFiles_AtomicBatch_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	files := map[string]string{
+		"/mirror/file1.txt":     "content",
+		"/mirror/dir/file2.txt": "content2",
+		"/mirror/file3.txt":     "content3",
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:  "/mirror",
+		RealRoot:    "/real",
+		AtomicBatch: true,
+		Verify:      true,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	require.Equal(t, 3, prog.state.movedFiles)
+	require.Empty(t, prog.state.stagedFiles)
+
+	for src, content := range files {
+		dst := strings.Replace(src, "/mirror", "/real", 1)
+
+		data, err := afero.ReadFile(fs, dst)
+		require.NoError(t, err)
+		require.Equal(t, content, string(data))
+
+		_, err = fs.Stat(src)
+		require.ErrorIs(t, err, os.ErrNotExist)
+
+		_, err = fs.Stat(dst + workingFileSuffix)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	}
+
+	// Verify all files were staged before the first one was committed.
+	log := stderr.String()
+	require.Less(t, strings.LastIndex(log, "file staged"), strings.Index(log, "file moved"))
+}
+
+// Expectation: The function should commit none of a batch where a copy failed mid-batch.
+func Test_Unit_MoveFiles_AtomicBatchCopyFailure_Error(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	files := map[string]string{
+		"/mirror/file1.txt": "content",
+		"/mirror/file2.txt": "content2",
+		"/mirror/file3.txt": "content3",
+	}
+	err := createFiles(memFs, files)
+	require.NoError(t, err)
+
+	err = createDirStructure(memFs, []string{"/real"})
+	require.NoError(t, err)
+
+	fs := &failingCreateFs{Fs: memFs, failOn: "file2.txt"}
+
+	opts := &programOptions{
+		MirrorRoot:  "/mirror",
+		RealRoot:    "/real",
+		AtomicBatch: true,
+		SkipFailed:  true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.ErrorIs(t, err, os.ErrPermission)
+
+	require.Equal(t, 0, prog.state.movedFiles)
+	require.Empty(t, prog.state.stagedFiles)
+
+	// Verify that nothing was committed and no working files were left behind.
+	entries, err := afero.ReadDir(memFs, "/real")
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	for src := range files {
+		_, err = memFs.Stat(src)
+		require.NoError(t, err)
+	}
+}
+
+// failingOpenFs is an [afero.Fs] failing the given number of openings of the given file.
+type failingOpenFs struct {
+	afero.Fs
+	failOn   string
+	failures int
+}
+
+func (ffs *failingOpenFs) Open(name string) (afero.File, error) {
+	if name == ffs.failOn && ffs.failures > 0 {
+		ffs.failures--
+
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EIO}
+	}
+
+	return ffs.Fs.Open(name)
+}
+
+// Expectation: The function should handle read errors of the --verify pass according to --verify-read-error.
+func Test_Unit_MoveFiles_VerifyReadError_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		verifyReadError string
+		failures        int
+		wantErr         bool
+		wantLog         string
+	}{
+		{"fail", verifyReadErrorFail, 1, true, ""},
+		{"retry-recovered", verifyReadErrorRetry, 1, false, "verify read failed"},
+		{"retry-exhausted", verifyReadErrorRetry, verifyReadRetries + 1, true, "verify read failed"},
+		{"skip", verifyReadErrorSkip, 1, false, "file not verified"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			memFs := setupTestFs()
+			err := createFiles(memFs, map[string]string{"/mirror/file.txt": "content"})
+			require.NoError(t, err)
+			err = createDirStructure(memFs, []string{"/real"})
+			require.NoError(t, err)
+
+			fs := &failingOpenFs{Fs: memFs, failOn: "/real/file.txt", failures: tt.failures}
+
+			opts := &programOptions{
+				MirrorRoot:      "/mirror",
+				RealRoot:        "/real",
+				Verify:          true,
+				VerifyReadError: tt.verifyReadError,
+			}
+
+			prog, _, stderr := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, syscall.EIO)
+
+				// Verify the source is left intact.
+				_, err = memFs.Stat("/mirror/file.txt")
+				require.NoError(t, err)
+			} else {
+				require.NoError(t, err)
+
+				_, err = memFs.Stat("/mirror/file.txt")
+				require.ErrorIs(t, err, os.ErrNotExist)
+
+				content, err := afero.ReadFile(memFs, "/real/file.txt")
+				require.NoError(t, err)
+				require.Equal(t, "content", string(content))
+			}
+
+			if tt.wantLog != "" {
+				require.Contains(t, stderr.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+// Expectation: The function should move and verify all files with --verify-concurrency.
+func Test_Unit_MoveFiles_VerifyConcurrency_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	files := make(map[string]string)
+	for i := range 50 {
+		files[fmt.Sprintf("/mirror/dir%d/file%d.txt", i%5, i)] = strings.Repeat("x", i)
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:        "/mirror",
+		RealRoot:          "/real",
+		Verify:            true,
+		VerifyConcurrency: 4,
+		StatBeforeRemove:  true,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Nil(t, prog.state.verifyPool)
+	require.Equal(t, len(files), prog.state.movedFiles)
+	require.Equal(t, len(files), strings.Count(stderr.String(), "verifyHash="))
+
+	for path, content := range files {
+		_, err = fs.Stat(path)
+		require.ErrorIs(t, err, os.ErrNotExist, path)
+
+		data, err := afero.ReadFile(fs, strings.Replace(path, "/mirror", "/real", 1))
+		require.NoError(t, err, path)
+		require.Equal(t, content, string(data), path)
+	}
+}
+
+// Expectation: The function should keep the source of a file failing its verify pass with --verify-concurrency.
+func Test_Unit_MoveFiles_VerifyConcurrencyFailure_Success(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	err := createFiles(memFs, map[string]string{
+		"/mirror/file1.txt": "content1",
+		"/mirror/file2.txt": "content2",
+		"/mirror/file3.txt": "content3",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(memFs, []string{"/real"})
+	require.NoError(t, err)
+
+	fs := &failingOpenFs{Fs: memFs, failOn: "/real/file2.txt", failures: 1}
+
+	opts := &programOptions{
+		MirrorRoot:        "/mirror",
+		RealRoot:          "/real",
+		Verify:            true,
+		VerifyConcurrency: 2,
+		SkipFailed:        true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.True(t, prog.state.hasPartialFailures)
+	require.Equal(t, 2, prog.state.movedFiles)
+
+	// The failed file is left in the mirror only.
+	_, err = memFs.Stat("/mirror/file2.txt")
+	require.NoError(t, err)
+	_, err = memFs.Stat("/real/file2.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	for _, name := range []string{"file1.txt", "file3.txt"} {
+		_, err = memFs.Stat("/mirror/" + name)
+		require.ErrorIs(t, err, os.ErrNotExist)
+		_, err = memFs.Stat("/real/" + name)
+		require.NoError(t, err)
+	}
+}
+
+// sleepingRunner is a [commandRunner] that only sleeps, slowing down each move.
+type sleepingRunner struct {
+	delay time.Duration
+}
+
+func (r sleepingRunner) Run(context.Context, string, ...string) error {
+	time.Sleep(r.delay)
+
+	return nil
+}
+
+// Expectation: The function should periodically log the throughput with --stats-interval.
+func Test_Unit_MoveFiles_StatsInterval_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	files := make(map[string]string)
+	for i := range 10 {
+		files[fmt.Sprintf("/mirror/file%d.txt", i)] = "content"
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:      "/mirror",
+		RealRoot:        "/real",
+		PostMoveCommand: "true",
+		StatsInterval:   10 * time.Millisecond,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	prog.runner = sleepingRunner{delay: 5 * time.Millisecond}
+	prog.log = slog.New(slog.NewJSONHandler(stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, len(files), prog.state.movedFiles)
+
+	logs := stderr.String()
+	require.Contains(t, logs, `"msg":"throughput"`)
+
+	// The rates are logged as numbers, not as formatted strings.
+	for _, line := range strings.Split(strings.TrimSpace(logs), "\n") {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+
+		if entry["msg"] == "throughput" {
+			require.IsType(t, float64(0), entry["files_per_sec"])
+			require.IsType(t, float64(0), entry["mb_per_sec"])
+		}
+	}
+
+	// No more throughput is logged after the move has completed.
+	count := strings.Count(stderr.String(), `"msg":"throughput"`)
+	time.Sleep(30 * time.Millisecond)
+	require.Equal(t, count, strings.Count(stderr.String(), `"msg":"throughput"`))
+}
+
+// interruptingFs is an [afero.Fs] calling the given function on opening the given file.
+type interruptingFs struct {
+	afero.Fs
+	onOpen    string
+	interrupt func()
+}
+
+func (ifs *interruptingFs) Open(name string) (afero.File, error) {
+	if name == ifs.onOpen {
+		ifs.interrupt()
+	}
+
+	return ifs.Fs.Open(name)
+}
+
+// Expectation: The function should finish the in-flight file, but then stop with --graceful-interrupt.
+func Test_Unit_MoveFiles_GracefulInterrupt_Error(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	err := createFiles(memFs, map[string]string{
+		"/mirror/a.txt": "content a",
+		"/mirror/b.txt": "content b",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(memFs, []string{"/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:        "/mirror",
+		RealRoot:          "/real",
+		GracefulInterrupt: true,
+	}
+
+	fs := &interruptingFs{Fs: memFs, onOpen: "/mirror/a.txt"}
+	prog, _, _ := setupTestProgram(fs, opts)
+
+	// The signal arrives while the first file is being copied.
+	fs.interrupt = func() { prog.state.interrupted.Store(true) }
+
+	err = prog.moveFiles(t.Context())
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, err, errGracefulInterrupt)
+
+	// The in-flight file was moved in full.
+	content, err := afero.ReadFile(memFs, "/real/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, "content a", string(content))
+	_, err = memFs.Stat("/mirror/a.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// The next file was not moved anymore.
+	_, err = memFs.Stat("/real/b.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = memFs.Stat("/mirror/b.txt")
+	require.NoError(t, err)
+
+	require.Equal(t, 1, prog.state.movedFiles)
+}
+
+// Expectation: An existing working file should be skipped with --no-clobber-working-file, but overwritten without it.
+func Test_Unit_MoveFiles_NoClobberWorkingFile_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		noClobber bool
+	}{
+		{"strict", true},
+		{"default", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{
+				"/mirror/file.txt":                   "test content",
+				"/real/file.txt" + workingFileSuffix: "concurrent",
+			})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:           "/mirror",
+				RealRoot:             "/real",
+				NoClobberWorkingFile: tt.noClobber,
+			}
+
+			prog, _, stderr := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+			require.False(t, prog.state.hasPartialFailures)
+
+			_, srcErr := fs.Stat("/mirror/file.txt")
+			_, dstErr := fs.Stat("/real/file.txt")
+
+			if tt.noClobber {
+				require.True(t, prog.state.hasUnmovedFiles)
+				require.Equal(t, 1, prog.state.unmovedFiles)
+				require.Contains(t, stderr.String(), "working_file_exists")
+
+				content, err := afero.ReadFile(fs, "/real/file.txt"+workingFileSuffix)
+				require.NoError(t, err)
+				require.Equal(t, "concurrent", string(content))
+
+				require.NoError(t, srcErr)
+				require.ErrorIs(t, dstErr, os.ErrNotExist)
+			} else {
+				require.False(t, prog.state.hasUnmovedFiles)
+
+				_, err := fs.Stat("/real/file.txt" + workingFileSuffix)
+				require.ErrorIs(t, err, os.ErrNotExist)
+
+				require.ErrorIs(t, srcErr, os.ErrNotExist)
+				require.NoError(t, dstErr)
+			}
+		})
+	}
+}
+
+// Expectation: A target path that changed between directory and file should be handled as per --type-change.
+func Test_Unit_MoveFiles_TypeChange_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		mirrorFile map[string]string
+		realFile   map[string]string
+		realDirs   []string
+		srcPath    string
+		policy     string
+	}{
+		{"dir-to-file-skip", map[string]string{"/mirror/x/file.txt": "content"}, map[string]string{"/real/x": "now a file"}, nil, "/mirror/x/file.txt", typeChangeSkip},
+		{"dir-to-file-fail", map[string]string{"/mirror/x/file.txt": "content"}, map[string]string{"/real/x": "now a file"}, nil, "/mirror/x/file.txt", typeChangeFail},
+		{"file-to-dir-skip", map[string]string{"/mirror/x": "content"}, nil, []string{"/real/x"}, "/mirror/x", typeChangeSkip},
+		{"file-to-dir-fail", map[string]string{"/mirror/x": "content"}, nil, []string{"/real/x"}, "/mirror/x", typeChangeFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			require.NoError(t, createDirStructure(fs, append([]string{"/mirror", "/real"}, tt.realDirs...)))
+			require.NoError(t, createFiles(fs, tt.mirrorFile))
+			require.NoError(t, createFiles(fs, tt.realFile))
+
+			opts := &programOptions{
+				Mode:       "move",
+				MirrorRoot: "/mirror",
+				RealRoot:   "/real",
+				TypeChange: tt.policy,
+			}
+
+			prog, _, stderr := setupTestProgram(fs, opts)
+			err := prog.moveFiles(t.Context())
+
+			if tt.policy == typeChangeSkip {
+				require.NoError(t, err)
+				require.True(t, prog.state.hasUnmovedFiles)
+				require.Contains(t, stderr.String(), "type_changed")
+			} else {
+				require.ErrorIs(t, err, errTargetTypeChanged)
+			}
+
+			// The source always remains within the mirror, and the target is left untouched.
+			_, err = fs.Stat(tt.srcPath)
+			require.NoError(t, err)
+
+			info, err := fs.Stat("/real/x")
+			require.NoError(t, err)
+			require.Equal(t, tt.realDirs != nil, info.IsDir())
+			require.Zero(t, prog.state.movedFiles)
+		})
+	}
+}
+
+// Expectation: The move should fail before any files are moved if the target is not writable with --probe-writable.
+func Test_Unit_MoveFiles_ProbeWritable_Error(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	err := createFiles(memFs, map[string]string{
+		"/mirror/file.txt": "test content",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(memFs, []string{"/real"})
+	require.NoError(t, err)
+
+	fs := &failingCreateFs{Fs: memFs, failOn: "/real/"}
+
+	opts := &programOptions{
+		Mode:          "move",
+		MirrorRoot:    "/mirror",
+		RealRoot:      "/real",
+		ProbeWritable: true,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.ErrorIs(t, err, errTargetNotWritable)
+	require.ErrorIs(t, err, os.ErrPermission)
+	require.NotContains(t, stderr.String(), "path skipped")
+
+	_, err = memFs.Stat("/mirror/file.txt")
+	require.NoError(t, err)
+	require.Zero(t, prog.state.movedFiles)
+}
+
+// Expectation: The probe file should be removed again from a writable target with --probe-writable.
+func Test_Unit_MoveFiles_ProbeWritable_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/file.txt": "test content",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		Mode:          "move",
+		MirrorRoot:    "/mirror",
+		RealRoot:      "/real",
+		ProbeWritable: true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 1, prog.state.movedFiles)
+
+	_, err = fs.Stat("/real/" + writableProbeName + workingFileSuffix)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The move should be aborted once more failures than --skip-failed-max were skipped.
+func Test_Unit_MoveFiles_SkipFailedMax_Error(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+
+	files := make(map[string]string)
+	for i := range 10 {
+		files[fmt.Sprintf("/mirror/file%d.txt", i)] = "content"
+	}
+	err := createFiles(memFs, files)
+	require.NoError(t, err)
+	err = createDirStructure(memFs, []string{"/real"})
+	require.NoError(t, err)
+
+	// The working file of every file fails to be created, as with a target gone read-only.
+	fs := &failingCreateFs{Fs: memFs, failOn: workingFileSuffix}
+
+	opts := &programOptions{
+		Mode:          "move",
+		MirrorRoot:    "/mirror",
+		RealRoot:      "/real",
+		SkipFailed:    true,
+		SkipFailedMax: 3,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.ErrorIs(t, err, errSkipFailedMax)
+	require.ErrorIs(t, err, os.ErrPermission)
+
+	require.Len(t, prog.state.failures, opts.SkipFailedMax+1)
+	require.Zero(t, prog.state.movedFiles)
+
+	for path := range files {
+		_, err = memFs.Stat(path)
+		require.NoError(t, err)
+	}
+}
+
+// slowReadFs is an [afero.Fs] opening the given file for slow reads, one byte after each delay.
+type slowReadFs struct {
+	afero.Fs
+	slowOn string
+	delay  time.Duration
+}
+
+type slowReadFile struct {
+	afero.File
+	delay time.Duration
+}
+
+func (sfs *slowReadFs) Open(name string) (afero.File, error) {
+	f, err := sfs.Fs.Open(name)
+	if err != nil || name != sfs.slowOn {
+		return f, err
+	}
+
+	return &slowReadFile{File: f, delay: sfs.delay}, nil
+}
+
+func (sf *slowReadFile) Read(p []byte) (int, error) {
+	time.Sleep(sf.delay)
+
+	return sf.File.Read(p[:min(len(p), 1)])
+}
+
+// Expectation: A file exceeding the --per-file-timeout should be failed and left unmoved, while the others are moved, on any of the copy paths.
+func Test_Unit_MoveFiles_PerFileTimeout_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		opts      programOptions
+		wantMoved int
+	}{
+		{"copy-and-remove", programOptions{}, 1},
+		{"verify-concurrency", programOptions{Verify: true, VerifyConcurrency: 2}, 1},
+		{"atomic-batch", programOptions{AtomicBatch: true}, 0}, // Any failed copy fails the entire batch.
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			memFs := setupTestFs()
+			err := createFiles(memFs, map[string]string{
+				"/mirror/fast.txt": "fast content",
+				"/mirror/slow.txt": "slow content",
+			})
+			require.NoError(t, err)
+			err = createDirStructure(memFs, []string{"/real"})
+			require.NoError(t, err)
+
+			fs := &slowReadFs{Fs: memFs, slowOn: "/mirror/slow.txt", delay: 20 * time.Millisecond}
+
+			opts := tt.opts
+			opts.Mode = "move"
+			opts.MirrorRoot = "/mirror"
+			opts.RealRoot = "/real"
+			opts.SkipFailed = true
+			opts.PerFileTimeout = 50 * time.Millisecond
+
+			prog, _, _ := setupTestProgram(fs, &opts)
+			err = prog.moveFiles(t.Context())
+
+			if opts.AtomicBatch {
+				require.ErrorIs(t, err, errPerFileTimeout)
+			} else {
+				require.NoError(t, err)
+				require.True(t, prog.state.hasPartialFailures)
+				require.Len(t, prog.state.failures, 1)
+				err = prog.state.failures[0].err
+			}
+			require.ErrorIs(t, err, errPerFileTimeout)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			require.Equal(t, tt.wantMoved, prog.state.movedFiles)
+
+			if tt.wantMoved > 0 {
+				content, err := afero.ReadFile(memFs, "/real/fast.txt")
+				require.NoError(t, err)
+				require.Equal(t, "fast content", string(content))
+			}
+
+			// The slow file is left in place, with its working file cleaned up.
+			_, err = memFs.Stat("/mirror/slow.txt")
+			require.NoError(t, err)
+			_, err = memFs.Stat("/real/slow.txt")
+			require.ErrorIs(t, err, os.ErrNotExist)
+			_, err = memFs.Stat("/real/slow.txt" + workingFileSuffix)
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
+
+// Expectation: The created target directories should inherit the permissions of their parent with --inherit-parent-perms.
+func Test_Unit_MoveFiles_InheritParentPerms_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		moveOrder string
+		inherit   bool
+		wantPerm  os.FileMode
+	}{
+		{"walk", moveOrderWalk, true, 0o750},
+		{"leaves-first", moveOrderLeavesFirst, true, 0o750},
+		{"not-inherited", moveOrderWalk, false, dirBasePerm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{
+				"/mirror/archive/new/sub/file.txt": "test content",
+			})
+			require.NoError(t, err)
+			err = createDirStructure(fs, []string{"/real/archive"})
+			require.NoError(t, err)
+			require.NoError(t, fs.Chmod("/real/archive", 0o750))
+
+			opts := &programOptions{
+				Mode:               "move",
+				MirrorRoot:         "/mirror",
+				RealRoot:           "/real",
+				MoveOrder:          tt.moveOrder,
+				InheritParentPerms: tt.inherit,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+
+			for _, dir := range []string{"/real/archive/new", "/real/archive/new/sub"} {
+				info, err := fs.Stat(dir)
+				require.NoError(t, err)
+				require.True(t, info.IsDir())
+				require.Equal(t, tt.wantPerm, info.Mode().Perm(), dir)
+			}
+
+			_, err = fs.Stat("/real/archive/new/sub/file.txt")
+			require.NoError(t, err)
+		})
+	}
+}
+
+// Expectation: The write permissions of each promoted file should be removed with --lock-promoted, leaving unmoved sources untouched.
+func Test_Unit_MoveFiles_LockPromoted_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		direct bool
+		verify bool
+		dryRun bool
+	}{
+		{"copy", false, false, false},
+		{"copy-verify", false, true, false},
+		{"direct", true, false, false},
+		{"dry-run", false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{
+				"/mirror/dir/file.txt":     "test content",
+				"/mirror/dir/conflict.txt": "new content",
+				"/real/dir/conflict.txt":   "old content",
+			})
+			require.NoError(t, err)
+			require.NoError(t, fs.Chmod("/mirror/dir/file.txt", 0o664))
+			require.NoError(t, fs.Chmod("/mirror/dir/conflict.txt", 0o664))
+
+			opts := &programOptions{
+				Mode:         "move",
+				MirrorRoot:   "/mirror",
+				RealRoot:     "/real",
+				Direct:       tt.direct,
+				Verify:       tt.verify,
+				DryRun:       tt.dryRun,
+				LockPromoted: true,
+			}
+
+			prog, _, stderr := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+			require.Contains(t, stderr.String(), `msg="file locked" op=move path=/real/dir/file.txt`)
+			require.NotContains(t, stderr.String(), "path=/real/dir/conflict.txt")
+
+			if tt.dryRun {
+				_, err = fs.Stat("/real/dir/file.txt")
+				require.ErrorIs(t, err, os.ErrNotExist)
+
+				info, err := fs.Stat("/mirror/dir/file.txt")
+				require.NoError(t, err)
+				require.Equal(t, os.FileMode(0o664), info.Mode().Perm())
+			} else {
+				info, err := fs.Stat("/real/dir/file.txt")
+				require.NoError(t, err)
+				require.Zero(t, info.Mode().Perm()&0o222)
+
+				if tt.direct {
+					// A renamed file keeps all of its other permissions.
+					require.Equal(t, os.FileMode(0o444), info.Mode().Perm())
+				}
+			}
+
+			// The unmoved (conflicting) source remains as it was within the mirror.
+			info, err := fs.Stat("/mirror/dir/conflict.txt")
+			require.NoError(t, err)
+			require.Equal(t, os.FileMode(0o664), info.Mode().Perm())
+		})
+	}
+}
+
+// Expectation: A directory absent from the target should be renamed as a whole, unless any of its contents would not be moved.
+func Test_Unit_MoveFiles_BulkRenameDirs_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		excluded bool
+		bulk     bool
+	}{
+		{"bulk", false, true},
+		{"excluded-descendant", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			files := map[string]string{
+				"/mirror/new/a.txt":     "content",
+				"/mirror/new/sub/b.txt": "content",
+			}
+			if tt.excluded {
+				files["/mirror/new/sub/skip.txt"] = "content"
+			}
+			err := createFiles(fs, files)
+			require.NoError(t, err)
+			err = createDirStructure(fs, []string{"/real"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:     "/mirror",
+				RealRoot:       "/real",
+				ExcludeNames:   nameArg{"skip.txt"},
+				BulkRenameDirs: true,
+			}
+
+			prog, _, stderr := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+
+			require.Equal(t, 2, prog.state.movedFiles)
+			require.Equal(t, 2, prog.state.createdDirs)
+			require.Equal(t, tt.bulk, strings.Contains(stderr.String(), "mode=bulk"))
+			require.Equal(t, 2, strings.Count(stderr.String(), "file moved"))
+
+			for _, path := range []string{"/real/new/a.txt", "/real/new/sub/b.txt"} {
+				_, err = fs.Stat(path)
+				require.NoError(t, err, path)
+			}
+
+			if tt.excluded {
+				_, err = fs.Stat("/mirror/new/sub/skip.txt")
+				require.NoError(t, err)
+
+				_, err = fs.Stat("/real/new/sub/skip.txt")
+				require.ErrorIs(t, err, os.ErrNotExist)
+			}
+
+			// The mirror directories remain, only the files were moved out of them.
+			for _, path := range []string{"/mirror/new", "/mirror/new/sub"} {
+				info, err := fs.Stat(path)
+				require.NoError(t, err, path)
+				require.True(t, info.IsDir(), path)
+			}
+
+			for _, path := range []string{"/mirror/new/a.txt", "/mirror/new/sub/b.txt"} {
+				_, err = fs.Stat(path)
+				require.ErrorIs(t, err, os.ErrNotExist, path)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// deviceFs is an [afero.Fs] that reports the given paths as residing on another device.
+type deviceFs struct {
+	afero.Fs
+	devices map[string]uint64
+}
+
+func (dfs *deviceFs) Stat(name string) (os.FileInfo, error) {
+	e, err := dfs.Fs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return fakeFileInfo{
+		name:    filepath.Base(name),
+		size:    e.Size(),
+		mode:    e.Mode(),
+		modTime: e.ModTime(),
+		isDir:   e.IsDir(),
+		sys:     &syscall.Stat_t{Dev: dfs.devices[name]},
+	}, nil
+}
+
+// Expectation: The function should only clear a mirror that is a mount point, unless allowed otherwise.
+func Test_Unit_CreateMirrorStructure_MountPointMirror_Success(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		allow       bool
+		wantCleared bool
+	}{
+		{"cleared", false, true},
+		{"allowed", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			memFs := setupTestFs()
+			err := createDirStructure(memFs, []string{"/real/dir", "/mirror/stale"})
+			require.NoError(t, err)
+
+			fs := &deviceFs{Fs: memFs, devices: map[string]uint64{"/mirror": 2}}
+
+			opts := &programOptions{
+				MirrorRoot:            "/mirror",
+				RealRoot:              "/real",
+				InitDepth:             -1,
+				AllowMountpointMirror: tt.allow,
+			}
+
+			prog, _, stderr := setupTestProgram(fs, opts)
+			err = prog.createMirrorStructure(t.Context())
+			require.NoError(t, err)
+
+			_, err = memFs.Stat("/mirror/dir")
+			require.NoError(t, err)
+
+			_, err = memFs.Stat("/mirror/stale")
+			require.ErrorIs(t, err, os.ErrNotExist)
+
+			if tt.wantCleared {
+				require.Contains(t, stderr.String(), "is_mount_point")
+				require.NotContains(t, stderr.String(), "mirror directory removed")
+			} else {
+				require.NotContains(t, stderr.String(), "is_mount_point")
+				require.Contains(t, stderr.String(), "mirror directory removed")
+			}
+		})
+	}
+}
+
+// Expectation: The function should fail without clearing anything of a mount point mirror that cannot be cleared in full.
+func Test_Unit_CreateMirrorStructure_MountPointMirrorNotRemovable_Error(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	err := createDirStructure(memFs, []string{"/real/dir", "/mirror/a/b", "/mirror/c"})
+	require.NoError(t, err)
+
+	fs := &deviceFs{Fs: &readOnlyDirFs{Fs: memFs, dir: "/mirror/a"}, devices: map[string]uint64{"/mirror": 2}}
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		InitDepth:  -1,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.createMirrorStructure(t.Context())
+	require.ErrorIs(t, err, errMirrorNotRemovable)
+	require.Contains(t, err.Error(), `"/mirror/a"`)
+	require.NotContains(t, stderr.String(), "mirror directory cleared")
+
+	// Verify the mirror was not left partially cleared.
+	for _, dir := range []string{"/mirror/a/b", "/mirror/c"} {
+		_, err = memFs.Stat(dir)
+		require.NoError(t, err, dir)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// fileDevice returns the device that a file resides on, if it can be known.
+func fileDevice(_ os.FileInfo) (uint64, bool) {
+	return 0, false
+}
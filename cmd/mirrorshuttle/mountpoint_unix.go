@@ -0,0 +1,18 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileDevice returns the device that a file resides on, if it can be known.
+func fileDevice(e os.FileInfo) (uint64, bool) {
+	st, ok := e.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(st.Dev), true //nolint:unconvert // The type differs between platforms.
+}
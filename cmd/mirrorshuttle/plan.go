@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	planOpMkdir = "mkdir"
+	planOpMove  = "move"
+	planOpSkip  = "skip"
+
+	planFilePerm = 0o644
+)
+
+// movePlan is the structured plan of a (dry) run in move mode, as written out
+// with --plan-out and executed again (after approval) with --plan-in.
+type movePlan struct {
+	Mirror     string          `json:"mirror"`
+	Target     string          `json:"target"`
+	Operations []planOperation `json:"operations"`
+}
+
+// planOperation is a single operation of a [movePlan], in order of execution.
+type planOperation struct {
+	Op     string    `json:"op"`
+	Src    string    `json:"src,omitempty"`
+	Dst    string    `json:"dst"`
+	Size   int64     `json:"size,omitempty"`
+	Mtime  time.Time `json:"mtime,omitzero"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// printReproduciblePlan outputs the operations that were recorded during a
+// dry run (with --dry-run-reproducible) to standard output, as sorted lines
+// without any timestamps, so that the previews of two runs can be diffed.
+func (prog *program) printReproduciblePlan() {
+	lines := make([]string, 0, len(prog.state.plannedOps))
+
+	for _, op := range prog.state.plannedOps {
+		lines = append(lines, prog.planLine(op))
+	}
+
+	slices.Sort(lines)
+
+	for _, line := range lines {
+		fmt.Fprintln(prog.stdout, line)
+	}
+}
+
+// printListPlan outputs the operations that were recorded during a dry run
+// (with --list-plan-only) to standard output, as lines in the order in which
+// they were decided, including the skipped paths with their reasons.
+func (prog *program) printListPlan() {
+	for _, op := range prog.state.plannedOps {
+		fmt.Fprintln(prog.stdout, prog.planLine(op))
+	}
+}
+
+// planLine formats a recorded operation as a single line of output, encoding
+// any paths that are not valid UTF-8 as per the --path-encoding.
+func (prog *program) planLine(op planOperation) string {
+	src := encodeInvalidUTF8(op.Src, prog.opts.PathEncoding)
+	dst := encodeInvalidUTF8(op.Dst, prog.opts.PathEncoding)
+
+	switch op.Op {
+	case planOpMkdir:
+		return fmt.Sprintf("%s %s", planOpMkdir, dst)
+	case planOpSkip:
+		return fmt.Sprintf("%s %s (%s)", planOpSkip, src, op.Reason)
+	default:
+		return fmt.Sprintf("%s %s -> %s", planOpMove, src, dst)
+	}
+}
+
+// recordPlan records an operation for the plan that is written out with
+// --plan-out (or --dry-run-reproducible, --list-plan-only), it does nothing
+// if no plan is to be written out.
+func (prog *program) recordPlan(op planOperation) {
+	if prog.opts.PlanOut == "" && !prog.opts.DryRunReproducible && !prog.opts.ListPlanOnly {
+		return
+	}
+
+	prog.state.plannedOps = append(prog.state.plannedOps, op)
+}
+
+// recordSkip records a skipped path for the list that is output with
+// --list-plan-only and for the --report, it does nothing otherwise (the skips
+// are not part of the plans that can be executed again with --plan-in).
+func (prog *program) recordSkip(path string, reason string) {
+	if prog.opts.Report != "" {
+		prog.state.skippedPaths = append(prog.state.skippedPaths, reportPath{Path: path, Reason: reason})
+	}
+
+	if !prog.opts.ListPlanOnly {
+		return
+	}
+
+	prog.state.plannedOps = append(prog.state.plannedOps, planOperation{Op: planOpSkip, Src: path, Reason: reason})
+}
+
+func (prog *program) writePlan() error {
+	plan := movePlan{
+		Mirror: prog.opts.MirrorRoot,
+		Target: prog.opts.RealRoot,
+		// The skips (with --list-plan-only) are only listed, they are not operations to execute.
+		Operations: slices.DeleteFunc(slices.Clone(prog.state.plannedOps), func(op planOperation) bool {
+			return op.Op == planOpSkip
+		}),
+	}
+
+	if plan.Operations == nil {
+		plan.Operations = []planOperation{}
+	}
+
+	out, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+
+	if err := afero.WriteFile(prog.fsys, prog.opts.PlanOut, append(out, '\n'), planFilePerm); err != nil {
+		return fmt.Errorf("failed to write: %q (%w)", prog.opts.PlanOut, err)
+	}
+	prog.log.Info("plan written", "op", prog.opts.Mode, "path", prog.opts.PlanOut, "operations", len(plan.Operations))
+
+	return nil
+}
+
+func (prog *program) readPlan(path string) (*movePlan, error) {
+	data, err := afero.ReadFile(prog.fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errPlanMissing, err)
+	}
+
+	var plan movePlan
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&plan); err != nil {
+		return nil, fmt.Errorf("%w: %w", errPlanMalformed, err)
+	}
+
+	return &plan, nil
+}
+
+// applyPlan executes exactly the operations of an approved plan. All of the
+// operations are checked against the filesystem before any of them are
+// executed, so that nothing is done at all for a plan that has gone stale.
+func (prog *program) applyPlan(ctx context.Context) error {
+	plan, err := prog.readPlan(prog.opts.PlanIn)
+	if err != nil {
+		return err
+	}
+
+	if plan.Mirror != prog.opts.MirrorRoot || plan.Target != prog.opts.RealRoot {
+		return fmt.Errorf("%w: planned for %q -> %q", errPlanStale, plan.Mirror, plan.Target)
+	}
+
+	var totalFiles int
+	var totalBytes int64
+	for _, op := range plan.Operations {
+		if err := prog.checkPlanOperation(op); err != nil {
+			return err
+		}
+		if op.Op == planOpMove {
+			totalFiles++
+			totalBytes += op.Size
+		}
+	}
+	prog.setProgressTotals(totalFiles, totalBytes)
+
+	prog.log.Info("plan checked; executing...", "op", prog.opts.Mode, "path", prog.opts.PlanIn, "operations", len(plan.Operations))
+
+	for _, op := range plan.Operations {
+		if err := prog.checkInterrupt(ctx); err != nil {
+			// An interrupt was received, so we also interrupt the plan.
+			return err
+		}
+
+		switch op.Op {
+		case planOpMkdir:
+			if err := prog.createDir(op.Dst); err != nil {
+				return err
+			}
+
+		case planOpMove:
+			e, err := prog.lstat(op.Src)
+			if err != nil {
+				return fmt.Errorf("failed to lstat: %q (%w)", op.Src, err)
+			}
+
+			if err := prog.moveFile(ctx, op.Src, op.Dst, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkPlanOperation checks that a planned operation stays within the mirror
+// and target roots, and that the filesystem has not diverged from the plan.
+func (prog *program) checkPlanOperation(op planOperation) error {
+	if !isWithinRoot(op.Dst, prog.opts.RealRoot) {
+		return fmt.Errorf("%w: %q is outside of --target", errPlanMalformed, op.Dst)
+	}
+
+	if _, err := prog.fsys.Stat(op.Dst); err == nil {
+		return fmt.Errorf("%w: %q (%s)", errPlanStale, op.Dst, "dst_already_exists")
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to stat: %q (%w)", op.Dst, err)
+	}
+
+	switch op.Op {
+	case planOpMkdir:
+		return nil
+
+	case planOpMove:
+		if !isWithinRoot(op.Src, prog.opts.MirrorRoot) {
+			return fmt.Errorf("%w: %q is outside of --mirror", errPlanMalformed, op.Src)
+		}
+
+		// The source is not followed if it was replaced by a symbolic link.
+		e, err := prog.lstat(op.Src)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %q (%s)", errPlanStale, op.Src, "src_no_longer_exists")
+		} else if err != nil {
+			return fmt.Errorf("failed to lstat: %q (%w)", op.Src, err)
+		}
+
+		// Plans written before the modification times were recorded are only checked by size.
+		if !e.Mode().IsRegular() || e.Size() != op.Size || (!op.Mtime.IsZero() && !e.ModTime().Equal(op.Mtime)) {
+			return fmt.Errorf("%w: %q (%s)", errPlanStale, op.Src, "src_has_changed")
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unknown operation %q", errPlanMalformed, op.Op)
+	}
+}
+
+func isWithinRoot(path string, root string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+
+	rel, err := filepath.Rel(root, filepath.Clean(path))
+
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// Expectation: The function should execute a plan written in dry mode, after it was approved.
+func Test_Unit_MoveFiles_PlanOutPlanIn_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	files := map[string]string{
+		"/mirror/file.txt":         "content",
+		"/mirror/dir/sub/file.txt": "content2",
+		"/real/existing/file.txt":  "content3",
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, path := range []string{"/mirror/file.txt", "/mirror/dir/sub/file.txt"} {
+		require.NoError(t, fs.Chtimes(path, mtime, mtime))
+	}
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		DryRun:     true,
+		PlanOut:    "/plan.json",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	plan, err := prog.readPlan("/plan.json")
+	require.NoError(t, err)
+	require.Equal(t, []planOperation{
+		{Op: planOpMkdir, Dst: "/real/dir"},
+		{Op: planOpMkdir, Dst: "/real/dir/sub"},
+		{Op: planOpMove, Src: "/mirror/dir/sub/file.txt", Dst: "/real/dir/sub/file.txt", Size: 8, Mtime: mtime},
+		{Op: planOpMove, Src: "/mirror/file.txt", Dst: "/real/file.txt", Size: 7, Mtime: mtime},
+	}, plan.Operations)
+
+	// A file appearing in the mirror after the plan was approved is not part of it.
+	err = createFiles(fs, map[string]string{"/mirror/unplanned.txt": "content4"})
+	require.NoError(t, err)
+
+	opts = &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		PlanIn:     "/plan.json",
+	}
+
+	prog, _, _ = setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	require.Equal(t, 2, prog.state.createdDirs)
+	require.Equal(t, 2, prog.state.movedFiles)
+
+	content, err := afero.ReadFile(fs, "/real/dir/sub/file.txt")
+	require.NoError(t, err)
+	require.Equal(t, "content2", string(content))
+
+	_, err = fs.Stat("/real/unplanned.txt")
+	require.Error(t, err)
+
+	_, err = fs.Stat("/mirror/unplanned.txt")
+	require.NoError(t, err)
+}
+
+// Expectation: A plan written together with --list-plan-only should not contain the listed skips, so that it can be executed.
+func Test_Unit_MoveFiles_PlanOutListPlanOnly_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/exists.txt": "content",
+		"/real/exists.txt":   "other",
+		"/mirror/file.txt":   "content",
+	})
+	require.NoError(t, err)
+
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, fs.Chtimes("/mirror/file.txt", mtime, mtime))
+
+	opts := &programOptions{
+		MirrorRoot:   "/mirror",
+		RealRoot:     "/real",
+		DryRun:       true,
+		ListPlanOnly: true,
+		PlanOut:      "/plan.json",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Len(t, prog.state.plannedOps, 2)
+
+	plan, err := prog.readPlan("/plan.json")
+	require.NoError(t, err)
+	require.Equal(t, []planOperation{
+		{Op: planOpMove, Src: "/mirror/file.txt", Dst: "/real/file.txt", Size: 7, Mtime: mtime},
+	}, plan.Operations)
+
+	opts = &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		PlanIn:     "/plan.json",
+	}
+
+	prog, _, _ = setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 1, prog.state.movedFiles)
+}
+
+// Expectation: The function should refuse to execute any of a plan that has gone stale.
+func Test_Unit_MoveFiles_PlanInStale_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		reason  string
+		diverge func(fs afero.Fs) error
+	}{
+		{
+			name:   "src_no_longer_exists",
+			reason: "src_no_longer_exists",
+			diverge: func(fs afero.Fs) error {
+				return fs.Remove("/mirror/file2.txt")
+			},
+		},
+		{
+			name:   "src_has_changed",
+			reason: "src_has_changed",
+			diverge: func(fs afero.Fs) error {
+				return afero.WriteFile(fs, "/mirror/file2.txt", []byte("changed content"), 0o644)
+			},
+		},
+		{
+			name:   "src_mtime_changed",
+			reason: "src_has_changed",
+			diverge: func(fs afero.Fs) error {
+				// The same size, but a different modification time.
+				mtime := time.Now().Add(-time.Hour)
+
+				return fs.Chtimes("/mirror/file2.txt", mtime, mtime)
+			},
+		},
+		{
+			name:   "dst_already_exists",
+			reason: "dst_already_exists",
+			diverge: func(fs afero.Fs) error {
+				return afero.WriteFile(fs, "/real/file2.txt", []byte("conflict"), 0o644)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			files := map[string]string{
+				"/mirror/file1.txt": "content",
+				"/mirror/file2.txt": "content2",
+			}
+			err := createFiles(fs, files)
+			require.NoError(t, err)
+
+			err = fs.MkdirAll("/real", 0o755)
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot: "/mirror",
+				RealRoot:   "/real",
+				DryRun:     true,
+				PlanOut:    "/plan.json",
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+
+			err = tt.diverge(fs)
+			require.NoError(t, err)
+
+			opts = &programOptions{
+				MirrorRoot: "/mirror",
+				RealRoot:   "/real",
+				PlanIn:     "/plan.json",
+			}
+
+			prog, _, _ = setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.ErrorIs(t, err, errPlanStale)
+			require.Contains(t, err.Error(), tt.reason)
+
+			// Verify that none of the plan was executed.
+			require.Equal(t, 0, prog.state.movedFiles)
+
+			_, err = fs.Stat("/mirror/file1.txt")
+			require.NoError(t, err)
+		})
+	}
+}
+
+// Expectation: The function should refuse a plan with operations outside of the roots.
+func Test_Unit_MoveFiles_PlanInOutsideRoots_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	files := map[string]string{
+		"/mirror/file.txt": "content",
+		"/plan.json":       `{"mirror": "/mirror", "target": "/real", "operations": [{"op": "move", "src": "/mirror/file.txt", "dst": "/etc/file.txt", "size": 7}]}`,
+	}
+	err := createFiles(fs, files)
+	require.NoError(t, err)
+
+	err = fs.MkdirAll("/real", 0o755)
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		PlanIn:     "/plan.json",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.ErrorIs(t, err, errPlanMalformed)
+
+	_, err = fs.Stat("/mirror/file.txt")
+	require.NoError(t, err)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	progressFilePerm = 0o644
+
+	progressStateRunning  = "running"
+	progressStateFinished = "finished"
+)
+
+// progressSnapshot is the content of the --progress-file, as it is polled by
+// any external user interfaces while a move is running.
+type progressSnapshot struct {
+	Mode        string    `json:"mode"`
+	State       string    `json:"state"`
+	DryRun      bool      `json:"dry_run"`
+	FilesMoved  int       `json:"files_moved"`
+	BytesMoved  int64     `json:"bytes_moved"`
+	CurrentFile string    `json:"current_file,omitempty"`
+	TotalFiles  int       `json:"total_files,omitempty"`
+	TotalBytes  int64     `json:"total_bytes,omitempty"`
+	Percent     *float64  `json:"percent,omitempty"` // Only set if the totals are known (such as with --plan-in).
+	Updated     time.Time `json:"updated"`
+}
+
+// setCurrentFile records the file that is currently being moved, for the
+// --progress-file.
+func (prog *program) setCurrentFile(path string) {
+	prog.state.mu.Lock()
+	defer prog.state.mu.Unlock()
+
+	prog.state.currentFile = path
+}
+
+// setProgressTotals records the files and bytes that are known to be moved in
+// total, so that the --progress-file can contain a percentage.
+func (prog *program) setProgressTotals(files int, bytes int64) {
+	prog.state.mu.Lock()
+	defer prog.state.mu.Unlock()
+
+	prog.state.totalFiles = files
+	prog.state.totalBytes = bytes
+}
+
+// startProgress writes the progress file from a background goroutine in the
+// configured interval. The returned function stops the goroutine, waits for
+// it and then writes the finalized progress file.
+func (prog *program) startProgress(ctx context.Context) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	prog.writeProgress(progressStateRunning)
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(prog.opts.ProgressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				prog.writeProgress(progressStateRunning)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+
+		prog.writeProgress(progressStateFinished)
+	}
+}
+
+// writeProgress replaces the progress file with a current snapshot, through a
+// working file that is renamed over it, so that pollers never read a partial
+// file. Any failures are only logged, as the progress is not essential to the
+// operation itself.
+func (prog *program) writeProgress(state string) {
+	path := prog.opts.ProgressFile
+
+	prog.state.mu.Lock()
+	snap := progressSnapshot{
+		Mode:        prog.opts.Mode,
+		State:       state,
+		DryRun:      prog.opts.DryRun,
+		FilesMoved:  prog.state.movedFiles,
+		BytesMoved:  prog.state.movedBytes,
+		CurrentFile: prog.state.currentFile,
+		TotalFiles:  prog.state.totalFiles,
+		TotalBytes:  prog.state.totalBytes,
+		Updated:     time.Now().UTC(),
+	}
+	prog.state.mu.Unlock()
+
+	if state == progressStateFinished {
+		snap.CurrentFile = ""
+	}
+
+	if snap.TotalBytes > 0 {
+		percent := min(100, float64(snap.BytesMoved)/float64(snap.TotalBytes)*100)
+		snap.Percent = &percent
+	} else if snap.TotalFiles > 0 {
+		percent := min(100, float64(snap.FilesMoved)/float64(snap.TotalFiles)*100)
+		snap.Percent = &percent
+	}
+
+	out, err := json.Marshal(snap)
+	if err == nil {
+		workingFile := path + workingFileSuffix
+
+		if err = afero.WriteFile(prog.fsys, workingFile, append(out, '\n'), progressFilePerm); err == nil {
+			if err = prog.fsys.Rename(workingFile, path); err != nil {
+				_ = prog.fsys.Remove(workingFile)
+				err = fmt.Errorf("failed to rename: %q -x-> %q (%w)", workingFile, path, err)
+			}
+		}
+	}
+
+	if err != nil {
+		prog.log.Warn("progress failed", "op", prog.opts.Mode, "path", path, "error", err, "error-type", "runtime")
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// snapshottingFs is an [afero.Fs] recording the content of the given file whenever it is renamed into place.
+type snapshottingFs struct {
+	afero.Fs
+	path string
+
+	mu        sync.Mutex
+	snapshots [][]byte
+}
+
+func (sfs *snapshottingFs) Rename(oldname, newname string) error {
+	if err := sfs.Fs.Rename(oldname, newname); err != nil {
+		return err
+	}
+
+	if newname == sfs.path {
+		data, err := afero.ReadFile(sfs.Fs, newname)
+		if err != nil {
+			return err
+		}
+
+		sfs.mu.Lock()
+		sfs.snapshots = append(sfs.snapshots, data)
+		sfs.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Expectation: The progress file should contain valid and advancing JSON during a multi-file move.
+func Test_Unit_MoveFiles_ProgressFile_Success(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+
+	files := make(map[string]string)
+	for i := range 10 {
+		files[fmt.Sprintf("/mirror/file%d.txt", i)] = "content"
+	}
+	err := createFiles(memFs, files)
+	require.NoError(t, err)
+	err = createDirStructure(memFs, []string{"/real"})
+	require.NoError(t, err)
+
+	fs := &snapshottingFs{Fs: memFs, path: "/progress.json"}
+
+	opts := &programOptions{
+		Mode:             "move",
+		MirrorRoot:       "/mirror",
+		RealRoot:         "/real",
+		PostMoveCommand:  "true",
+		ProgressFile:     "/progress.json",
+		ProgressInterval: 10 * time.Millisecond,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	prog.runner = sleepingRunner{delay: 5 * time.Millisecond}
+
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	require.Greater(t, len(fs.snapshots), 2)
+
+	var last progressSnapshot
+	for i, data := range fs.snapshots {
+		var snap progressSnapshot
+		require.NoError(t, json.Unmarshal(data, &snap), string(data))
+		require.Equal(t, "move", snap.Mode)
+		require.Nil(t, snap.Percent)
+
+		if i > 0 {
+			require.GreaterOrEqual(t, snap.FilesMoved, last.FilesMoved)
+			require.GreaterOrEqual(t, snap.BytesMoved, last.BytesMoved)
+			require.False(t, snap.Updated.Before(last.Updated))
+		}
+		last = snap
+	}
+
+	require.Equal(t, progressStateFinished, last.State)
+	require.Equal(t, len(files), last.FilesMoved)
+	require.Equal(t, int64(len(files)*len("content")), last.BytesMoved)
+	require.Empty(t, last.CurrentFile)
+
+	// Some of the snapshots in between should show the move in progress.
+	var running bool
+	for _, data := range fs.snapshots[:len(fs.snapshots)-1] {
+		var snap progressSnapshot
+		require.NoError(t, json.Unmarshal(data, &snap))
+		if snap.State == progressStateRunning && snap.FilesMoved > 0 && snap.FilesMoved < len(files) {
+			running = true
+			require.NotEmpty(t, snap.CurrentFile)
+		}
+	}
+	require.True(t, running)
+
+	_, err = memFs.Stat("/progress.json" + workingFileSuffix)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The progress file should contain a percentage once the totals are known.
+func Test_Unit_WriteProgress_Percent_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	opts := &programOptions{
+		Mode:         "move",
+		ProgressFile: "/progress.json",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	prog.setProgressTotals(4, 400)
+	prog.countMoved(100)
+	prog.setCurrentFile("/mirror/file.txt")
+
+	prog.writeProgress(progressStateRunning)
+
+	data, err := afero.ReadFile(fs, "/progress.json")
+	require.NoError(t, err)
+
+	var snap progressSnapshot
+	require.NoError(t, json.Unmarshal(data, &snap))
+	require.Equal(t, progressStateRunning, snap.State)
+	require.Equal(t, 4, snap.TotalFiles)
+	require.Equal(t, int64(400), snap.TotalBytes)
+	require.Equal(t, "/mirror/file.txt", snap.CurrentFile)
+	require.NotNil(t, snap.Percent)
+	require.InDelta(t, 25.0, *snap.Percent, 0.001)
+}
+
+// Expectation: A progress file that cannot be written should only be logged as a warning.
+func Test_Unit_WriteProgress_WriteFailure_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	opts := &programOptions{
+		Mode:         "move",
+		ProgressFile: "/missing/progress.json",
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	prog.fsys = afero.NewReadOnlyFs(fs)
+
+	prog.writeProgress(progressStateRunning)
+
+	require.Contains(t, stderr.String(), "progress failed")
+}
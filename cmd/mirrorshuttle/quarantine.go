@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	quarantineTargetDir = "target" // Holds the failed destination files, relative to --target.
+	quarantineMirrorDir = "mirror" // Holds the sources of these (with --quarantine-source), relative to --mirror.
+)
+
+// checkQuarantineDir creates the --quarantine-dir if it does not exist and
+// probes it for being writable, before any files are moved, so that a failed
+// file is never removed only because it could not be quarantined.
+func (prog *program) checkQuarantineDir() error {
+	if prog.opts.DryRun {
+		// No files are copied in dry mode, so none can fail into the quarantine.
+		return nil
+	}
+
+	return prog.checkRelocationDir(prog.opts.QuarantineDir, errQuarantineNotWritable)
+}
+
+// checkTargetTrashDir creates the --target-trash-dir if it does not exist and
+// probes it for being writable, before any files are moved, so that a target
+// file is never overwritten only because it could not be moved aside.
+func (prog *program) checkTargetTrashDir() error {
+	if prog.opts.DryRun {
+		// No target files are overwritten in dry mode, so none are trashed.
+		return nil
+	}
+
+	return prog.checkRelocationDir(prog.opts.TargetTrashDir, errTargetTrashNotWritable)
+}
+
+// checkRelocationDir creates a directory that files are relocated into if it
+// does not exist, and probes it for being writable.
+func (prog *program) checkRelocationDir(dir string, errNotWritable error) error {
+	if err := prog.fsys.MkdirAll(dir, dirBasePerm); err != nil {
+		return fmt.Errorf("%w: %q (%w)", errNotWritable, dir, err)
+	}
+
+	if err := prog.probeDir(dir); err != nil {
+		return fmt.Errorf("%w: %q (%w)", errNotWritable, dir, err)
+	}
+
+	return nil
+}
+
+// discardFailedFile relocates the working (or already renamed destination)
+// file of a failed move into the --quarantine-dir for inspection, or removes
+// it (as without the setting) if it cannot be quarantined.
+func (prog *program) discardFailedFile(src string, workingFile string, reason error) {
+	if prog.opts.QuarantineDir != "" && prog.quarantineFile(src, workingFile, reason) {
+		return
+	}
+
+	prog.removeWorkingFile(src, workingFile)
+}
+
+// quarantineFile relocates a failed file (and its source, with the setting
+// --quarantine-source) into the --quarantine-dir, preserving its relative
+// path. It returns false if the failed file itself was not relocated.
+func (prog *program) quarantineFile(src string, workingFile string, reason error) bool {
+	dst := strings.TrimSuffix(workingFile, workingFileSuffix)
+
+	qPath, err := prog.relocateFile(workingFile, dst, prog.opts.RealRoot, filepath.Join(prog.opts.QuarantineDir, quarantineTargetDir))
+	if err != nil {
+		prog.log.Warn("file not quarantined", "op", prog.opts.Mode+"_cleanup", "path", workingFile, "error", err, "reason", "error_occurred")
+
+		return false
+	}
+	prog.log.Warn("file quarantined", "op", prog.opts.Mode+"_cleanup", "path", workingFile, "quarantine", qPath, "error", reason, "error-type", "runtime")
+
+	if prog.opts.QuarantineSource {
+		qPath, err := prog.relocateFile(src, src, prog.opts.MirrorRoot, filepath.Join(prog.opts.QuarantineDir, quarantineMirrorDir))
+		if err != nil {
+			// The source remains within the mirror, where it is not lost.
+			prog.log.Warn("file not quarantined", "op", prog.opts.Mode+"_cleanup", "path", src, "error", err, "reason", "error_occurred")
+
+			return true
+		}
+		prog.log.Warn("file quarantined", "op", prog.opts.Mode+"_cleanup", "path", src, "quarantine", qPath, "error", reason, "error-type", "runtime")
+	}
+
+	return true
+}
+
+// trashTargetFile relocates an existing target file into the --target-trash-dir
+// right before its complete working file replaces it (with the --overwrite
+// setting), so that the overwrite can be reverted. The file keeps its path
+// relative to --target, along with its modification time, as it is only renamed.
+// It returns the path within the trash, or an empty string if nothing was trashed.
+func (prog *program) trashTargetFile(dst string) (string, error) {
+	if prog.opts.TargetTrashDir == "" {
+		return "", nil
+	}
+
+	if _, err := prog.fsys.Stat(dst); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to stat: %q (%w)", dst, err)
+	}
+
+	trashPath, err := prog.relocateFile(dst, dst, prog.opts.RealRoot, prog.opts.TargetTrashDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to trash: %q (%w)", dst, err)
+	}
+	prog.log.Info("target trashed", "op", prog.opts.Mode, "path", dst, "trash", trashPath)
+
+	return trashPath, nil
+}
+
+// restoreTrashedFile renames a trashed target file back into place, after the
+// file that was to replace it failed to be moved and was discarded again. It is
+// left within the trash if the failed file could not be discarded (such as when
+// its source no longer exists), as that file is never overwritten.
+func (prog *program) restoreTrashedFile(dst string, trashPath string) {
+	if trashPath == "" {
+		return
+	}
+
+	if _, err := prog.lstat(dst); !errors.Is(err, os.ErrNotExist) {
+		prog.log.Warn("target not restored", "op", prog.opts.Mode+"_cleanup", "path", dst, "trash", trashPath, "reason", "dst_not_discarded")
+
+		return
+	}
+
+	if err := prog.fsys.Rename(trashPath, dst); err != nil {
+		prog.log.Error("target not restored", "op", prog.opts.Mode+"_cleanup", "path", dst, "trash", trashPath, "error", err, "error-type", "runtime")
+
+		return
+	}
+	prog.log.Warn("target restored", "op", prog.opts.Mode+"_cleanup", "path", dst, "trash", trashPath)
+}
+
+// relocateFile renames a file into the given directory (such as a subdirectory
+// of the quarantine), at the path of name relative to root. It never overwrites
+// an existing file.
+func (prog *program) relocateFile(path string, name string, root string, dir string) (string, error) {
+	relPath, err := filepath.Rel(root, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get relative path: %q (%w)", name, err)
+	}
+	qPath := filepath.Join(dir, relPath)
+
+	if _, err := prog.fsys.Stat(qPath); err == nil {
+		return "", fmt.Errorf("failed to relocate: %q (%w)", qPath, os.ErrExist)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to stat: %q (%w)", qPath, err)
+	}
+
+	if err := prog.fsys.MkdirAll(filepath.Dir(qPath), dirBasePerm); err != nil {
+		return "", fmt.Errorf("failed to create: %q (%w)", filepath.Dir(qPath), err)
+	}
+
+	if err := prog.fsys.Rename(path, qPath); err != nil {
+		return "", fmt.Errorf("failed to rename: %q -x-> %q (%w)", path, qPath, err)
+	}
+
+	return qPath, nil
+}
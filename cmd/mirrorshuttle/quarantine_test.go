@@ -0,0 +1,387 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// Expectation: A file failing its --verify pass should land in the quarantine, and the run should continue.
+func Test_Unit_MoveFiles_QuarantineDir_Success(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		quarantineSource bool
+	}{
+		{"target-only", false},
+		{"with-source", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			memFs := setupTestFs()
+			err := createFiles(memFs, map[string]string{
+				"/mirror/dir/file.txt":  "test content",
+				"/mirror/dir/file2.txt": "test content 2",
+			})
+			require.NoError(t, err)
+			err = createDirStructure(memFs, []string{"/real/dir"})
+			require.NoError(t, err)
+
+			// Every renamed file is truncated, so that each of the --verify passes fails.
+			fs := &truncatingRenameFs{Fs: memFs}
+
+			opts := &programOptions{
+				MirrorRoot:       "/mirror",
+				RealRoot:         "/real",
+				Verify:           true,
+				SkipFailed:       true,
+				QuarantineDir:    "/quarantine",
+				QuarantineSource: tt.quarantineSource,
+			}
+
+			prog, _, stderr := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+			require.True(t, prog.state.hasPartialFailures)
+			require.Contains(t, stderr.String(), "file quarantined")
+			require.Contains(t, stderr.String(), errVerifyHashMismatch.Error())
+
+			for _, name := range []string{"file.txt", "file2.txt"} {
+				// Verify the incorrect destination was relocated, preserving its relative path.
+				_, err = memFs.Stat("/real/dir/" + name)
+				require.ErrorIs(t, err, os.ErrNotExist)
+
+				_, err = memFs.Stat("/quarantine/target/dir/" + name)
+				require.NoError(t, err)
+
+				_, srcErr := memFs.Stat("/mirror/dir/" + name)
+				_, qSrcErr := memFs.Stat("/quarantine/mirror/dir/" + name)
+
+				if tt.quarantineSource {
+					require.ErrorIs(t, srcErr, os.ErrNotExist)
+					require.NoError(t, qSrcErr)
+				} else {
+					require.NoError(t, srcErr)
+					require.ErrorIs(t, qSrcErr, os.ErrNotExist)
+				}
+			}
+		})
+	}
+}
+
+// Expectation: A failed file should be removed as usual if it cannot be quarantined, never overwriting the quarantine.
+func Test_Unit_CopyAndRemove_QuarantineExists_Success(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	err := createFiles(memFs, map[string]string{
+		"/mirror/file.txt":            "test content",
+		"/quarantine/target/file.txt": "earlier",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(memFs, []string{"/real"})
+	require.NoError(t, err)
+
+	fs := &truncatingRenameFs{Fs: memFs}
+
+	opts := &programOptions{
+		MirrorRoot:    "/mirror",
+		RealRoot:      "/real",
+		Verify:        true,
+		QuarantineDir: "/quarantine",
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	_, err = prog.copyAndRemove(t.Context(), "/mirror/file.txt", "/real/file.txt")
+	require.ErrorIs(t, err, errVerifyHashMismatch)
+	require.Contains(t, stderr.String(), "file not quarantined")
+
+	_, err = memFs.Stat("/real/file.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	content, err := afero.ReadFile(memFs, "/quarantine/target/file.txt")
+	require.NoError(t, err)
+	require.Equal(t, "earlier", string(content))
+
+	content, err = afero.ReadFile(memFs, "/mirror/file.txt")
+	require.NoError(t, err)
+	require.Equal(t, "test content", string(content))
+}
+
+// Expectation: The move should fail before any files are moved if the quarantine is not writable.
+func Test_Unit_MoveFiles_QuarantineNotWritable_Error(t *testing.T) {
+	t.Parallel()
+
+	memFs := setupTestFs()
+	err := createFiles(memFs, map[string]string{
+		"/mirror/file.txt": "test content",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(memFs, []string{"/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:    "/mirror",
+		RealRoot:      "/real",
+		QuarantineDir: "/quarantine",
+	}
+
+	prog, _, _ := setupTestProgram(afero.NewReadOnlyFs(memFs), opts)
+	err = prog.moveFiles(t.Context())
+	require.ErrorIs(t, err, errQuarantineNotWritable)
+
+	_, err = memFs.Stat("/mirror/file.txt")
+	require.NoError(t, err)
+}
+
+// Expectation: The function should only accept an absolute quarantine outside of the mirror and the target.
+func Test_Unit_ValidateOpts_QuarantineDir_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		dir     string
+		wantErr bool
+	}{
+		{"outside", "/quarantine", false},
+		{"relative", "quarantine", true},
+		{"within-mirror", "/mirror/quarantine", true},
+		{"within-target", "/real/quarantine", true},
+		{"is-mirror", "/mirror", true},
+		{"is-target", "/real/", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			prog, _, _ := setupTestProgram(setupTestFs(), nil)
+			prog.opts = &programOptions{
+				Mode:          "move",
+				MirrorRoot:    "/mirror",
+				RealRoot:      "/real",
+				QuarantineDir: tt.dir,
+				LogLevel:      "info",
+			}
+
+			err := prog.validateOpts()
+			if tt.wantErr {
+				require.ErrorIs(t, err, errArgQuarantineDirInvalid)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+// Expectation: An overwritten target file should be recoverable from the --target-trash-dir, with its modification time.
+func Test_Unit_MoveFiles_TargetTrashDir_Success(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		atomicBatch bool
+	}{
+		{"copy-and-remove", false},
+		{"atomic-batch", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{
+				"/mirror/dir/file.txt": "new content",
+				"/real/dir/file.txt":   "old content",
+			})
+			require.NoError(t, err)
+
+			oldTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			require.NoError(t, fs.Chtimes("/real/dir/file.txt", oldTime, oldTime))
+
+			opts := &programOptions{
+				MirrorRoot:     "/mirror",
+				RealRoot:       "/real",
+				Overwrite:      overwriteAlways,
+				TargetTrashDir: "/trash",
+				AtomicBatch:    tt.atomicBatch,
+			}
+
+			prog, _, stderr := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+			require.Contains(t, stderr.String(), "target trashed")
+
+			content, err := afero.ReadFile(fs, "/real/dir/file.txt")
+			require.NoError(t, err)
+			require.Equal(t, "new content", string(content))
+
+			content, err = afero.ReadFile(fs, "/trash/dir/file.txt")
+			require.NoError(t, err)
+			require.Equal(t, "old content", string(content))
+
+			info, err := fs.Stat("/trash/dir/file.txt")
+			require.NoError(t, err)
+			require.True(t, info.ModTime().Equal(oldTime))
+		})
+	}
+}
+
+// Expectation: A target file should not be overwritten if it cannot be trashed, never overwriting the trash.
+func Test_Unit_MoveFiles_TargetTrashExists_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/file.txt": "new content",
+		"/real/file.txt":   "old content",
+		"/trash/file.txt":  "earlier",
+	})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:     "/mirror",
+		RealRoot:       "/real",
+		Overwrite:      overwriteAlways,
+		TargetTrashDir: "/trash",
+		SkipFailed:     true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.True(t, prog.state.hasPartialFailures)
+
+	for path, want := range map[string]string{
+		"/mirror/file.txt": "new content",
+		"/real/file.txt":   "old content",
+		"/trash/file.txt":  "earlier",
+	} {
+		content, err := afero.ReadFile(fs, path)
+		require.NoError(t, err)
+		require.Equal(t, want, string(content))
+	}
+}
+
+// failingWorkingRenameFs is an [afero.Fs] failing any rename of a working file into its destination.
+type failingWorkingRenameFs struct {
+	afero.Fs
+}
+
+func (ffs *failingWorkingRenameFs) Rename(oldname, newname string) error {
+	if strings.HasSuffix(oldname, workingFileSuffix) {
+		return errors.New("rename failed")
+	}
+
+	return ffs.Fs.Rename(oldname, newname)
+}
+
+// Expectation: A trashed target file should be restored if the file replacing it fails to be moved.
+func Test_Unit_MoveFiles_TargetTrashRestored_Success(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		atomicBatch       bool
+		verifyConcurrency int
+	}{
+		{"copy-and-remove", false, 0},
+		{"verify-pool", false, 2},
+		{"atomic-batch", true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			memFs := setupTestFs()
+			err := createFiles(memFs, map[string]string{
+				"/mirror/dir/file.txt": "new content",
+				"/real/dir/file.txt":   "old content",
+			})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:        "/mirror",
+				RealRoot:          "/real",
+				Overwrite:         overwriteAlways,
+				TargetTrashDir:    "/trash",
+				AtomicBatch:       tt.atomicBatch,
+				Verify:            tt.verifyConcurrency > 0,
+				VerifyConcurrency: tt.verifyConcurrency,
+				SkipFailed:        true,
+			}
+
+			prog, _, stderr := setupTestProgram(&failingWorkingRenameFs{Fs: memFs}, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+			require.True(t, prog.state.hasPartialFailures)
+			require.Contains(t, stderr.String(), "target restored")
+
+			for path, want := range map[string]string{
+				"/mirror/dir/file.txt": "new content",
+				"/real/dir/file.txt":   "old content",
+			} {
+				content, err := afero.ReadFile(memFs, path)
+				require.NoError(t, err)
+				require.Equal(t, want, string(content))
+			}
+
+			_, err = memFs.Stat("/trash/dir/file.txt")
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
+
+// Expectation: The function should only accept an absolute trash outside of the mirror and the target, along with --overwrite.
+func Test_Unit_ValidateOpts_TargetTrashDir_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		dir       string
+		overwrite string
+		wantErr   bool
+	}{
+		{"outside", "/trash", overwriteAlways, false},
+		{"without-overwrite", "/trash", "", true},
+		{"overwrite-never", "/trash", overwriteNever, true},
+		{"relative", "trash", overwriteAlways, true},
+		{"within-mirror", "/mirror/trash", overwriteAlways, true},
+		{"within-target", "/real/trash", overwriteAlways, true},
+		{"is-target", "/real/", overwriteAlways, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			prog, _, _ := setupTestProgram(setupTestFs(), nil)
+			prog.opts = &programOptions{
+				Mode:           "move",
+				MirrorRoot:     "/mirror",
+				RealRoot:       "/real",
+				Overwrite:      tt.overwrite,
+				TargetTrashDir: tt.dir,
+				LogLevel:       "info",
+			}
+
+			err := prog.validateOpts()
+			if tt.wantErr {
+				require.ErrorIs(t, err, errArgTargetTrashDirInvalid)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// renameRuleSeparator separates the pattern from the replacement of a rule
+// within the --rename-rules file.
+const renameRuleSeparator = "=>"
+
+// renameRule is one of the --rename-rules, which transforms the relative path
+// of a moved file that matches its pattern into its location within the target.
+type renameRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// loadRenameRules reads and compiles the --rename-rules file, which contains
+// one `regex => replacement` rule per line, in the order in which they are
+// tried. Empty lines and lines starting with a '#' are ignored.
+func (prog *program) loadRenameRules() ([]renameRule, error) {
+	f, err := prog.fsys.Open(prog.opts.RenameRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open: %q (%w)", prog.opts.RenameRules, err)
+	}
+	defer f.Close()
+
+	var rules []renameRule
+
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		expr, replacement, ok := strings.Cut(line, renameRuleSeparator)
+		expr = strings.TrimSpace(expr)
+		if !ok || expr == "" {
+			return nil, fmt.Errorf("line %d: missing %q between the pattern and the replacement", lineNum, renameRuleSeparator)
+		}
+
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+
+		rules = append(rules, renameRule{pattern: pattern, replacement: strings.TrimSpace(replacement)})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read: %q (%w)", prog.opts.RenameRules, err)
+	}
+
+	return rules, nil
+}
+
+// renameTarget applies the first of the --rename-rules that matches the
+// (mirror-relative) path of a file, returning the relative path of its new
+// location within the target. A path that matches no rule is returned as is.
+// A transformed path must remain within the target, and outside the mirror.
+func (prog *program) renameTarget(relPath string) (string, error) {
+	slashPath := filepath.ToSlash(relPath)
+
+	for _, rule := range prog.renameRules {
+		if !rule.pattern.MatchString(slashPath) {
+			continue
+		}
+
+		renamed := filepath.Clean(filepath.FromSlash(rule.pattern.ReplaceAllString(slashPath, rule.replacement)))
+		movePath := filepath.Join(prog.opts.RealRoot, renamed)
+
+		if filepath.IsAbs(renamed) || !isWithinRoot(movePath, prog.opts.RealRoot) ||
+			prog.samePath(movePath, prog.opts.MirrorRoot) || isWithinRoot(movePath, prog.opts.MirrorRoot) {
+			return "", fmt.Errorf("%w: %q -> %q (rule %q)", errRenameRuleOutside, slashPath, renamed, rule.pattern.String())
+		}
+
+		return renamed, nil
+	}
+
+	return relPath, nil
+}
+
+// findDuplicateTargets walks the mirror ahead of the move and finds the files
+// that the --rename-rules would transform onto the same target path, handling
+// them according to the --on-duplicate-target setting. The first file of the
+// walk keeps the target path, while the others either fail the move before any
+// files were moved, are skipped, or are given a disambiguated target path.
+func (prog *program) findDuplicateTargets(ctx context.Context) error {
+	var order []string
+	sources := make(map[string][]string)
+	targets := make(map[string]string) // The (not case-folded) relative target path, by its key.
+	taken := make(map[string]bool)
+
+	if err := afero.Walk(prog.fsys, prog.opts.MirrorRoot, func(path string, e os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			// An interrupt was received, so we also interrupt the walk.
+			return fmt.Errorf("failed checking context: %w", err)
+		}
+
+		if err != nil {
+			// Any failures are left to the handling of the move itself.
+			return nil //nolint:nilerr
+		}
+
+		relPath, err := filepath.Rel(prog.opts.MirrorRoot, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %q (%w)", path, err)
+		}
+
+		if !e.IsDir() {
+			if relPath, err = prog.renameTarget(relPath); err != nil {
+				return nil // The file is failed by the move itself.
+			}
+		}
+		movePath := filepath.Join(prog.opts.RealRoot, relPath)
+
+		if _, excluded := prog.checkExclusion(path, movePath, e.IsDir()); excluded || prog.samePath(movePath, prog.opts.MirrorRoot) {
+			if e.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if e.IsDir() || (!prog.state.movedSince.IsZero() && e.ModTime().Before(prog.state.movedSince)) {
+			return nil
+		}
+
+		key := relPath
+		if prog.opts.CaseInsensitivePaths {
+			key = strings.ToLower(key)
+		}
+
+		if _, ok := sources[key]; !ok {
+			order = append(order, key)
+			targets[key] = relPath
+		}
+		sources[key] = append(sources[key], path)
+		taken[key] = true
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	prog.state.duplicateTargets = make(map[string]string)
+	duplicates := 0
+
+	for _, key := range order {
+		for _, src := range sources[key][1:] {
+			duplicates++
+
+			switch prog.opts.OnDuplicateTarget {
+			case duplicateTargetFail:
+				prog.log.Error("duplicate target path", "op", prog.opts.Mode, "src", src, "dst", filepath.Join(prog.opts.RealRoot, targets[key]), "first", sources[key][0])
+
+			case duplicateTargetRename:
+				renamed := prog.disambiguateTarget(targets[key], taken)
+				prog.state.duplicateTargets[src] = renamed
+				prog.log.Warn("duplicate target path renamed", "op", prog.opts.Mode, "src", src, "dst", filepath.Join(prog.opts.RealRoot, renamed), "first", sources[key][0])
+
+			default:
+				prog.state.duplicateTargets[src] = ""
+			}
+		}
+	}
+
+	if duplicates > 0 && prog.opts.OnDuplicateTarget == duplicateTargetFail {
+		return fmt.Errorf("%w: %d files", errDuplicateTarget, duplicates)
+	}
+
+	return nil
+}
+
+// disambiguateTarget returns the first relative path of the form "name (N).ext"
+// (counting from 2) that is not yet taken by any of the files, marking it taken.
+func (prog *program) disambiguateTarget(relPath string, taken map[string]bool) string {
+	ext := filepath.Ext(relPath)
+	if ext == filepath.Base(relPath) {
+		ext = "" // A name such as ".profile" has no extension.
+	}
+	stem := strings.TrimSuffix(relPath, ext)
+
+	for n := 2; ; n++ {
+		renamed := fmt.Sprintf("%s (%d)%s", stem, n, ext)
+
+		key := renamed
+		if prog.opts.CaseInsensitivePaths {
+			key = strings.ToLower(key)
+		}
+
+		if !taken[key] {
+			taken[key] = true
+
+			return renamed
+		}
+	}
+}
+
+// skipDuplicateTarget skips a file that the --rename-rules transformed onto the
+// target path of another file of the run (with --on-duplicate-target=first-wins).
+func (prog *program) skipDuplicateTarget(path string, movePath string) error {
+	prog.state.hasUnmovedFiles = true
+	prog.state.unmovedFiles++
+	prog.log.Warn("path skipped", "op", prog.opts.Mode, "path", path, "dst", movePath, "reason", "duplicate_target", "action", "skipped")
+	prog.recordSkip(path, "duplicate_target")
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// Expectation: A file matching a rule should be moved to its transformed location, others should pass through unchanged.
+func Test_Unit_MoveFiles_RenameRules_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/incoming/2024/photos/a.jpg": "content",
+		"/mirror/incoming/2025/b.jpg":        "content2",
+		"/mirror/other/c.txt":                "content3",
+		"/rules":                             "# Route last year's files into the archive.\n\n^incoming/(2024)/(.*)$ => archive/$1/$2\n^incoming/ => archive/\n",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/mirror/incoming/2024/photos", "/real/incoming/2024/photos", "/real/incoming/2025", "/real/other"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:  "/mirror",
+		RealRoot:    "/real",
+		RenameRules: "/rules",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	prog.renameRules, err = prog.loadRenameRules()
+	require.NoError(t, err)
+	require.Len(t, prog.renameRules, 2)
+
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 3, prog.state.movedFiles)
+
+	// The first matching rule wins, with any missing parents created.
+	content, err := afero.ReadFile(fs, "/real/archive/2024/photos/a.jpg")
+	require.NoError(t, err)
+	require.Equal(t, "content", string(content))
+
+	content, err = afero.ReadFile(fs, "/real/archive/2025/b.jpg")
+	require.NoError(t, err)
+	require.Equal(t, "content2", string(content))
+
+	// A file matching no rule passes through unchanged.
+	content, err = afero.ReadFile(fs, "/real/other/c.txt")
+	require.NoError(t, err)
+	require.Equal(t, "content3", string(content))
+
+	_, err = fs.Stat("/real/incoming/2024/photos/a.jpg")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The conflict handling should apply to the transformed path, and a path escaping the target should fail.
+func Test_Unit_MoveFiles_RenameRules_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/incoming/a.txt": "content",
+		"/mirror/escape/b.txt":   "content2",
+		"/real/archive/a.txt":    "existing",
+		"/rules":                 "^incoming/ => archive/\n^escape/ => ../\n",
+	})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real/incoming", "/real/escape"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:  "/mirror",
+		RealRoot:    "/real",
+		RenameRules: "/rules",
+		SkipFailed:  true,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	prog.renameRules, err = prog.loadRenameRules()
+	require.NoError(t, err)
+
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	require.Zero(t, prog.state.movedFiles)
+	require.Equal(t, 1, prog.state.unmovedFiles)
+	require.Contains(t, stderr.String(), "dst=/real/archive/a.txt")
+
+	require.Len(t, prog.state.failures, 1)
+	require.ErrorIs(t, prog.state.failures[0].err, errRenameRuleOutside)
+
+	content, err := afero.ReadFile(fs, "/real/archive/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, "existing", string(content))
+
+	for _, path := range []string{"/mirror/incoming/a.txt", "/mirror/escape/b.txt"} {
+		_, err = fs.Stat(path)
+		require.NoError(t, err)
+	}
+}
+
+// Expectation: An invalid --rename-rules file should be rejected by the validation of the configuration.
+func Test_Integ_Run_RenameRules_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{"missing separator", "^incoming/ archive/\n"},
+		{"empty pattern", " => archive/\n"},
+		{"invalid pattern", "^incoming/(2024 => archive/$1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{"/rules": tt.rules})
+			require.NoError(t, err)
+
+			var stdout, stderr bytes.Buffer
+			args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--rename-rules=/rules"}
+
+			prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+			require.Nil(t, prog)
+			require.ErrorIs(t, err, errArgRenameRulesInvalid)
+			require.Contains(t, err.Error(), "line 1")
+		})
+	}
+}
+
+// Expectation: Two files transformed onto the same target path should be handled according to --on-duplicate-target.
+func Test_Unit_MoveFiles_OnDuplicateTarget_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		onDuplicate string
+		wantErr     error
+		wantMoved   int
+		wantTargets map[string]string
+	}{
+		{
+			name:        "fail",
+			onDuplicate: duplicateTargetFail,
+			wantErr:     errDuplicateTarget,
+			wantMoved:   0,
+			wantTargets: map[string]string{},
+		},
+		{
+			name:        "first wins",
+			onDuplicate: duplicateTargetFirstWins,
+			wantMoved:   2,
+			wantTargets: map[string]string{"/real/archive/a.txt": "content1", "/real/archive/b.txt": "content3"},
+		},
+		{
+			name:        "rename",
+			onDuplicate: duplicateTargetRename,
+			wantMoved:   3,
+			wantTargets: map[string]string{"/real/archive/a.txt": "content1", "/real/archive/a (2).txt": "content2", "/real/archive/b.txt": "content3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{
+				"/mirror/one/a.txt": "content1",
+				"/mirror/two/a.txt": "content2",
+				"/mirror/two/b.txt": "content3",
+				"/rules":            "^[^/]+/(.*)$ => archive/$1\n",
+			})
+			require.NoError(t, err)
+			err = createDirStructure(fs, []string{"/real/one", "/real/two"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:        "/mirror",
+				RealRoot:          "/real",
+				RenameRules:       "/rules",
+				OnDuplicateTarget: tt.onDuplicate,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			prog.renameRules, err = prog.loadRenameRules()
+			require.NoError(t, err)
+
+			err = prog.moveFiles(t.Context())
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantMoved, prog.state.movedFiles)
+
+			entries, err := afero.ReadDir(fs, "/real/archive")
+			if len(tt.wantTargets) == 0 {
+				require.ErrorIs(t, err, os.ErrNotExist)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, entries, len(tt.wantTargets))
+			}
+
+			for path, want := range tt.wantTargets {
+				content, err := afero.ReadFile(fs, path)
+				require.NoError(t, err)
+				require.Equal(t, want, string(content))
+			}
+
+			// The second file onto the same target is never lost.
+			if tt.onDuplicate != duplicateTargetRename {
+				_, err = fs.Stat("/mirror/two/a.txt")
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	reportStdout   = "-"
+	reportFilePerm = 0o644
+)
+
+// exitCodeDescriptions are the human descriptions of the return codes, as
+// output with --explain-exit and within the usage.
+var exitCodeDescriptions = map[int]string{
+	exitCodeSuccess:        "Success",
+	exitCodeFailure:        "Failure",
+	exitCodePartialFailure: "Partial Failure (with --skip-failed)",
+	exitCodeMirrNotEmpty:   "Mirror directory contains unmoved files (with --mode=init)",
+	exitCodeUnmovedFiles:   "Unmoved files due to conflicting target files (with --mode=move)",
+	exitCodeConfigFailure:  "Invalid command-line arguments and/or configuration file provided",
+	exitCodeFailedChecks:   "Files failed verification against the manifest (with --mode=check)",
+	exitCodeTargetDiverged: "Target structure diverged (with --verify-target-structure)",
+	exitCodeDeferredVerify: "Moved files failed their deferred verification (with --deferred-verify)",
+	exitCodeDeferredLoad:   "Run deferred, as the system load was too high (with --max-load)",
+	exitCodeMirrorDiffers:  "Mirror structure would change (with --diff-exit)",
+}
+
+// runResult is the single-line result of a run, as printed to standard output
+// with --result-json, for capturing it from within shell scripts.
+type runResult struct {
+	Mode    string `json:"mode"`
+	Exit    int    `json:"exit"`
+	Moved   int    `json:"moved"`
+	Unmoved int    `json:"unmoved"`
+	Created int    `json:"created"`
+	Bytes   int64  `json:"bytes"`
+}
+
+func (prog *program) printResult(exitCode int) {
+	out, err := json.Marshal(runResult{
+		Mode:    prog.opts.Mode,
+		Exit:    exitCode,
+		Moved:   prog.state.movedFiles,
+		Unmoved: prog.state.unmovedFiles,
+		Created: prog.state.createdDirs,
+		Bytes:   prog.state.movedBytes,
+	})
+	if err != nil {
+		// The logger may not be set up yet, when the configuration was rejected.
+		fmt.Fprintf(prog.stderr, "error: failed to marshal result: %v\n", err)
+
+		return
+	}
+
+	fmt.Fprintln(prog.stdout, string(out))
+}
+
+// writeReport writes the --report to its file (or to standard output). Any
+// failures are only logged, as the report does not change the result itself.
+func (prog *program) writeReport(exitCode int, started time.Time) {
+	report := runReport{
+		Mode:     prog.opts.Mode,
+		DryRun:   prog.opts.DryRun,
+		Exit:     exitCode,
+		Started:  started.UTC(),
+		Duration: time.Since(started).Seconds(),
+		Created:  prog.state.createdDirs,
+		Moved:    prog.state.movedFiles,
+		Unmoved:  prog.state.unmovedFiles,
+		Bytes:    prog.state.movedBytes,
+		Failures: len(prog.state.failures),
+		Skipped:  prog.state.skippedPaths,
+	}
+
+	if report.Skipped == nil {
+		report.Skipped = []reportPath{}
+	}
+
+	var out []byte
+	var err error
+
+	if prog.opts.ReportFormat == reportFormatCSV {
+		out, err = marshalReportCSV(report)
+	} else if out, err = json.MarshalIndent(report, "", "  "); err == nil {
+		out = append(out, '\n')
+	}
+
+	if err == nil {
+		if prog.opts.Report == reportStdout {
+			_, err = prog.stdout.Write(out)
+		} else if err = afero.WriteFile(prog.fsys, prog.opts.Report, out, reportFilePerm); err != nil {
+			err = fmt.Errorf("failed to write: %q (%w)", prog.opts.Report, err)
+		}
+	}
+
+	if err != nil {
+		prog.log.Error("report failed", "op", prog.opts.Mode, "path", prog.opts.Report, "error", err, "error-type", "runtime")
+	}
+}
+
+// marshalReportCSV renders a --report as CSV (with --report-format=csv), with a
+// header row and a row for each of the skipped paths. The counts of the run are
+// left out, as these do not fit into the same columns.
+func marshalReportCSV(report runReport) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write([]string{"path", "reason"}); err != nil {
+		return nil, fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	for _, p := range report.Skipped {
+		if err := w.Write([]string{p.Path, p.Reason}); err != nil {
+			return nil, fmt.Errorf("failed to write csv: %w", err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// runSummary are the fields of a run, as available within the --summary-template.
+type runSummary struct {
+	Mode     string
+	ExitCode int
+	Moved    int
+	Unmoved  int
+	Created  int
+	Bytes    int64
+}
+
+// parseSummaryTemplate parses a --summary-template, also executing it once on
+// an empty summary, so that any references to unknown fields are caught early.
+func parseSummaryTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("summary").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tmpl.Execute(io.Discard, runSummary{}); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+func (prog *program) printSummary(exitCode int) {
+	tmpl, err := parseSummaryTemplate(prog.opts.SummaryTemplate)
+	if err != nil {
+		fmt.Fprintf(prog.stderr, "error: failed to parse summary template: %v\n", err)
+
+		return
+	}
+
+	// The summary is rendered in full first, so that no partial line is printed.
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, runSummary{
+		Mode:     prog.opts.Mode,
+		ExitCode: exitCode,
+		Moved:    prog.state.movedFiles,
+		Unmoved:  prog.state.unmovedFiles,
+		Created:  prog.state.createdDirs,
+		Bytes:    prog.state.movedBytes,
+	}); err != nil {
+		fmt.Fprintf(prog.stderr, "error: failed to render summary template: %v\n", err)
+
+		return
+	}
+
+	fmt.Fprintln(prog.stdout, strings.TrimSuffix(buf.String(), "\n"))
+}
+
+// infoWriter returns the writer for any informational (non-log) output, which
+// is moved to standard error with --result-json, --summary-template, --report=-,
+// --dry-run-reproducible or --list-plan-only, keeping standard output clean.
+func (prog *program) infoWriter() io.Writer {
+	if prog.opts.ResultJSON || prog.opts.SummaryTemplate != "" || prog.opts.Report == reportStdout || prog.opts.DryRunReproducible || prog.opts.ListPlanOnly {
+		return prog.stderr
+	}
+
+	return prog.stdout
+}
+
+// explainExit outputs the description of the return code given with the
+// --explain-exit setting, for interpreting the results of unattended runs.
+func (prog *program) explainExit() (int, error) {
+	code, err := strconv.Atoi(prog.opts.ExplainExit)
+
+	desc, ok := exitCodeDescriptions[code]
+	if err != nil || !ok {
+		fmt.Fprintf(prog.stderr, "error: %v: %q\n", errArgExplainExitInvalid, prog.opts.ExplainExit)
+
+		return exitCodeConfigFailure, fmt.Errorf("%w: %q", errArgExplainExitInvalid, prog.opts.ExplainExit)
+	}
+
+	fmt.Fprintf(prog.stdout, "%d: %s\n", code, desc)
+
+	return exitCodeSuccess, nil
+}
+
+// printExitCodes outputs the table of all of the return codes.
+func printExitCodes(w io.Writer) {
+	fmt.Fprintf(w, "return codes:\n")
+
+	for _, code := range slices.Sorted(maps.Keys(exitCodeDescriptions)) {
+		fmt.Fprintf(w, "  %d\t%s\n", code, exitCodeDescriptions[code])
+	}
+}
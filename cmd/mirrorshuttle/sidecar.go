@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	checksumSidecarPerm = 0o644
+	checksumSidecarExt  = "." + hashAlgoSHA256
+)
+
+// isChecksumSidecar checks if a walked mirror file is the checksum sidecar of
+// another file (with the --checksum-sidecar setting), that is one carrying the
+// extension of the sidecars next to a file of its name within the mirror or the
+// target. These are never promoted, as they could otherwise replace the sidecar
+// of that file, whereas any other files of that extension are moved as usual.
+func (prog *program) isChecksumSidecar(path string, movePath string) bool {
+	if !prog.opts.ChecksumSidecar || !strings.HasSuffix(path, checksumSidecarExt) {
+		return false
+	}
+
+	for _, file := range []string{path, movePath} {
+		if e, err := prog.lstat(strings.TrimSuffix(file, checksumSidecarExt)); err == nil && e.Mode().IsRegular() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sidecarHash returns the SHA-256 digest of the source from the given hashes,
+// or an empty string if it was not computed (with the --hash-algorithms).
+func sidecarHash(hashes fileHashes) string {
+	for _, d := range hashes.srcHashes {
+		if d.algo == hashAlgoSHA256 {
+			return d.hash
+		}
+	}
+
+	return ""
+}
+
+// writeChecksumSidecar writes the checksum sidecar of a moved file next to it
+// (with the --checksum-sidecar setting), in the format of sha256sum, so that
+// the target can be checked with it. The SHA-256 digest of the source is used
+// if it was already computed while moving, which is not the case for a rename
+// (with --direct) or other --hash-algorithms, so the moved file is hashed then.
+// An existing file of the name of the sidecar is never replaced, but warned.
+func (prog *program) writeChecksumSidecar(ctx context.Context, dst string, hashes fileHashes) error {
+	if !prog.opts.ChecksumSidecar {
+		return nil
+	}
+
+	sidecar := dst + checksumSidecarExt
+
+	if !prog.opts.DryRun {
+		hash := sidecarHash(hashes)
+		if hash == "" {
+			var err error
+			if hash, err = prog.hashFile(ctx, dst, hashAlgoSHA256); err != nil {
+				return err
+			}
+		}
+
+		f, err := prog.fsys.OpenFile(sidecar, os.O_WRONLY|os.O_CREATE|os.O_EXCL, checksumSidecarPerm)
+		if errors.Is(err, os.ErrExist) {
+			prog.log.Warn("checksum sidecar skipped", "op", prog.opts.Mode, "path", sidecar, "reason", "already_exists")
+
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to open: %q (%w)", sidecar, err)
+		}
+
+		content := fmt.Sprintf("%s  %s\n", hash, filepath.Base(dst))
+		if _, err := f.WriteString(content); err != nil {
+			f.Close()
+			_ = prog.fsys.Remove(sidecar)
+
+			return fmt.Errorf("failed to write: %q (%w)", sidecar, err)
+		}
+
+		if err := f.Close(); err != nil {
+			_ = prog.fsys.Remove(sidecar)
+
+			return fmt.Errorf("failed to close: %q (%w)", sidecar, err)
+		}
+	}
+	prog.log.Info("checksum sidecar written", "op", prog.opts.Mode, "path", sidecar, "dry-run", prog.opts.DryRun)
+
+	return nil
+}
+
+// finishMove runs the steps that follow each of the moved files: locking it
+// with --lock-promoted, writing its --checksum-sidecar and running the
+// --post-move-command.
+func (prog *program) finishMove(ctx context.Context, src string, dst string, hashes fileHashes, e os.FileInfo) error {
+	if err := prog.lockPromotedFile(dst); err != nil {
+		return prog.walkError(src, e, err)
+	}
+
+	if err := prog.writeChecksumSidecar(ctx, dst, hashes); err != nil {
+		return prog.walkError(src, e, fmt.Errorf("failed to write checksum sidecar: %q (%w)", dst, err))
+	}
+
+	return prog.runPostMoveCommand(ctx, src, dst, hashes.srcHash, e)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// Expectation: A moved file should get a sidecar in the format of sha256sum, also when it was renamed or hashed otherwise.
+func Test_Unit_MoveFiles_ChecksumSidecar_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		direct       bool
+		checksumAlgo string
+	}{
+		{"copy-and-remove", false, ""},
+		{"direct", true, ""},
+		{"checksum-algo", false, hashAlgoBLAKE3},
+		{"checksum-algo-direct", true, hashAlgoCRC32C},
+	}
+
+	sum := sha256.Sum256([]byte("content"))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{"/mirror/dir/file.txt": "content"})
+			require.NoError(t, err)
+			err = createDirStructure(fs, []string{"/real/dir"})
+			require.NoError(t, err)
+
+			opts := &programOptions{
+				MirrorRoot:      "/mirror",
+				RealRoot:        "/real",
+				Direct:          tt.direct,
+				ChecksumAlgo:    tt.checksumAlgo,
+				ChecksumSidecar: true,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err = prog.moveFiles(t.Context())
+			require.NoError(t, err)
+			require.Equal(t, 1, prog.state.movedFiles)
+
+			data, err := afero.ReadFile(fs, "/real/dir/file.txt.sha256")
+			require.NoError(t, err)
+			require.Equal(t, hex.EncodeToString(sum[:])+"  file.txt\n", string(data))
+		})
+	}
+}
+
+// Expectation: A mirror file being the checksum sidecar of another file should never be moved, unlike any other files of that extension.
+func Test_Unit_MoveFiles_ChecksumSidecarNotMoved_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{"/mirror/file.txt": "content"})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:      "/mirror",
+		RealRoot:        "/real",
+		ChecksumSidecar: true,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	sidecar, err := afero.ReadFile(fs, "/real/file.txt.sha256")
+	require.NoError(t, err)
+
+	// A subsequent run finds sidecars within the mirror (such as copied back by a client).
+	err = createFiles(fs, map[string]string{
+		"/mirror/file.txt.sha256":  "tampered",
+		"/mirror/other.txt.sha256": "tampered",
+	})
+	require.NoError(t, err)
+
+	prog, _, _ = setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.True(t, prog.state.hasUnmovedFiles)
+	require.Equal(t, 1, prog.state.unmovedFiles)
+	require.Equal(t, 1, prog.state.movedFiles)
+
+	data, err := afero.ReadFile(fs, "/real/file.txt.sha256")
+	require.NoError(t, err)
+	require.Equal(t, sidecar, data)
+
+	_, err = fs.Stat("/mirror/file.txt.sha256")
+	require.NoError(t, err)
+
+	// A user file of that extension, which is not the sidecar of another file.
+	data, err = afero.ReadFile(fs, "/real/other.txt.sha256")
+	require.NoError(t, err)
+	require.Equal(t, "tampered", string(data))
+}
+
+// Expectation: An existing file of the name of the sidecar should never be replaced.
+func Test_Unit_MoveFiles_ChecksumSidecarExists_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/file.txt":      "content",
+		"/real/file.txt.sha256": "existing",
+	})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:      "/mirror",
+		RealRoot:        "/real",
+		ChecksumSidecar: true,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 1, prog.state.movedFiles)
+	require.Contains(t, stderr.String(), "checksum sidecar skipped")
+
+	data, err := afero.ReadFile(fs, "/real/file.txt.sha256")
+	require.NoError(t, err)
+	require.Equal(t, "existing", string(data))
+
+	_, err = fs.Stat("/real/file.txt")
+	require.NoError(t, err)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	sinceFilePerm = 0o644
+
+	// sinceFileMargin is subtracted from the recorded time of the --since-file,
+	// so that files with slightly skewed modification times are not missed.
+	sinceFileMargin = time.Minute
+)
+
+// readSinceFile returns the time from which on mirror files are considered in
+// --mode=move, being the recorded start of the last successful move less the
+// [sinceFileMargin]. It is the zero time if no move was recorded yet.
+func (prog *program) readSinceFile() (time.Time, error) {
+	data, err := afero.ReadFile(prog.fsys, prog.opts.SinceFile)
+	if errors.Is(err, os.ErrNotExist) {
+		prog.log.Info("since file not found, considering all files", "op", prog.opts.Mode, "path", prog.opts.SinceFile)
+
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read: %q (%w)", prog.opts.SinceFile, err)
+	}
+
+	since, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q (%w)", errSinceFileMalformed, prog.opts.SinceFile, err)
+	}
+	prog.log.Info("since file read", "op", prog.opts.Mode, "path", prog.opts.SinceFile, "since", since.Format(time.RFC3339))
+
+	return since.Add(-sinceFileMargin), nil
+}
+
+// writeSinceFile records the start of a successful move in the --since-file,
+// so that the next move only considers the mirror files changed since then.
+func (prog *program) writeSinceFile(start time.Time) error {
+	if !prog.opts.DryRun {
+		data := []byte(start.UTC().Format(time.RFC3339Nano) + "\n")
+
+		if err := afero.WriteFile(prog.fsys, prog.opts.SinceFile, data, sinceFilePerm); err != nil {
+			return fmt.Errorf("failed to write: %q (%w)", prog.opts.SinceFile, err)
+		}
+	}
+	prog.log.Info("since file written", "op", prog.opts.Mode, "path", prog.opts.SinceFile, "since", start.Format(time.RFC3339), "dry-run", prog.opts.DryRun)
+
+	return nil
+}
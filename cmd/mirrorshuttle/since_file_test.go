@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// Expectation: The second move should only consider the files changed since the start of the first one.
+func Test_Unit_MoveFiles_SinceFile_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{"/mirror/a/file1.txt": "content"})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real/a"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		SinceFile:  "/since",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 1, prog.state.movedFiles)
+
+	content, err := afero.ReadFile(fs, "/since")
+	require.NoError(t, err)
+	since, err := time.Parse(time.RFC3339Nano, string(content[:len(content)-1]))
+	require.NoError(t, err)
+
+	// An unchanged file (outside the margin) and a newly changed file appear in the mirror.
+	err = createFiles(fs, map[string]string{
+		"/mirror/a/unchanged.txt": "content",
+		"/mirror/a/touched.txt":   "content",
+	})
+	require.NoError(t, err)
+	old := since.Add(-2 * sinceFileMargin)
+	require.NoError(t, fs.Chtimes("/mirror/a/unchanged.txt", old, old))
+
+	prog, _, _ = setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+
+	require.Equal(t, 1, prog.state.movedFiles)
+	require.False(t, prog.state.hasUnmovedFiles)
+
+	_, err = fs.Stat("/real/a/touched.txt")
+	require.NoError(t, err)
+
+	_, err = fs.Stat("/mirror/a/unchanged.txt")
+	require.NoError(t, err)
+}
+
+// Expectation: The start of a move should not be recorded if any files were left behind in the mirror.
+func Test_Unit_MoveFiles_SinceFileUnmovedFiles_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{
+		"/mirror/a/file.txt": "content",
+		"/real/a/file.txt":   "other",
+	})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		SinceFile:  "/since",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.NoError(t, err)
+	require.True(t, prog.state.hasUnmovedFiles)
+
+	_, err = fs.Stat("/since")
+	require.Error(t, err)
+}
+
+// Expectation: The move should fail on a since file not containing a valid time.
+func Test_Unit_MoveFiles_SinceFileMalformed_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{"/since": "yesterday"})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/mirror", "/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+		SinceFile:  "/since",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.moveFiles(t.Context())
+	require.ErrorIs(t, err, errSinceFileMalformed)
+}
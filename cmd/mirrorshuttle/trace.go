@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	traceIDSize = 16
+	spanIDSize  = 8
+	runIDSize   = 4
+
+	spanRun       = "run"
+	spanPreflight = "preflight"
+)
+
+// span is a phase of a run (with the --trace-spans setting), which is logged as
+// a pair of begin and end events sharing its span ID. All log lines of the run
+// carry the same trace ID, so that these can be correlated with the spans.
+type span struct {
+	prog   *program
+	name   string
+	id     string
+	parent string
+	start  time.Time
+}
+
+// newTraceID returns a random hex-encoded identifier of the given byte size.
+func newTraceID(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b) // Never returns an error.
+
+	return hex.EncodeToString(b)
+}
+
+// newRunID returns an identifier for an invocation, made of the (UTC) time of
+// its start and a random suffix, so that the identifiers also sort by time.
+func newRunID(now time.Time) string {
+	return now.UTC().Format("20060102T150405Z") + "-" + newTraceID(runIDSize)
+}
+
+// startSpan logs the begin event of a span and returns it, or returns nil if
+// spans are not traced, which is safe to end.
+func (prog *program) startSpan(name string, parent *span) *span {
+	if !prog.opts.TraceSpans {
+		return nil
+	}
+
+	s := &span{
+		prog:  prog,
+		name:  name,
+		id:    newTraceID(spanIDSize),
+		start: time.Now(),
+	}
+	if parent != nil {
+		s.parent = parent.id
+	}
+
+	prog.log.Info("span started", "op", prog.opts.Mode, "span", s.name, "span_id", s.id, "parent_span_id", s.parent)
+
+	return s
+}
+
+// end logs the end event of the span, along with its duration and any error.
+func (s *span) end(err error) {
+	if s == nil {
+		return
+	}
+
+	logArgs := []any{
+		"op", s.prog.opts.Mode,
+		"span", s.name,
+		"span_id", s.id,
+		"parent_span_id", s.parent,
+		"duration", time.Since(s.start),
+	}
+	if err != nil {
+		logArgs = append(logArgs, "error", err)
+	}
+
+	s.prog.log.Info("span ended", logArgs...)
+}
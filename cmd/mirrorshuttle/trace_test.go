@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Expectation: Matching begin and end span events should be logged, with all log lines sharing one trace ID.
+func Test_Integ_Run_TraceSpans_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{"/mirror/dir/file.txt": "test content"})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--trace-spans", "--log-format=json"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NoError(t, err)
+
+	exitCode, err := prog.run(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeSuccess, exitCode)
+
+	started := make(map[string]map[string]any)
+	ended := make(map[string]map[string]any)
+	traceIDs := make(map[string]bool)
+
+	for line := range strings.SplitSeq(strings.TrimSpace(stderr.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record), line)
+
+		traceID, _ := record["trace_id"].(string)
+		require.Len(t, traceID, traceIDSize*2, line)
+		traceIDs[traceID] = true
+
+		switch record["msg"] {
+		case "span started":
+			started[record["span_id"].(string)] = record
+		case "span ended":
+			ended[record["span_id"].(string)] = record
+			require.Contains(t, record, "duration")
+		}
+	}
+
+	require.Len(t, traceIDs, 1)
+	require.Len(t, started, 3)
+	require.Len(t, ended, len(started))
+
+	names := make(map[string]map[string]any)
+	for id, begin := range started {
+		end, ok := ended[id]
+		require.True(t, ok, id)
+		require.Equal(t, begin["span"], end["span"])
+		require.Equal(t, begin["parent_span_id"], end["parent_span_id"])
+		names[begin["span"].(string)] = begin
+	}
+
+	runID := names[spanRun]["span_id"]
+	require.Empty(t, names[spanRun]["parent_span_id"])
+	require.Equal(t, runID, names[spanPreflight]["parent_span_id"])
+	require.Equal(t, runID, names["move"]["parent_span_id"])
+}
+
+// Expectation: No span events or trace IDs should be logged without --trace-spans.
+func Test_Integ_Run_TraceSpansDisabled_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createDirStructure(fs, []string{"/mirror", "/real"})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--log-format=json"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NoError(t, err)
+
+	_, err = prog.run(t.Context())
+	require.NoError(t, err)
+
+	require.NotContains(t, stderr.String(), "span started")
+	require.NotContains(t, stderr.String(), "trace_id")
+}
+
+// Expectation: All log lines of a run should share one run ID, which is either generated or taken from --run-id.
+func Test_Integ_Run_RunID_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantRunID string
+	}{
+		{"generated", nil, ""},
+		{"overridden", []string{"--run-id=nightly-42"}, "nightly-42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			err := createFiles(fs, map[string]string{"/mirror/dir/file.txt": "test content"})
+			require.NoError(t, err)
+			err = createDirStructure(fs, []string{"/real"})
+			require.NoError(t, err)
+
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--log-format=json"}, tt.args...)
+
+			prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+			require.NoError(t, err)
+
+			exitCode, err := prog.run(t.Context())
+			require.NoError(t, err)
+			require.Equal(t, exitCodeSuccess, exitCode)
+
+			runIDs := make(map[string]bool)
+			lines := 0
+
+			for line := range strings.SplitSeq(strings.TrimSpace(stderr.String()), "\n") {
+				var record map[string]any
+				require.NoError(t, json.Unmarshal([]byte(line), &record), line)
+
+				runID, _ := record["run_id"].(string)
+				require.NotEmpty(t, runID, line)
+				runIDs[runID] = true
+				lines++
+			}
+
+			require.Positive(t, lines)
+			require.Len(t, runIDs, 1)
+			require.True(t, runIDs[prog.opts.RunID])
+
+			if tt.wantRunID != "" {
+				require.Equal(t, tt.wantRunID, prog.opts.RunID)
+			} else {
+				require.Regexp(t, `^\d{8}T\d{6}Z-[0-9a-f]{8}$`, prog.opts.RunID)
+			}
+		})
+	}
+}
+
+// Expectation: A --run-id containing whitespace should be rejected by the validation of the configuration.
+func Test_Integ_Run_RunIDInvalid_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--run-id=nightly 42"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.Nil(t, prog)
+	require.ErrorIs(t, err, errArgRunIDInvalid)
+}
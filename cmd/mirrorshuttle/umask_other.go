@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// setProcessUmask does nothing, as there is no umask on this platform.
+func setProcessUmask(_ int) (int, bool) {
+	return 0, false
+}
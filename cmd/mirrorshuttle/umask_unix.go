@@ -0,0 +1,10 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// setProcessUmask sets the umask of the process, returning the previous one.
+func setProcessUmask(mask int) (int, bool) {
+	return syscall.Umask(mask), true
+}
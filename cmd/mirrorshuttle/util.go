@@ -2,16 +2,32 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
+	"hash/crc32"
 	"io"
 	"io/fs"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/spf13/afero"
+	"golang.org/x/text/unicode/norm"
+	"lukechampine.com/blake3"
 )
 
 type excludeArg []string
@@ -28,6 +44,153 @@ func (s *excludeArg) Set(value string) error {
 	return nil
 }
 
+type includeArg []string
+
+func (s *includeArg) String() string {
+	return fmt.Sprint(*s)
+}
+
+func (s *includeArg) Set(value string) error {
+	cleanPath := filepath.Clean(strings.TrimSpace(value))
+
+	*s = append(*s, cleanPath)
+
+	return nil
+}
+
+type nameArg []string
+
+func (s *nameArg) String() string {
+	return fmt.Sprint(*s)
+}
+
+func (s *nameArg) Set(value string) error {
+	*s = append(*s, normalizeName(value))
+
+	return nil
+}
+
+// normalizeName normalizes a basename for comparisons, as the same name could
+// otherwise be encoded in different unicode forms (such as on macOS clients).
+func normalizeName(name string) string {
+	return norm.NFC.String(strings.TrimSpace(name))
+}
+
+type globArg []string
+
+func (s *globArg) String() string {
+	return fmt.Sprint(*s)
+}
+
+func (s *globArg) Set(value string) error {
+	cleanPattern := filepath.Clean(strings.TrimSpace(value))
+
+	*s = append(*s, cleanPattern)
+
+	return nil
+}
+
+type depthRuleArg []string
+
+func (s *depthRuleArg) String() string {
+	return fmt.Sprint(*s)
+}
+
+func (s *depthRuleArg) Set(value string) error {
+	*s = append(*s, strings.TrimSpace(value))
+
+	return nil
+}
+
+type hashAlgoArg []string
+
+func (s *hashAlgoArg) String() string {
+	return fmt.Sprint(*s)
+}
+
+func (s *hashAlgoArg) Set(value string) error {
+	for algo := range strings.SplitSeq(value, ",") {
+		*s = append(*s, strings.ToLower(strings.TrimSpace(algo)))
+	}
+
+	return nil
+}
+
+// newHasher returns a new [hash.Hash] for the named hashing algorithm.
+func newHasher(algo string) (hash.Hash, error) {
+	switch algo {
+	case hashAlgoSHA256:
+		return sha256.New(), nil
+	case hashAlgoSHA512:
+		return sha512.New(), nil
+	case hashAlgoBLAKE3:
+		return blake3.New(blake3Size, nil), nil
+	case hashAlgoCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	default:
+		return nil, errArgHashAlgorithmInvalid
+	}
+}
+
+// emptyDigest returns the well-known digest of empty content for the named
+// hashing algorithm.
+func emptyDigest(algo string) (string, error) {
+	h, err := newHasher(algo)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashAlgorithms returns the user configured hashing algorithms, the first of
+// which is the primary one (used for comparisons), or the default algorithm.
+// An algorithm set with --checksum-algo is always the primary one.
+func (prog *program) hashAlgorithms() []string {
+	if prog.opts.ChecksumAlgo != "" {
+		algos := []string{prog.opts.ChecksumAlgo}
+		for _, algo := range prog.opts.HashAlgorithms {
+			if algo != prog.opts.ChecksumAlgo {
+				algos = append(algos, algo)
+			}
+		}
+
+		return algos
+	}
+
+	if len(prog.opts.HashAlgorithms) == 0 {
+		return []string{hashAlgoSHA256}
+	}
+
+	return prog.opts.HashAlgorithms
+}
+
+// encodeInvalidUTF8 encodes a string that is not valid UTF-8 for output, by
+// either escaping each invalid byte as \xNN or encoding the entire string as
+// base64 (prefixed with "base64:"). Valid strings are returned unchanged.
+func encodeInvalidUTF8(s string, encoding string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+
+	if encoding == pathEncodingBase64 {
+		return pathEncodingBase64 + ":" + base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			fmt.Fprintf(&sb, "\\x%02x", s[i])
+		} else {
+			sb.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+
+	return sb.String()
+}
+
 func parseLogLevel(levelStr string) (slog.Level, error) {
 	switch strings.TrimSpace(levelStr) {
 	case "debug":
@@ -43,16 +206,168 @@ func parseLogLevel(levelStr string) (slog.Level, error) {
 	}
 }
 
-func (prog *program) walkError(e fs.FileInfo, err error) error {
-	if !errors.Is(err, context.Canceled) && prog.opts.SkipFailed {
-		prog.state.hasPartialFailures = true
+// parseUmask parses an octal umask (such as "022" or "0027").
+func parseUmask(maskStr string) (int, error) {
+	mask, err := strconv.ParseUint(strings.TrimSpace(maskStr), 8, 32)
+	if err != nil || mask > 0o777 {
+		return 0, errArgUmaskInvalid
+	}
+
+	return int(mask), nil
+}
+
+// parseTargetPerms parses the --require-target-perms, being the octal mode that
+// the target root must have exactly, or at most (with the "max:" prefix).
+func parseTargetPerms(permStr string) (perm os.FileMode, atMost bool, err error) {
+	permStr = strings.TrimSpace(permStr)
+	atMost = strings.HasPrefix(permStr, targetPermsMaxPrefix)
+
+	mode, err := strconv.ParseUint(strings.TrimPrefix(permStr, targetPermsMaxPrefix), 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, false, errArgTargetPermsInvalid
+	}
+
+	return os.FileMode(mode), atMost, nil
+}
+
+// parseBytes parses a size in bytes, optionally with a binary suffix (such as
+// "512", "64K", "64KiB", "4M", "4MiB", "1G" or "2TiB"), reporting whether the
+// size was valid.
+func parseBytes(sizeStr string) (int64, bool) {
+	sizeStr = strings.TrimSpace(sizeStr)
+
+	numStr := strings.TrimRight(sizeStr, "KMGTBikmgtb")
+	unit := strings.ToLower(sizeStr[len(numStr):])
+
+	num, err := strconv.ParseInt(strings.TrimSpace(numStr), 10, 64)
+	if err != nil || num < 0 {
+		return 0, false
+	}
+
+	var shift uint
+
+	switch unit {
+	case "", "b":
+	case "k", "kib":
+		shift = 10
+	case "m", "mib":
+		shift = 20
+	case "g", "gib":
+		shift = 30
+	case "t", "tib":
+		shift = 40
+	default:
+		return 0, false
+	}
+
+	if num > math.MaxInt64>>shift {
+		return 0, false
+	}
+
+	return num << shift, true
+}
+
+// parseByteSize parses a size in bytes (see [parseBytes]), within the allowed
+// range for the --copy-buffer-size.
+func parseByteSize(sizeStr string) (int, error) {
+	num, ok := parseBytes(sizeStr)
+	if !ok || num < minCopyBufferSize || num > maxCopyBufferSize {
+		return 0, errArgCopyBufferSizeInvalid
+	}
+
+	return int(num), nil
+}
+
+// newCopyBufferPool returns a pool of buffers of the given size for copying
+// files (with the --copy-buffer-size setting), so that a buffer is not
+// allocated for each of the copied files.
+func newCopyBufferPool(size int) *sync.Pool {
+	return &sync.Pool{
+		New: func() any {
+			buf := make([]byte, size)
+
+			return &buf
+		},
+	}
+}
+
+// dirSize returns the recursive size of all files below the given directory,
+// caching the sizes of the directory and all of its subdirectories for later
+// calls on them, as the walk of --init-skip-dirs-over descends into them.
+func (prog *program) dirSize(ctx context.Context, path string, cache map[string]int64) (int64, error) {
+	if size, ok := cache[path]; ok {
+		return size, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		// An interrupt was received, also interrupt the calculation.
+		return 0, fmt.Errorf("failed checking context: %w", err)
+	}
+
+	entries, err := afero.ReadDir(prog.fsys, path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read: %q (%w)", path, err)
+	}
+
+	var size int64
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			size += e.Size()
+
+			continue
+		}
+
+		subSize, err := prog.dirSize(ctx, filepath.Join(path, e.Name()), cache)
+		if err != nil {
+			return 0, err
+		}
+		size += subSize
+	}
+
+	cache[path] = size
+
+	return size, nil
+}
+
+// checkInterrupt returns an error if the operation was interrupted, either by
+// the cancellation of the context or by a first interrupt signal (with the
+// --graceful-interrupt setting), which stops before the next element only.
+func (prog *program) checkInterrupt(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed checking context: %w", err)
+	}
+
+	if prog.state.interrupted.Load() {
+		return fmt.Errorf("failed checking context: %w (%w)", context.Canceled, errGracefulInterrupt)
+	}
+
+	return nil
+}
+
+func (prog *program) walkError(path string, e fs.FileInfo, err error) error {
+	if !errors.Is(err, context.Canceled) && (prog.opts.SkipFailed || prog.opts.NoFailFast) {
+		if prog.opts.SkipFailed {
+			prog.state.hasPartialFailures = true
+		} else {
+			// Without --skip-failed, the failure still fails the operation, only at its end.
+			prog.state.hasHardFailures = true
+		}
+		prog.state.failures = append(prog.state.failures, pathFailure{path: path, err: err})
 
 		prog.log.Error("path skipped",
 			"op", prog.opts.Mode,
+			"path", path,
 			"error", err,
 			"error-type", "runtime",
 			"reason", "error_occurred",
 		)
+		prog.recordSkip(path, "error_occurred")
+
+		if prog.opts.SkipFailed && prog.opts.SkipFailedMax > 0 && len(prog.state.failures) > prog.opts.SkipFailedMax {
+			// So many failures point to a systemic problem (such as a target gone read-only).
+			return fmt.Errorf("%w: %d > %d (%w)", errSkipFailedMax, len(prog.state.failures), prog.opts.SkipFailedMax, err)
+		}
 
 		if e.IsDir() {
 			return filepath.SkipDir // Do not traverse deeper.
@@ -64,7 +379,370 @@ func (prog *program) walkError(e fs.FileInfo, err error) error {
 	return err
 }
 
+// lstat returns the information of a path without following it if it is a
+// symbolic link, where the filesystem supports telling these apart.
+func (prog *program) lstat(path string) (os.FileInfo, error) {
+	if lstater, ok := prog.fsys.(afero.Lstater); ok {
+		e, _, err := lstater.LstatIfPossible(path)
+
+		return e, err //nolint:wrapcheck // Wrapped by the callers.
+	}
+
+	return prog.fsys.Stat(path) //nolint:wrapcheck // Wrapped by the callers.
+}
+
+// checkTargetRoot guards against a target root that is itself a symbolic link,
+// as operations could then land on an unexpected underlying store. Such a root
+// is refused, unless the user allows it, in which case it is resolved and the
+// program works on the resolved path (along with any paths contained within).
+func (prog *program) checkTargetRoot() error {
+	lstater, ok := prog.fsys.(afero.Lstater)
+	if !ok {
+		// The filesystem cannot tell symbolic links apart, nothing to check.
+		return nil
+	}
+
+	resolved := prog.opts.RealRoot
+
+	for range maxSymlinkHops {
+		e, lstatCalled, err := lstater.LstatIfPossible(resolved)
+		if errors.Is(err, os.ErrNotExist) {
+			// A non-existing target root is left to the respective mode to report.
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to lstat: %q (%w)", resolved, err)
+		}
+
+		if !lstatCalled || e.Mode()&os.ModeSymlink == 0 {
+			break
+		}
+
+		if !prog.opts.AllowSymlinkedTarget {
+			return fmt.Errorf("%w: %q", errTargetIsSymlink, prog.opts.RealRoot)
+		}
+
+		reader, ok := prog.fsys.(afero.LinkReader)
+		if !ok {
+			return fmt.Errorf("%w: %q", errTargetIsSymlink, prog.opts.RealRoot)
+		}
+
+		link, err := reader.ReadlinkIfPossible(resolved)
+		if err != nil {
+			return fmt.Errorf("failed to readlink: %q (%w)", resolved, err)
+		}
+
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(resolved), link)
+		}
+		resolved = filepath.Clean(link)
+	}
+
+	if resolved == prog.opts.RealRoot {
+		return nil
+	}
+
+	if e, _, err := lstater.LstatIfPossible(resolved); err == nil && e.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%w: %q (too many levels of links)", errTargetIsSymlink, prog.opts.RealRoot)
+	}
+
+	prog.log.Warn("target resolved", "op", prog.opts.Mode, "path", prog.opts.RealRoot, "resolved", resolved, "reason", "target_is_symlink")
+
+	// Any paths contained within the target root need to follow it to the resolved path.
+	rebase := func(path string) string {
+		if rel, err := filepath.Rel(prog.opts.RealRoot, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.Join(resolved, rel)
+		}
+
+		return path
+	}
+
+	if prog.opts.MirrorRoot != "" {
+		prog.opts.MirrorRoot = rebase(prog.opts.MirrorRoot)
+	}
+	for i, excl := range prog.opts.Excludes {
+		prog.opts.Excludes[i] = rebase(excl)
+	}
+	prog.opts.RealRoot = resolved
+
+	return nil
+}
+
+// checkTargetPerms enforces the --require-target-perms on the target root, so
+// that nothing is done on a target that is more permissive than intended.
+func (prog *program) checkTargetPerms() error {
+	if prog.opts.RequireTargetPerms == "" {
+		return nil
+	}
+
+	perm, atMost, err := parseTargetPerms(prog.opts.RequireTargetPerms)
+	if err != nil {
+		return fmt.Errorf("%w: %q", err, prog.opts.RequireTargetPerms)
+	}
+
+	e, err := prog.fsys.Stat(prog.opts.RealRoot)
+	if errors.Is(err, os.ErrNotExist) {
+		// A non-existing target root is left to the respective mode to report.
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to stat: %q (%w)", prog.opts.RealRoot, err)
+	}
+
+	if actual := e.Mode().Perm(); (atMost && actual&^perm != 0) || (!atMost && actual != perm) {
+		return fmt.Errorf("%w: %q (%04o, requires %s)", errTargetPermsMismatch, prog.opts.RealRoot, actual, prog.opts.RequireTargetPerms)
+	}
+
+	return nil
+}
+
+// startHeartbeat touches the heartbeat file from a background goroutine in the
+// configured interval, giving any watchdogs a liveness signal that is distinct
+// from the output. The returned function stops the goroutine and waits for it.
+func (prog *program) startHeartbeat(ctx context.Context) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	prog.touchHeartbeat()
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(prog.opts.HeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				prog.touchHeartbeat()
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// touchHeartbeat updates the modification time of the heartbeat file, creating
+// it if it does not yet exist. Any failures are only logged, as the heartbeat
+// is not essential to the operation itself.
+func (prog *program) touchHeartbeat() {
+	path := prog.opts.HeartbeatFile
+
+	f, err := prog.fsys.OpenFile(path, os.O_CREATE|os.O_WRONLY, heartbeatFilePerm)
+	if err == nil {
+		err = f.Close()
+	}
+
+	if err == nil {
+		now := time.Now()
+		err = prog.fsys.Chtimes(path, now, now)
+	}
+
+	if err != nil {
+		prog.log.Warn("heartbeat failed", "op", prog.opts.Mode, "path", path, "error", err, "error-type", "runtime")
+	}
+}
+
+// countMoved counts a moved file of the given size towards the moved counters.
+func (prog *program) countMoved(size int64) {
+	prog.state.mu.Lock()
+	defer prog.state.mu.Unlock()
+
+	prog.state.movedFiles++
+	prog.state.movedBytes += size
+}
+
+// startStats logs the throughput of the moved files (since the last interval)
+// from a background goroutine in the configured interval, so that slowdowns
+// of long runs become visible. The returned function stops the goroutine and
+// waits for it.
+func (prog *program) startStats(ctx context.Context) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(prog.opts.StatsInterval)
+		defer ticker.Stop()
+
+		var lastFiles int
+		var lastBytes int64
+		last := time.Now()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				prog.state.mu.Lock()
+				files, bytes := prog.state.movedFiles, prog.state.movedBytes
+				prog.state.mu.Unlock()
+
+				secs := now.Sub(last).Seconds()
+				prog.log.Info("throughput",
+					"op", prog.opts.Mode,
+					"files_per_sec", math.Round(float64(files-lastFiles)/secs*100)/100,
+					"mb_per_sec", math.Round(float64(bytes-lastBytes)/secs/1e6*100)/100,
+					"files_moved", files,
+					"bytes_moved", bytes,
+					"dry-run", prog.opts.DryRun)
+
+				lastFiles, lastBytes, last = files, bytes, now
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// isMountPoint checks if a directory is a mount point, by it residing on
+// another device than its parent. It is false if the devices cannot be known.
+func (prog *program) isMountPoint(path string) (bool, error) {
+	e, err := prog.fsys.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat: %q (%w)", path, err)
+	}
+
+	parent, err := prog.fsys.Stat(filepath.Dir(path))
+	if err != nil {
+		return false, fmt.Errorf("failed to stat: %q (%w)", filepath.Dir(path), err)
+	}
+
+	dev, ok := fileDevice(e)
+	parentDev, parentOk := fileDevice(parent)
+
+	return ok && parentOk && dev != parentDev, nil
+}
+
+// checkRemovable checks that a structure can be removed in full, by probing
+// that each of its directories (and its parent) allows for removing entries,
+// so that a removal is not aborted halfway through with a partial structure.
+// With keepRoot, only the contents are removed (such as of a mount point), so
+// the parent is not probed.
+func (prog *program) checkRemovable(ctx context.Context, path string, keepRoot bool) error {
+	var dirs []string
+	if !keepRoot {
+		dirs = append(dirs, filepath.Dir(path))
+	}
+
+	if err := afero.Walk(prog.fsys, path, func(subpath string, e os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			// An interrupt was received, also interrupt the walk.
+			return fmt.Errorf("failed checking context: %w", err)
+		}
+
+		if err != nil {
+			// An error has occurred (permissioning, ...), not safe to continue.
+			return fmt.Errorf("failed to walk: %q (%w)", subpath, err)
+		}
+
+		if e.IsDir() {
+			dirs = append(dirs, subpath)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		probe := filepath.Join(dir, removableProbeName+workingFileSuffix)
+
+		f, err := prog.fsys.Create(probe)
+		if err != nil {
+			return fmt.Errorf("%w: %q (%w)", errMirrorNotRemovable, dir, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close: %q (%w)", probe, err)
+		}
+
+		if err := prog.fsys.Remove(probe); err != nil {
+			return fmt.Errorf("%w: %q (%w)", errMirrorNotRemovable, dir, err)
+		}
+	}
+
+	return nil
+}
+
+// probeWritable checks that the target root is writable (with --probe-writable)
+// so that a read-only (re)mounted target fails the move before it starts rather
+// than in its middle.
+func (prog *program) probeWritable() error {
+	if err := prog.probeDir(prog.opts.RealRoot); err != nil {
+		return fmt.Errorf("%w: %q (%w)", errTargetNotWritable, prog.opts.RealRoot, err)
+	}
+
+	prog.log.Debug("target writable", "op", prog.opts.Mode, "path", prog.opts.RealRoot)
+
+	return nil
+}
+
+// probeDir checks that a directory is writable by creating, writing and
+// removing a probe file within it.
+func (prog *program) probeDir(dir string) error {
+	probe := filepath.Join(dir, writableProbeName+workingFileSuffix)
+
+	f, err := prog.fsys.Create(probe)
+	if err != nil {
+		return fmt.Errorf("failed to create: %q (%w)", probe, err)
+	}
+
+	if _, err := f.Write([]byte(writableProbeName)); err != nil {
+		f.Close()
+		_ = prog.fsys.Remove(probe)
+
+		return fmt.Errorf("failed to write: %q (%w)", probe, err)
+	}
+
+	if err := f.Close(); err != nil {
+		_ = prog.fsys.Remove(probe)
+
+		return fmt.Errorf("failed to close: %q (%w)", probe, err)
+	}
+
+	if err := prog.fsys.Remove(probe); err != nil {
+		return fmt.Errorf("failed to remove: %q (%w)", probe, err)
+	}
+
+	return nil
+}
+
+// clearDir removes all of the contents of a directory, but not the directory.
+func (prog *program) clearDir(path string) error {
+	entries, err := afero.ReadDir(prog.fsys, path)
+	if err != nil {
+		return fmt.Errorf("failed to read: %q (%w)", path, err)
+	}
+
+	if prog.opts.DryRun {
+		return nil
+	}
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(path, entry.Name())
+
+		if err := prog.fsys.RemoveAll(entryPath); err != nil {
+			return fmt.Errorf("failed to remove: %q (%w)", entryPath, err)
+		}
+	}
+
+	return nil
+}
+
 func (prog *program) isEmptyStructure(ctx context.Context, path string) (bool, error) {
+	return prog.isEmptyTree(ctx, path, prog.opts.Mode == "init")
+}
+
+// isEmptyTree checks if a structure contains no files, with listFiles outputting
+// all of them found (rather than returning immediately with the first of them).
+func (prog *program) isEmptyTree(ctx context.Context, path string, listFiles bool) (bool, error) {
 	path = filepath.Clean(strings.TrimSpace(path))
 
 	empty := true
@@ -81,13 +759,13 @@ func (prog *program) isEmptyStructure(ctx context.Context, path string) (bool, e
 			return fmt.Errorf("failed to walk: %q (%w)", subpath, err)
 		}
 
-		if !e.IsDir() {
+		if !e.IsDir() && subpath != prog.mirrorReadmePath() {
 			empty = false
-			if prog.opts.Mode == "init" {
+			if listFiles {
 				// Output the file that was found, but also continue to get the full list.
 				prog.log.Warn("unmoved file found", "op", prog.opts.Mode, "path", subpath)
 			} else {
-				// Immediately return in other modes, where we do not care about the output.
+				// Immediately return otherwise, where we do not care about the output.
 				return filepath.SkipAll
 			}
 		}
@@ -106,14 +784,96 @@ func (prog *program) isEmptyStructure(ctx context.Context, path string) (bool, e
 	return true, nil
 }
 
-func isExcluded(path string, excludes []string) bool {
-	path = filepath.Clean(strings.TrimSpace(path))
+// createParentDirs creates any missing parent directories of a relative path
+// below the given root, counting and logging each of the created directories.
+// The known map caches directories that are known to exist (or would exist, in
+// dry mode) so that repeated calls do not stat or create them again.
+func (prog *program) createParentDirs(root string, relPath string, known map[string]bool) error {
+	var missing []string
 
-	for _, excl := range excludes {
-		if path == excl {
+	for p := filepath.Dir(relPath); p != "."; p = filepath.Dir(p) {
+		parentPath := filepath.Join(root, p)
+
+		if known[parentPath] {
+			break
+		}
+
+		if _, err := prog.fsys.Stat(parentPath); err == nil {
+			known[parentPath] = true
+
+			break
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to stat: %q (%w)", parentPath, err)
+		}
+
+		missing = append(missing, parentPath)
+	}
+
+	// Create the missing parents from the top downwards.
+	for i := len(missing) - 1; i >= 0; i-- {
+		if !prog.opts.DryRun {
+			if root == prog.opts.RealRoot {
+				// The parents within the target are created as any other target directory.
+				if err := prog.mkdirTarget(missing[i]); err != nil {
+					return err
+				}
+			} else if err := prog.fsys.Mkdir(missing[i], dirBasePerm); errors.Is(err, os.ErrExist) && (prog.opts.InitChangedSince > 0 || prog.opts.InitMerge) {
+				// The parent appeared since the check above, within a mirror that is kept.
+				known[missing[i]] = true
+
+				continue
+			} else if err != nil {
+				return fmt.Errorf("failed to create: %q (%w)", missing[i], err)
+			}
+		}
+		prog.state.createdDirs++
+		known[missing[i]] = true
+
+		if root != prog.opts.RealRoot {
+			prog.recordDiffDir(root, missing[i])
+		}
+
+		prog.recordPlan(planOperation{Op: planOpMkdir, Dst: missing[i]})
+		prog.log.Info("directory created", "op", prog.opts.Mode, "path", missing[i], "reason", "is_parent_dir", "dry-run", prog.opts.DryRun)
+	}
+
+	return nil
+}
+
+// isExcludedPath checks if a walked path is excluded by the user, and outputs
+// the matching exclusion with the --list-excluded setting.
+func (prog *program) isExcludedPath(path string) bool {
+	excl, excluded := matchExclude(path, prog.opts.Excludes, prog.opts.CaseInsensitivePaths)
+
+	if excluded && prog.opts.ListExcluded {
+		prog.log.Info("path excluded", "op", prog.opts.Mode, "path", path, "exclude", excl)
+	}
+
+	return excluded
+}
+
+// isIncluded checks if a walked path is one of the user's included paths or
+// within one of them, or (for a directory) a parent leading to one of them.
+// Without any included paths, all paths are included.
+func (prog *program) isIncluded(path string, isDir bool) bool {
+	if len(prog.opts.Includes) == 0 {
+		return true
+	}
+
+	path = filepath.Clean(path)
+	if prog.opts.CaseInsensitivePaths {
+		path = strings.ToLower(path)
+	}
+
+	for _, incl := range prog.opts.Includes {
+		if prog.opts.CaseInsensitivePaths {
+			incl = strings.ToLower(incl)
+		}
+
+		if rel, err := filepath.Rel(incl, path); err == nil && !strings.HasPrefix(rel, "..") {
 			return true
 		}
-		if rel, err := filepath.Rel(excl, path); err == nil && !strings.HasPrefix(rel, "..") {
+		if rel, err := filepath.Rel(path, incl); isDir && err == nil && !strings.HasPrefix(rel, "..") {
 			return true
 		}
 	}
@@ -121,14 +881,449 @@ func isExcluded(path string, excludes []string) bool {
 	return false
 }
 
+// isExcludedName checks if the basename of a walked path is excluded by the
+// user, and outputs the matching exclusion with the --list-excluded setting.
+func (prog *program) isExcludedName(path string) bool {
+	if len(prog.opts.ExcludeNames) == 0 {
+		return false
+	}
+
+	name, excluded := matchExcludeName(filepath.Base(path), prog.opts.ExcludeNames, prog.opts.ExcludeNameIgnoreCase)
+
+	if excluded && prog.opts.ListExcluded {
+		prog.log.Info("path excluded", "op", prog.opts.Mode, "path", path, "exclude-name", name)
+	}
+
+	return excluded
+}
+
+// defaultSystemDirNames are the well-known names of the directories that the
+// NAS systems (and operating systems) keep within shares for their own use.
+var defaultSystemDirNames = []string{
+	"@eaDir",
+	"#recycle",
+	"#snapshot",
+	".snapshots",
+	"@Recycle",
+	"@Recently-Snapshot",
+	"$RECYCLE.BIN",
+	"System Volume Information",
+}
+
+// isSystemDir checks if the basename of a walked directory is among the system
+// directories (with --skip-system-dirs), which are compared case-insensitively,
+// and outputs the matching name with the --list-excluded setting.
+func (prog *program) isSystemDir(path string) bool {
+	if !prog.opts.SkipSystemDirs {
+		return false
+	}
+
+	name, excluded := matchExcludeName(filepath.Base(path), prog.opts.SystemDirNames, true)
+
+	if excluded && prog.opts.ListExcluded {
+		prog.log.Info("path excluded", "op", prog.opts.Mode, "path", path, "system-dir-name", name)
+	}
+
+	return excluded
+}
+
+// matchExcludeName returns the first of the excluded names that a basename
+// matches exactly, or case-insensitively with ignoreCase.
+func matchExcludeName(base string, names []string, ignoreCase bool) (string, bool) {
+	base = normalizeName(base)
+
+	for _, name := range names {
+		if base == name || (ignoreCase && strings.EqualFold(base, name)) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// isExcludedGlob checks if a walked path (other than the walked root) is
+// excluded by any of the user's glob patterns, and outputs the matching
+// pattern with the --list-excluded setting.
+func (prog *program) isExcludedGlob(path string, root string, patterns []string) bool {
+	if len(patterns) == 0 || path == root {
+		return false
+	}
+
+	pattern, excluded := matchExcludeGlob(path, patterns)
+
+	if excluded && prog.opts.ListExcluded {
+		prog.log.Info("path excluded", "op", prog.opts.Mode, "path", path, "exclude-glob", pattern)
+	}
+
+	return excluded
+}
+
+// matchExcludeGlob returns the first of the patterns that a path is matched by.
+// Patterns without a separator are matched against the basename at any depth,
+// all others (which are absolute) against the full path (see [matchExcludePattern]).
+func matchExcludeGlob(path string, patterns []string) (string, bool) {
+	for _, pattern := range patterns {
+		if !strings.ContainsRune(pattern, filepath.Separator) {
+			if matchGlobPart(pattern, filepath.Base(path), false) {
+				return pattern, true
+			}
+
+			continue
+		}
+
+		if matchExcludePattern(path, pattern, false) {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
+// pruneExcludes removes any excludes that are redundant, being equal to or
+// contained within another one. As each walked path is matched against all
+// excludes, this keeps long lists cheaper.
+func (prog *program) pruneExcludes() {
+	// Shorter paths come first, so that any parent is kept before its children.
+	excludes := slices.Clone(prog.opts.Excludes)
+	slices.SortStableFunc(excludes, func(a string, b string) int {
+		return len(a) - len(b)
+	})
+
+	kept := make([]string, 0, len(excludes))
+	for _, excl := range excludes {
+		if parent, redundant := matchExclude(excl, kept, prog.opts.CaseInsensitivePaths); redundant {
+			prog.log.Debug("redundant exclude pruned", "op", prog.opts.Mode, "exclude", excl, "within", parent)
+
+			continue
+		}
+		kept = append(kept, excl)
+	}
+
+	prog.opts.Excludes = kept
+}
+
+func isExcluded(path string, excludes []string) bool {
+	_, excluded := matchExclude(path, excludes, false)
+
+	return excluded
+}
+
+// matchExclude returns the first of the excludes that a path is excluded by,
+// comparing the paths case-insensitively with ignoreCase. An exclude that
+// contains glob metacharacters is also matched as a pattern (see
+// [matchExcludePattern]), with the same subtree semantics as for a path.
+func matchExclude(path string, excludes []string, ignoreCase bool) (string, bool) {
+	path = filepath.Clean(strings.TrimSpace(path))
+
+	cmpPath := path
+	if ignoreCase {
+		cmpPath = strings.ToLower(path)
+	}
+
+	for _, excl := range excludes {
+		cmpExcl := excl
+		if ignoreCase {
+			cmpExcl = strings.ToLower(excl)
+		}
+
+		if cmpPath == cmpExcl {
+			return excl, true
+		}
+		if rel, err := filepath.Rel(cmpExcl, cmpPath); err == nil && !strings.HasPrefix(rel, "..") {
+			return excl, true
+		}
+		if isGlobPattern(excl) && matchExcludePattern(path, excl, ignoreCase) {
+			return excl, true
+		}
+	}
+
+	return "", false
+}
+
+// isGlobPattern checks if an exclude contains any glob metacharacters.
+func isGlobPattern(excl string) bool {
+	return strings.ContainsAny(excl, "*?[")
+}
+
+// matchExcludePattern checks if a path, or any of its parents, is matched by
+// a glob pattern (see [matchGlobParts]). A relative pattern (such as "*.tmp"
+// or "*/node_modules") is matched at any depth of the path.
+func matchExcludePattern(path string, pattern string, ignoreCase bool) bool {
+	patternParts := strings.Split(pattern, string(filepath.Separator))
+	if !filepath.IsAbs(pattern) {
+		patternParts = append([]string{"**"}, patternParts...)
+	}
+
+	matched, _ := matchGlobParts(strings.Split(path, string(filepath.Separator)), patternParts, ignoreCase)
+
+	return matched
+}
+
+// matchGlobParts compares path components with pattern components, one at a
+// time (see [matchGlobPart]), where a "**" component matches any number of
+// path components. It reports whether the leading path components are matched
+// by all of the pattern components, so that the remaining ones are within a
+// match, and whether the path ran out with the pattern still matching, so
+// that any of its descendants could still be matched.
+func matchGlobParts(pathParts []string, patternParts []string, ignoreCase bool) (matched bool, descend bool) {
+	if len(patternParts) == 0 {
+		return true, true
+	}
+
+	if patternParts[0] == "**" {
+		for i := 0; i <= len(pathParts); i++ {
+			m, d := matchGlobParts(pathParts[i:], patternParts[1:], ignoreCase)
+			if m {
+				return true, true
+			}
+			descend = descend || d
+		}
+
+		return false, descend
+	}
+
+	if len(pathParts) == 0 {
+		return false, true
+	}
+
+	if !matchGlobPart(patternParts[0], pathParts[0], ignoreCase) {
+		return false, false
+	}
+
+	return matchGlobParts(pathParts[1:], patternParts[1:], ignoreCase)
+}
+
+// matchGlobPart checks if a single path component is matched by a pattern
+// (with [filepath.Match]), case-insensitively with ignoreCase.
+func matchGlobPart(pattern string, name string, ignoreCase bool) bool {
+	if ignoreCase {
+		pattern = foldGlobCase(pattern)
+	}
+
+	ok, _ := filepath.Match(pattern, name)
+
+	return ok
+}
+
+// foldGlobCase rewrites a pattern to match either case of its letters, rather
+// than lowering it, which would change the meaning of any character classes
+// (such as "[A-Z]"). Each letter becomes a class of both of its cases, and each
+// class is extended by its own lower- and uppercased ranges.
+func foldGlobCase(pattern string) string {
+	var sb strings.Builder
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == '\\' && runtime.GOOS != "windows" && i+1 < len(runes):
+			i++
+			if lower, upper := unicode.ToLower(runes[i]), unicode.ToUpper(runes[i]); lower != upper {
+				sb.WriteString("[" + string(lower) + string(upper) + "]")
+			} else {
+				sb.WriteRune('\\')
+				sb.WriteRune(runes[i])
+			}
+
+		case r == '[':
+			end := classEnd(runes, i+1)
+			if end < 0 {
+				sb.WriteString(string(runes[i:])) // Malformed, left for [filepath.Match] to reject.
+
+				return sb.String()
+			}
+
+			body := string(runes[i+1 : end])
+			negate := ""
+			if strings.HasPrefix(body, "^") {
+				negate, body = "^", body[1:]
+			}
+			sb.WriteString("[" + negate + body + strings.ToLower(body) + strings.ToUpper(body) + "]")
+			i = end
+
+		default:
+			if lower, upper := unicode.ToLower(r), unicode.ToUpper(r); lower != upper {
+				sb.WriteString("[" + string(lower) + string(upper) + "]")
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// classEnd returns the index of the "]" closing a character class that has its
+// body starting at the given index, or -1 if the class is never closed.
+func classEnd(runes []rune, start int) int {
+	for i := start; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && runtime.GOOS != "windows":
+			i++
+		case runes[i] == ']':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// samePath compares two (clean) paths, case-insensitively with the setting
+// --case-insensitive-paths, as both refer to the same path on such volumes.
+func (prog *program) samePath(a string, b string) bool {
+	if prog.opts.CaseInsensitivePaths {
+		return strings.EqualFold(a, b)
+	}
+
+	return a == b
+}
+
+// matchTargetGlobs reports whether a relative path is matched by any of the
+// patterns, either directly or through one of its ancestors, and whether any
+// of its descendants could still be matched by one of the patterns.
+func matchTargetGlobs(relPath string, patterns []string) (matched bool, descend bool) {
+	relParts := strings.Split(filepath.Clean(relPath), string(filepath.Separator))
+	if relPath == "." {
+		relParts = nil
+	}
+
+	for _, pattern := range patterns {
+		m, d := matchGlobParts(relParts, strings.Split(pattern, string(filepath.Separator)), false)
+		if m {
+			return true, true
+		}
+		descend = descend || d
+	}
+
+	return false, descend
+}
+
+// depthRule is a parsed --init-depth-rule, limiting the depth of the mirrored
+// directories below the prefix (both relative to the target root).
+type depthRule struct {
+	prefix string
+	depth  int
+}
+
+func parseDepthRules(values []string) ([]depthRule, error) {
+	rules := make([]depthRule, 0, len(values))
+
+	for _, v := range values {
+		sep := strings.LastIndex(v, ":")
+		if sep < 0 {
+			return nil, fmt.Errorf("%w: %q", errArgInitDepthRuleInvalid, v)
+		}
+
+		prefix := filepath.Clean(v[:sep])
+		if prefix == "." || filepath.IsAbs(prefix) || prefix == ".." || strings.HasPrefix(prefix, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%w: %q", errArgInitDepthRuleInvalid, v)
+		}
+
+		depth, err := strconv.Atoi(v[sep+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", errArgInitDepthRuleInvalid, v)
+		}
+
+		rules = append(rules, depthRule{prefix: prefix, depth: depth})
+	}
+
+	return rules, nil
+}
+
+// initDepthLimit returns the depth of a relative path and the maximum depth it
+// may have, either from the most specific of the rules whose prefix it is below
+// or otherwise from the global limit. Any prefixes (and their parents) are never
+// limited, so that the rules can also reach below the global limit.
+func initDepthLimit(relPath string, rules []depthRule, globalLimit int) (depth int, limit int) {
+	var match *depthRule
+
+	for i, r := range rules {
+		if r.prefix == relPath || strings.HasPrefix(r.prefix, relPath+string(filepath.Separator)) || relPath == "." {
+			return dirDepth(relPath), -1
+		}
+
+		if strings.HasPrefix(relPath, r.prefix+string(filepath.Separator)) && (match == nil || len(r.prefix) > len(match.prefix)) {
+			match = &rules[i]
+		}
+	}
+
+	if match == nil {
+		return dirDepth(relPath), globalLimit
+	}
+
+	return dirDepth(strings.TrimPrefix(relPath, match.prefix+string(filepath.Separator))), match.depth
+}
+
 func dirDepth(relPath string) int {
 	return strings.Count(filepath.Clean(relPath), string(filepath.Separator))
 }
 
+// hashFile reads the file at the given path in full, returning its hex-encoded
+// hash of the given algorithm. The read is aborted if the context is cancelled.
+func (prog *program) hashFile(ctx context.Context, path string, algo string) (string, error) {
+	hasher, err := newHasher(algo)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", err, algo)
+	}
+
+	f, err := prog.fsys.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open: %q (%w)", path, err)
+	}
+	defer f.Close()
+
+	ctxReader := &contextReader{ctx, f}
+
+	if _, err := io.Copy(hasher, ctxReader); err != nil {
+		return "", fmt.Errorf("failed to read: %q (%w)", path, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to close: %q (%w)", path, err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// runReport is the content of the --report, as written at the end of a run,
+// for capturing its results (including any skipped paths) from within scripts.
+type runReport struct {
+	Mode     string       `json:"mode"`
+	DryRun   bool         `json:"dry_run"`
+	Exit     int          `json:"exit"`
+	Started  time.Time    `json:"started"`
+	Duration float64      `json:"duration_seconds"`
+	Created  int          `json:"created"`
+	Moved    int          `json:"moved"`
+	Unmoved  int          `json:"unmoved"`
+	Bytes    int64        `json:"bytes"`
+	Failures int          `json:"failures"`
+	Skipped  []reportPath `json:"skipped"`
+}
+
+// reportPath is a path that was skipped in the run, along with the reason.
+type reportPath struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// pathFailure is a failure that was skipped over (with --skip-failed).
+type pathFailure struct {
+	path string
+	err  error
+}
+
 type fileHashes struct {
 	srcHash    string
 	dstHash    string
 	verifyHash string
+	srcHashes  []fileDigest // All digests of the source, one per hashing algorithm.
+}
+
+type fileDigest struct {
+	algo string
+	hash string
 }
 
 // contextReader is an implementation of [io.Reader] that is Context-aware for
@@ -143,8 +1338,30 @@ type contextReader struct {
 func (cr *contextReader) Read(p []byte) (int, error) {
 	select {
 	case <-cr.ctx.Done():
-		return 0, context.Canceled
+		return 0, cr.ctx.Err() //nolint:wrapcheck
 	default:
 		return cr.reader.Read(p) //nolint:wrapcheck
 	}
 }
+
+// canonicalCase returns the relative path with each of its components cased
+// as they were first seen, as recorded in the known map (keyed by lower-cased
+// relative path). The second return value reports if the path differs only by
+// case from an already known path, otherwise the path is recorded as known.
+func canonicalCase(relPath string, known map[string]string) (string, bool) {
+	canonPath := relPath
+
+	// Any parent was already seen, so resolve it towards its first seen casing.
+	if parent, ok := known[strings.ToLower(filepath.Dir(relPath))]; ok {
+		canonPath = filepath.Join(parent, filepath.Base(relPath))
+	}
+
+	key := strings.ToLower(canonPath)
+	if first, ok := known[key]; ok {
+		return first, first != relPath
+	}
+
+	known[key] = canonPath
+
+	return canonPath, false
+}
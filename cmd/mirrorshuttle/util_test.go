@@ -7,9 +7,13 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,6 +33,30 @@ func (f fakeFileInfo) ModTime() time.Time { return f.modTime }
 func (f fakeFileInfo) IsDir() bool        { return f.isDir }
 func (f fakeFileInfo) Sys() any           { return f.sys }
 
+// symlinkFs is an [afero.Fs] that reports the given paths as symbolic links.
+type symlinkFs struct {
+	afero.Fs
+	links map[string]string
+}
+
+func (sfs *symlinkFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	if _, ok := sfs.links[name]; ok {
+		return fakeFileInfo{name: filepath.Base(name), mode: os.ModeSymlink | 0o777}, true, nil
+	}
+
+	e, err := sfs.Stat(name)
+
+	return e, true, err
+}
+
+func (sfs *symlinkFs) ReadlinkIfPossible(name string) (string, error) {
+	if link, ok := sfs.links[name]; ok {
+		return link, nil
+	}
+
+	return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+}
+
 // Expectation: The function should handle the exclusions according to the table's expectations.
 func Test_Unit_IsExcluded_Table(t *testing.T) {
 	t.Parallel()
@@ -87,6 +115,66 @@ func Test_Unit_IsExcluded_Table(t *testing.T) {
 			excludes: []string{"/tmp/cache"},
 			expected: true,
 		},
+		{
+			name:     "Single-star match",
+			path:     "/mnt/user/share/cache",
+			excludes: []string{"/mnt/user/*/cache"},
+			expected: true,
+		},
+		{
+			name:     "Single-star sub-path match",
+			path:     "/mnt/user/share/cache/file.txt",
+			excludes: []string{"/mnt/user/*/cache"},
+			expected: true,
+		},
+		{
+			name:     "Single-star spans only one component",
+			path:     "/mnt/user/share/sub/cache",
+			excludes: []string{"/mnt/user/*/cache"},
+			expected: false,
+		},
+		{
+			name:     "Double-star match at any depth",
+			path:     "/mnt/user/share/sub/cache/file.txt",
+			excludes: []string{"/mnt/user/**/cache"},
+			expected: true,
+		},
+		{
+			name:     "Double-star matching no components",
+			path:     "/mnt/user/cache",
+			excludes: []string{"/mnt/user/**/cache"},
+			expected: true,
+		},
+		{
+			name:     "Double-star outside of its root",
+			path:     "/mnt/other/share/cache",
+			excludes: []string{"/mnt/user/**/cache"},
+			expected: false,
+		},
+		{
+			name:     "Relative name pattern at any depth",
+			path:     "/mnt/user/share/file.tmp",
+			excludes: []string{"*.tmp"},
+			expected: true,
+		},
+		{
+			name:     "Relative name pattern not matching",
+			path:     "/mnt/user/share/file.txt",
+			excludes: []string{"*.tmp"},
+			expected: false,
+		},
+		{
+			name:     "Relative directory pattern at any depth",
+			path:     "/mnt/user/project/node_modules/pkg/index.js",
+			excludes: []string{"*/node_modules"},
+			expected: true,
+		},
+		{
+			name:     "Literal path with glob metacharacters",
+			path:     "/mnt/user/Movies [2020]/file.mkv",
+			excludes: []string{"/mnt/user/Movies [2020]"},
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -98,6 +186,140 @@ func Test_Unit_IsExcluded_Table(t *testing.T) {
 	}
 }
 
+// Expectation: The function should match the excludes case-insensitively, without changing the meaning of any character classes.
+func Test_Unit_MatchExclude_CaseInsensitive_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		path       string
+		excludes   []string
+		ignoreCase bool
+		expected   bool
+	}{
+		{"Path in another case", "/Real/Incoming/file.txt", []string{"/real/incoming"}, true, true},
+		{"Path in another case without ignoring case", "/Real/Incoming/file.txt", []string{"/real/incoming"}, false, false},
+		{"Pattern in another case", "/real/share/FILE.TMP", []string{"*.tmp"}, true, true},
+		{"Uppercase class matching lowercase", "/real/share/a1", []string{"[A-Z]1"}, true, true},
+		{"Uppercase class matching uppercase", "/real/share/A1", []string{"[A-Z]1"}, true, true},
+		{"Uppercase class not matching digit", "/real/share/11", []string{"[A-Z]1"}, true, false},
+		{"Uppercase class without ignoring case", "/real/share/a1", []string{"[A-Z]1"}, false, false},
+		{"Negated class in another case", "/real/share/a1", []string{"[^A-Z]1"}, true, false},
+		{"Negated class matching digit", "/real/share/11", []string{"[^A-Z]1"}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, excluded := matchExclude(tt.path, tt.excludes, tt.ignoreCase)
+			require.Equal(t, tt.expected, excluded)
+		})
+	}
+}
+
+// Expectation: The function should include the paths within, and the directories leading to, the included paths.
+func Test_Unit_IsIncluded_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		path     string
+		isDir    bool
+		includes includeArg
+		expected bool
+	}{
+		{"No includes", "/any/path", false, nil, true},
+		{"Exact match", "/real/media", true, includeArg{"/real/media"}, true},
+		{"Sub-path match", "/real/media/movies/file.mkv", false, includeArg{"/real/media"}, true},
+		{"Parent directory", "/real", true, includeArg{"/real/media"}, true},
+		{"Parent file name", "/real", false, includeArg{"/real/media"}, false},
+		{"Sibling directory", "/real/docs", true, includeArg{"/real/media"}, false},
+		{"Sibling with common prefix", "/real/mediaold", true, includeArg{"/real/media"}, false},
+		{"Second include", "/real/docs/file.txt", false, includeArg{"/real/media", "/real/docs"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			prog, _, _ := setupTestProgram(setupTestFs(), &programOptions{Includes: tt.includes})
+			require.Equal(t, tt.expected, prog.isIncluded(tt.path, tt.isDir))
+		})
+	}
+}
+
+// Expectation: The function should match the target globs according to the table's expectations.
+func Test_Unit_MatchTargetGlobs_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		relPath         string
+		patterns        []string
+		expectedMatch   bool
+		expectedDescend bool
+	}{
+		{
+			name:            "Root is an ancestor of a match",
+			relPath:         ".",
+			patterns:        []string{"projects/*/src"},
+			expectedMatch:   false,
+			expectedDescend: true,
+		},
+		{
+			name:            "Ancestor of a match",
+			relPath:         "projects/a",
+			patterns:        []string{"projects/*/src"},
+			expectedMatch:   false,
+			expectedDescend: true,
+		},
+		{
+			name:            "Exact match",
+			relPath:         "projects/a/src",
+			patterns:        []string{"projects/*/src"},
+			expectedMatch:   true,
+			expectedDescend: true,
+		},
+		{
+			name:            "Descendant of a match",
+			relPath:         "projects/a/src/deep",
+			patterns:        []string{"projects/*/src"},
+			expectedMatch:   true,
+			expectedDescend: true,
+		},
+		{
+			name:            "Sibling of a match",
+			relPath:         "projects/a/docs",
+			patterns:        []string{"projects/*/src"},
+			expectedMatch:   false,
+			expectedDescend: false,
+		},
+		{
+			name:            "Double-star match at any depth",
+			relPath:         "projects/a/b/src",
+			patterns:        []string{"projects/**/src"},
+			expectedMatch:   true,
+			expectedDescend: true,
+		},
+		{
+			name:            "Second pattern matches",
+			relPath:         "media/photos",
+			patterns:        []string{"projects/*/src", "media/*"},
+			expectedMatch:   true,
+			expectedDescend: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			matched, descend := matchTargetGlobs(tt.relPath, tt.patterns)
+			require.Equal(t, tt.expectedMatch, matched)
+			require.Equal(t, tt.expectedDescend, descend)
+		})
+	}
+}
+
 // Expectation: The function should report and skip errors, not return them.
 func Test_Unit_WalkError_SkipFailedTrue_Success(t *testing.T) {
 	t.Parallel()
@@ -113,7 +335,7 @@ func Test_Unit_WalkError_SkipFailedTrue_Success(t *testing.T) {
 		isDir: false,
 	}
 
-	result := prog.walkError(e, mockErr)
+	result := prog.walkError("/path", e, mockErr)
 
 	require.NoError(t, result)
 	require.True(t, prog.state.hasPartialFailures)
@@ -135,7 +357,7 @@ func Test_Unit_WalkError_SkipFailedTrueDir_Success(t *testing.T) {
 		isDir: true,
 	}
 
-	result := prog.walkError(e, mockErr)
+	result := prog.walkError("/path", e, mockErr)
 
 	require.Equal(t, filepath.SkipDir, result)
 	require.True(t, prog.state.hasPartialFailures)
@@ -155,7 +377,7 @@ func Test_Unit_WalkError_SkipFailedTrueCtx_Error(t *testing.T) {
 		isDir: false,
 	}
 
-	result := prog.walkError(e, context.Canceled)
+	result := prog.walkError("/path", e, context.Canceled)
 
 	require.Equal(t, context.Canceled, result)
 	require.False(t, prog.state.hasPartialFailures)
@@ -177,13 +399,37 @@ func Test_Unit_WalkError_SkipFailedFalse_Error(t *testing.T) {
 		isDir: false,
 	}
 
-	result := prog.walkError(e, mockErr)
+	result := prog.walkError("/path", e, mockErr)
 
 	require.Equal(t, mockErr, result)
 	require.False(t, prog.state.hasPartialFailures)
 	require.NotContains(t, stdout.String(), "skipped")
 }
 
+// Expectation: The function should capture all of the skipped failures along with their paths.
+func Test_Unit_WalkError_SkipFailedMultiple_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	opts := &programOptions{SkipFailed: true}
+	prog, _, _ := setupTestProgram(fs, opts)
+
+	mockErr1 := errors.New("mock error 1")
+	mockErr2 := errors.New("mock error 2")
+
+	result := prog.walkError("/path/file1", &fakeFileInfo{isDir: false}, mockErr1)
+	require.NoError(t, result)
+
+	result = prog.walkError("/path/dir", &fakeFileInfo{isDir: true}, mockErr2)
+	require.Equal(t, filepath.SkipDir, result)
+
+	require.Equal(t, []pathFailure{
+		{path: "/path/file1", err: mockErr1},
+		{path: "/path/dir", err: mockErr2},
+	}, prog.state.failures)
+}
+
 // Expectation: The function should parse the log level according to the table's expectations.
 func Test_Unit_ParseLogLevel_Table(t *testing.T) {
 	t.Parallel()
@@ -221,6 +467,101 @@ func Test_Unit_ParseLogLevel_Table(t *testing.T) {
 	}
 }
 
+// Expectation: The function should parse sizes with binary suffixes within the allowed range.
+func Test_Unit_ParseByteSize_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input       string
+		expected    int
+		expectError bool
+	}{
+		{"4096", 4096, false},
+		{"64K", 64 << 10, false},
+		{" 64KiB ", 64 << 10, false},
+		{"4m", 4 << 20, false},
+		{"4MiB", 4 << 20, false},
+		{"256MiB", 256 << 20, false},
+		{"1G", 0, true},
+		{"1K", 0, true},
+		{"", 0, true},
+		{"MiB", 0, true},
+		{"4MB", 0, true},
+		{"-4MiB", 0, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+
+			size, err := parseByteSize(tc.input)
+
+			if tc.expectError {
+				require.ErrorIs(t, err, errArgCopyBufferSizeInvalid)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, size)
+			}
+		})
+	}
+}
+
+// Expectation: The function should match the names according to the table's expectations.
+func Test_Unit_MatchExcludeName_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		base       string
+		names      []string
+		ignoreCase bool
+		expected   bool
+	}{
+		{"Thumbs.db", []string{"Thumbs.db"}, false, true},
+		{"thumbs.db", []string{"Thumbs.db"}, false, false},
+		{"thumbs.db", []string{"Thumbs.db"}, true, true},
+		{"Thumbs.db.bak", []string{"Thumbs.db"}, true, false},
+		{"Cafe\u0301", []string{normalizeName("Caf\u00e9")}, false, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.base, func(t *testing.T) {
+			t.Parallel()
+
+			_, matched := matchExcludeName(tc.base, tc.names, tc.ignoreCase)
+			require.Equal(t, tc.expected, matched)
+		})
+	}
+}
+
+// Expectation: The function should parse the sizes according to the table's expectations.
+func Test_Unit_ParseBytes_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input    string
+		expected int64
+		valid    bool
+	}{
+		{"0", 0, true},
+		{"512B", 512, true},
+		{"500GiB", 500 << 30, true},
+		{"2T", 2 << 40, true},
+		{"8388608TiB", 0, false},
+		{"-1", 0, false},
+		{"1.5G", 0, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+
+			size, ok := parseBytes(tc.input)
+			require.Equal(t, tc.valid, ok)
+			require.Equal(t, tc.expected, size)
+		})
+	}
+}
+
 // Expectation: The function should calculate the depth level according to the table's expectations.
 func Test_Unit_DirDepth_Table(t *testing.T) {
 	t.Parallel()
@@ -361,3 +702,205 @@ func Test_Unit_IsEmptyStructure_NonExistentPath_Error(t *testing.T) {
 	require.ErrorIs(t, err, os.ErrNotExist)
 	require.False(t, empty)
 }
+
+// Expectation: The function should refuse a target root that is a symbolic link by default.
+func Test_Unit_CheckTargetRoot_Symlink_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := &symlinkFs{Fs: setupTestFs(), links: map[string]string{"/mnt/user": "/mnt/cache/user"}}
+	err := createDirStructure(fs, []string{"/mnt/cache/user"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/mnt/user",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.checkTargetRoot()
+	require.ErrorIs(t, err, errTargetIsSymlink)
+
+	require.Equal(t, "/mnt/user", prog.opts.RealRoot)
+}
+
+// Expectation: The function should resolve a target root that is a symbolic link, if allowed.
+func Test_Unit_CheckTargetRoot_SymlinkAllowed_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := &symlinkFs{Fs: setupTestFs(), links: map[string]string{"/mnt/user": "cache/user"}}
+	err := createDirStructure(fs, []string{"/mnt/cache/user"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot:           "/mnt/user/mirror",
+		RealRoot:             "/mnt/user",
+		Excludes:             []string{"/mnt/user/skip", "/other"},
+		AllowSymlinkedTarget: true,
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	err = prog.checkTargetRoot()
+	require.NoError(t, err)
+
+	require.Equal(t, "/mnt/cache/user", prog.opts.RealRoot)
+	require.Equal(t, "/mnt/cache/user/mirror", prog.opts.MirrorRoot)
+	require.Equal(t, []string{"/mnt/cache/user/skip", "/other"}, []string(prog.opts.Excludes))
+	require.Contains(t, stderr.String(), "target_is_symlink")
+}
+
+// Expectation: The function should accept a target root that is not a symbolic link.
+func Test_Unit_CheckTargetRoot_NoSymlink_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := &symlinkFs{Fs: setupTestFs(), links: map[string]string{"/mnt/user": "/mnt/cache/user"}}
+	err := createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	opts := &programOptions{
+		MirrorRoot: "/mirror",
+		RealRoot:   "/real",
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	err = prog.checkTargetRoot()
+	require.NoError(t, err)
+
+	require.Equal(t, "/real", prog.opts.RealRoot)
+}
+
+// Expectation: The function should keep advancing the heartbeat file's modification time while running.
+func Test_Unit_StartHeartbeat_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+
+	opts := &programOptions{
+		HeartbeatFile:     "/heartbeat",
+		HeartbeatInterval: 10 * time.Millisecond,
+	}
+
+	prog, _, _ := setupTestProgram(fs, opts)
+	stop := prog.startHeartbeat(t.Context())
+
+	// The heartbeat file is created right away.
+	e, err := fs.Stat("/heartbeat")
+	require.NoError(t, err)
+	first := e.ModTime()
+
+	// Simulate a slow run, during which the heartbeat needs to advance.
+	require.Eventually(t, func() bool {
+		e, err := fs.Stat("/heartbeat")
+
+		return err == nil && e.ModTime().After(first)
+	}, time.Second, 5*time.Millisecond)
+
+	stop()
+
+	e, err = fs.Stat("/heartbeat")
+	require.NoError(t, err)
+	last := e.ModTime()
+
+	// Verify the heartbeat has stopped.
+	time.Sleep(30 * time.Millisecond)
+
+	e, err = fs.Stat("/heartbeat")
+	require.NoError(t, err)
+	require.Equal(t, last, e.ModTime())
+}
+
+// Expectation: Strings that are not valid UTF-8 should be encoded according to the path encoding.
+func Test_Unit_EncodeInvalidUTF8_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		encoding string
+		want     string
+	}{
+		{"valid-escape", "/mirror/café.txt", pathEncodingEscape, "/mirror/café.txt"},
+		{"valid-base64", "/mirror/café.txt", pathEncodingBase64, "/mirror/café.txt"},
+		{"latin1-escape", "/mirror/caf\xe9.txt", pathEncodingEscape, `/mirror/caf\xe9.txt`},
+		{"mixed-escape", "/mirror/é/\xff\xfe.txt", pathEncodingEscape, `/mirror/é/\xff\xfe.txt`},
+		{"latin1-base64", "caf\xe9", pathEncodingBase64, "base64:Y2Fm6Q=="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := encodeInvalidUTF8(tt.input, tt.encoding)
+			require.Equal(t, tt.want, got)
+			require.True(t, utf8.ValidString(got))
+		})
+	}
+}
+
+// Expectation: Redundant excludes should be pruned, without changing which paths are excluded.
+func Test_Unit_PruneExcludes_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	excludes := excludeArg{"/a/b", "/c/d/e", "/a", "/ab", "/c", "/a", "/a/b/c"}
+
+	opts := &programOptions{
+		Excludes: slices.Clone(excludes),
+	}
+
+	prog, _, stderr := setupTestProgram(fs, opts)
+	prog.log = slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	prog.pruneExcludes()
+	require.ElementsMatch(t, excludeArg{"/a", "/ab", "/c"}, prog.opts.Excludes)
+	require.Equal(t, 4, strings.Count(stderr.String(), "redundant exclude pruned"))
+
+	for _, path := range []string{"/a", "/a/x", "/a/b/c/d", "/ab", "/ab/x", "/abc", "/b", "/c/d", "/cd", "/"} {
+		require.Equal(t, isExcluded(path, excludes), isExcluded(path, prog.opts.Excludes), path)
+	}
+}
+
+// Expectation: The target root should be checked against the required permissions.
+func Test_Unit_CheckTargetPerms_Table(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		actual   os.FileMode
+		required string
+		wantErr  error
+	}{
+		{"exact-match", 0o755, "0755", nil},
+		{"exact-mismatch", 0o775, "0755", errTargetPermsMismatch},
+		{"exact-stricter", 0o700, "755", errTargetPermsMismatch},
+		{"at-most-equal", 0o755, "max:0755", nil},
+		{"at-most-stricter", 0o700, "max:0755", nil},
+		{"at-most-group-writable", 0o775, "max:0755", errTargetPermsMismatch},
+		{"at-most-world-readable", 0o754, "max:0750", errTargetPermsMismatch},
+		{"invalid", 0o755, "0999", errArgTargetPermsInvalid},
+		{"unset", 0o777, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := setupTestFs()
+			require.NoError(t, fs.Mkdir("/real", 0o755))
+			require.NoError(t, fs.Chmod("/real", tt.actual))
+
+			opts := &programOptions{
+				RealRoot:           "/real",
+				RequireTargetPerms: tt.required,
+			}
+
+			prog, _, _ := setupTestProgram(fs, opts)
+			err := prog.checkTargetPerms()
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
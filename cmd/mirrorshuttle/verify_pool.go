@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// verifyJob is a file that was copied and renamed into place, awaiting its
+// --verify pass in the [verifyPool] before its source can be removed.
+type verifyJob struct {
+	src        string
+	dst        string
+	info       os.FileInfo
+	hashes     fileHashes
+	dedupeHash string
+	trashPath  string // The replaced target file within the --target-trash-dir, if any.
+	err        error  // The outcome of the verify pass, as set by the pool.
+}
+
+// verifyPool runs the --verify passes of moved files concurrently (with the
+// --verify-concurrency setting), while the files themselves are still moved
+// one by one. Only the re-reading happens within the pool's goroutines, all
+// of the results are reconciled (and the sources removed) by the caller.
+type verifyPool struct {
+	jobs    chan *verifyJob
+	results chan *verifyJob
+	pending int // Jobs that were queued, but not yet reconciled.
+	wg      sync.WaitGroup
+}
+
+func (prog *program) startVerifyPool(ctx context.Context, workers int) {
+	// The channels hold all of the pending jobs, so that no goroutine can ever block on them.
+	pool := &verifyPool{
+		jobs:    make(chan *verifyJob, workers*verifyQueueFactor),
+		results: make(chan *verifyJob, workers*verifyQueueFactor),
+	}
+
+	for range workers {
+		pool.wg.Add(1)
+
+		go func() {
+			defer pool.wg.Done()
+
+			for job := range pool.jobs {
+				job.err = prog.verifyFile(ctx, job.dst, &job.hashes)
+				pool.results <- job
+			}
+		}()
+	}
+
+	prog.state.verifyPool = pool
+}
+
+// copyAndQueueVerify copies a file into its destination like [copyAndRemove],
+// but leaves its verify pass and the removal of its source to the verify pool.
+func (prog *program) copyAndQueueVerify(ctx context.Context, path string, movePath string, e os.FileInfo, dedupeHash string) error {
+	retHashes, workingFile, err := prog.copyToWorkingFile(ctx, path, movePath)
+	if errors.Is(err, errWorkingFileExists) {
+		return prog.skipExistingWorkingFile(path, movePath)
+	} else if err != nil {
+		return prog.walkError(path, e, fmt.Errorf("failed to move: %q -x-> %q (%w)", path, movePath, err))
+	}
+
+	trashPath, err := prog.trashTargetFile(movePath)
+	if err != nil {
+		prog.removeWorkingFile(path, workingFile)
+
+		return prog.walkError(path, e, err)
+	}
+
+	if err := prog.fsys.Rename(workingFile, movePath); err != nil {
+		prog.removeWorkingFile(path, workingFile)
+		prog.restoreTrashedFile(movePath, trashPath)
+		err = fmt.Errorf("failed to rename: %q -x-> %q (%w)", workingFile, movePath, err)
+
+		return prog.walkError(path, e, fmt.Errorf("failed to move: %q -x-> %q (%w)", path, movePath, err))
+	}
+
+	return prog.queueVerify(ctx, &verifyJob{
+		src:        path,
+		dst:        movePath,
+		info:       e,
+		hashes:     retHashes,
+		dedupeHash: dedupeHash,
+		trashPath:  trashPath,
+	})
+}
+
+// queueVerify hands a job to the verify pool, waiting for an earlier job first
+// if the pool is full, and then reconciles any of the jobs that have completed.
+func (prog *program) queueVerify(ctx context.Context, job *verifyJob) error {
+	pool := prog.state.verifyPool
+
+	var done []*verifyJob
+	if pool.pending == cap(pool.jobs) {
+		done = append(done, <-pool.results)
+		pool.pending--
+	}
+
+	pool.jobs <- job
+	pool.pending++
+
+	for collecting := true; collecting; {
+		select {
+		case r := <-pool.results:
+			done = append(done, r)
+			pool.pending--
+		default:
+			collecting = false
+		}
+	}
+
+	// All of the completed jobs need reconciling, even if one of them fails the operation.
+	var firstErr error
+	for _, job := range done {
+		if err := prog.reconcileVerify(ctx, job); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// finishVerifyPool waits for all of the pending jobs of the verify pool and
+// reconciles them, then stops the pool. It does nothing if no pool is running.
+func (prog *program) finishVerifyPool(ctx context.Context) error {
+	pool := prog.state.verifyPool
+	if pool == nil {
+		return nil
+	}
+	prog.state.verifyPool = nil
+
+	close(pool.jobs)
+
+	var firstErr error
+	for ; pool.pending > 0; pool.pending-- {
+		if err := prog.reconcileVerify(ctx, <-pool.results); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	pool.wg.Wait()
+
+	return firstErr
+}
+
+// reconcileVerify completes the move of a file after its verify pass, by then
+// removing its source, or removing its destination again if anything failed.
+func (prog *program) reconcileVerify(ctx context.Context, job *verifyJob) error {
+	err := job.err
+
+	if err == nil && prog.opts.StatBeforeRemove {
+		err = prog.statFile(job.src, job.dst)
+	}
+
+	if err == nil {
+		if rmErr := prog.fsys.Remove(job.src); rmErr != nil {
+			err = fmt.Errorf("failed to remove (after move): %q (%w)", job.src, rmErr)
+		}
+	}
+
+	if err != nil {
+		prog.discardFailedFile(job.src, job.dst, err)
+		prog.restoreTrashedFile(job.dst, job.trashPath)
+
+		return prog.walkError(job.src, job.info, fmt.Errorf("failed to move: %q -x-> %q (%w)", job.src, job.dst, err))
+	}
+
+	prog.logFileMoved("c+r", job.src, job.dst, job.hashes)
+	prog.countMoved(job.info.Size())
+	prog.rememberMovedHash(job.dedupeHash, job.dst)
+
+	return prog.finishMove(ctx, job.src, job.dst, job.hashes, job.info)
+}
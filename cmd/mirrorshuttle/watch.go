@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// watchTotals are the statistics accumulated over all of the cycles of a
+// --watch, as they are logged after each cycle and once the watch stops.
+type watchTotals struct {
+	cycles      int
+	createdDirs int
+	movedFiles  int
+	movedBytes  int64
+}
+
+// watch re-runs the move in cycles (with the --watch setting), waiting for the
+// --watch-interval between them, until it is stopped (with a first interrupt
+// signal, after the current cycle) or its context is cancelled. Each cycle
+// starts out with a fresh state, so failures of a cycle (such as the target
+// being unmounted) are retried in the next one; only the cancellation of the
+// context ends the watch with its error. The return code of the last cycle is
+// returned.
+func (prog *program) watch(ctx context.Context) (int, error) {
+	if prog.opts.ExplainExit != "" || prog.opts.ValidateConfig != "" {
+		return prog.run(ctx)
+	}
+
+	var totals watchTotals
+
+	var events <-chan struct{}
+	if prog.opts.WatchEvents {
+		// Watching starts before the first cycle, so no arriving files are missed.
+		trigger, stop, err := prog.startEventWatch(ctx)
+		if err != nil {
+			prog.log.Error("failed watching mirror", "op", prog.opts.Mode, "error", err, "error-type", "fatal")
+
+			return exitCodeFailure, fmt.Errorf("failed watching mirror: %w", err)
+		}
+		defer stop()
+		events = trigger
+	}
+
+	for {
+		prog.state = &programState{}
+
+		exitCode, err := prog.run(ctx)
+
+		totals.cycles++
+		totals.createdDirs += prog.state.createdDirs
+		totals.movedFiles += prog.state.movedFiles
+		totals.movedBytes += prog.state.movedBytes
+
+		if errors.Is(err, context.Canceled) {
+			return exitCode, err
+		}
+
+		prog.log.Info("watch cycle completed",
+			"op", prog.opts.Mode,
+			"cycle", totals.cycles,
+			"code", exitCode,
+			"total_dirs_created", totals.createdDirs,
+			"total_files_moved", totals.movedFiles,
+			"total_bytes_moved", totals.movedBytes,
+		)
+
+		if stopped, err := prog.awaitNextCycle(ctx, events); err != nil {
+			return exitCode, err
+		} else if !stopped {
+			continue
+		}
+
+		prog.log.Info("watch stopped",
+			"op", prog.opts.Mode,
+			"cycles", totals.cycles,
+			"total_dirs_created", totals.createdDirs,
+			"total_files_moved", totals.movedFiles,
+			"total_bytes_moved", totals.movedBytes,
+		)
+
+		return exitCode, nil
+	}
+}
+
+// awaitNextCycle waits for the --watch-interval (or the events, with the
+// --watch-events setting) before the next cycle of a --watch, returning true
+// if the watch was stopped in the meantime (or already during the last cycle).
+func (prog *program) awaitNextCycle(ctx context.Context, events <-chan struct{}) (bool, error) {
+	select {
+	case <-prog.stopWatch:
+		return true, nil
+	default:
+	}
+
+	var interval <-chan time.Time
+	if prog.opts.WatchInterval > 0 {
+		interval = prog.after(prog.opts.WatchInterval)
+	}
+
+	select {
+	case <-ctx.Done():
+		return false, fmt.Errorf("failed checking context: %w", ctx.Err())
+
+	case <-prog.stopWatch:
+		return true, nil
+
+	case <-interval:
+		return false, nil
+
+	case <-events:
+		return false, nil
+	}
+}
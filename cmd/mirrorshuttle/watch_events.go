@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/afero"
+)
+
+// eventWatcher watches directories for filesystem events (with the
+// --watch-events setting), as an interface so that it can be replaced.
+type eventWatcher interface {
+	Add(path string) error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	Close() error
+}
+
+// fsnotifyWatcher is the [eventWatcher] of the operating system.
+type fsnotifyWatcher struct {
+	w *fsnotify.Watcher
+}
+
+func newFsnotifyWatcher() (eventWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Wrapped by the caller.
+	}
+
+	return fsnotifyWatcher{w: w}, nil
+}
+
+func (fw fsnotifyWatcher) Add(path string) error {
+	return fw.w.Add(path) //nolint:wrapcheck // Wrapped by the caller.
+}
+
+func (fw fsnotifyWatcher) Events() <-chan fsnotify.Event {
+	return fw.w.Events
+}
+
+func (fw fsnotifyWatcher) Errors() <-chan error {
+	return fw.w.Errors
+}
+
+func (fw fsnotifyWatcher) Close() error {
+	return fw.w.Close() //nolint:wrapcheck // Nothing to add.
+}
+
+// startEventWatch starts watching the mirror tree for arriving files (with the
+// --watch-events setting), returning a channel that receives once no further
+// events were seen for the --watch-quiet-period, so that partially written
+// files are not grabbed, and a function that stops the watching again.
+func (prog *program) startEventWatch(ctx context.Context) (<-chan struct{}, func(), error) {
+	w, err := prog.newWatcher()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", errWatchEventsUnavailable, err)
+	}
+
+	if err := prog.addEventWatches(w, prog.opts.MirrorRoot); err != nil {
+		w.Close()
+
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	trigger := make(chan struct{}, 1)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		prog.debounceEvents(ctx, w, trigger)
+	}()
+
+	return trigger, func() {
+		cancel()
+		<-done
+		w.Close()
+	}, nil
+}
+
+// addEventWatches adds a directory and all of its subdirectories to the
+// watcher, as the events of a directory do not cover its subdirectories.
+func (prog *program) addEventWatches(w eventWatcher, root string) error {
+	return afero.Walk(prog.fsys, root, func(path string, e os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk: %q (%w)", path, err)
+		}
+
+		if !e.IsDir() {
+			return nil
+		}
+
+		if err := w.Add(path); err != nil {
+			return fmt.Errorf("failed to watch: %q (%w)", path, err)
+		}
+
+		return nil
+	})
+}
+
+// debounceEvents receives the events of the watcher until the context is
+// cancelled. Any new directories are added to the watcher, and the trigger
+// receives once the --watch-quiet-period has passed after the last event.
+// Removals are not considered, as these result from the moves themselves.
+func (prog *program) debounceEvents(ctx context.Context, w eventWatcher, trigger chan<- struct{}) {
+	var quiet <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case ev, ok := <-w.Events():
+			if !ok {
+				return
+			}
+
+			if !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Write) {
+				continue
+			}
+
+			if e, err := prog.fsys.Stat(ev.Name); err == nil && e.IsDir() {
+				// A new directory could already contain others (such as with mkdir -p).
+				if err := prog.addEventWatches(w, ev.Name); err != nil {
+					prog.log.Warn("directory not watched", "op", prog.opts.Mode, "path", ev.Name, "error", err, "reason", "error_occurred")
+				}
+			}
+			quiet = prog.after(prog.opts.WatchQuietPeriod)
+
+		case err, ok := <-w.Errors():
+			if !ok {
+				return
+			}
+			prog.log.Warn("watch event error", "op", prog.opts.Mode, "error", err)
+
+		case <-quiet:
+			quiet = nil
+
+			select {
+			case trigger <- struct{}{}:
+			default: // A cycle is already pending.
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWatcher is an [eventWatcher] that is driven by the synthetic events of a test.
+type fakeWatcher struct {
+	mu     sync.Mutex
+	added  []string
+	events chan fsnotify.Event
+	errors chan error
+}
+
+func (fw *fakeWatcher) Add(path string) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	fw.added = append(fw.added, path)
+
+	return nil
+}
+
+func (fw *fakeWatcher) watched(path string) bool {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	return slices.Contains(fw.added, path)
+}
+
+func (fw *fakeWatcher) Events() <-chan fsnotify.Event { return fw.events }
+func (fw *fakeWatcher) Errors() <-chan error          { return fw.errors }
+func (fw *fakeWatcher) Close() error                  { return nil }
+
+// Expectation: The program should re-run the move after each interval, accumulating the stats until it is stopped.
+func Test_Integ_Watch_TwoCycles_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{"/mirror/first.txt": "content"})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real"})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--watch", "--watch-interval=1h", "--log-format=logfmt"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	var waits []time.Duration
+	prog.after = func(d time.Duration) <-chan time.Time {
+		waits = append(waits, d)
+
+		if len(waits) == 2 {
+			// The second cycle has completed, so no further cycle is to be started.
+			close(prog.stopWatch)
+
+			return nil
+		}
+
+		// A new file arrives within the mirror before the next cycle.
+		require.NoError(t, createFiles(fs, map[string]string{"/mirror/second.txt": "content"}))
+
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+
+		return ch
+	}
+
+	exitCode, err := prog.watch(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, exitCodeSuccess, exitCode)
+
+	require.Equal(t, []time.Duration{time.Hour, time.Hour}, waits)
+	require.Contains(t, stderr.String(), "op=move cycle=2 code=0 total_dirs_created=0 total_files_moved=2")
+	require.Contains(t, stderr.String(), "msg=\"watch stopped\"")
+	require.Contains(t, stderr.String(), "op=move cycles=2")
+
+	for _, path := range []string{"/real/first.txt", "/real/second.txt"} {
+		_, err = fs.Stat(path)
+		require.NoError(t, err, path)
+	}
+
+	_, err = fs.Stat("/mirror/second.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Expectation: The program should reject --watch without a positive --watch-interval.
+func Test_Integ_NewProgram_WatchNoInterval_Error(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--watch"}
+
+	prog, err := newProgram(args, fs, nil, &stdout, &stderr)
+	require.ErrorIs(t, err, errArgWatchInvalid)
+	require.Nil(t, prog)
+}
+
+// Expectation: The program should start a cycle once the events of arriving files have been quiet, watching any new directories.
+func Test_Integ_Watch_Events_Success(t *testing.T) {
+	t.Parallel()
+
+	fs := setupTestFs()
+	err := createFiles(fs, map[string]string{"/mirror/first.txt": "content"})
+	require.NoError(t, err)
+	err = createDirStructure(fs, []string{"/real/new"})
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"program", "--mode=move", "--mirror=/mirror", "--target=/real", "--watch", "--watch-events", "--watch-quiet-period=1m"}
+
+	prog, _ := newProgram(args, fs, nil, &stdout, &stderr)
+	require.NotNil(t, prog)
+
+	watcher := &fakeWatcher{events: make(chan fsnotify.Event), errors: make(chan error)}
+	prog.newWatcher = func() (eventWatcher, error) {
+		return watcher, nil
+	}
+
+	quiet := make(chan time.Time)
+	prog.after = func(time.Duration) <-chan time.Time {
+		return quiet
+	}
+
+	type result struct {
+		exitCode int
+		err      error
+	}
+	done := make(chan result, 1)
+
+	go func() {
+		exitCode, err := prog.watch(t.Context())
+		done <- result{exitCode, err}
+	}()
+
+	// The first cycle runs right away, the mirror root is watched before it.
+	require.Eventually(t, func() bool {
+		_, err := fs.Stat("/real/first.txt")
+
+		return err == nil && watcher.watched("/mirror")
+	}, 5*time.Second, time.Millisecond)
+
+	require.NoError(t, createFiles(fs, map[string]string{"/mirror/new/second.txt": "content"}))
+	watcher.events <- fsnotify.Event{Name: "/mirror/new", Op: fsnotify.Create}
+	watcher.events <- fsnotify.Event{Name: "/mirror/new/second.txt", Op: fsnotify.Write}
+	require.True(t, watcher.watched("/mirror/new"))
+
+	// No cycle starts before the quiet period has passed.
+	_, err = fs.Stat("/real/new/second.txt")
+	require.ErrorIs(t, err, os.ErrNotExist)
+	quiet <- time.Now()
+
+	require.Eventually(t, func() bool {
+		_, err := fs.Stat("/real/new/second.txt")
+
+		return err == nil
+	}, 5*time.Second, time.Millisecond)
+	close(prog.stopWatch)
+
+	res := <-done
+	require.NoError(t, res.err)
+	require.Equal(t, exitCodeSuccess, res.exitCode)
+}
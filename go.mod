@@ -3,14 +3,18 @@ module github.com/desertwitch/mirrorshuttle
 go 1.24.1
 
 require (
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/lmittmann/tint v1.1.2
 	github.com/spf13/afero v1.14.0
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/sys v0.13.0
+	golang.org/x/text v0.23.0
 	gopkg.in/yaml.v3 v3.0.1
+	lukechampine.com/blake3 v1.4.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/text v0.23.0 // indirect
 )